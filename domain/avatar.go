@@ -56,17 +56,30 @@ type AvatarRotation struct {
 	Rotations []float32 `json:"rotations"`
 }
 
-// GetPublicAvatarByID gets avatar by id.
-func GetPublicAvatarByID(ctx context.Context, id int64) (Avatar, error) {
-	avatar := new(Avatar)
+// AvatarRepository is persistence of Avatar.
+type AvatarRepository interface {
+	GetPublicByID(ctx context.Context, id int64) (Avatar, error)
+	GetCurrentUsersByID(ctx context.Context, id int64, userID int64) (Avatar, error)
+	GetCurrentUsers(ctx context.Context, userID int64) ([]Avatar, error)
+	GetPublic(ctx context.Context) ([]Avatar, error)
+	Create(ctx context.Context, avatar *Avatar, user *User) error
+}
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return *avatar, err
-	}
+type avatarRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewAvatarRepository returns AvatarRepository uses the store.
+func NewAvatarRepository(store infrastructure.Datastore) AvatarRepository {
+	return &avatarRepository{store: store}
+}
+
+// GetPublicByID gets avatar by id.
+func (r *avatarRepository) GetPublicByID(ctx context.Context, id int64) (Avatar, error) {
+	avatar := new(Avatar)
 
 	key := datastore.IDKey("Avatar", id, nil)
-	if err := client.Get(ctx, key, avatar); err != nil {
+	if err := r.store.Get(ctx, key, avatar); err != nil {
 		if err == datastore.ErrNoSuchEntity {
 			return *avatar, AvatarNotFound
 		} else {
@@ -80,17 +93,12 @@ func GetPublicAvatarByID(ctx context.Context, id int64) (Avatar, error) {
 	return *avatar, nil
 }
 
-func GetCurrentUsersAvatarByID(ctx context.Context, id int64, userID int64) (Avatar, error) {
+func (r *avatarRepository) GetCurrentUsersByID(ctx context.Context, id int64, userID int64) (Avatar, error) {
 	avatar := new(Avatar)
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return *avatar, err
-	}
-
 	ancestor := datastore.IDKey("User", userID, nil)
 	key := datastore.IDKey("Avatar", id, ancestor)
-	if err := client.Get(ctx, key, avatar); err != nil {
+	if err := r.store.Get(ctx, key, avatar); err != nil {
 		if err == datastore.ErrNoSuchEntity {
 			return *avatar, AvatarNotFound
 		} else {
@@ -109,18 +117,13 @@ func GetCurrentUsersAvatarByID(ctx context.Context, id int64, userID int64) (Ava
 	return *avatar, nil
 }
 
-// GetCurrentUsersAvatars gets avatars belongs to user.
-func GetCurrentUsersAvatars(ctx context.Context, userID int64) ([]Avatar, error) {
+// GetCurrentUsers gets avatars belongs to user.
+func (r *avatarRepository) GetCurrentUsers(ctx context.Context, userID int64) ([]Avatar, error) {
 	var avatars []Avatar
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, err
-	}
-
 	ancestor := datastore.IDKey("User", userID, nil)
-	query := datastore.NewQuery("Avatar").Ancestor(ancestor).Order("-Created")
-	keys, err := client.GetAll(ctx, query, &avatars)
+	query := infrastructure.NewQuery("Avatar").Ancestor(ancestor).Order("-Created")
+	keys, err := r.store.GetAll(ctx, query, &avatars)
 	if err != nil {
 		return nil, err
 	}
@@ -138,17 +141,12 @@ func GetCurrentUsersAvatars(ctx context.Context, userID int64) ([]Avatar, error)
 	return avatars, nil
 }
 
-// GetPublicAvatars gets public avatars.
-func GetPublicAvatars(ctx context.Context) ([]Avatar, error) {
+// GetPublic gets public avatars.
+func (r *avatarRepository) GetPublic(ctx context.Context) ([]Avatar, error) {
 	var avatars []Avatar
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return avatars, err
-	}
-
-	query := datastore.NewQuery("Avatar").Filter("IsPublic =", true).Order("-Created")
-	keys, err := client.GetAll(ctx, query, &avatars)
+	query := infrastructure.NewQuery("Avatar").Filter("IsPublic =", true).Order("-Created")
+	keys, err := r.store.GetAll(ctx, query, &avatars)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +161,7 @@ func GetPublicAvatars(ctx context.Context) ([]Avatar, error) {
 
 func createAvatarPublicURL(id int64) string {
 	fileID := strconv.FormatInt(id, 10)
-	return infrastructure.CloudStorageURL + infrastructure.PublicBucketName() + "/avatar/" + fileID + ".zst"
+	return infrastructure.PublicURL(infrastructure.PublicBucketName(), "avatar/"+fileID+".zst")
 }
 
 func createAvatarSignedURLs(ctx context.Context, id int64) (string, error) {
@@ -171,7 +169,7 @@ func createAvatarSignedURLs(ctx context.Context, id int64) (string, error) {
 	filePath := infrastructure.StorageObjectFilePath("Avatar", fileID, "zst")
 	bucketName := infrastructure.MaterialBucketName()
 
-	url, err := infrastructure.GetGCSSignedURL(ctx, bucketName, filePath, "GET", "")
+	url, err := infrastructure.GetSignedURL(ctx, bucketName, filePath, "GET", "")
 	if err != nil {
 		return url, err
 	}
@@ -179,13 +177,8 @@ func createAvatarSignedURLs(ctx context.Context, id int64) (string, error) {
 	return url, nil
 }
 
-// CreateAvatar creates a new avatar belongs to user.
-func CreateAvatar(ctx context.Context, avatar *Avatar, user *User) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
+// Create creates a new avatar belongs to user.
+func (r *avatarRepository) Create(ctx context.Context, avatar *Avatar, user *User) error {
 	currentTime := time.Now()
 	avatar.IsPublic = false
 	avatar.Created = currentTime
@@ -193,7 +186,7 @@ func CreateAvatar(ctx context.Context, avatar *Avatar, user *User) error {
 
 	ancestor := datastore.IDKey("User", user.ID, nil)
 	key := datastore.IncompleteKey("Avatar", ancestor)
-	putKey, err := client.Put(ctx, key, avatar)
+	putKey, err := r.store.Put(ctx, key, avatar)
 	if err != nil {
 		return err
 	}
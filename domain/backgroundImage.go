@@ -4,7 +4,6 @@ import (
 	"context"
 	"strconv"
 
-	"cloud.google.com/go/datastore"
 	"github.com/super-dog-human/teraconnectgo/infrastructure"
 )
 
@@ -31,16 +30,26 @@ func (e BackgroundImageErrorCode) Error() string {
 	}
 }
 
-// GetAllBackgroundImagesはSortIDの照準でソートした全てのBackgroundImageを返します
-func GetAllBackgroundImages(ctx context.Context) ([]BackgroundImage, error) {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, err
-	}
+// BackgroundImageRepositoryは、BackgroundImageの参照を行います。
+type BackgroundImageRepository interface {
+	GetAll(ctx context.Context) ([]BackgroundImage, error)
+	GetOne(ctx context.Context) (BackgroundImage, error)
+}
+
+type backgroundImageRepository struct {
+	store infrastructure.Datastore
+}
 
+// NewBackgroundImageRepositoryは、storeを使用するBackgroundImageRepositoryを返します。
+func NewBackgroundImageRepository(store infrastructure.Datastore) BackgroundImageRepository {
+	return &backgroundImageRepository{store: store}
+}
+
+// GetAllはSortIDの照準でソートした全てのBackgroundImageを返します
+func (r *backgroundImageRepository) GetAll(ctx context.Context) ([]BackgroundImage, error) {
 	var images []BackgroundImage
-	query := datastore.NewQuery("BackgroundImage").Order("SortID")
-	keys, err := client.GetAll(ctx, query, &images)
+	query := infrastructure.NewQuery("BackgroundImage").Order("SortID")
+	keys, err := r.store.GetAll(ctx, query, &images)
 	if err != nil {
 		return nil, err
 	}
@@ -53,18 +62,13 @@ func GetAllBackgroundImages(ctx context.Context) ([]BackgroundImage, error) {
 	return images, nil
 }
 
-// GetBackgroundImageは、ソートを行わずに一つだけBackGroundImageを返します
-func GetBackgroundImage(ctx context.Context) (BackgroundImage, error) {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-
+// GetOneは、ソートを行わずに一つだけBackGroundImageを返します
+func (r *backgroundImageRepository) GetOne(ctx context.Context) (BackgroundImage, error) {
 	var backgroundImage BackgroundImage
-	if err != nil {
-		return backgroundImage, err
-	}
 
 	var images []BackgroundImage
-	query := datastore.NewQuery("BackgroundImage").Order("SortID").Limit(1)
-	keys, err := client.GetAll(ctx, query, &images)
+	query := infrastructure.NewQuery("BackgroundImage").Order("SortID").Limit(1)
+	keys, err := r.store.GetAll(ctx, query, &images)
 	if err != nil {
 		return backgroundImage, err
 	}
@@ -19,16 +19,27 @@ type BackgroundMusic struct {
 	Created  time.Time `json:"created"`
 }
 
-// GetPublicBackgroundMusics is return sorted public musics.
-func GetPublicBackgroundMusics(ctx context.Context) ([]BackgroundMusic, error) {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, err
-	}
+// BackgroundMusicRepository is persistence of BackgroundMusic.
+type BackgroundMusicRepository interface {
+	GetPublic(ctx context.Context) ([]BackgroundMusic, error)
+	GetCurrentUsers(ctx context.Context, userID int64) ([]BackgroundMusic, error)
+	Create(ctx context.Context, userID int64, backgroundMusic *BackgroundMusic) error
+}
+
+type backgroundMusicRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewBackgroundMusicRepository returns BackgroundMusicRepository uses the store.
+func NewBackgroundMusicRepository(store infrastructure.Datastore) BackgroundMusicRepository {
+	return &backgroundMusicRepository{store: store}
+}
 
+// GetPublic is return sorted public musics.
+func (r *backgroundMusicRepository) GetPublic(ctx context.Context) ([]BackgroundMusic, error) {
 	var musics []BackgroundMusic
-	query := datastore.NewQuery("BackgroundMusic").Filter("IsPublic =", true).Order("SortID")
-	keys, err := client.GetAll(ctx, query, &musics)
+	query := infrastructure.NewQuery("BackgroundMusic").Filter("IsPublic =", true).Order("SortID")
+	keys, err := r.store.GetAll(ctx, query, &musics)
 	if err != nil {
 		return nil, err
 	}
@@ -41,16 +52,11 @@ func GetPublicBackgroundMusics(ctx context.Context) ([]BackgroundMusic, error) {
 	return musics, nil
 }
 
-func GetCurrentUsersBackgroundMusics(ctx context.Context, userID int64) ([]BackgroundMusic, error) {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, err
-	}
-
+func (r *backgroundMusicRepository) GetCurrentUsers(ctx context.Context, userID int64) ([]BackgroundMusic, error) {
 	var musics []BackgroundMusic
 	ancestor := datastore.IDKey("User", userID, nil)
-	query := datastore.NewQuery("BackgroundMusic").Ancestor(ancestor).Order("-Created")
-	keys, err := client.GetAll(ctx, query, &musics)
+	query := infrastructure.NewQuery("BackgroundMusic").Ancestor(ancestor).Order("-Created")
+	keys, err := r.store.GetAll(ctx, query, &musics)
 	if err != nil {
 		return nil, err
 	}
@@ -67,17 +73,12 @@ func GetCurrentUsersBackgroundMusics(ctx context.Context, userID int64) ([]Backg
 	return musics, nil
 }
 
-func CreateBackgroundMusic(ctx context.Context, userID int64, backgroundMusic *BackgroundMusic) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
+func (r *backgroundMusicRepository) Create(ctx context.Context, userID int64, backgroundMusic *BackgroundMusic) error {
 	backgroundMusic.Created = time.Now()
 
 	ancestor := datastore.IDKey("User", userID, nil)
 	key := datastore.IncompleteKey("BackgroundMusic", ancestor)
-	putKey, err := client.Put(ctx, key, backgroundMusic)
+	putKey, err := r.store.Put(ctx, key, backgroundMusic)
 	if err != nil {
 		return err
 	}
@@ -92,7 +93,7 @@ func getBackgroundMusicSignedURL(ctx context.Context, id int64) (string, error)
 	filePath := infrastructure.StorageObjectFilePath("bgm", fileID, "mp3")
 	fileType := "" // this is unnecessary when GET request
 	bucketName := infrastructure.MaterialBucketName()
-	url, err := infrastructure.GetGCSSignedURL(ctx, bucketName, filePath, "GET", fileType)
+	url, err := infrastructure.GetSignedURL(ctx, bucketName, filePath, "GET", fileType)
 
 	if err != nil {
 		return "", err
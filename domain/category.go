@@ -10,13 +10,18 @@ import (
 type CategoryErrorCode uint
 
 const (
-	CategoryNotFound CategoryErrorCode = 1
+	CategoryNotFound           CategoryErrorCode = 1
+	TooManySecondaryCategories CategoryErrorCode = 2
 )
 
+const secondaryCategoriesMaxCount = 3
+
 func (e CategoryErrorCode) Error() string {
 	switch e {
 	case CategoryNotFound:
 		return "category not found"
+	case TooManySecondaryCategories:
+		return "too many secondary categories"
 	default:
 		return "unknown category error"
 	}
@@ -41,16 +46,27 @@ type ShortCategory struct {
 	SortID      int64  `json:"-"`
 }
 
-// GetJapaneseCategories is return categories by the subject.
-func GetJapaneseCategories(ctx context.Context, subjectID int64) ([]ShortCategory, error) {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, err
-	}
+// CategoryRepository is reference of categories.
+type CategoryRepository interface {
+	GetJapaneseCategories(ctx context.Context, subjectID int64) ([]ShortCategory, error)
+	GetAllJapaneseCategories(ctx context.Context) ([]ShortCategory, error)
+	GetJapaneseCategory(ctx context.Context, id int64, subjectID int64) (Category, error)
+}
+
+type categoryRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewCategoryRepository returns CategoryRepository uses the store.
+func NewCategoryRepository(store infrastructure.Datastore) CategoryRepository {
+	return &categoryRepository{store: store}
+}
 
+// GetJapaneseCategories is return categories by the subject.
+func (r *categoryRepository) GetJapaneseCategories(ctx context.Context, subjectID int64) ([]ShortCategory, error) {
 	var categories []ShortCategory
-	query := datastore.NewQuery("JapaneseCategory").Filter("SubjectID =", subjectID).Order("SortID")
-	keys, err := client.GetAll(ctx, query, &categories)
+	query := infrastructure.NewQuery("JapaneseCategory").Filter("SubjectID =", subjectID).Order("SortID")
+	keys, err := r.store.GetAll(ctx, query, &categories)
 	if err != nil {
 		return nil, err
 	}
@@ -63,15 +79,10 @@ func GetJapaneseCategories(ctx context.Context, subjectID int64) ([]ShortCategor
 }
 
 // GetAllJapaneseCategories is return all sorted categories.
-func GetAllJapaneseCategories(ctx context.Context) ([]ShortCategory, error) {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, err
-	}
-
+func (r *categoryRepository) GetAllJapaneseCategories(ctx context.Context) ([]ShortCategory, error) {
 	var categories []ShortCategory
-	query := datastore.NewQuery("JapaneseCategory").Order("SubjectID").Order("SortID")
-	keys, err := client.GetAll(ctx, query, &categories)
+	query := infrastructure.NewQuery("JapaneseCategory").Order("SubjectID").Order("SortID")
+	keys, err := r.store.GetAll(ctx, query, &categories)
 	if err != nil {
 		return nil, err
 	}
@@ -84,16 +95,11 @@ func GetAllJapaneseCategories(ctx context.Context) ([]ShortCategory, error) {
 }
 
 // GetJapaneseCategory is return a category from id.
-func GetJapaneseCategory(ctx context.Context, id int64, subjectID int64) (Category, error) {
+func (r *categoryRepository) GetJapaneseCategory(ctx context.Context, id int64, subjectID int64) (Category, error) {
 	category := new(Category)
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return *category, err
-	}
-
 	key := datastore.IDKey("JapaneseCategory", id, nil)
-	if err := client.Get(ctx, key, category); err != nil {
+	if err := r.store.Get(ctx, key, category); err != nil {
 		if err == datastore.ErrNoSuchEntity {
 			return *category, CategoryNotFound
 		}
@@ -1,27 +1,513 @@
 package domain
 
 import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
 	"time"
 
 	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
 )
 
 // DeleteOrderはエンティティの削除予約を作成します。LessonまたはUserの関連エンティティの定時削除に使用されることを想定しています。
 type DeleteOrder struct {
-	ID         int64 `datastore:"-"`
-	EntityName string
-	TargetID   int64 `datastore:",noindex"`
-	Created    time.Time
+	ID             int64 `datastore:"-"`
+	EntityName     string
+	TargetID       int64     `datastore:",noindex"`
+	CompletedSteps []string  `datastore:",noindex"` // 処理済みの削除ステップ。中断した場合は未処理のステップから再開する
+	LastError      string    `datastore:",noindex"`
+	NotBefore      time.Time // この日時より前は処理しない。ゴミ箱に移動したLessonの完全な削除に使用する。即座に処理するものは作成日時
+	Created        time.Time
+	Updated        time.Time `datastore:",noindex"`
 }
 
-func CreateDeleteLessonOrderInTransaction(tx *datastore.Transaction, lessonID int64) error {
+type DeleteOrderErrorCode uint
+
+const (
+	UnknownDeleteOrderEntity DeleteOrderErrorCode = 1
+	DeleteOrderNotDue        DeleteOrderErrorCode = 2
+)
+
+func (e DeleteOrderErrorCode) Error() string {
+	switch e {
+	case UnknownDeleteOrderEntity:
+		return "unknown entity name of delete order"
+	case DeleteOrderNotDue:
+		return "delete order is not due yet"
+	default:
+		return "unknown delete order error"
+	}
+}
+
+// DeleteOrderQueueは、DeleteOrderを処理するタスクのキューです。
+var DeleteOrderQueue = infrastructure.QueueConfig{
+	Name:        "deleteOrder",
+	RelativeURI: "/delete_orders",
+	Retry:       infrastructure.RetryPolicy{MaxAttempts: 10, MinBackoff: time.Minute, MaxBackoff: time.Hour},
+}
+
+// datastoreのDeleteMultiで一度に削除できるエンティティの上限
+const deleteOrderBatchSize = 500
+
+type deleteOrderStep struct {
+	name string
+	run  func(ctx context.Context, targetID int64) error
+}
+
+// DeleteOrderRepositoryは、DeleteOrderの永続化と、削除予約されたエンティティに関連するデータの削除を行います。
+type DeleteOrderRepository interface {
+	CreateLessonOrderInTransaction(tx infrastructure.Transaction, lessonID int64) error
+	CreateUserOrderInTransaction(tx infrastructure.Transaction, userID int64) error
+	GetPending(ctx context.Context, currentTime time.Time, limit int) ([]DeleteOrder, error)
+	RefreshAll(ctx context.Context) (int, error)
+	Process(ctx context.Context, order *DeleteOrder) error
+}
+
+type deleteOrderRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewDeleteOrderRepositoryは、storeを使用するDeleteOrderRepositoryを返します。
+func NewDeleteOrderRepository(store infrastructure.Datastore) DeleteOrderRepository {
+	return &deleteOrderRepository{store: store}
+}
+
+func (r *deleteOrderRepository) CreateLessonOrderInTransaction(tx infrastructure.Transaction, lessonID int64) error {
+	return createDeleteOrderInTransaction(tx, "Lesson", lessonID, time.Time{})
+}
+
+func (r *deleteOrderRepository) CreateUserOrderInTransaction(tx infrastructure.Transaction, userID int64) error {
+	return createDeleteOrderInTransaction(tx, "User", userID, time.Time{})
+}
+
+// GetPendingは、currentTimeに処理できる未処理のDeleteOrderを処理日時の順に最大limit件返します。
+func (r *deleteOrderRepository) GetPending(ctx context.Context, currentTime time.Time, limit int) ([]DeleteOrder, error) {
+	var orders []DeleteOrder
+
+	query := infrastructure.NewQuery("DeleteOrder").Filter("NotBefore <=", currentTime).Order("NotBefore").Limit(limit)
+	keys, err := r.store.GetAll(ctx, query, &orders)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		orders[i].ID = key.ID
+	}
+
+	return orders, nil
+}
+
+// RefreshAllは、全てのDeleteOrderを保存し直し、保存した件数を返します。
+// NotBeforeがインデックスされる前に作成されたDeleteOrderも、GetPendingで取得できるようになります。
+func (r *deleteOrderRepository) RefreshAll(ctx context.Context) (int, error) {
+	var orders []DeleteOrder
+	keys, err := r.store.GetAll(ctx, infrastructure.NewQuery("DeleteOrder"), &orders)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range orders {
+		if orders[i].NotBefore.IsZero() {
+			orders[i].NotBefore = orders[i].Created
+		}
+	}
+
+	for start := 0; start < len(keys); start += deleteOrderBatchSize {
+		end := start + deleteOrderBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if _, err := r.store.PutMulti(ctx, keys[start:end], orders[start:end]); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(keys), nil
+}
+
+// Processは、orderの対象に関連するエンティティとファイルを削除し、全て完了したらorderを削除します。
+// 各ステップは冪等で、完了したステップはorderに記録されるので、途中で失敗しても再実行で続きから処理されます。
+// orderの処理日時になっていない場合はDeleteOrderNotDueを返します。
+func (r *deleteOrderRepository) Process(ctx context.Context, order *DeleteOrder) error {
+	var steps []deleteOrderStep
+	switch order.EntityName {
+	case "Lesson":
+		steps = r.lessonSteps()
+	case "User":
+		steps = r.userSteps()
+	default:
+		return UnknownDeleteOrderEntity
+	}
+
+	currentTime := time.Now()
+	if !order.IsDue(currentTime) {
+		return DeleteOrderNotDue
+	}
+
+	key := datastore.IDKey("DeleteOrder", order.ID, nil)
+	if order.EntityName == "Lesson" && len(order.CompletedSteps) == 0 {
+		if err := r.checkTrashedLesson(ctx, key, order, currentTime); err != nil {
+			return err
+		}
+	}
+
+	for _, step := range steps {
+		if order.hasCompleted(step.name) {
+			continue
+		}
+
+		if err := step.run(ctx, order.TargetID); err != nil {
+			order.LastError = fmt.Sprintf("%s: %v", step.name, err)
+			order.Updated = time.Now()
+			if _, putErr := r.store.Put(ctx, key, order); putErr != nil {
+				return putErr
+			}
+			return err
+		}
+
+		order.CompletedSteps = append(order.CompletedSteps, step.name)
+		order.LastError = ""
+		order.Updated = time.Now()
+		if _, err := r.store.Put(ctx, key, order); err != nil {
+			return err
+		}
+	}
+
+	return r.store.Delete(ctx, key)
+}
+
+// IsDueは、currentTimeにorderを処理できるかを返します。
+func (o *DeleteOrder) IsDue(currentTime time.Time) bool {
+	return !currentTime.Before(o.NotBefore)
+}
+
+// checkTrashedLessonは、ゴミ箱に移動したLessonのorderを処理してよいかを確認します。
+// Lessonが復元済みの場合はorderを削除し、復元できる期間が延びていた場合は処理日時を更新して、どちらもDeleteOrderNotDueを返します。
+func (r *deleteOrderRepository) checkTrashedLesson(ctx context.Context, key *datastore.Key, order *DeleteOrder, currentTime time.Time) error {
+	var lesson Lesson
+	if err := r.store.Get(ctx, datastore.IDKey("Lesson", order.TargetID, nil), &lesson); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return nil
+		}
+		return err
+	}
+
+	if lesson.Status != LessonStatusDeleted {
+		if err := r.store.Delete(ctx, key); err != nil {
+			return err
+		}
+		return DeleteOrderNotDue
+	}
+
+	if restorableUntil := lessonRestorableUntil(&lesson); currentTime.Before(restorableUntil) {
+		order.NotBefore = restorableUntil
+		order.Updated = currentTime
+		if _, err := r.store.Put(ctx, key, order); err != nil {
+			return err
+		}
+		return DeleteOrderNotDue
+	}
+
+	return nil
+}
+
+func (o *DeleteOrder) hasCompleted(stepName string) bool {
+	for _, name := range o.CompletedSteps {
+		if name == stepName {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *deleteOrderRepository) lessonSteps() []deleteOrderStep {
+	return []deleteOrderStep{
+		{name: "lesson", run: r.deleteLesson},
+		{name: "lessonMaterials", run: r.deleteLessonMaterials},
+		{name: "lessonVersions", run: r.deleteLessonVersions},
+		{name: "lessonSchedule", run: r.deleteLessonSchedule},
+		{name: "lessonCollaborators", run: r.deleteLessonCollaborators},
+		{name: "shareLinks", run: r.deleteLessonShareLinks},
+		{name: "lessonReactions", run: r.deleteLessonReactions},
+		{name: "lessonComments", run: r.deleteLessonComments},
+		{name: "graphics", run: r.deleteLessonGraphics},
+		{name: "voices", run: r.deleteLessonVoices},
+		{name: "files", run: r.deleteLessonFiles},
+		{name: "searchIndex", run: r.deleteLessonSearchIndex},
+	}
+}
+
+func (r *deleteOrderRepository) userSteps() []deleteOrderStep {
+	return []deleteOrderStep{
+		{name: "lessons", run: r.deleteUserLessons},
+		{name: "series", run: r.deleteUserSeries},
+		{name: "lessonCollaborators", run: r.deleteUserLessonCollaborators},
+		{name: "graphics", run: r.deleteUserGraphics},
+		{name: "avatars", run: r.deleteUserAvatars},
+		{name: "backgroundMusics", run: r.deleteUserBackgroundMusics},
+		{name: "files", run: r.deleteUserFiles},
+	}
+}
+
+// deleteLessonは、ゴミ箱にあるLessonを削除します。ゴミ箱を経由せずに削除されたLessonは既に存在しません。
+func (r *deleteOrderRepository) deleteLesson(ctx context.Context, lessonID int64) error {
+	return r.store.Delete(ctx, datastore.IDKey("Lesson", lessonID, nil))
+}
+
+func (r *deleteOrderRepository) deleteLessonMaterials(ctx context.Context, lessonID int64) error {
+	ancestor := datastore.IDKey("Lesson", lessonID, nil)
+	query := infrastructure.NewQuery("LessonMaterial").Ancestor(ancestor).KeysOnly()
+	return r.deleteAll(ctx, query)
+}
+
+func (r *deleteOrderRepository) deleteLessonVersions(ctx context.Context, lessonID int64) error {
+	ancestor := datastore.IDKey("Lesson", lessonID, nil)
+	query := infrastructure.NewQuery("LessonVersion").Ancestor(ancestor).KeysOnly()
+	return r.deleteAll(ctx, query)
+}
+
+func (r *deleteOrderRepository) deleteLessonSchedule(ctx context.Context, lessonID int64) error {
+	return r.store.Delete(ctx, lessonScheduleKey(lessonID))
+}
+
+func (r *deleteOrderRepository) deleteLessonCollaborators(ctx context.Context, lessonID int64) error {
+	ancestor := datastore.IDKey("Lesson", lessonID, nil)
+	query := infrastructure.NewQuery("LessonCollaborator").Ancestor(ancestor).KeysOnly()
+	return r.deleteAll(ctx, query)
+}
+
+func (r *deleteOrderRepository) deleteLessonShareLinks(ctx context.Context, lessonID int64) error {
+	ancestor := datastore.IDKey("Lesson", lessonID, nil)
+	query := infrastructure.NewQuery("ShareLink").Ancestor(ancestor).KeysOnly()
+	return r.deleteAll(ctx, query)
+}
+
+func (r *deleteOrderRepository) deleteLessonReactions(ctx context.Context, lessonID int64) error {
+	if err := r.store.Delete(ctx, lessonReactionHistoryKey(lessonID)); err != nil {
+		return err
+	}
+
+	ancestor := datastore.IDKey("Lesson", lessonID, nil)
+	query := infrastructure.NewQuery("LessonReaction").Ancestor(ancestor).KeysOnly()
+	return r.deleteAll(ctx, query)
+}
+
+func (r *deleteOrderRepository) deleteLessonComments(ctx context.Context, lessonID int64) error {
+	ancestor := datastore.IDKey("Lesson", lessonID, nil)
+	query := infrastructure.NewQuery("LessonComment").Ancestor(ancestor).KeysOnly()
+	return r.deleteAll(ctx, query)
+}
+
+func (r *deleteOrderRepository) deleteLessonGraphics(ctx context.Context, lessonID int64) error {
+	var graphics []Graphic
+	query := infrastructure.NewQuery("Graphic").Filter("LessonID =", lessonID)
+	keys, err := r.store.GetAll(ctx, query, &graphics)
+	if err != nil {
+		return err
+	}
+
+	return r.deleteGraphics(ctx, keys, graphics)
+}
+
+func (r *deleteOrderRepository) deleteLessonVoices(ctx context.Context, lessonID int64) error {
+	// 音声ファイルはvoice/{lessonID}/以下にまとめて保存されている
+	if err := deleteFilesWithPrefix(ctx, infrastructure.PublicBucketName(), fmt.Sprintf("voice/%d/", lessonID)); err != nil {
+		return err
+	}
+
+	query := infrastructure.NewQuery("Voice").Filter("LessonID =", lessonID).KeysOnly()
+	return r.deleteAll(ctx, query)
+}
+
+func (r *deleteOrderRepository) deleteLessonFiles(ctx context.Context, lessonID int64) error {
+	// サムネイル、body-*.zst、speech-*.mp3は公開状態に応じてどちらかのバケットのlesson/{lessonID}/以下にある
+	prefix := fmt.Sprintf("lesson/%d/", lessonID)
+	for _, bucketName := range []string{infrastructure.PublicBucketName(), infrastructure.MaterialBucketName()} {
+		if err := deleteFilesWithPrefix(ctx, bucketName, prefix); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *deleteOrderRepository) deleteLessonSearchIndex(ctx context.Context, lessonID int64) error {
+	return NewLessonSearchRepository(r.store).Remove(ctx, lessonID)
+}
+
+// deleteUserLessonsは、Userの全てのLessonを削除し、関連データの削除をLessonのDeleteOrderとして予約します。
+// 公開中だったLessonは、DeleteLessonAndResourcesと同様にTagの件数も減らします。
+func (r *deleteOrderRepository) deleteUserLessons(ctx context.Context, userID int64) error {
+	query := infrastructure.NewQuery("Lesson").Filter("UserID =", userID).KeysOnly()
+	keys, err := r.store.GetAll(ctx, query, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		var deleted Lesson
+		err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+			deleted = Lesson{}
+			if err := tx.Get(key, &deleted); err != nil {
+				return err
+			}
+			if err := tx.Delete(key); err != nil {
+				return err
+			}
+			return r.CreateLessonOrderInTransaction(tx, key.ID)
+		})
+		if err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				continue
+			}
+			return err
+		}
+
+		if deleted.Status == LessonStatusPublic {
+			// Lessonは削除済みなので、Tagの件数の更新に失敗しても削除は続ける。件数はrebuild-lesson-listsで作り直せる
+			if err := NewTagRepository(r.store).Apply(ctx, deleted.Tags, nil); err != nil {
+				log.Printf("failed to update tags of deleted lesson. %v\n", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (r *deleteOrderRepository) deleteUserSeries(ctx context.Context, userID int64) error {
+	return NewSeriesRepository(r.store).DeleteByUserID(ctx, userID)
+}
+
+// deleteUserLessonCollaboratorsは、Userが共同編集者として招待されているか参加している他のUserのLessonから、Userを外します。
+func (r *deleteOrderRepository) deleteUserLessonCollaborators(ctx context.Context, userID int64) error {
+	query := infrastructure.NewQuery("LessonCollaborator").Filter("UserID =", userID).KeysOnly()
+	return r.deleteAll(ctx, query)
+}
+
+func (r *deleteOrderRepository) deleteUserGraphics(ctx context.Context, userID int64) error {
+	var graphics []Graphic
+	ancestor := datastore.IDKey("User", userID, nil)
+	query := infrastructure.NewQuery("Graphic").Ancestor(ancestor)
+	keys, err := r.store.GetAll(ctx, query, &graphics)
+	if err != nil {
+		return err
+	}
+
+	return r.deleteGraphics(ctx, keys, graphics)
+}
+
+func (r *deleteOrderRepository) deleteUserAvatars(ctx context.Context, userID int64) error {
+	ancestor := datastore.IDKey("User", userID, nil)
+	query := infrastructure.NewQuery("Avatar").Ancestor(ancestor).KeysOnly()
+	keys, err := r.store.GetAll(ctx, query, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		filePath := infrastructure.StorageObjectFilePath("Avatar", strconv.FormatInt(key.ID, 10), "zst")
+		if err := infrastructure.DeleteFile(ctx, infrastructure.MaterialBucketName(), filePath); err != nil {
+			return err
+		}
+	}
+
+	return r.deleteKeys(ctx, keys)
+}
+
+func (r *deleteOrderRepository) deleteUserBackgroundMusics(ctx context.Context, userID int64) error {
+	ancestor := datastore.IDKey("User", userID, nil)
+	query := infrastructure.NewQuery("BackgroundMusic").Ancestor(ancestor).KeysOnly()
+	keys, err := r.store.GetAll(ctx, query, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		filePath := infrastructure.StorageObjectFilePath("bgm", strconv.FormatInt(key.ID, 10), "mp3")
+		if err := infrastructure.DeleteFile(ctx, infrastructure.MaterialBucketName(), filePath); err != nil {
+			return err
+		}
+	}
+
+	return r.deleteKeys(ctx, keys)
+}
+
+func (r *deleteOrderRepository) deleteUserFiles(ctx context.Context, userID int64) error {
+	filePath := infrastructure.StorageObjectFilePath("user", strconv.FormatInt(userID, 10), "png")
+	return infrastructure.DeleteFile(ctx, infrastructure.PublicBucketName(), filePath)
+}
+
+// deleteGraphicsは、Graphicのファイルとエンティティを削除します。PublicGraphicのファイルは共有されているので削除しません。
+func (r *deleteOrderRepository) deleteGraphics(ctx context.Context, keys []*datastore.Key, graphics []Graphic) error {
+	for i, graphic := range graphics {
+		if graphic.PublicGraphicID != 0 {
+			continue
+		}
+
+		filePath := infrastructure.StorageObjectFilePath("Graphic", strconv.FormatInt(keys[i].ID, 10), graphic.FileType)
+		if err := infrastructure.DeleteFile(ctx, infrastructure.MaterialBucketName(), filePath); err != nil {
+			return err
+		}
+	}
+
+	return r.deleteKeys(ctx, keys)
+}
+
+func (r *deleteOrderRepository) deleteAll(ctx context.Context, query *infrastructure.Query) error {
+	keys, err := r.store.GetAll(ctx, query, nil)
+	if err != nil {
+		return err
+	}
+
+	return r.deleteKeys(ctx, keys)
+}
+
+func (r *deleteOrderRepository) deleteKeys(ctx context.Context, keys []*datastore.Key) error {
+	for start := 0; start < len(keys); start += deleteOrderBatchSize {
+		end := start + deleteOrderBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		if err := r.store.DeleteMulti(ctx, keys[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func deleteFilesWithPrefix(ctx context.Context, bucketName, prefix string) error {
+	paths, err := infrastructure.ListFiles(ctx, bucketName, prefix)
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := infrastructure.DeleteFile(ctx, bucketName, path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func createDeleteOrderInTransaction(tx infrastructure.Transaction, entityName string, targetID int64, notBefore time.Time) error {
 	order := new(DeleteOrder)
-	order.EntityName = "Lesson"
-	order.TargetID = lessonID
+	order.EntityName = entityName
+	order.TargetID = targetID
 	order.Created = time.Now()
+	order.Updated = order.Created
+	// 即座に処理するものは作成日時にして、GetPendingで作成順に取得されるようにする
+	order.NotBefore = notBefore
+	if notBefore.IsZero() {
+		order.NotBefore = order.Created
+	}
 
 	key := datastore.IncompleteKey("DeleteOrder", nil)
-	if _, err := tx.Put(key, order); err != nil {
+	if err := tx.Put(key, order); err != nil {
 		return err
 	}
 
@@ -0,0 +1,262 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+func TestDeleteOrderProcess(t *testing.T) {
+	retention := 30 * 24 * time.Hour
+	currentTime := time.Now()
+
+	tests := []struct {
+		name          string
+		entityName    string
+		lesson        *Lesson // nilの場合、Lessonはゴミ箱を経由せずに削除済み
+		notBefore     time.Time
+		wantErr       error
+		wantLesson    bool // 処理後もLessonが残っている
+		wantOrder     bool // 処理後もDeleteOrderが残っている
+		wantNotBefore time.Time
+	}{
+		{
+			name:       "deletes lesson past the retention period",
+			entityName: "Lesson",
+			lesson:     &Lesson{UserID: 1, Status: LessonStatusDeleted, Deleted: currentTime.Add(-retention - time.Hour)},
+			notBefore:  currentTime.Add(-time.Hour),
+		},
+		{
+			name:       "deletes resources of lesson already deleted",
+			entityName: "Lesson",
+		},
+		{
+			name:       "keeps order before not before",
+			entityName: "Lesson",
+			lesson:     &Lesson{UserID: 1, Status: LessonStatusDeleted, Deleted: currentTime},
+			notBefore:  currentTime.Add(retention),
+			wantErr:    DeleteOrderNotDue,
+			wantLesson: true,
+			wantOrder:  true,
+		},
+		{
+			name:          "postpones order of lesson trashed again",
+			entityName:    "Lesson",
+			lesson:        &Lesson{UserID: 1, Status: LessonStatusDeleted, Deleted: currentTime.Add(-time.Hour)},
+			notBefore:     currentTime.Add(-time.Hour),
+			wantErr:       DeleteOrderNotDue,
+			wantLesson:    true,
+			wantOrder:     true,
+			wantNotBefore: currentTime.Add(-time.Hour + retention),
+		},
+		{
+			name:       "drops order of restored lesson",
+			entityName: "Lesson",
+			lesson:     &Lesson{UserID: 1, Status: LessonStatusPublic},
+			wantErr:    DeleteOrderNotDue,
+			wantLesson: true,
+		},
+		{
+			name:       "rejects unknown entity",
+			entityName: "Unknown",
+			wantErr:    UnknownDeleteOrderEntity,
+			wantOrder:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestDatastore(t)
+			repository := NewDeleteOrderRepository(store)
+
+			var lessonID int64 = 100
+			if tt.lesson != nil {
+				lessonID = putTestLesson(t, store, *tt.lesson).ID
+			}
+			lessonKey := datastore.IDKey("Lesson", lessonID, nil)
+
+			comment := LessonComment{UserID: 1, Body: "comment"}
+			if _, err := store.Put(ctx, datastore.IncompleteKey("LessonComment", lessonKey), &comment); err != nil {
+				t.Fatal(err)
+			}
+			thumbnailPath := fmt.Sprintf("lesson/%d/thumbnail.png", lessonID)
+			if err := infrastructure.CreateFile(ctx, infrastructure.PublicBucketName(), thumbnailPath, "image/png", []byte("png")); err != nil {
+				t.Fatal(err)
+			}
+
+			err := store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+				return createDeleteOrderInTransaction(tx, tt.entityName, lessonID, tt.notBefore)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var orders []DeleteOrder
+			keys, err := store.GetAll(ctx, infrastructure.NewQuery("DeleteOrder"), &orders)
+			if err != nil {
+				t.Fatal(err)
+			}
+			order := orders[0]
+			order.ID = keys[0].ID
+
+			if err := repository.Process(ctx, &order); err != tt.wantErr {
+				t.Fatalf("Process() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var lesson Lesson
+			err = store.Get(ctx, lessonKey, &lesson)
+			if gotLesson := err == nil; gotLesson != tt.wantLesson {
+				t.Errorf("lesson exists = %v, want %v", gotLesson, tt.wantLesson)
+			}
+
+			var savedOrder DeleteOrder
+			err = store.Get(ctx, keys[0], &savedOrder)
+			if gotOrder := err == nil; gotOrder != tt.wantOrder {
+				t.Errorf("order exists = %v, want %v", gotOrder, tt.wantOrder)
+			}
+			if !tt.wantNotBefore.IsZero() && !savedOrder.NotBefore.Equal(tt.wantNotBefore) {
+				t.Errorf("order.NotBefore = %v, want %v", savedOrder.NotBefore, tt.wantNotBefore)
+			}
+
+			// 処理が完了した場合のみ、関連するエンティティとファイルが削除される
+			completed := tt.wantErr == nil
+			comments, err := store.GetAll(ctx, infrastructure.NewQuery("LessonComment").Ancestor(lessonKey).KeysOnly(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if gotDeleted := len(comments) == 0; gotDeleted != completed {
+				t.Errorf("comments deleted = %v, want %v", gotDeleted, completed)
+			}
+			_, err = infrastructure.GetFile(ctx, infrastructure.PublicBucketName(), thumbnailPath)
+			if gotDeleted := err != nil; gotDeleted != completed {
+				t.Errorf("thumbnail deleted = %v, want %v", gotDeleted, completed)
+			}
+		})
+	}
+}
+
+func TestDeleteOrderProcessUser(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDatastore(t)
+	repository := NewDeleteOrderRepository(store)
+
+	var userID int64 = 1
+	tags := []string{"go"}
+	lessons := []Lesson{
+		putTestLesson(t, store, Lesson{UserID: userID, Status: LessonStatusPublic, Tags: tags}),
+		putTestLesson(t, store, Lesson{UserID: userID, Status: LessonStatusDeleted, Tags: tags, Deleted: time.Now()}),
+		putTestLesson(t, store, Lesson{UserID: 2, Status: LessonStatusPublic, Tags: tags}),
+	}
+
+	// ゴミ箱にあるLessonのタグは、移動時に件数から除かれている
+	if _, err := store.Put(ctx, tagKey("go"), &Tag{Name: "go", LessonCount: 2}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		return repository.CreateUserOrderInTransaction(tx, userID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	orders, err := repository.GetPending(ctx, time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 {
+		t.Fatalf("GetPending() = %d orders, want 1", len(orders))
+	}
+
+	if err := repository.Process(ctx, &orders[0]); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	// Userの全てのLessonは、ゴミ箱にあるかに関わらず削除され、関連データの削除がLessonのDeleteOrderとして予約される
+	for _, lesson := range lessons {
+		var got Lesson
+		err := store.Get(ctx, datastore.IDKey("Lesson", lesson.ID, nil), &got)
+		if wantExists := lesson.UserID != userID; (err == nil) != wantExists {
+			t.Errorf("lesson %d exists = %v, want %v", lesson.ID, err == nil, wantExists)
+		}
+	}
+
+	// 公開中だったLessonの分だけ、Tagの件数が減る
+	var tag Tag
+	if err := store.Get(ctx, tagKey("go"), &tag); err != nil {
+		t.Fatal(err)
+	}
+	if tag.LessonCount != 1 {
+		t.Errorf("tag.LessonCount = %d, want 1", tag.LessonCount)
+	}
+
+	orders, err = repository.GetPending(ctx, time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 2 {
+		t.Fatalf("GetPending() = %d orders, want 2", len(orders))
+	}
+	for i := range orders {
+		if orders[i].EntityName != "Lesson" {
+			t.Errorf("order.EntityName = %s, want Lesson", orders[i].EntityName)
+		}
+		if err := repository.Process(ctx, &orders[i]); err != nil {
+			t.Errorf("Process() error = %v", err)
+		}
+	}
+}
+
+func TestDeleteOrderGetPending(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDatastore(t)
+	repository := NewDeleteOrderRepository(store)
+
+	currentTime := time.Now()
+	err := store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		if err := createDeleteOrderInTransaction(tx, "Lesson", 1, currentTime.Add(time.Hour)); err != nil {
+			return err
+		}
+		if err := createDeleteOrderInTransaction(tx, "Lesson", 2, currentTime.Add(-time.Hour)); err != nil {
+			return err
+		}
+		return repository.CreateLessonOrderInTransaction(tx, 3)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 処理日時になっていないDeleteOrderは取得されず、処理日時の順に返される
+	orders, err := repository.GetPending(ctx, time.Now(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var targetIDs []int64
+	for _, order := range orders {
+		targetIDs = append(targetIDs, order.TargetID)
+	}
+	if fmt.Sprint(targetIDs) != fmt.Sprint([]int64{2, 3}) {
+		t.Errorf("GetPending() target IDs = %v, want [2 3]", targetIDs)
+	}
+
+	// NotBeforeのないDeleteOrderは、保存し直すと作成日時を処理日時として取得される
+	legacy := DeleteOrder{EntityName: "User", TargetID: 4, Created: currentTime.Add(-2 * time.Hour)}
+	if _, err := store.Put(ctx, datastore.IncompleteKey("DeleteOrder", nil), &legacy); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := repository.RefreshAll(ctx); err != nil || count != 4 {
+		t.Fatalf("RefreshAll() = %d, %v, want 4", count, err)
+	}
+	orders, err = repository.GetPending(ctx, time.Now(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(orders) != 1 || orders[0].TargetID != 4 {
+		t.Errorf("GetPending() = %+v, want the order of user 4", orders)
+	}
+}
@@ -11,6 +11,7 @@ const (
 	LessonStatusDraft   LessonStatus = 0
 	LessonStatusLimited LessonStatus = 1
 	LessonStatusPublic  LessonStatus = 2
+	LessonStatusDeleted LessonStatus = 3 // ゴミ箱に移動され、完全な削除を待っている。リクエストでは指定できない
 )
 
 func (r LessonStatus) String() string {
@@ -21,6 +22,8 @@ func (r LessonStatus) String() string {
 		return "limited"
 	case LessonStatusPublic:
 		return "public"
+	case LessonStatusDeleted:
+		return "deleted"
 	default:
 		return "unknown"
 	}
@@ -265,3 +268,278 @@ func (a *MusicAction) UnmarshalJSON(data []byte) error {
 	*a = action
 	return nil
 }
+
+// LessonQuizTypeは、LessonQuizの出題形式です。
+type LessonQuizType int8
+
+const (
+	LessonQuizTypeMultipleChoice LessonQuizType = 0
+	LessonQuizTypeTrueFalse      LessonQuizType = 1
+	LessonQuizTypeShortAnswer    LessonQuizType = 2
+)
+
+func (r LessonQuizType) String() string {
+	switch r {
+	case LessonQuizTypeMultipleChoice:
+		return "multipleChoice"
+	case LessonQuizTypeTrueFalse:
+		return "trueFalse"
+	case LessonQuizTypeShortAnswer:
+		return "shortAnswer"
+	default:
+		return "unknown"
+	}
+}
+
+func (r LessonQuizType) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (t *LessonQuizType) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("data should be a string, got %s", data)
+	}
+
+	var quizType LessonQuizType
+	switch str {
+	case "multipleChoice":
+		quizType = LessonQuizTypeMultipleChoice
+	case "trueFalse":
+		quizType = LessonQuizTypeTrueFalse
+	case "shortAnswer":
+		quizType = LessonQuizTypeShortAnswer
+	default:
+		return fmt.Errorf("invalid LessonQuizType %s", str)
+	}
+	*t = quizType
+	return nil
+}
+
+// LessonReviewStatusは、レビュアー毎のレビューの状態と、それらをまとめたLessonのレビューの状態です。
+type LessonReviewStatus int8
+
+const (
+	LessonReviewStatusNone             LessonReviewStatus = 0
+	LessonReviewStatusRequested        LessonReviewStatus = 1
+	LessonReviewStatusApproved         LessonReviewStatus = 2
+	LessonReviewStatusChangesRequested LessonReviewStatus = 3
+)
+
+func (r LessonReviewStatus) String() string {
+	switch r {
+	case LessonReviewStatusNone:
+		return "none"
+	case LessonReviewStatusRequested:
+		return "requested"
+	case LessonReviewStatusApproved:
+		return "approved"
+	case LessonReviewStatusChangesRequested:
+		return "changesRequested"
+	default:
+		return "unknown"
+	}
+}
+
+func (r LessonReviewStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (s *LessonReviewStatus) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("data should be a string, got %s", data)
+	}
+
+	var status LessonReviewStatus
+	switch str {
+	case "none":
+		status = LessonReviewStatusNone
+	case "requested":
+		status = LessonReviewStatusRequested
+	case "approved":
+		status = LessonReviewStatusApproved
+	case "changesRequested":
+		status = LessonReviewStatusChangesRequested
+	default:
+		return fmt.Errorf("invalid LessonReviewStatus %s", str)
+	}
+	*s = status
+	return nil
+}
+
+// LessonLicenseは、Lessonの利用許諾です。Creative Commonsのライセンスに対応します。
+type LessonLicense int8
+
+const (
+	LessonLicenseAllRightsReserved LessonLicense = 0
+	LessonLicenseCCBy              LessonLicense = 1
+	LessonLicenseCCBySA            LessonLicense = 2
+	LessonLicenseCCByNC            LessonLicense = 3
+	LessonLicenseCCByNCSA          LessonLicense = 4
+	LessonLicenseCCByND            LessonLicense = 5
+	LessonLicenseCCByNCND          LessonLicense = 6
+)
+
+func (r LessonLicense) String() string {
+	switch r {
+	case LessonLicenseAllRightsReserved:
+		return "allRightsReserved"
+	case LessonLicenseCCBy:
+		return "ccBy"
+	case LessonLicenseCCBySA:
+		return "ccBySa"
+	case LessonLicenseCCByNC:
+		return "ccByNc"
+	case LessonLicenseCCByNCSA:
+		return "ccByNcSa"
+	case LessonLicenseCCByND:
+		return "ccByNd"
+	case LessonLicenseCCByNCND:
+		return "ccByNcNd"
+	default:
+		return "unknown"
+	}
+}
+
+// PermitsDerivativesは、他のUserによる改変を許可するライセンスかを返します。
+func (r LessonLicense) PermitsDerivatives() bool {
+	switch r {
+	case LessonLicenseCCBy, LessonLicenseCCBySA, LessonLicenseCCByNC, LessonLicenseCCByNCSA:
+		return true
+	default:
+		return false
+	}
+}
+
+func (r LessonLicense) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (l *LessonLicense) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("data should be a string, got %s", data)
+	}
+
+	var license LessonLicense
+	switch str {
+	case "allRightsReserved":
+		license = LessonLicenseAllRightsReserved
+	case "ccBy":
+		license = LessonLicenseCCBy
+	case "ccBySa":
+		license = LessonLicenseCCBySA
+	case "ccByNc":
+		license = LessonLicenseCCByNC
+	case "ccByNcSa":
+		license = LessonLicenseCCByNCSA
+	case "ccByNd":
+		license = LessonLicenseCCByND
+	case "ccByNcNd":
+		license = LessonLicenseCCByNCND
+	default:
+		return fmt.Errorf("invalid LessonLicense %s", str)
+	}
+	*l = license
+	return nil
+}
+
+// LessonRoleは、Lessonに対するUserの権限です。上位の権限は下位の権限を含みます。
+type LessonRole int8
+
+const (
+	LessonRoleNone   LessonRole = 0
+	LessonRoleViewer LessonRole = 1
+	LessonRoleEditor LessonRole = 2
+	LessonRoleOwner  LessonRole = 3
+)
+
+func (r LessonRole) String() string {
+	switch r {
+	case LessonRoleNone:
+		return "none"
+	case LessonRoleViewer:
+		return "viewer"
+	case LessonRoleEditor:
+		return "editor"
+	case LessonRoleOwner:
+		return "owner"
+	default:
+		return "unknown"
+	}
+}
+
+// Includesは、rがrequiredの権限を含むかを返します。
+func (r LessonRole) Includes(required LessonRole) bool {
+	return r >= required
+}
+
+func (r LessonRole) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (s *LessonRole) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("data should be a string, got %s", data)
+	}
+
+	var role LessonRole
+	switch str {
+	case "none":
+		role = LessonRoleNone
+	case "viewer":
+		role = LessonRoleViewer
+	case "editor":
+		role = LessonRoleEditor
+	case "owner":
+		role = LessonRoleOwner
+	default:
+		return fmt.Errorf("invalid LessonRole %s", str)
+	}
+	*s = role
+	return nil
+}
+
+// LessonCollaboratorStatusは、共同編集者の招待の状態です。
+type LessonCollaboratorStatus int8
+
+const (
+	LessonCollaboratorStatusInvited  LessonCollaboratorStatus = 0
+	LessonCollaboratorStatusAccepted LessonCollaboratorStatus = 1
+)
+
+func (r LessonCollaboratorStatus) String() string {
+	switch r {
+	case LessonCollaboratorStatusInvited:
+		return "invited"
+	case LessonCollaboratorStatusAccepted:
+		return "accepted"
+	default:
+		return "unknown"
+	}
+}
+
+func (r LessonCollaboratorStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (s *LessonCollaboratorStatus) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return fmt.Errorf("data should be a string, got %s", data)
+	}
+
+	var status LessonCollaboratorStatus
+	switch str {
+	case "invited":
+		status = LessonCollaboratorStatusInvited
+	case "accepted":
+		status = LessonCollaboratorStatusAccepted
+	default:
+		return fmt.Errorf("invalid LessonCollaboratorStatus %s", str)
+	}
+	*s = status
+	return nil
+}
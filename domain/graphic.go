@@ -35,18 +35,32 @@ type Graphic struct {
 	Created         time.Time `json:"created"`
 }
 
-func GetGraphicByID(ctx context.Context, id int64, userID int64) (Graphic, error) {
-	graphic := new(Graphic)
+// GraphicRepositoryは、Userを祖先に持つGraphicの永続化を行います。
+type GraphicRepository interface {
+	GetByID(ctx context.Context, id int64, userID int64) (Graphic, error)
+	GetByLessonID(ctx context.Context, lessonID int64, graphics *[]*Graphic) error
+	GetByIDs(ctx context.Context, userID int64, ids []int64) ([]*Graphic, error)
+	Create(ctx context.Context, userID int64, graphics []*Graphic) error
+	CreateIntroduction(ctx context.Context, userID int64, lessonID int64) error
+	DeleteByID(ctx context.Context, id int64, userID int64) error
+}
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return *graphic, err
-	}
+type graphicRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewGraphicRepositoryは、storeを使用するGraphicRepositoryを返します。
+func NewGraphicRepository(store infrastructure.Datastore) GraphicRepository {
+	return &graphicRepository{store: store}
+}
+
+func (r *graphicRepository) GetByID(ctx context.Context, id int64, userID int64) (Graphic, error) {
+	graphic := new(Graphic)
 
 	ancestor := datastore.IDKey("User", userID, nil)
 	key := datastore.IDKey("Graphic", id, ancestor)
 
-	if err := client.Get(ctx, key, graphic); err != nil {
+	if err := r.store.Get(ctx, key, graphic); err != nil {
 		if err == datastore.ErrNoSuchEntity {
 			return *graphic, GraphicNotFound
 		}
@@ -58,15 +72,10 @@ func GetGraphicByID(ctx context.Context, id int64, userID int64) (Graphic, error
 	return *graphic, nil
 }
 
-func GetGraphicsByLessonID(ctx context.Context, lessonID int64, graphics *[]*Graphic) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
-	query := datastore.NewQuery("Graphic").Filter("LessonID =", lessonID).Order("Created")
+func (r *graphicRepository) GetByLessonID(ctx context.Context, lessonID int64, graphics *[]*Graphic) error {
+	query := infrastructure.NewQuery("Graphic").Filter("LessonID =", lessonID).Order("Created")
 
-	keys, err := client.GetAll(ctx, query, graphics)
+	keys, err := r.store.GetAll(ctx, query, graphics)
 	if err != nil {
 		return err
 	}
@@ -98,12 +107,7 @@ func GetGraphicsByLessonID(ctx context.Context, lessonID int64, graphics *[]*Gra
 	return nil
 }
 
-func GetGraphicsByIDs(ctx context.Context, userID int64, ids []int64) ([]*Graphic, error) {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, err
-	}
-
+func (r *graphicRepository) GetByIDs(ctx context.Context, userID int64, ids []int64) ([]*Graphic, error) {
 	ancestor := datastore.IDKey("User", userID, nil)
 	keys := make([]*datastore.Key, len(ids))
 	for i, id := range ids {
@@ -111,7 +115,7 @@ func GetGraphicsByIDs(ctx context.Context, userID int64, ids []int64) ([]*Graphi
 	}
 
 	graphics := make([]*Graphic, len(ids))
-	if err = client.GetMulti(ctx, keys, graphics); err != nil {
+	if err := r.store.GetMulti(ctx, keys, graphics); err != nil {
 		if _, ok := err.(datastore.MultiError); ok {
 			return nil, GraphicNotFound
 		}
@@ -125,12 +129,7 @@ func GetGraphicsByIDs(ctx context.Context, userID int64, ids []int64) ([]*Graphi
 	return graphics, nil
 }
 
-func CreateGraphics(ctx context.Context, userID int64, graphics []*Graphic) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
+func (r *graphicRepository) Create(ctx context.Context, userID int64, graphics []*Graphic) error {
 	ancestor := datastore.IDKey("User", userID, nil)
 
 	keys := make([]*datastore.Key, len(graphics))
@@ -140,7 +139,7 @@ func CreateGraphics(ctx context.Context, userID int64, graphics []*Graphic) erro
 		graphic.Created = currentTime
 	}
 
-	putKeys, err := client.PutMulti(ctx, keys, graphics)
+	putKeys, err := r.store.PutMulti(ctx, keys, graphics)
 	if err != nil {
 		return err
 	}
@@ -152,8 +151,8 @@ func CreateGraphics(ctx context.Context, userID int64, graphics []*Graphic) erro
 	return nil
 }
 
-func CreateIntroductionGraphics(ctx context.Context, userID int64, lessonID int64) error {
-	publicGraphics, err := GetPublicGraphicsForIntroduction(ctx)
+func (r *graphicRepository) CreateIntroduction(ctx context.Context, userID int64, lessonID int64) error {
+	publicGraphics, err := NewPublicGraphicRepository(r.store).GetForIntroduction(ctx)
 	if err != nil {
 		return err
 	}
@@ -170,12 +169,7 @@ func CreateIntroductionGraphics(ctx context.Context, userID int64, lessonID int6
 		graphics[i] = graphic
 	}
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
-	_, err = client.PutMulti(ctx, keys, graphics)
+	_, err = r.store.PutMulti(ctx, keys, graphics)
 	if err != nil {
 		return err
 	}
@@ -183,15 +177,10 @@ func CreateIntroductionGraphics(ctx context.Context, userID int64, lessonID int6
 	return nil
 }
 
-func DeleteGraphicByID(ctx context.Context, id int64, userID int64) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
+func (r *graphicRepository) DeleteByID(ctx context.Context, id int64, userID int64) error {
 	ancestor := datastore.IDKey("User", userID, nil)
 	key := datastore.IDKey("Graphic", id, ancestor)
-	if err := client.Delete(ctx, key); err != nil {
+	if err := r.store.Delete(ctx, key); err != nil {
 		return err
 	}
 
@@ -211,7 +200,7 @@ func GetGraphicSignedURL(ctx context.Context, graphic *Graphic) (string, error)
 
 	filePath := infrastructure.StorageObjectFilePath("Graphic", fileID, graphic.FileType)
 	fileType := "" // this is unnecessary when GET request
-	url, err := infrastructure.GetGCSSignedURL(ctx, bucketName, filePath, "GET", fileType)
+	url, err := infrastructure.GetSignedURL(ctx, bucketName, filePath, "GET", fileType)
 
 	if err != nil {
 		return "", err
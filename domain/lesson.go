@@ -2,51 +2,66 @@ package domain
 
 import (
 	"context"
-	"os"
-	"strconv"
+	"reflect"
 	"time"
 
 	"cloud.google.com/go/datastore"
-	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
 	"github.com/super-dog-human/teraconnectgo/infrastructure"
-	"google.golang.org/api/iterator"
 )
 
 // Lesson is the lesson infomation type.
 type Lesson struct {
-	ID                   int64             `json:"id" datastore:"-"`
-	UserID               int64             `json:"userID"`
-	Author               User              `json:"author" datastore:"-"`
-	MaterialID           int64             `json:"materialID"`
-	AvatarID             int64             `json:"avatarID" datastore:",noindex"`         // 公開処理完了時にLessonMaterialの値で更新される
-	AvatarLightColor     string            `json:"avatarLightColor" datastore:",noindex"` // 公開処理完了時にLessonMaterialの値で更新される
-	Avatar               Avatar            `json:"avatar,omitempty" datastore:"-"`
-	PrevLessonID         int64             `json:"prevLessonID" datastore:",noindex"`
-	PrevLessonTitle      string            `json:"prevLessonTitle" datastore:"-"`
-	NextLessonID         int64             `json:"nextLessonID" datastore:",noindex"`
-	NextLessonTitle      string            `json:"nextLessonTitle" datastore:"-"`
-	NeedsRecording       bool              `json:"needsRecording" datastore:",noindex"` // 収録画面での収録必要の有無
-	IsIntroduction       bool              `json:"isIntroduction"`                      // 自己紹介用の授業
-	HasThumbnail         bool              `json:"hasThumbnail" datastore:",noindex"`
-	ThumbnailURL         string            `json:"thumbnailURL" datastore:"-"`
-	SpeechURL            string            `json:"speechURL" datastore:"-"`
-	BodyURL              string            `json:"bodyURL" datastore:"-"`
-	Status               LessonStatus      `json:"status"`
-	References           []LessonReference `json:"references" datastore:",noindex"`
-	Reviews              []LessonReview    `json:"reviews" datastore:",noindex"`
-	SubjectID            int64             `json:"subjectID"`
-	SubjectName          string            `json:"subjectName" datastore:",noindex"`
-	JapaneseCategoryID   int64             `json:"japaneseCategoryID"`
-	JapaneseCategoryName string            `json:"japaneseCategoryName" datastore:",noindex"`
-	Title                string            `json:"title"`
-	Description          string            `json:"description"`
-	DurationSec          float32           `json:"durationSec" datastore:",noindex"`
-	ViewCount            int64             `json:"viewCount" datastore:",noindex"`
-	ViewKey              string            `json:"viewKey" datastore:",noindex"`
-	Version              int32             `json:"version" datastore:",noindex"`
-	Created              time.Time         `json:"created"`
-	Updated              time.Time         `json:"updated" datastore:",noindex"`
-	Published            time.Time         `json:"published"` // 公開処理完了時にLessonMaterialのUpdatedの値で更新される
+	ID                   int64                 `json:"id" datastore:"-"`
+	UserID               int64                 `json:"userID"`
+	Author               User                  `json:"author" datastore:"-"`
+	MaterialID           int64                 `json:"materialID"`
+	AvatarID             int64                 `json:"avatarID" datastore:",noindex"`         // 公開処理完了時にLessonMaterialの値で更新される
+	AvatarLightColor     string                `json:"avatarLightColor" datastore:",noindex"` // 公開処理完了時にLessonMaterialの値で更新される
+	Avatar               Avatar                `json:"avatar,omitempty" datastore:"-"`
+	PrevLessonID         int64                 `json:"prevLessonID" datastore:",noindex"`
+	PrevLessonTitle      string                `json:"prevLessonTitle" datastore:"-"`
+	NextLessonID         int64                 `json:"nextLessonID" datastore:",noindex"`
+	NextLessonTitle      string                `json:"nextLessonTitle" datastore:"-"`
+	SeriesID             int64                 `json:"seriesID" datastore:",noindex"`
+	Series               *LessonSeriesPosition `json:"series,omitempty" datastore:"-"`
+	NeedsRecording       bool                  `json:"needsRecording" datastore:",noindex"` // 収録画面での収録必要の有無
+	IsIntroduction       bool                  `json:"isIntroduction"`                      // 自己紹介用の授業
+	HasThumbnail         bool                  `json:"hasThumbnail"`
+	ThumbnailURL         string                `json:"thumbnailURL" datastore:"-"`
+	SpeechURL            string                `json:"speechURL" datastore:"-"`
+	BodyURL              string                `json:"bodyURL" datastore:"-"`
+	Status               LessonStatus          `json:"status"`
+	License              LessonLicense         `json:"license" datastore:",noindex"`
+	OriginalLessonID     int64                 `json:"originalLessonID" datastore:",noindex"` // 複製元のLesson
+	OriginalUserID       int64                 `json:"originalUserID" datastore:",noindex"`
+	OriginalVersion      int32                 `json:"originalVersion" datastore:",noindex"`
+	References           []LessonReference     `json:"references" datastore:",noindex"`
+	Reviews              []LessonReview        `json:"reviews" datastore:",noindex"`
+	ReviewStatus         LessonReviewStatus    `json:"reviewStatus" datastore:",noindex"` // Reviewsの状態をまとめたもの
+	SubjectID            int64                 `json:"subjectID"`
+	SubjectName          string                `json:"subjectName" datastore:",noindex"`
+	JapaneseCategoryID   int64                 `json:"japaneseCategoryID"`
+	JapaneseCategoryName string                `json:"japaneseCategoryName" datastore:",noindex"`
+	SecondaryCategoryIDs []int64               `json:"secondaryCategoryIDs" datastore:",noindex"` // JapaneseCategoryIDと同じSubjectの副カテゴリ
+	JapaneseCategoryIDs  []int64               `json:"-"`                                         // JapaneseCategoryIDと副カテゴリ。カテゴリの一覧の検索に使用する
+	Tags                 []string              `json:"tags"`                                      // 正規化済みのタグ
+	CommentsClosed       bool                  `json:"commentsClosed" datastore:",noindex"`       // LessonCommentの新規作成を受け付けない
+	Title                string                `json:"title"`
+	Description          string                `json:"description"`
+	DurationSec          float32               `json:"durationSec"`
+	ViewCount            int64                 `json:"viewCount"`
+	LikeCount            int64                 `json:"likeCount" datastore:",noindex"`     // LessonReactionから定時バッチで集計される
+	RatingCount          int64                 `json:"ratingCount" datastore:",noindex"`   // LessonReactionから定時バッチで集計される
+	RatingTotal          int64                 `json:"-" datastore:",noindex"`             // 評価の合計。RatingAverageの計算に使用する
+	RatingAverage        float32               `json:"ratingAverage" datastore:",noindex"` // LessonReactionから定時バッチで集計される
+	ViewKey              string                `json:"-" datastore:",noindex"`             // ShareLinkの導入前に発行された限定公開用のキー。参照時にShareLinkへ移行される
+	Version              int32                 `json:"version" datastore:",noindex"`
+	Created              time.Time             `json:"created"`
+	Updated              time.Time             `json:"updated" datastore:",noindex"`
+	Published            time.Time             `json:"published"`                    // 公開処理完了時にLessonMaterialのUpdatedの値で更新される
+	Deleted              time.Time             `json:"deleted" datastore:",noindex"` // ゴミ箱に移動した日時
+	StatusBeforeDeletion LessonStatus          `json:"-" datastore:",noindex"`       // 復元時に戻す状態
+	RestorableUntil      time.Time             `json:"restorableUntil,omitempty" datastore:"-"`
 }
 
 type ShortLesson struct {
@@ -64,9 +79,20 @@ type LessonReference struct {
 
 // LessonReview is review status of lesson by other users.
 type LessonReview struct {
-	ReviewerUserID int64     `json:"userID"`
-	Comment        string    `json:"comment"`
-	Created        time.Time `json:"created"`
+	ReviewerUserID int64                 `json:"userID"`
+	ReviewKey      string                `json:"-"` // レビュアーが下書きを参照するためのキー
+	Status         LessonReviewStatus    `json:"status"`
+	Comment        string                `json:"comment"` // 承認または修正依頼の際の総評
+	Comments       []LessonReviewComment `json:"comments"`
+	Created        time.Time             `json:"created"` // レビューを依頼した日時
+	Updated        time.Time             `json:"updated"`
+}
+
+// LessonReviewCommentは、教材の再生時刻に紐付いたレビュアーのコメントです。
+type LessonReviewComment struct {
+	ElapsedTime float32   `json:"elapsedTime"`
+	Body        string    `json:"body"`
+	Created     time.Time `json:"created"`
 }
 
 type LessonErrorCode uint
@@ -84,38 +110,57 @@ func (e LessonErrorCode) Error() string {
 	}
 }
 
-func GetLessonByID(ctx context.Context, id int64) (Lesson, error) {
+// LessonRepositoryは、Lessonの永続化を行います。
+type LessonRepository interface {
+	GetByID(ctx context.Context, id int64) (Lesson, error)
+	GetPublicByUserID(ctx context.Context, userID int64) ([]Lesson, error)
+	GetByUserID(ctx context.Context, userID int64) ([]Lesson, error)
+	GetPublicList(ctx context.Context, filter *LessonListFilter, cursorStr string) ([]Lesson, string, error)
+	RefreshPublicList(ctx context.Context) (int, error)
+	Create(ctx context.Context, lesson *Lesson) error
+	CreateIntroduction(ctx context.Context, user *User, lesson *Lesson) error
+	Update(ctx context.Context, lesson *Lesson) error
+	UpdateWithMaterial(ctx context.Context, user *User, lesson *Lesson, needsCopyThumbnail bool, republishes bool, requestID string, jsonBody *map[string]interface{}, lessonFields *[]string, lessonMaterialFields *[]string) error
+	Delete(ctx context.Context, id int64) error
+	DeleteInTransaction(tx infrastructure.Transaction, id int64) error
+}
+
+type lessonRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonRepositoryは、storeを使用するLessonRepositoryを返します。
+func NewLessonRepository(store infrastructure.Datastore) LessonRepository {
+	return &lessonRepository{store: store}
+}
+
+// GetByIDは、idから同定したLessonを返します。ゴミ箱に移動したLessonは存在しないものとしてdatastore.ErrNoSuchEntityを返します。
+func (r *lessonRepository) GetByID(ctx context.Context, id int64) (Lesson, error) {
 	lesson := new(Lesson)
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
+	key := datastore.IDKey("Lesson", id, nil)
+	if err := r.store.Get(ctx, key, lesson); err != nil {
 		return *lesson, err
 	}
 
-	key := datastore.IDKey("Lesson", id, nil)
-	if err := client.Get(ctx, key, lesson); err != nil {
-		return *lesson, err
+	if lesson.Status == LessonStatusDeleted {
+		return Lesson{}, datastore.ErrNoSuchEntity
 	}
 
 	lesson.ID = id
 
-	if err = SetLessonThumbnailURL(ctx, lesson); err != nil {
+	if err := SetLessonThumbnailURL(ctx, lesson); err != nil {
 		return *lesson, err
 	}
 
 	return *lesson, nil
 }
 
-func GetPublicLessonsByUserID(ctx context.Context, userID int64) ([]Lesson, error) {
+func (r *lessonRepository) GetPublicByUserID(ctx context.Context, userID int64) ([]Lesson, error) {
 	var lessons []Lesson
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, err
-	}
-
-	query := datastore.NewQuery("Lesson").Filter("UserID =", userID).Filter("Status = ", int32(LessonStatusPublic)).Filter("IsIntroduction =", false).Order("-Created")
-	keys, err := client.GetAll(ctx, query, &lessons)
+	query := infrastructure.NewQuery("Lesson").Filter("UserID =", userID).Filter("Status = ", int32(LessonStatusPublic)).Filter("IsIntroduction =", false).Order("-Created")
+	keys, err := r.store.GetAll(ctx, query, &lessons)
 	if err != nil {
 		return nil, err
 	}
@@ -130,77 +175,35 @@ func GetPublicLessonsByUserID(ctx context.Context, userID int64) ([]Lesson, erro
 	return lessons, nil
 }
 
-func GetLessonsByUserID(ctx context.Context, userID int64) ([]Lesson, error) {
+// GetByUserIDは、userIDのUserのLessonを新しい順に返します。ゴミ箱に移動したLessonは含みません。
+func (r *lessonRepository) GetByUserID(ctx context.Context, userID int64) ([]Lesson, error) {
 	var lessons []Lesson
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, err
-	}
-
-	query := datastore.NewQuery("Lesson").Filter("UserID =", userID).Filter("IsIntroduction =", false).Order("-Created")
-	keys, err := client.GetAll(ctx, query, &lessons)
+	query := infrastructure.NewQuery("Lesson").Filter("UserID =", userID).Filter("IsIntroduction =", false).Order("-Created")
+	keys, err := r.store.GetAll(ctx, query, &lessons)
 	if err != nil {
 		return nil, err
 	}
 
+	visibleLessons := lessons[:0]
 	for i, key := range keys {
-		lessons[i].ID = key.ID
-		if err := SetLessonThumbnailURL(ctx, &lessons[i]); err != nil {
-			return nil, err
-		}
-	}
-
-	return lessons, nil
-}
-
-func GetLessonsByCategoryID(ctx context.Context, cursorStr string, categoryID int64) ([]ShortLesson, string, error) {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, "", err
-	}
-
-	const lessonPageSize = 18
-	query := datastore.NewQuery("Lesson").Project("UserID", "Title", "Description").
-		Filter("JapaneseCategoryID =", categoryID).Filter("Status = ", int32(LessonStatusPublic)).Order("-Published").Limit(lessonPageSize)
-
-	if cursorStr != "" {
-		cursor, err := datastore.DecodeCursor(cursorStr)
-		if err != nil {
-			return nil, "", err
+		if lessons[i].Status == LessonStatusDeleted {
+			continue
 		}
-		query = query.Start(cursor)
-	}
 
-	var lessons []ShortLesson
-	it := client.Run(ctx, query)
-	for {
-		var lesson ShortLesson
-		key, err := it.Next(&lesson)
-		if err == iterator.Done {
-			break
-		} else if err != nil {
-			return nil, "", err
-		}
+		lesson := lessons[i]
 		lesson.ID = key.ID
-		lessons = append(lessons, lesson)
-	}
-
-	nextCursor, err := it.Cursor()
-	if err != nil {
-		return nil, "", err
+		if err := SetLessonThumbnailURL(ctx, &lesson); err != nil {
+			return nil, err
+		}
+		visibleLessons = append(visibleLessons, lesson)
 	}
 
-	return lessons, nextCursor.String(), nil
+	return visibleLessons, nil
 }
 
-func CreateLesson(ctx context.Context, lesson *Lesson) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
-	if err := setCategoryAndSubject(ctx, lesson); err != nil {
+func (r *lessonRepository) Create(ctx context.Context, lesson *Lesson) error {
+	if err := r.setCategoryAndSubject(ctx, lesson); err != nil {
 		return err
 	}
 
@@ -210,7 +213,7 @@ func CreateLesson(ctx context.Context, lesson *Lesson) error {
 	lesson.Created = currentTime
 	lesson.Updated = currentTime
 
-	key, err := client.Put(ctx, datastore.IncompleteKey("Lesson", nil), lesson)
+	key, err := r.store.Put(ctx, datastore.IncompleteKey("Lesson", nil), lesson)
 
 	if err != nil {
 		return err
@@ -221,14 +224,9 @@ func CreateLesson(ctx context.Context, lesson *Lesson) error {
 	return nil
 }
 
-func CreateIntroductionLesson(ctx context.Context, user *User, lesson *Lesson) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
-	query := datastore.NewQuery("Lesson").KeysOnly().Filter("UserID =", user.ID).Filter("IsIntroduction =", true).Limit(1)
-	keys, err := client.GetAll(ctx, query, nil)
+func (r *lessonRepository) CreateIntroduction(ctx context.Context, user *User, lesson *Lesson) error {
+	query := infrastructure.NewQuery("Lesson").KeysOnly().Filter("UserID =", user.ID).Filter("IsIntroduction =", true).Limit(1)
+	keys, err := r.store.GetAll(ctx, query, nil)
 	if err != nil {
 		return err
 	}
@@ -246,7 +244,7 @@ func CreateIntroductionLesson(ctx context.Context, user *User, lesson *Lesson) e
 	lesson.Created = currentTime
 	lesson.Updated = currentTime
 
-	key, err := client.Put(ctx, datastore.IncompleteKey("Lesson", nil), lesson)
+	key, err := r.store.Put(ctx, datastore.IncompleteKey("Lesson", nil), lesson)
 	if err != nil {
 		return err
 	}
@@ -255,67 +253,65 @@ func CreateIntroductionLesson(ctx context.Context, user *User, lesson *Lesson) e
 	return nil
 }
 
-func UpdateLesson(ctx context.Context, lesson *Lesson) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
+func (r *lessonRepository) Update(ctx context.Context, lesson *Lesson) error {
 	lesson.Updated = time.Now()
 
 	key := datastore.IDKey("Lesson", lesson.ID, nil)
-	if _, err := client.Put(ctx, key, lesson); err != nil {
+	if _, err := r.store.Put(ctx, key, lesson); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// UpdateLessonAndMaterialは、jsonのフィールドを既存のLesson/LessonMaterialへマージし、トランザクション中で二つのエンティティを更新します。
+// UpdateWithMaterialは、jsonのフィールドを既存のLesson/LessonMaterialへマージし、トランザクション中で二つのエンティティを更新します。
 // jsonのフィールド名がlessonFieldsまたはlessonMaterialFieldsに含まれない場合、そのフィールドは無視されます。
-func UpdateLessonAndMaterial(ctx context.Context, user *User, lesson *Lesson, needsCopyThumbnail bool, requestID string, jsonBody *map[string]interface{}, lessonFields *[]string, lessonMaterialFields *[]string) error {
-	currentStatus := lesson.Status
+// republishesがtrueで、更新後のLessonが下書きでない場合は、LessonMaterialを検証してから圧縮して公開します。
+// LessonMaterialにエラーがある場合はLessonMaterialNotPublishableを返します。falseの場合は公開中の内容を変更しません。
+func (r *lessonRepository) UpdateWithMaterial(ctx context.Context, user *User, lesson *Lesson, needsCopyThumbnail bool, republishes bool, requestID string, jsonBody *map[string]interface{}, lessonFields *[]string, lessonMaterialFields *[]string) error {
 	currentSubjectID := lesson.SubjectID
 	currentJapaneseCategoryID := lesson.JapaneseCategoryID
+	currentSecondaryCategoryIDs := lesson.SecondaryCategoryIDs
 
 	MergeJsonToStruct(jsonBody, lesson, lessonFields)
 
-	if lesson.SubjectID != currentSubjectID || lesson.JapaneseCategoryID != currentJapaneseCategoryID {
-		if err := setCategoryAndSubject(ctx, lesson); err != nil {
+	if lesson.SubjectID != currentSubjectID || lesson.JapaneseCategoryID != currentJapaneseCategoryID ||
+		!reflect.DeepEqual(lesson.SecondaryCategoryIDs, currentSecondaryCategoryIDs) {
+		if err := r.setCategoryAndSubject(ctx, lesson); err != nil {
 			return err
 		}
 	}
+	// 副カテゴリの導入前に作成されたLessonも、更新時にカテゴリの一覧に含まれるようにする
+	lesson.JapaneseCategoryIDs = lesson.allJapaneseCategoryIDs()
 
-	if currentStatus != lesson.Status && needsCopyThumbnail {
-		if err := CopyLessonThumbnail(ctx, lesson.ID, currentStatus, lesson.Status); err != nil {
-			return err
-		}
+	tags, err := NormalizeTags(lesson.Tags)
+	if err != nil {
+		return err
 	}
+	lesson.Tags = tags
 
-	currentTime := time.Now()
-	if lesson.Status == LessonStatusLimited && lesson.ViewKey == "" {
-		uuid, err := UUIDWithoutHypen()
-		if err != nil {
+	// 状態を変更しない更新でも現在のLessonMaterialを公開し直すので、公開できる内容かを毎回検証する
+	republishes = republishes && lesson.Status != LessonStatusDraft
+	if republishes {
+		if err := r.validateMaterialForPublishing(ctx, lesson, jsonBody, lessonMaterialFields); err != nil {
 			return err
 		}
-		lesson.ViewKey = uuid
-	} else if lesson.Status == LessonStatusPublic && lesson.ViewKey != "" {
-		lesson.ViewKey = ""
 	}
+
+	currentTime := time.Now()
 	lesson.Updated = currentTime
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
+	materialRepository := &lessonMaterialRepository{store: r.store}
 
+	var previous Lesson
 	var lessonMaterial LessonMaterial
-	_, err = client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
-		if err = updateLessonInTransaction(tx, lesson); err != nil {
+	err = r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var err error
+		if previous, err = updateLessonInTransaction(tx, lesson, jsonBody, lessonFields); err != nil {
 			return err
 		}
 
-		if lessonMaterial, err = updateLessonMaterialInTransaction(tx, lesson.MaterialID, lesson.ID, jsonBody, lessonMaterialFields, currentTime); err != nil {
+		if lessonMaterial, err = materialRepository.updateInTransaction(tx, lesson.MaterialID, lesson.ID, jsonBody, lessonMaterialFields, currentTime); err != nil {
 			return err
 		}
 
@@ -326,54 +322,67 @@ func UpdateLessonAndMaterial(ctx context.Context, user *User, lesson *Lesson, ne
 		return err
 	}
 
-	if lesson.Status != LessonStatusDraft {
+	// 他の処理で状態が変わっている場合もあるので、トランザクション中で読み込んだ更新前の状態からコピーする
+	if previous.Status != lesson.Status && needsCopyThumbnail {
+		if err := CopyLessonThumbnail(ctx, lesson.ID, previous.Status, lesson.Status); err != nil {
+			return err
+		}
+	}
+
+	// 検証していない場合は、並行して公開された場合も公開し直さない
+	if republishes && lesson.Status != LessonStatusDraft {
 		taskName := infrastructure.LessonCompressingTaskName(lesson.ID, currentTime, requestID)
-		if err := createLessonMaterialForCompressing(ctx, taskName, &lessonMaterial); err != nil {
+		if err := createLessonMaterialForCompressing(ctx, r.store, taskName, &lessonMaterial); err != nil {
 			return err
 		}
 
-		if err := createCompressingTask(ctx, taskName, lesson.MaterialID, currentTime); err != nil {
+		if err := createCompressingTask(ctx, taskName, lesson.ID, currentTime); err != nil {
 			return err
 		}
 	}
 
-	if currentStatus == LessonStatusPublic && lesson.Status != LessonStatusPublic {
-		if lesson.IsIntroduction {
-			// 自己紹介の公開を取りやめる際はUserを更新
-			user.IsPublishedIntroduction = false
-			if err := UpdateUser(ctx, user); err != nil {
-				return err
-			}
-		} else {
-			// 授業の公開を取りやめる際は検索インデックスから登録を削除
-			client := search.NewClient(os.Getenv("ALGOLIA_APPLICATION_ID"), os.Getenv("ALGOLIA_ADMIN_API_KEY"))
-			index := client.InitIndex(os.Getenv("ALGOLIA_INDEX_NAME"))
-			if _, err := index.DeleteObject(strconv.FormatInt(lesson.ID, 10)); err != nil {
-				return err
-			}
+	if previous.Status == LessonStatusPublic && lesson.Status != LessonStatusPublic && lesson.IsIntroduction {
+		// 自己紹介の公開を取りやめる際はUserを更新
+		user.IsPublishedIntroduction = false
+		if err := NewUserRepository(r.store).Update(ctx, user); err != nil {
+			return err
 		}
 	}
 
-	return nil
-}
+	// 公開中の授業は検索インデックスの登録を更新し、公開を取りやめた授業は登録を削除
+	if previous.Status == LessonStatusPublic || lesson.Status == LessonStatusPublic {
+		if err := NewLessonSearchRepository(r.store).Upsert(ctx, lesson); err != nil {
+			return err
+		}
+	}
 
-// DeleteLessonは、idから同定したLessonを削除します。
-func DeleteLesson(ctx context.Context, id int64) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
+	// Tagの件数は公開中のLessonのみを数える
+	var beforeTags, afterTags []string
+	if previous.Status == LessonStatusPublic {
+		beforeTags = previous.Tags
+	}
+	if lesson.Status == LessonStatusPublic {
+		afterTags = lesson.Tags
+	}
+	if err := NewTagRepository(r.store).Apply(ctx, beforeTags, afterTags); err != nil {
 		return err
 	}
 
+	return nil
+}
+
+// Deleteは、idから同定したLessonを削除します。
+func (r *lessonRepository) Delete(ctx context.Context, id int64) error {
 	key := datastore.IDKey("Lesson", id, nil)
-	if err := client.Delete(ctx, key); err != nil {
+	if err := r.store.Delete(ctx, key); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// DeleteLessonInTransactionは、トランザクションでidから同定したLessonを削除します。
-func DeleteLessonInTransaction(tx *datastore.Transaction, id int64) error {
+// DeleteInTransactionは、トランザクションでidから同定したLessonを削除します。
+func (r *lessonRepository) DeleteInTransaction(tx infrastructure.Transaction, id int64) error {
 	key := datastore.IDKey("Lesson", id, nil)
 	if err := tx.Delete(key); err != nil {
 		return err
@@ -382,13 +391,13 @@ func DeleteLessonInTransaction(tx *datastore.Transaction, id int64) error {
 	return nil
 }
 
-func setCategoryAndSubject(ctx context.Context, lesson *Lesson) error {
-	subject, err := GetSubject(ctx, lesson.SubjectID)
+func (r *lessonRepository) setCategoryAndSubject(ctx context.Context, lesson *Lesson) error {
+	subject, err := NewSubjectRepository(r.store).GetByID(ctx, lesson.SubjectID)
 	if err != nil {
 		return err
 	}
 
-	category, err := GetJapaneseCategory(ctx, lesson.JapaneseCategoryID, lesson.SubjectID)
+	category, err := NewCategoryRepository(r.store).GetJapaneseCategory(ctx, lesson.JapaneseCategoryID, lesson.SubjectID)
 	if err != nil {
 		return err
 	}
@@ -396,14 +405,102 @@ func setCategoryAndSubject(ctx context.Context, lesson *Lesson) error {
 	lesson.SubjectName = subject.JapaneseName
 	lesson.JapaneseCategoryName = category.Name
 
+	// 副カテゴリは主カテゴリと同じSubjectのものに限り、重複と主カテゴリを取り除く
+	var secondaryIDs []int64
+	for _, id := range lesson.SecondaryCategoryIDs {
+		if id == lesson.JapaneseCategoryID || containsInt64(secondaryIDs, id) {
+			continue
+		}
+		secondary, err := NewCategoryRepository(r.store).GetJapaneseCategory(ctx, id, lesson.SubjectID)
+		if err != nil {
+			return err
+		}
+		if secondary.SubjectID != lesson.SubjectID {
+			return CategoryNotFound
+		}
+		secondaryIDs = append(secondaryIDs, id)
+	}
+	if len(secondaryIDs) > secondaryCategoriesMaxCount {
+		return TooManySecondaryCategories
+	}
+	lesson.SecondaryCategoryIDs = secondaryIDs
+	lesson.JapaneseCategoryIDs = lesson.allJapaneseCategoryIDs()
+
 	return nil
 }
 
-func updateLessonInTransaction(tx *datastore.Transaction, lesson *Lesson) error {
-	key := datastore.IDKey("Lesson", lesson.ID, nil)
-	if _, err := tx.Put(key, lesson); err != nil {
+// allJapaneseCategoryIDsは、主カテゴリと副カテゴリのIDを返します。
+func (lesson *Lesson) allJapaneseCategoryIDs() []int64 {
+	return append([]int64{lesson.JapaneseCategoryID}, lesson.SecondaryCategoryIDs...)
+}
+
+func containsInt64(s []int64, e int64) bool {
+	for _, a := range s {
+		if a == e {
+			return true
+		}
+	}
+	return false
+}
+
+// lessonCategoryFieldsは、変更時にSubjectNameとJapaneseCategoryNameも合わせて更新されるフィールドです。
+var lessonCategoryFields = []string{"SubjectID", "JapaneseCategoryID", "SecondaryCategoryIDs"}
+
+// updateLessonInTransactionは、トランザクション中で最新のLessonを読み込み、jsonに含まれるlessonFieldsのフィールドのみをlessonからコピーして更新します。
+// トランザクションの外で読み込んだlessonが古くなっていても、他の処理による更新を上書きしません。
+// lessonには更新後の値を反映し、更新前のLessonを返します。ゴミ箱に移動したLessonはdatastore.ErrNoSuchEntityを返します。
+// validateMaterialForPublishingは、jsonの変更を反映したLessonMaterialが公開できるかを検証し、エラーがあればLessonMaterialNotPublishableを返します。
+func (r *lessonRepository) validateMaterialForPublishing(ctx context.Context, lesson *Lesson, jsonBody *map[string]interface{}, lessonMaterialFields *[]string) error {
+	var lessonMaterial LessonMaterial
+	key := datastore.IDKey("LessonMaterial", lesson.MaterialID, datastore.IDKey("Lesson", lesson.ID, nil))
+	if err := r.store.Get(ctx, key, &lessonMaterial); err != nil {
 		return err
 	}
+	MergeJsonToStruct(jsonBody, &lessonMaterial, lessonMaterialFields)
+
+	validation, err := NewLessonMaterialRepository(r.store).Validate(ctx, lesson.ID, &lessonMaterial)
+	if err != nil {
+		return err
+	}
+	if validation.HasErrors() {
+		return LessonMaterialNotPublishable
+	}
 
 	return nil
 }
+
+func updateLessonInTransaction(tx infrastructure.Transaction, lesson *Lesson, jsonBody *map[string]interface{}, lessonFields *[]string) (Lesson, error) {
+	key := datastore.IDKey("Lesson", lesson.ID, nil)
+
+	var current Lesson
+	if err := tx.Get(key, &current); err != nil {
+		return current, err
+	}
+
+	if current.Status == LessonStatusDeleted {
+		return current, datastore.ErrNoSuchEntity
+	}
+
+	previous := current
+	previous.ID = lesson.ID
+
+	fields := mergedFieldNames(jsonBody, lessonFields)
+	copyStructFields(&current, lesson, fields)
+	for _, name := range lessonCategoryFields {
+		if Contains(&fields, name) {
+			copyStructFields(&current, lesson, []string{"SubjectName", "JapaneseCategoryName", "SecondaryCategoryIDs"})
+			break
+		}
+	}
+	current.JapaneseCategoryIDs = current.allJapaneseCategoryIDs()
+	current.Updated = lesson.Updated
+
+	if err := tx.Put(key, &current); err != nil {
+		return previous, err
+	}
+
+	// datastoreに保存しないフィールドは呼び出し元の値を引き継ぐ
+	copyStructFields(lesson, &current, datastoreFieldNames(&current))
+
+	return previous, nil
+}
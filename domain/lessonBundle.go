@@ -0,0 +1,754 @@
+package domain
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"mime"
+	"path"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+const (
+	lessonBundleFormatVersion = 1
+	lessonBundleManifestPath  = "manifest.json"
+	lessonBundleLessonPath    = "lesson.json"
+	lessonBundleMaterialPath  = "material.json"
+)
+
+// LessonBundleMaxSizeは、インポートできるアーカイブと、その展開後の合計の最大サイズです。
+// App Engineのリクエストの上限の32MBに、ヘッダーの分の余裕を持たせています。
+const LessonBundleMaxSize = 30 << 20
+
+// LessonBundleManifestは、アーカイブに含まれるファイルとそのチェックサムの一覧です。
+type LessonBundleManifest struct {
+	FormatVersion int                `json:"formatVersion"`
+	ProjectID     string             `json:"projectID"` // エクスポート元のプロジェクト
+	LessonID      int64              `json:"lessonID"`
+	Exported      time.Time          `json:"exported"`
+	Files         []LessonBundleFile `json:"files"`
+}
+
+type LessonBundleFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// LessonBundleLessonは、アーカイブのlesson.jsonの内容です。Lessonのメタデータと、教材が参照するファイルの一覧を持ちます。
+type LessonBundleLesson struct {
+	Title                string                        `json:"title"`
+	Description          string                        `json:"description"`
+	References           []LessonReference             `json:"references"`
+	License              LessonLicense                 `json:"license"`
+	SubjectID            int64                         `json:"subjectID"`
+	SubjectName          string                        `json:"subjectName"`
+	JapaneseCategoryID   int64                         `json:"japaneseCategoryID"`
+	JapaneseCategoryName string                        `json:"japaneseCategoryName"`
+	SecondaryCategoryIDs []int64                       `json:"secondaryCategoryIDs,omitempty"`
+	Tags                 []string                      `json:"tags,omitempty"`
+	DurationSec          float32                       `json:"durationSec"`
+	OriginalLessonID     int64                         `json:"originalLessonID"`
+	OriginalUserID       int64                         `json:"originalUserID"`
+	OriginalVersion      int32                         `json:"originalVersion"`
+	Avatar               *LessonBundleAvatar           `json:"avatar,omitempty"`
+	Graphics             []LessonBundleGraphic         `json:"graphics"`
+	Voices               []LessonBundleVoice           `json:"voices"`
+	BackgroundMusics     []LessonBundleBackgroundMusic `json:"backgroundMusics"` // 公開されているものはIDのみを参照する
+}
+
+type LessonBundleAvatar struct {
+	ID       int64        `json:"id"`
+	Name     string       `json:"name"`
+	Config   AvatarConfig `json:"config"`
+	Version  int64        `json:"version"`
+	IsPublic bool         `json:"isPublic"`
+	File     string       `json:"file"`
+}
+
+type LessonBundleGraphic struct {
+	ID       int64  `json:"id"`
+	FileType string `json:"fileType"`
+	File     string `json:"file"`
+}
+
+type LessonBundleVoice struct {
+	ID          int64   `json:"id"`
+	FileKey     string  `json:"fileKey"`
+	ElapsedTime float32 `json:"elapsedTime"`
+	DurationSec float32 `json:"durationSec"`
+	Text        string  `json:"text"`
+	IsTexted    bool    `json:"isTexted"`
+	IsSynthesis bool    `json:"isSynthesis"`
+	File        string  `json:"file,omitempty"` // 文字起こしのみのVoiceは音声ファイルを持たない
+}
+
+type LessonBundleBackgroundMusic struct {
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	IsPublic bool   `json:"isPublic"`
+	File     string `json:"file,omitempty"`
+}
+
+type LessonBundleErrorCode uint
+
+const (
+	InvalidLessonBundle  LessonBundleErrorCode = 1
+	LessonBundleTooLarge LessonBundleErrorCode = 2
+)
+
+func (e LessonBundleErrorCode) Error() string {
+	switch e {
+	case InvalidLessonBundle:
+		return "invalid lesson bundle"
+	case LessonBundleTooLarge:
+		return "lesson bundle too large"
+	default:
+		return "unknown lesson bundle error"
+	}
+}
+
+// LessonBundleRepositoryは、LessonをLessonMaterialと参照するファイルを含めた一つのzipアーカイブに書き出し、読み込みます。
+type LessonBundleRepository interface {
+	Export(ctx context.Context, lesson *Lesson) ([]byte, error)
+	Import(ctx context.Context, userID int64, data []byte, lesson *Lesson) error
+}
+
+type lessonBundleRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonBundleRepositoryは、storeを使用するLessonBundleRepositoryを返します。
+func NewLessonBundleRepository(store infrastructure.Datastore) LessonBundleRepository {
+	return &lessonBundleRepository{store: store}
+}
+
+// Exportは、Lessonの編集中の教材と、Graphic、Voice、独自のBackgroundMusic、Avatarのファイルをアーカイブにして返します。
+func (r *lessonBundleRepository) Export(ctx context.Context, lesson *Lesson) ([]byte, error) {
+	var material LessonMaterial
+	lessonKey := datastore.IDKey("Lesson", lesson.ID, nil)
+	if err := r.store.Get(ctx, datastore.IDKey("LessonMaterial", lesson.MaterialID, lessonKey), &material); err != nil {
+		return nil, err
+	}
+
+	bundle := LessonBundleLesson{
+		Title:                lesson.Title,
+		Description:          lesson.Description,
+		References:           lesson.References,
+		License:              lesson.License,
+		SubjectID:            lesson.SubjectID,
+		SubjectName:          lesson.SubjectName,
+		JapaneseCategoryID:   lesson.JapaneseCategoryID,
+		JapaneseCategoryName: lesson.JapaneseCategoryName,
+		SecondaryCategoryIDs: lesson.SecondaryCategoryIDs,
+		Tags:                 lesson.Tags,
+		DurationSec:          lesson.DurationSec,
+		OriginalLessonID:     lesson.OriginalLessonID,
+		OriginalUserID:       lesson.OriginalUserID,
+		OriginalVersion:      lesson.OriginalVersion,
+	}
+	writer := newLessonBundleWriter()
+
+	if err := r.exportGraphics(ctx, lesson.ID, &bundle, writer); err != nil {
+		return nil, err
+	}
+	if err := r.exportVoices(ctx, lesson.ID, &bundle, writer); err != nil {
+		return nil, err
+	}
+	if err := r.exportBackgroundMusics(ctx, lesson.UserID, &material, &bundle, writer); err != nil {
+		return nil, err
+	}
+	if err := r.exportAvatar(ctx, lesson.UserID, material.AvatarID, &bundle, writer); err != nil {
+		return nil, err
+	}
+
+	if err := writer.addJSON(lessonBundleLessonPath, bundle); err != nil {
+		return nil, err
+	}
+	if err := writer.addJSON(lessonBundleMaterialPath, material); err != nil {
+		return nil, err
+	}
+
+	manifest := LessonBundleManifest{
+		FormatVersion: lessonBundleFormatVersion,
+		ProjectID:     infrastructure.ProjectID(),
+		LessonID:      lesson.ID,
+		Exported:      time.Now(),
+	}
+
+	return writer.close(&manifest)
+}
+
+// Importは、アーカイブからuserIDの下書きのLessonを新しいIDで作成します。全てのファイルのチェックサムを検証してから作成を始めます。
+// 途中で失敗した場合、lesson.IDが0でなければ作成済みのLessonが残っているので、呼び出し側で削除する必要があります。
+// 作成済みのBackgroundMusicとAvatarは、Import内で削除します。
+func (r *lessonBundleRepository) Import(ctx context.Context, userID int64, data []byte, lesson *Lesson) (err error) {
+	files, err := readLessonBundleFiles(data)
+	if err != nil {
+		return err
+	}
+
+	var bundle LessonBundleLesson
+	if err := json.Unmarshal(files[lessonBundleLessonPath], &bundle); err != nil {
+		return InvalidLessonBundle
+	}
+	var material LessonMaterial
+	if err := json.Unmarshal(files[lessonBundleMaterialPath], &material); err != nil {
+		return InvalidLessonBundle
+	}
+	if err := validateLessonBundleFiles(&bundle, files); err != nil {
+		return err
+	}
+	tags, err := NormalizeTags(bundle.Tags)
+	if err != nil {
+		return InvalidLessonBundle
+	}
+
+	currentTime := time.Now()
+	*lesson = Lesson{
+		UserID:               userID,
+		SubjectID:            bundle.SubjectID,
+		SubjectName:          bundle.SubjectName,
+		JapaneseCategoryID:   bundle.JapaneseCategoryID,
+		JapaneseCategoryName: bundle.JapaneseCategoryName,
+		SecondaryCategoryIDs: bundle.SecondaryCategoryIDs,
+		Tags:                 tags,
+		Title:                bundle.Title,
+		Description:          bundle.Description,
+		References:           bundle.References,
+		License:              bundle.License,
+		DurationSec:          bundle.DurationSec,
+		OriginalLessonID:     bundle.OriginalLessonID,
+		OriginalUserID:       bundle.OriginalUserID,
+		OriginalVersion:      bundle.OriginalVersion,
+		Status:               LessonStatusDraft,
+		Created:              currentTime,
+		Updated:              currentTime,
+	}
+
+	lessonKey, err := r.store.Put(ctx, datastore.IncompleteKey("Lesson", nil), lesson)
+	if err != nil {
+		return err
+	}
+	lesson.ID = lessonKey.ID
+
+	graphicIDs, err := r.importGraphics(ctx, userID, lesson.ID, bundle.Graphics, files)
+	if err != nil {
+		return err
+	}
+	voiceIDs, err := r.importVoices(ctx, userID, lesson.ID, bundle.Voices, files)
+	if err != nil {
+		return err
+	}
+
+	// BackgroundMusicとAvatarはLessonではなくUserに属し、Lessonと一緒に削除されないので、最後に作成して以降の失敗時には削除する
+	var imported lessonBundleUserResources
+	defer func() {
+		if err != nil {
+			imported.delete(ctx, r.store)
+		}
+	}()
+
+	musicIDs, err := r.importBackgroundMusics(ctx, userID, bundle.BackgroundMusics, files, &imported)
+	if err != nil {
+		return err
+	}
+	if bundle.Avatar != nil {
+		avatarID, err := r.importAvatar(ctx, userID, bundle.Avatar, files, &imported)
+		if err != nil {
+			return err
+		}
+		material.AvatarID = avatarID
+	}
+
+	material.UserID = userID
+	material.Created = currentTime
+	material.Updated = currentTime
+	for i, graphic := range material.Graphics {
+		material.Graphics[i].GraphicID = graphicIDs[graphic.GraphicID]
+	}
+	for i, speech := range material.Speeches {
+		if speech.VoiceID != 0 {
+			material.Speeches[i].VoiceID = voiceIDs[speech.VoiceID]
+		}
+	}
+	for i, music := range material.Musics {
+		if id, ok := musicIDs[music.BackgroundMusicID]; ok {
+			material.Musics[i].BackgroundMusicID = id
+		}
+	}
+
+	materialKey, err := r.store.Put(ctx, datastore.IncompleteKey("LessonMaterial", lessonKey), &material)
+	if err != nil {
+		return err
+	}
+
+	lesson.MaterialID = materialKey.ID
+	lesson.AvatarID = material.AvatarID
+	lesson.AvatarLightColor = material.AvatarLightColor
+	if _, err := r.store.Put(ctx, lessonKey, lesson); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (r *lessonBundleRepository) exportGraphics(ctx context.Context, lessonID int64, bundle *LessonBundleLesson, writer *lessonBundleWriter) error {
+	var graphics []Graphic
+	query := infrastructure.NewQuery("Graphic").Filter("LessonID =", lessonID)
+	keys, err := r.store.GetAll(ctx, query, &graphics)
+	if err != nil {
+		return err
+	}
+
+	for i, graphic := range graphics {
+		// PublicGraphicのファイルも含め、インポート先では独自のGraphicとして作成する
+		bucketName := infrastructure.MaterialBucketName()
+		fileID := strconv.FormatInt(keys[i].ID, 10)
+		if graphic.PublicGraphicID != 0 {
+			bucketName = infrastructure.PublicBucketName()
+			fileID = strconv.FormatInt(graphic.PublicGraphicID, 10)
+		}
+
+		contents, err := infrastructure.GetFile(ctx, bucketName, infrastructure.StorageObjectFilePath("Graphic", fileID, graphic.FileType))
+		if err != nil {
+			return err
+		}
+
+		filePath := fmt.Sprintf("graphics/%d.%s", keys[i].ID, graphic.FileType)
+		if err := writer.add(filePath, contents); err != nil {
+			return err
+		}
+		bundle.Graphics = append(bundle.Graphics, LessonBundleGraphic{ID: keys[i].ID, FileType: graphic.FileType, File: filePath})
+	}
+
+	return nil
+}
+
+func (r *lessonBundleRepository) exportVoices(ctx context.Context, lessonID int64, bundle *LessonBundleLesson, writer *lessonBundleWriter) error {
+	var voices []Voice
+	query := infrastructure.NewQuery("Voice").Filter("LessonID =", lessonID)
+	keys, err := r.store.GetAll(ctx, query, &voices)
+	if err != nil {
+		return err
+	}
+
+	for i, voice := range voices {
+		bundleVoice := LessonBundleVoice{
+			ID:          keys[i].ID,
+			FileKey:     voice.FileKey,
+			ElapsedTime: voice.ElapsedTime,
+			DurationSec: voice.DurationSec,
+			Text:        voice.Text,
+			IsTexted:    voice.IsTexted,
+			IsSynthesis: voice.IsSynthesis,
+		}
+
+		contents, err := infrastructure.GetFile(ctx, infrastructure.PublicBucketName(), CloudStorageVoiceFilePath(lessonID, keys[i].ID, voice.FileKey))
+		if err == nil {
+			bundleVoice.File = fmt.Sprintf("voices/%d.mp3", keys[i].ID)
+			if err := writer.add(bundleVoice.File, contents); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, infrastructure.ErrObjectNotFound) {
+			return err
+		}
+
+		bundle.Voices = append(bundle.Voices, bundleVoice)
+	}
+
+	return nil
+}
+
+// exportBackgroundMusicsは、教材で使用しているBackgroundMusicのうち、Userが登録したもののみファイルを含めます。
+func (r *lessonBundleRepository) exportBackgroundMusics(ctx context.Context, userID int64, material *LessonMaterial, bundle *LessonBundleLesson, writer *lessonBundleWriter) error {
+	exported := make(map[int64]bool)
+	for _, music := range material.Musics {
+		id := music.BackgroundMusicID
+		if id == 0 || exported[id] {
+			continue
+		}
+		exported[id] = true
+
+		var backgroundMusic BackgroundMusic
+		key := datastore.IDKey("BackgroundMusic", id, datastore.IDKey("User", userID, nil))
+		if err := r.store.Get(ctx, key, &backgroundMusic); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				bundle.BackgroundMusics = append(bundle.BackgroundMusics, LessonBundleBackgroundMusic{ID: id, IsPublic: true})
+				continue
+			}
+			return err
+		}
+
+		contents, err := infrastructure.GetFile(ctx, infrastructure.MaterialBucketName(), infrastructure.StorageObjectFilePath("bgm", strconv.FormatInt(id, 10), "mp3"))
+		if err != nil {
+			return err
+		}
+
+		filePath := fmt.Sprintf("bgm/%d.mp3", id)
+		if err := writer.add(filePath, contents); err != nil {
+			return err
+		}
+		bundle.BackgroundMusics = append(bundle.BackgroundMusics, LessonBundleBackgroundMusic{ID: id, Name: backgroundMusic.Name, File: filePath})
+	}
+
+	return nil
+}
+
+// exportAvatarは、Userが登録したAvatarか公開されているAvatarのファイルを含めます。
+func (r *lessonBundleRepository) exportAvatar(ctx context.Context, userID int64, avatarID int64, bundle *LessonBundleLesson, writer *lessonBundleWriter) error {
+	if avatarID == 0 {
+		return nil
+	}
+
+	var avatar Avatar
+	bucketName := infrastructure.MaterialBucketName()
+	err := r.store.Get(ctx, datastore.IDKey("Avatar", avatarID, datastore.IDKey("User", userID, nil)), &avatar)
+	if err == datastore.ErrNoSuchEntity {
+		bucketName = infrastructure.PublicBucketName()
+		err = r.store.Get(ctx, datastore.IDKey("Avatar", avatarID, nil), &avatar)
+	}
+	if err != nil {
+		return err
+	}
+
+	contents, err := infrastructure.GetFile(ctx, bucketName, infrastructure.StorageObjectFilePath("Avatar", strconv.FormatInt(avatarID, 10), "zst"))
+	if err != nil {
+		return err
+	}
+
+	filePath := fmt.Sprintf("avatar/%d.zst", avatarID)
+	if err := writer.add(filePath, contents); err != nil {
+		return err
+	}
+	bundle.Avatar = &LessonBundleAvatar{
+		ID:       avatarID,
+		Name:     avatar.Name,
+		Config:   avatar.Config,
+		Version:  avatar.Version,
+		IsPublic: avatar.IsPublic,
+		File:     filePath,
+	}
+
+	return nil
+}
+
+func (r *lessonBundleRepository) importGraphics(ctx context.Context, userID int64, lessonID int64, bundleGraphics []LessonBundleGraphic, files map[string][]byte) (map[int64]int64, error) {
+	ids := make(map[int64]int64, len(bundleGraphics))
+	if len(bundleGraphics) == 0 {
+		return ids, nil
+	}
+
+	ancestor := datastore.IDKey("User", userID, nil)
+	keys := make([]*datastore.Key, len(bundleGraphics))
+	graphics := make([]Graphic, len(bundleGraphics))
+	currentTime := time.Now()
+	for i, bundleGraphic := range bundleGraphics {
+		keys[i] = datastore.IncompleteKey("Graphic", ancestor)
+		graphics[i] = Graphic{LessonID: lessonID, FileType: bundleGraphic.FileType, Created: currentTime}
+	}
+
+	putKeys, err := r.store.PutMulti(ctx, keys, graphics)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketName := infrastructure.MaterialBucketName()
+	for i, bundleGraphic := range bundleGraphics {
+		ids[bundleGraphic.ID] = putKeys[i].ID
+
+		filePath := infrastructure.StorageObjectFilePath("Graphic", strconv.FormatInt(putKeys[i].ID, 10), bundleGraphic.FileType)
+		if err := infrastructure.CreateFile(ctx, bucketName, filePath, lessonBundleContentType(bundleGraphic.File), files[bundleGraphic.File]); err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, nil
+}
+
+func (r *lessonBundleRepository) importVoices(ctx context.Context, userID int64, lessonID int64, bundleVoices []LessonBundleVoice, files map[string][]byte) (map[int64]int64, error) {
+	ids := make(map[int64]int64, len(bundleVoices))
+	if len(bundleVoices) == 0 {
+		return ids, nil
+	}
+
+	keys := make([]*datastore.Key, len(bundleVoices))
+	voices := make([]Voice, len(bundleVoices))
+	currentTime := time.Now()
+	for i, bundleVoice := range bundleVoices {
+		keys[i] = datastore.IncompleteKey("Voice", nil)
+		voices[i] = Voice{
+			UserID:      userID,
+			LessonID:    lessonID,
+			FileKey:     bundleVoice.FileKey,
+			ElapsedTime: bundleVoice.ElapsedTime,
+			DurationSec: bundleVoice.DurationSec,
+			Text:        bundleVoice.Text,
+			IsTexted:    bundleVoice.IsTexted,
+			IsSynthesis: bundleVoice.IsSynthesis,
+			Created:     currentTime,
+		}
+	}
+
+	putKeys, err := r.store.PutMulti(ctx, keys, voices)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketName := infrastructure.PublicBucketName()
+	for i, bundleVoice := range bundleVoices {
+		ids[bundleVoice.ID] = putKeys[i].ID
+		if bundleVoice.File == "" {
+			continue
+		}
+
+		filePath := CloudStorageVoiceFilePath(lessonID, putKeys[i].ID, bundleVoice.FileKey)
+		if err := infrastructure.CreateFile(ctx, bucketName, filePath, "audio/mpeg", files[bundleVoice.File]); err != nil {
+			return nil, err
+		}
+	}
+
+	return ids, nil
+}
+
+// importBackgroundMusicsは、アーカイブに含まれるBackgroundMusicをuserIDのものとして作成します。公開されているものは元のIDのまま参照します。
+func (r *lessonBundleRepository) importBackgroundMusics(ctx context.Context, userID int64, bundleMusics []LessonBundleBackgroundMusic, files map[string][]byte, imported *lessonBundleUserResources) (map[int64]int64, error) {
+	ids := make(map[int64]int64, len(bundleMusics))
+	repository := NewBackgroundMusicRepository(r.store)
+
+	for _, bundleMusic := range bundleMusics {
+		if bundleMusic.IsPublic {
+			continue
+		}
+
+		backgroundMusic := BackgroundMusic{Name: bundleMusic.Name}
+		if err := repository.Create(ctx, userID, &backgroundMusic); err != nil {
+			return nil, err
+		}
+
+		filePath := infrastructure.StorageObjectFilePath("bgm", strconv.FormatInt(backgroundMusic.ID, 10), "mp3")
+		imported.add(datastore.IDKey("BackgroundMusic", backgroundMusic.ID, datastore.IDKey("User", userID, nil)), filePath)
+		if err := infrastructure.CreateFile(ctx, infrastructure.MaterialBucketName(), filePath, "audio/mpeg", files[bundleMusic.File]); err != nil {
+			return nil, err
+		}
+		ids[bundleMusic.ID] = backgroundMusic.ID
+	}
+
+	return ids, nil
+}
+
+// importAvatarは、公開されているAvatarがインポート先にも存在すればそれを参照し、存在しなければuserIDのAvatarとして作成します。
+func (r *lessonBundleRepository) importAvatar(ctx context.Context, userID int64, bundleAvatar *LessonBundleAvatar, files map[string][]byte, imported *lessonBundleUserResources) (int64, error) {
+	if bundleAvatar.IsPublic {
+		if avatar, err := NewAvatarRepository(r.store).GetPublicByID(ctx, bundleAvatar.ID); err == nil && avatar.IsPublic {
+			return bundleAvatar.ID, nil
+		} else if err != nil && err != AvatarNotFound {
+			return 0, err
+		}
+	}
+
+	avatar := Avatar{Name: bundleAvatar.Name, Config: bundleAvatar.Config, Version: bundleAvatar.Version}
+	if err := NewAvatarRepository(r.store).Create(ctx, &avatar, &User{ID: userID}); err != nil {
+		return 0, err
+	}
+
+	filePath := infrastructure.StorageObjectFilePath("Avatar", strconv.FormatInt(avatar.ID, 10), "zst")
+	imported.add(datastore.IDKey("Avatar", avatar.ID, datastore.IDKey("User", userID, nil)), filePath)
+	if err := infrastructure.CreateFile(ctx, infrastructure.MaterialBucketName(), filePath, "application/zstd", files[bundleAvatar.File]); err != nil {
+		return 0, err
+	}
+
+	return avatar.ID, nil
+}
+
+// lessonBundleUserResourcesは、インポートで作成したUserに属するエンティティと、マテリアル用のバケットのファイルです。
+type lessonBundleUserResources struct {
+	keys      []*datastore.Key
+	filePaths []string
+}
+
+func (i *lessonBundleUserResources) add(key *datastore.Key, filePath string) {
+	i.keys = append(i.keys, key)
+	i.filePaths = append(i.filePaths, filePath)
+}
+
+// deleteは、作成したエンティティとファイルを削除します。インポートの失敗時に呼び出すので、削除のエラーは記録のみ行います。
+func (i *lessonBundleUserResources) delete(ctx context.Context, store infrastructure.Datastore) {
+	for _, filePath := range i.filePaths {
+		if err := infrastructure.DeleteFile(ctx, infrastructure.MaterialBucketName(), filePath); err != nil {
+			log.Printf("failed to delete imported file %s. %v\n", filePath, err)
+		}
+	}
+
+	if len(i.keys) == 0 {
+		return
+	}
+	if err := store.DeleteMulti(ctx, i.keys); err != nil {
+		log.Printf("failed to delete imported entities. %v\n", err)
+	}
+}
+
+// lessonBundleWriterは、zipに書き込んだファイルのチェックサムを記録し、最後にmanifest.jsonを書き込みます。
+type lessonBundleWriter struct {
+	buf   bytes.Buffer
+	zip   *zip.Writer
+	files []LessonBundleFile
+}
+
+func newLessonBundleWriter() *lessonBundleWriter {
+	w := &lessonBundleWriter{}
+	w.zip = zip.NewWriter(&w.buf)
+	return w
+}
+
+func (w *lessonBundleWriter) add(filePath string, contents []byte) error {
+	f, err := w.zip.Create(filePath)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(contents); err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(contents)
+	w.files = append(w.files, LessonBundleFile{Path: filePath, Size: int64(len(contents)), SHA256: hex.EncodeToString(sum[:])})
+	return nil
+}
+
+func (w *lessonBundleWriter) addJSON(filePath string, v interface{}) error {
+	contents, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return w.add(filePath, contents)
+}
+
+func (w *lessonBundleWriter) close(manifest *LessonBundleManifest) ([]byte, error) {
+	manifest.Files = w.files
+
+	contents, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	f, err := w.zip.Create(lessonBundleManifestPath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := f.Write(contents); err != nil {
+		return nil, err
+	}
+
+	if err := w.zip.Close(); err != nil {
+		return nil, err
+	}
+
+	return w.buf.Bytes(), nil
+}
+
+// readLessonBundleFilesは、アーカイブのファイルをmanifest.jsonのチェックサムと照合して返します。manifest.jsonに無いファイルは無効とします。
+func readLessonBundleFiles(data []byte) (map[string][]byte, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, InvalidLessonBundle
+	}
+
+	var totalSize uint64
+	contents := make(map[string][]byte, len(reader.File))
+	for _, f := range reader.File {
+		if _, ok := contents[f.Name]; ok {
+			return nil, InvalidLessonBundle
+		}
+
+		// 展開後のサイズも、展開する前にヘッダーの値で制限する
+		if f.UncompressedSize64 > LessonBundleMaxSize-totalSize {
+			return nil, LessonBundleTooLarge
+		}
+		totalSize += f.UncompressedSize64
+
+		rc, err := f.Open()
+		if err != nil {
+			return nil, InvalidLessonBundle
+		}
+		// ヘッダーの値より大きい内容は読み込まずに無効とする
+		body, err := ioutil.ReadAll(io.LimitReader(rc, int64(f.UncompressedSize64)+1))
+		rc.Close()
+		if err != nil || uint64(len(body)) != f.UncompressedSize64 {
+			return nil, InvalidLessonBundle
+		}
+		contents[f.Name] = body
+	}
+
+	var manifest LessonBundleManifest
+	if err := json.Unmarshal(contents[lessonBundleManifestPath], &manifest); err != nil || manifest.FormatVersion != lessonBundleFormatVersion {
+		return nil, InvalidLessonBundle
+	}
+	if len(manifest.Files) != len(contents)-1 {
+		return nil, InvalidLessonBundle
+	}
+
+	files := make(map[string][]byte, len(manifest.Files))
+	for _, file := range manifest.Files {
+		body, ok := contents[file.Path]
+		if !ok || file.Path == lessonBundleManifestPath || int64(len(body)) != file.Size {
+			return nil, InvalidLessonBundle
+		}
+		sum := sha256.Sum256(body)
+		if hex.EncodeToString(sum[:]) != file.SHA256 {
+			return nil, InvalidLessonBundle
+		}
+		files[file.Path] = body
+	}
+
+	return files, nil
+}
+
+// validateLessonBundleFilesは、lesson.jsonが参照するファイルが全てアーカイブに含まれていることを確認します。
+func validateLessonBundleFiles(bundle *LessonBundleLesson, files map[string][]byte) error {
+	var paths []string
+	for _, graphic := range bundle.Graphics {
+		if graphic.FileType == "" || path.Ext(graphic.File) != "."+graphic.FileType {
+			return InvalidLessonBundle
+		}
+		paths = append(paths, graphic.File)
+	}
+	for _, voice := range bundle.Voices {
+		if voice.File != "" {
+			paths = append(paths, voice.File)
+		}
+	}
+	for _, music := range bundle.BackgroundMusics {
+		if !music.IsPublic {
+			paths = append(paths, music.File)
+		}
+	}
+	if bundle.Avatar != nil {
+		paths = append(paths, bundle.Avatar.File)
+	}
+
+	for _, filePath := range paths {
+		if _, ok := files[filePath]; !ok {
+			return InvalidLessonBundle
+		}
+	}
+
+	return nil
+}
+
+func lessonBundleContentType(filePath string) string {
+	if contentType := mime.TypeByExtension(path.Ext(filePath)); contentType != "" {
+		return contentType
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"strconv"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+func TestReadLessonBundleFilesSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		sizes   []int
+		wantErr error
+	}{
+		{name: "rejects large entry", sizes: []int{LessonBundleMaxSize + 1}, wantErr: LessonBundleTooLarge},
+		{name: "rejects large total", sizes: []int{LessonBundleMaxSize / 2, LessonBundleMaxSize/2 + 1}, wantErr: LessonBundleTooLarge},
+		{name: "reads entries within limit", sizes: []int{1024}, wantErr: InvalidLessonBundle}, // manifest.jsonがないので無効
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// 圧縮後は小さくても、展開後のサイズで制限される
+			var buf bytes.Buffer
+			w := zip.NewWriter(&buf)
+			for i, size := range tt.sizes {
+				f, err := w.Create(string(rune('a' + i)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if _, err := f.Write(make([]byte, size)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := readLessonBundleFiles(buf.Bytes()); err != tt.wantErr {
+				t.Errorf("readLessonBundleFiles() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLessonBundleUserResourcesDelete(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDatastore(t)
+
+	backgroundMusic := BackgroundMusic{Name: "bgm"}
+	if err := NewBackgroundMusicRepository(store).Create(ctx, 1, &backgroundMusic); err != nil {
+		t.Fatal(err)
+	}
+	filePath := infrastructure.StorageObjectFilePath("bgm", strconv.FormatInt(backgroundMusic.ID, 10), "mp3")
+	if err := infrastructure.CreateFile(ctx, infrastructure.MaterialBucketName(), filePath, "audio/mpeg", []byte("music")); err != nil {
+		t.Fatal(err)
+	}
+
+	// インポートに失敗した場合、作成済みのBackgroundMusicとファイルは削除される
+	var imported lessonBundleUserResources
+	key := datastore.IDKey("BackgroundMusic", backgroundMusic.ID, datastore.IDKey("User", 1, nil))
+	imported.add(key, filePath)
+	imported.delete(ctx, store)
+
+	if err := store.Get(ctx, key, &BackgroundMusic{}); err != datastore.ErrNoSuchEntity {
+		t.Errorf("Get() error = %v, want %v", err, datastore.ErrNoSuchEntity)
+	}
+	if _, err := infrastructure.GetFile(ctx, infrastructure.MaterialBucketName(), filePath); err != infrastructure.ErrObjectNotFound {
+		t.Errorf("GetFile() error = %v, want %v", err, infrastructure.ErrObjectNotFound)
+	}
+}
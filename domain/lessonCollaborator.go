@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// LessonCollaboratorは、Lessonの共同編集者です。Lessonを祖先に持ち、キーのIDは共同編集者のUserのIDです。
+// Lessonの作成者は常にLessonRoleOwnerで、LessonCollaboratorは作成されません。
+type LessonCollaborator struct {
+	LessonID    int64                    `json:"lessonID" datastore:"-"`
+	LessonTitle string                   `json:"lessonTitle,omitempty" datastore:"-"`
+	UserID      int64                    `json:"userID"`
+	Role        LessonRole               `json:"role" datastore:",noindex"`
+	Status      LessonCollaboratorStatus `json:"status" datastore:",noindex"`
+	InvitedBy   int64                    `json:"invitedBy" datastore:",noindex"`
+	Created     time.Time                `json:"created" datastore:",noindex"`
+	Updated     time.Time                `json:"updated" datastore:",noindex"`
+}
+
+type LessonCollaboratorErrorCode uint
+
+const (
+	LessonCollaboratorNotFound LessonCollaboratorErrorCode = 1
+	InvalidLessonCollaborator  LessonCollaboratorErrorCode = 2
+)
+
+func (e LessonCollaboratorErrorCode) Error() string {
+	switch e {
+	case LessonCollaboratorNotFound:
+		return "lesson collaborator not found"
+	case InvalidLessonCollaborator:
+		return "invalid lesson collaborator"
+	default:
+		return "unknown lesson collaborator error"
+	}
+}
+
+// LessonCollaboratorRepositoryは、LessonCollaboratorの永続化と、Lessonに対するUserの権限の判定を行います。
+type LessonCollaboratorRepository interface {
+	GetByLessonID(ctx context.Context, lessonID int64) ([]LessonCollaborator, error)
+	GetByUserID(ctx context.Context, userID int64) ([]LessonCollaborator, error)
+	GetRole(ctx context.Context, lesson *Lesson, userID int64) (LessonRole, error)
+	Invite(ctx context.Context, lesson *Lesson, userID int64, role LessonRole) (LessonCollaborator, error)
+	Accept(ctx context.Context, lessonID int64, userID int64) (LessonCollaborator, error)
+	Remove(ctx context.Context, lessonID int64, userID int64) error
+}
+
+type lessonCollaboratorRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonCollaboratorRepositoryは、storeを使用するLessonCollaboratorRepositoryを返します。
+func NewLessonCollaboratorRepository(store infrastructure.Datastore) LessonCollaboratorRepository {
+	return &lessonCollaboratorRepository{store: store}
+}
+
+func (r *lessonCollaboratorRepository) GetByLessonID(ctx context.Context, lessonID int64) ([]LessonCollaborator, error) {
+	var collaborators []LessonCollaborator
+
+	ancestor := datastore.IDKey("Lesson", lessonID, nil)
+	query := infrastructure.NewQuery("LessonCollaborator").Ancestor(ancestor)
+	if _, err := r.store.GetAll(ctx, query, &collaborators); err != nil {
+		return nil, err
+	}
+
+	for i := range collaborators {
+		collaborators[i].LessonID = lessonID
+	}
+
+	return collaborators, nil
+}
+
+// GetByUserIDは、Userが招待されているか参加しているLessonのLessonCollaboratorを返します。
+func (r *lessonCollaboratorRepository) GetByUserID(ctx context.Context, userID int64) ([]LessonCollaborator, error) {
+	var collaborators []LessonCollaborator
+
+	query := infrastructure.NewQuery("LessonCollaborator").Filter("UserID =", userID)
+	keys, err := r.store.GetAll(ctx, query, &collaborators)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		collaborators[i].LessonID = key.Parent.ID
+	}
+
+	return collaborators, nil
+}
+
+// GetRoleは、Lessonに対するuserIDのUserの権限を返します。招待を承諾していない場合はLessonRoleNoneです。
+func (r *lessonCollaboratorRepository) GetRole(ctx context.Context, lesson *Lesson, userID int64) (LessonRole, error) {
+	if lesson.UserID == userID {
+		return LessonRoleOwner, nil
+	}
+
+	var collaborator LessonCollaborator
+	if err := r.store.Get(ctx, lessonCollaboratorKey(lesson.ID, userID), &collaborator); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return LessonRoleNone, nil
+		}
+		return LessonRoleNone, err
+	}
+
+	if collaborator.Status != LessonCollaboratorStatusAccepted {
+		return LessonRoleNone, nil
+	}
+
+	return collaborator.Role, nil
+}
+
+// Inviteは、userIDのUserをroleの共同編集者として招待します。招待済みや参加済みの場合は、状態を維持して権限のみ変更します。
+func (r *lessonCollaboratorRepository) Invite(ctx context.Context, lesson *Lesson, userID int64, role LessonRole) (LessonCollaborator, error) {
+	var collaborator LessonCollaborator
+	if role != LessonRoleViewer && role != LessonRoleEditor {
+		return collaborator, InvalidLessonCollaborator
+	}
+	if userID == lesson.UserID || lesson.IsIntroduction {
+		return collaborator, InvalidLessonCollaborator
+	}
+
+	key := lessonCollaboratorKey(lesson.ID, userID)
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var user User
+		if err := tx.Get(datastore.IDKey("User", userID, nil), &user); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return InvalidLessonCollaborator
+			}
+			return err
+		}
+
+		currentTime := time.Now()
+		collaborator = LessonCollaborator{}
+		if err := tx.Get(key, &collaborator); err == datastore.ErrNoSuchEntity {
+			collaborator = LessonCollaborator{
+				UserID:    userID,
+				Status:    LessonCollaboratorStatusInvited,
+				InvitedBy: lesson.UserID,
+				Created:   currentTime,
+			}
+		} else if err != nil {
+			return err
+		}
+
+		collaborator.Role = role
+		collaborator.Updated = currentTime
+		return tx.Put(key, &collaborator)
+	})
+
+	if err != nil {
+		return collaborator, err
+	}
+	collaborator.LessonID = lesson.ID
+
+	return collaborator, nil
+}
+
+// Acceptは、userIDのUserへの招待を承諾します。
+func (r *lessonCollaboratorRepository) Accept(ctx context.Context, lessonID int64, userID int64) (LessonCollaborator, error) {
+	var collaborator LessonCollaborator
+
+	key := lessonCollaboratorKey(lessonID, userID)
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		collaborator = LessonCollaborator{}
+		if err := tx.Get(key, &collaborator); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return LessonCollaboratorNotFound
+			}
+			return err
+		}
+
+		collaborator.Status = LessonCollaboratorStatusAccepted
+		collaborator.Updated = time.Now()
+		return tx.Put(key, &collaborator)
+	})
+
+	if err != nil {
+		return collaborator, err
+	}
+	collaborator.LessonID = lessonID
+
+	return collaborator, nil
+}
+
+// Removeは、userIDのUserを共同編集者から外します。招待中の場合は招待を取り消します。
+func (r *lessonCollaboratorRepository) Remove(ctx context.Context, lessonID int64, userID int64) error {
+	key := lessonCollaboratorKey(lessonID, userID)
+	return r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var collaborator LessonCollaborator
+		if err := tx.Get(key, &collaborator); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return LessonCollaboratorNotFound
+			}
+			return err
+		}
+
+		return tx.Delete(key)
+	})
+}
+
+func lessonCollaboratorKey(lessonID int64, userID int64) *datastore.Key {
+	return datastore.IDKey("LessonCollaborator", userID, datastore.IDKey("Lesson", lessonID, nil))
+}
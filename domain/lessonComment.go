@@ -0,0 +1,388 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+	"google.golang.org/api/iterator"
+)
+
+// LessonCommentは、公開中のLessonへの質問やコメントと、それへの返信です。Lessonを祖先に持ちます。
+// ParentIDが0のものがスレッドの先頭で、返信はスレッドの先頭にのみ付けられます。
+type LessonComment struct {
+	ID             int64     `json:"id" datastore:"-"`
+	LessonID       int64     `json:"lessonID" datastore:"-"`
+	UserID         int64     `json:"userID"`
+	AuthorName     string    `json:"authorName" datastore:"-"`
+	ParentID       int64     `json:"parentID"`
+	HasElapsedTime bool      `json:"hasElapsedTime" datastore:",noindex"`
+	ElapsedTime    float32   `json:"elapsedTime" datastore:",noindex"` // 教材の再生時刻。スレッドの先頭のみ
+	Body           string    `json:"body" datastore:",noindex"`
+	ReplyCount     int64     `json:"replyCount" datastore:",noindex"` // 非表示のものを除く返信の数
+	AnswerID       int64     `json:"answerID" datastore:",noindex"`   // Lessonの作者が回答に選んだ返信
+	Hidden         bool      `json:"hidden"`                          // Lessonの作者により非表示にされたもの
+	Edited         time.Time `json:"edited" datastore:",noindex"`
+	Created        time.Time `json:"created"`
+	Updated        time.Time `json:"updated" datastore:",noindex"`
+}
+
+type LessonCommentErrorCode uint
+
+const (
+	LessonCommentNotFound LessonCommentErrorCode = 1
+	InvalidLessonComment  LessonCommentErrorCode = 2
+	LessonCommentsClosed  LessonCommentErrorCode = 3
+)
+
+func (e LessonCommentErrorCode) Error() string {
+	switch e {
+	case LessonCommentNotFound:
+		return "lesson comment not found"
+	case InvalidLessonComment:
+		return "invalid lesson comment"
+	case LessonCommentsClosed:
+		return "lesson comments are closed"
+	default:
+		return "unknown lesson comment error"
+	}
+}
+
+const (
+	LessonCommentDefaultPageSize = 20
+	LessonCommentMaxPageSize     = 50
+	lessonCommentBodyMaxLength   = 1000
+)
+
+// LessonCommentRepositoryは、LessonCommentの永続化を行います。
+type LessonCommentRepository interface {
+	GetByID(ctx context.Context, lessonID int64, id int64) (LessonComment, error)
+	GetList(ctx context.Context, lessonID int64, parentID int64, includesHidden bool, pageSize int, cursorStr string) ([]LessonComment, string, error)
+	Create(ctx context.Context, lesson *Lesson, comment *LessonComment) error
+	UpdateBody(ctx context.Context, lessonID int64, id int64, body string) (LessonComment, error)
+	SetHidden(ctx context.Context, lessonID int64, id int64, hidden bool) (LessonComment, error)
+	SetAnswer(ctx context.Context, lessonID int64, id int64, answerID int64) (LessonComment, error)
+	Delete(ctx context.Context, lessonID int64, id int64) error
+}
+
+type lessonCommentRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonCommentRepositoryは、storeを使用するLessonCommentRepositoryを返します。
+func NewLessonCommentRepository(store infrastructure.Datastore) LessonCommentRepository {
+	return &lessonCommentRepository{store: store}
+}
+
+func (r *lessonCommentRepository) GetByID(ctx context.Context, lessonID int64, id int64) (LessonComment, error) {
+	var comment LessonComment
+	if err := r.store.Get(ctx, lessonCommentKey(lessonID, id), &comment); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return comment, LessonCommentNotFound
+		}
+		return comment, err
+	}
+
+	comment.ID = id
+	comment.LessonID = lessonID
+
+	return comment, nil
+}
+
+// GetListは、parentIDが0の場合はスレッドの先頭を新しい順に、それ以外はparentIDのスレッドへの返信を古い順に、最大pageSize件と続きを取得するためのカーソルを返します。
+// 続きがない場合、カーソルは空文字列です。includesHiddenがfalseの場合は、非表示のものを除きます。
+func (r *lessonCommentRepository) GetList(ctx context.Context, lessonID int64, parentID int64, includesHidden bool, pageSize int, cursorStr string) ([]LessonComment, string, error) {
+	if pageSize == 0 {
+		pageSize = LessonCommentDefaultPageSize
+	}
+	if pageSize < 0 || pageSize > LessonCommentMaxPageSize {
+		return nil, "", InvalidLessonComment
+	}
+
+	ancestor := datastore.IDKey("Lesson", lessonID, nil)
+	query := infrastructure.NewQuery("LessonComment").Ancestor(ancestor).Filter("ParentID =", parentID)
+	if !includesHidden {
+		query = query.Filter("Hidden =", false)
+	}
+	if parentID == 0 {
+		query = query.Order("-Created")
+	} else {
+		query = query.Order("Created")
+	}
+	query = query.Limit(pageSize)
+	if cursorStr != "" {
+		query = query.Start(cursorStr)
+	}
+
+	var comments []LessonComment
+	it := r.store.Run(ctx, query)
+	for {
+		var comment LessonComment
+		key, err := it.Next(&comment)
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, "", err
+		}
+		comment.ID = key.ID
+		comment.LessonID = lessonID
+		comments = append(comments, comment)
+	}
+
+	if len(comments) < pageSize {
+		return comments, "", nil
+	}
+
+	nextCursor, err := it.Cursor()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return comments, nextCursor, nil
+}
+
+// Createは、LessonCommentを作成します。返信の場合はスレッドの先頭の返信の数を増分します。
+// コメントを受け付けていないLessonや、非表示のスレッドには作成できません。
+func (r *lessonCommentRepository) Create(ctx context.Context, lesson *Lesson, comment *LessonComment) error {
+	if lesson.CommentsClosed {
+		return LessonCommentsClosed
+	}
+
+	comment.Body = strings.TrimSpace(comment.Body)
+	if err := validateLessonCommentBody(comment.Body); err != nil {
+		return err
+	}
+	if comment.HasElapsedTime {
+		if comment.ParentID != 0 || comment.ElapsedTime < 0 || (lesson.DurationSec > 0 && comment.ElapsedTime > lesson.DurationSec) {
+			return InvalidLessonComment
+		}
+	} else {
+		comment.ElapsedTime = 0
+	}
+
+	if comment.ParentID != 0 {
+		parent, err := r.GetByID(ctx, lesson.ID, comment.ParentID)
+		if err != nil {
+			return err
+		}
+		if parent.ParentID != 0 {
+			return InvalidLessonComment
+		}
+		if parent.Hidden {
+			return LessonCommentNotFound
+		}
+	}
+
+	currentTime := time.Now()
+	comment.LessonID = lesson.ID
+	comment.ReplyCount = 0
+	comment.AnswerID = 0
+	comment.Hidden = false
+	comment.Edited = time.Time{}
+	comment.Created = currentTime
+	comment.Updated = currentTime
+
+	// 不完全キーの採番結果はトランザクション中に取得できないので、作成してからスレッドの先頭を更新し、失敗した場合は取り消す
+	key, err := r.store.Put(ctx, datastore.IncompleteKey("LessonComment", datastore.IDKey("Lesson", lesson.ID, nil)), comment)
+	if err != nil {
+		return err
+	}
+	comment.ID = key.ID
+
+	if comment.ParentID == 0 {
+		return nil
+	}
+
+	err = r.updateParent(ctx, lesson.ID, comment.ParentID, func(parent *LessonComment) error {
+		if parent.Hidden {
+			return LessonCommentNotFound
+		}
+		parent.ReplyCount++
+		return nil
+	})
+	if err != nil {
+		if deleteErr := r.store.Delete(ctx, key); deleteErr != nil {
+			return deleteErr
+		}
+		return err
+	}
+
+	return nil
+}
+
+// UpdateBodyは、LessonCommentの本文を変更します。
+func (r *lessonCommentRepository) UpdateBody(ctx context.Context, lessonID int64, id int64, body string) (LessonComment, error) {
+	body = strings.TrimSpace(body)
+	if err := validateLessonCommentBody(body); err != nil {
+		return LessonComment{}, err
+	}
+
+	return r.update(ctx, lessonID, id, func(tx infrastructure.Transaction, comment *LessonComment) error {
+		currentTime := time.Now()
+		comment.Body = body
+		comment.Edited = currentTime
+		comment.Updated = currentTime
+		return nil
+	})
+}
+
+// SetHiddenは、LessonCommentを非表示にするか、再表示します。返信の場合はスレッドの先頭の返信の数を更新し、回答に選ばれていれば選択を解除します。
+func (r *lessonCommentRepository) SetHidden(ctx context.Context, lessonID int64, id int64, hidden bool) (LessonComment, error) {
+	return r.update(ctx, lessonID, id, func(tx infrastructure.Transaction, comment *LessonComment) error {
+		if comment.Hidden == hidden {
+			return nil
+		}
+		comment.Hidden = hidden
+		comment.Updated = time.Now()
+
+		if comment.ParentID == 0 {
+			return nil
+		}
+
+		parentKey := lessonCommentKey(lessonID, comment.ParentID)
+		var parent LessonComment
+		if err := tx.Get(parentKey, &parent); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+		if hidden {
+			parent.ReplyCount--
+			if parent.AnswerID == id {
+				parent.AnswerID = 0
+			}
+		} else {
+			parent.ReplyCount++
+		}
+		return tx.Put(parentKey, &parent)
+	})
+}
+
+// SetAnswerは、スレッドの先頭のLessonCommentに、回答として返信のanswerIDを設定します。answerIDが0の場合は選択を解除します。
+func (r *lessonCommentRepository) SetAnswer(ctx context.Context, lessonID int64, id int64, answerID int64) (LessonComment, error) {
+	return r.update(ctx, lessonID, id, func(tx infrastructure.Transaction, comment *LessonComment) error {
+		if comment.ParentID != 0 {
+			return InvalidLessonComment
+		}
+
+		if answerID != 0 {
+			var answer LessonComment
+			if err := tx.Get(lessonCommentKey(lessonID, answerID), &answer); err != nil {
+				if err == datastore.ErrNoSuchEntity {
+					return LessonCommentNotFound
+				}
+				return err
+			}
+			if answer.ParentID != id || answer.Hidden {
+				return InvalidLessonComment
+			}
+		}
+
+		comment.AnswerID = answerID
+		comment.Updated = time.Now()
+		return nil
+	})
+}
+
+// Deleteは、LessonCommentを削除します。スレッドの先頭の場合は全ての返信も削除し、返信の場合はスレッドの先頭の返信の数と回答の選択を更新します。
+func (r *lessonCommentRepository) Delete(ctx context.Context, lessonID int64, id int64) error {
+	comment, err := r.GetByID(ctx, lessonID, id)
+	if err != nil {
+		return err
+	}
+
+	if comment.ParentID == 0 {
+		ancestor := datastore.IDKey("Lesson", lessonID, nil)
+		query := infrastructure.NewQuery("LessonComment").Ancestor(ancestor).Filter("ParentID =", id).KeysOnly()
+		keys, err := r.store.GetAll(ctx, query, nil)
+		if err != nil {
+			return err
+		}
+		// 先頭を先に削除し、返信の削除に失敗しても一覧には表示されないようにする
+		if err := r.store.Delete(ctx, lessonCommentKey(lessonID, id)); err != nil {
+			return err
+		}
+		return r.store.DeleteMulti(ctx, keys)
+	}
+
+	return r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		key := lessonCommentKey(lessonID, id)
+		var reply LessonComment
+		if err := tx.Get(key, &reply); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return LessonCommentNotFound
+			}
+			return err
+		}
+
+		parentKey := lessonCommentKey(lessonID, reply.ParentID)
+		var parent LessonComment
+		if err := tx.Get(parentKey, &parent); err == nil {
+			if !reply.Hidden {
+				parent.ReplyCount--
+			}
+			if parent.AnswerID == id {
+				parent.AnswerID = 0
+			}
+			parent.Updated = time.Now()
+			if err := tx.Put(parentKey, &parent); err != nil {
+				return err
+			}
+		} else if err != datastore.ErrNoSuchEntity {
+			return err
+		}
+
+		return tx.Delete(key)
+	})
+}
+
+// updateは、トランザクションでLessonCommentを取得してfで変更し、保存します。
+func (r *lessonCommentRepository) update(ctx context.Context, lessonID int64, id int64, f func(tx infrastructure.Transaction, comment *LessonComment) error) (LessonComment, error) {
+	var comment LessonComment
+
+	key := lessonCommentKey(lessonID, id)
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		comment = LessonComment{}
+		if err := tx.Get(key, &comment); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return LessonCommentNotFound
+			}
+			return err
+		}
+
+		if err := f(tx, &comment); err != nil {
+			return err
+		}
+		return tx.Put(key, &comment)
+	})
+
+	if err != nil {
+		return comment, err
+	}
+	comment.ID = id
+	comment.LessonID = lessonID
+
+	return comment, nil
+}
+
+func (r *lessonCommentRepository) updateParent(ctx context.Context, lessonID int64, parentID int64, f func(parent *LessonComment) error) error {
+	_, err := r.update(ctx, lessonID, parentID, func(tx infrastructure.Transaction, parent *LessonComment) error {
+		return f(parent)
+	})
+	return err
+}
+
+func validateLessonCommentBody(body string) error {
+	if body == "" || utf8.RuneCountInString(body) > lessonCommentBodyMaxLength {
+		return InvalidLessonComment
+	}
+	return nil
+}
+
+func lessonCommentKey(lessonID int64, id int64) *datastore.Key {
+	return datastore.IDKey("LessonComment", id, datastore.IDKey("Lesson", lessonID, nil))
+}
@@ -2,35 +2,198 @@ package domain
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"cloud.google.com/go/datastore"
+	"github.com/klauspost/compress/zstd"
 	"github.com/super-dog-human/teraconnectgo/infrastructure"
 )
 
-func createLessonMaterialForCompressing(ctx context.Context, id string, lessonMaterial *LessonMaterial) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
+// LessonCompressingTaskは、圧縮タスクのPayloadです。
+type LessonCompressingTask struct {
+	LessonID int64 `json:"lessonID"`
+}
+
+type LessonCompressingErrorCode uint
+
+const (
+	LessonVersionConflicted LessonCompressingErrorCode = 1
+	InvalidCompressingTask  LessonCompressingErrorCode = 2
+)
+
+func (e LessonCompressingErrorCode) Error() string {
+	switch e {
+	case LessonVersionConflicted:
+		return "lesson version has been changed while compressing"
+	case InvalidCompressingTask:
+		return "invalid compressing task"
+	default:
+		return "unknown lesson compressing error"
+	}
+}
+
+// LessonCompressingRepositoryは、LessonMaterialForCompressingのスナップショットを圧縮して公開します。
+type LessonCompressingRepository interface {
+	Compress(ctx context.Context, taskName string, lessonID int64) error
+}
+
+type lessonCompressingRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonCompressingRepositoryは、storeを使用するLessonCompressingRepositoryを返します。
+func NewLessonCompressingRepository(store infrastructure.Datastore) LessonCompressingRepository {
+	return &lessonCompressingRepository{store: store}
+}
+
+// LessonBodyFilePathは、圧縮したLessonMaterialのファイルパスを返します。
+func LessonBodyFilePath(lessonID int64, version int32) string {
+	return fmt.Sprintf("lesson/%d/body-%d.zst", lessonID, version)
+}
+
+// LessonIDFromCompressingTaskNameは、LessonCompressingTaskNameで作成したタスク名からLessonのIDを取り出します。
+func LessonIDFromCompressingTaskName(taskName string) (int64, error) {
+	parts := strings.Split(taskName, "-")
+	if len(parts) < 3 {
+		return 0, InvalidCompressingTask
+	}
+
+	lessonID, err := strconv.ParseInt(parts[len(parts)-2], 10, 64)
 	if err != nil {
+		return 0, InvalidCompressingTask
+	}
+
+	return lessonID, nil
+}
+
+// Compressは、taskNameのスナップショットをzstdで圧縮してLessonの公開状態に応じたバケットに保存し、Lessonのバージョンを更新してLessonVersionに記録します。
+// 処理済みのスナップショットや、より新しい内容が公開済みの場合は何もしないので、同じタスクが複数回実行されても問題ありません。
+func (r *lessonCompressingRepository) Compress(ctx context.Context, taskName string, lessonID int64) error {
+	snapshotKey := datastore.NameKey("LessonMaterialForCompressing", taskName, nil)
+	var snapshot LessonMaterial
+	if err := r.store.Get(ctx, snapshotKey, &snapshot); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return nil
+		}
 		return err
 	}
 
+	lessonKey := datastore.IDKey("Lesson", lessonID, nil)
+	var lesson Lesson
+	if err := r.store.Get(ctx, lessonKey, &lesson); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return r.store.Delete(ctx, snapshotKey)
+		}
+		return err
+	}
+
+	if lesson.Status == LessonStatusDraft || !snapshot.Updated.After(lesson.Published) {
+		// 非公開に戻されたか、同じかより新しい内容が公開済み
+		return r.store.Delete(ctx, snapshotKey)
+	}
+
+	snapshot.ID = lesson.MaterialID
+	body, err := compressLessonMaterial(&snapshot)
+	if err != nil {
+		return err
+	}
+
+	version := lesson.Version + 1
+	bucketName := LessonBucketName(lesson.Status)
+
+	if err := infrastructure.CreateFile(ctx, bucketName, LessonBodyFilePath(lessonID, version), "application/zstd", body); err != nil {
+		return err
+	}
+
+	var published Lesson
+	err = r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var current Lesson
+		if err := tx.Get(lessonKey, &current); err != nil {
+			return err
+		}
+
+		if current.Version != lesson.Version {
+			// 並行して別のタスクがバージョンを更新したので、再試行で改めて比較する
+			return LessonVersionConflicted
+		}
+
+		current.Version = version
+		current.AvatarID = snapshot.AvatarID
+		current.AvatarLightColor = snapshot.AvatarLightColor
+		current.DurationSec = snapshot.DurationSec
+		current.Published = snapshot.Updated
+		if err := tx.Put(lessonKey, &current); err != nil {
+			return err
+		}
+
+		lessonVersion := LessonVersion{
+			UserID:      current.UserID,
+			Title:       current.Title,
+			Description: current.Description,
+			Status:      lesson.Status,
+			DurationSec: snapshot.DurationSec,
+			Material:    snapshot,
+			Published:   snapshot.Updated,
+		}
+		if err := createLessonVersionInTransaction(tx, lessonID, version, &lessonVersion); err != nil {
+			return err
+		}
+
+		published = current
+		published.ID = lessonID
+		return tx.Delete(snapshotKey)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// 公開日時が更新されたので検索インデックスにも反映する
+	return NewLessonSearchRepository(r.store).Upsert(ctx, &published)
+}
+
+// compressLessonMaterialは、公開用の教材を作成します。LessonQuizの正解と解説は含めません。
+func compressLessonMaterial(lessonMaterial *LessonMaterial) ([]byte, error) {
+	publicMaterial := *lessonMaterial
+	publicMaterial.Quizzes = publicLessonQuizzes(lessonMaterial.Quizzes)
+
+	body, err := json.Marshal(&publicMaterial)
+	if err != nil {
+		return nil, err
+	}
+
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	defer encoder.Close()
+
+	return encoder.EncodeAll(body, nil), nil
+}
+
+func createLessonMaterialForCompressing(ctx context.Context, store infrastructure.Datastore, id string, lessonMaterial *LessonMaterial) error {
 	key := datastore.NameKey("LessonMaterialForCompressing", id, nil)
-	if _, err := client.Put(ctx, key, lessonMaterial); err != nil {
+	if _, err := store.Put(ctx, key, lessonMaterial); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func createCompressingTask(ctx context.Context, taskName string, materialID int64, currentTime time.Time) error {
-	var taskEta time.Time
-	if infrastructure.AppEnv() == "production" {
-		taskEta = currentTime.Add(5 * time.Minute)
-	} else {
-		taskEta = currentTime.Add(1 * time.Minute)
+func createCompressingTask(ctx context.Context, taskName string, lessonID int64, currentTime time.Time) error {
+	taskEta := currentTime.Add(infrastructure.CurrentConfig().LessonCompressingDelay)
+
+	// スナップショットはtaskNameで取得できるので、PayloadにはLessonのIDのみを含める
+	task, err := infrastructure.NewJSONTask(LessonCompressingQueue, taskName, taskEta, LessonCompressingTask{LessonID: lessonID})
+	if err != nil {
+		return err
 	}
-	// タスクに必要な情報はtaskNameで事足りるのでmessageは空文字でよい
-	if _, err := infrastructure.CreateTask(ctx, taskName, taskEta, ""); err != nil {
+
+	if err := infrastructure.EnqueueTask(ctx, task); err != nil {
 		return err
 	}
 
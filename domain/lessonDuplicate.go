@@ -0,0 +1,286 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/klauspost/compress/zstd"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// LessonDuplicateRepositoryは、LessonをLessonMaterial、Graphic、Voiceとそれらのファイルを含めて複製します。
+type LessonDuplicateRepository interface {
+	Duplicate(ctx context.Context, userID int64, source *Lesson, duplicated *Lesson) error
+}
+
+type lessonDuplicateRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonDuplicateRepositoryは、storeを使用するLessonDuplicateRepositoryを返します。
+func NewLessonDuplicateRepository(store infrastructure.Datastore) LessonDuplicateRepository {
+	return &lessonDuplicateRepository{store: store}
+}
+
+// Duplicateは、sourceを複製したuserIDの下書きのLessonをduplicatedに作成します。
+// 他のUserのLessonは公開済みの内容を、自分のLessonは編集中の内容を複製します。教材中のGraphicとVoiceのIDは複製したものに書き換えます。
+// 途中で失敗した場合、duplicated.IDが0でなければ作成済みのLessonが残っているので、呼び出し側で削除する必要があります。
+func (r *lessonDuplicateRepository) Duplicate(ctx context.Context, userID int64, source *Lesson, duplicated *Lesson) error {
+	material, err := r.sourceMaterial(ctx, userID, source)
+	if err != nil {
+		return err
+	}
+
+	currentTime := time.Now()
+	*duplicated = Lesson{
+		UserID:               userID,
+		SubjectID:            source.SubjectID,
+		SubjectName:          source.SubjectName,
+		JapaneseCategoryID:   source.JapaneseCategoryID,
+		JapaneseCategoryName: source.JapaneseCategoryName,
+		SecondaryCategoryIDs: source.SecondaryCategoryIDs,
+		JapaneseCategoryIDs:  source.JapaneseCategoryIDs,
+		Tags:                 source.Tags,
+		Title:                source.Title,
+		Description:          source.Description,
+		References:           source.References,
+		License:              source.License,
+		AvatarID:             source.AvatarID,
+		AvatarLightColor:     source.AvatarLightColor,
+		DurationSec:          source.DurationSec,
+		OriginalLessonID:     source.ID,
+		OriginalUserID:       source.UserID,
+		OriginalVersion:      source.Version,
+		Status:               LessonStatusDraft,
+		Created:              currentTime,
+		Updated:              currentTime,
+	}
+
+	lessonKey, err := r.store.Put(ctx, datastore.IncompleteKey("Lesson", nil), duplicated)
+	if err != nil {
+		return err
+	}
+	duplicated.ID = lessonKey.ID
+
+	graphicIDs, err := r.duplicateGraphics(ctx, userID, source.ID, duplicated.ID, referencedGraphicIDs(&material))
+	if err != nil {
+		return err
+	}
+
+	voiceIDs, err := r.duplicateVoices(ctx, userID, source.ID, duplicated.ID, referencedVoiceIDs(&material))
+	if err != nil {
+		return err
+	}
+
+	material.UserID = userID
+	material.Created = currentTime
+	material.Updated = currentTime
+	for i, graphic := range material.Graphics {
+		material.Graphics[i].GraphicID = graphicIDs[graphic.GraphicID]
+	}
+	for i, speech := range material.Speeches {
+		if speech.VoiceID != 0 {
+			material.Speeches[i].VoiceID = voiceIDs[speech.VoiceID]
+		}
+	}
+
+	materialKey, err := r.store.Put(ctx, datastore.IncompleteKey("LessonMaterial", lessonKey), &material)
+	if err != nil {
+		return err
+	}
+
+	duplicated.MaterialID = materialKey.ID
+	if _, err := r.store.Put(ctx, lessonKey, duplicated); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// sourceMaterialは、複製元の教材を返します。他のUserのLessonは、公開されたバージョンの教材を使用し、編集中の内容は複製しません。
+func (r *lessonDuplicateRepository) sourceMaterial(ctx context.Context, userID int64, source *Lesson) (LessonMaterial, error) {
+	var material LessonMaterial
+
+	if source.UserID != userID {
+		if source.Version == 0 {
+			return material, LessonVersionNotPublished
+		}
+
+		version, err := NewLessonVersionRepository(r.store).Get(ctx, source.ID, source.Version)
+		if err == nil {
+			return version.Material, nil
+		} else if !errors.Is(err, LessonVersionNotFound) {
+			return material, err
+		}
+
+		// 履歴の記録を始める前に公開されたLessonは、公開済みの圧縮ファイルから複製する
+		return publishedLessonMaterial(ctx, source)
+	}
+
+	ancestor := datastore.IDKey("Lesson", source.ID, nil)
+	key := datastore.IDKey("LessonMaterial", source.MaterialID, ancestor)
+	if err := r.store.Get(ctx, key, &material); err != nil {
+		return material, err
+	}
+
+	return material, nil
+}
+
+// publishedLessonMaterialは、公開済みの圧縮された教材を読み込みます。LessonQuizの正解と解説は含まれません。
+func publishedLessonMaterial(ctx context.Context, lesson *Lesson) (LessonMaterial, error) {
+	var material LessonMaterial
+
+	body, err := infrastructure.GetFile(ctx, LessonBucketName(lesson.Status), LessonBodyFilePath(lesson.ID, lesson.Version))
+	if err != nil {
+		if errors.Is(err, infrastructure.ErrObjectNotFound) {
+			return material, LessonVersionNotPublished
+		}
+		return material, err
+	}
+
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return material, err
+	}
+	defer decoder.Close()
+
+	decoded, err := decoder.DecodeAll(body, nil)
+	if err != nil {
+		return material, err
+	}
+
+	if err := json.Unmarshal(decoded, &material); err != nil {
+		return material, err
+	}
+
+	return material, nil
+}
+
+// duplicateGraphicsは、LessonのGraphicのうち教材が参照するものとそのファイルをuserIDのものとして複製し、複製元のIDから複製先のIDへの対応を返します。
+func (r *lessonDuplicateRepository) duplicateGraphics(ctx context.Context, userID int64, sourceLessonID int64, lessonID int64, referencedIDs map[int64]bool) (map[int64]int64, error) {
+	var allGraphics []Graphic
+	query := infrastructure.NewQuery("Graphic").Filter("LessonID =", sourceLessonID)
+	allKeys, err := r.store.GetAll(ctx, query, &allGraphics)
+	if err != nil {
+		return nil, err
+	}
+
+	// 教材から参照されていないGraphicは、公開されていない可能性があるので複製しない
+	var graphics []Graphic
+	var sourceKeys []*datastore.Key
+	for i, graphic := range allGraphics {
+		if referencedIDs[allKeys[i].ID] {
+			graphics = append(graphics, graphic)
+			sourceKeys = append(sourceKeys, allKeys[i])
+		}
+	}
+
+	ids := make(map[int64]int64, len(graphics))
+	if len(graphics) == 0 {
+		return ids, nil
+	}
+
+	ancestor := datastore.IDKey("User", userID, nil)
+	keys := make([]*datastore.Key, len(graphics))
+	for i := range graphics {
+		keys[i] = datastore.IncompleteKey("Graphic", ancestor)
+		graphics[i].LessonID = lessonID
+	}
+
+	putKeys, err := r.store.PutMulti(ctx, keys, graphics)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketName := infrastructure.MaterialBucketName()
+	for i, graphic := range graphics {
+		ids[sourceKeys[i].ID] = putKeys[i].ID
+
+		if graphic.PublicGraphicID != 0 {
+			continue // PublicGraphicのファイルは共有されている
+		}
+
+		srcPath := infrastructure.StorageObjectFilePath("Graphic", strconv.FormatInt(sourceKeys[i].ID, 10), graphic.FileType)
+		dstPath := infrastructure.StorageObjectFilePath("Graphic", strconv.FormatInt(putKeys[i].ID, 10), graphic.FileType)
+		if err := infrastructure.CopyFile(ctx, bucketName, srcPath, bucketName, dstPath); err != nil && !errors.Is(err, infrastructure.ErrObjectNotFound) {
+			return nil, err
+		}
+	}
+
+	return ids, nil
+}
+
+// duplicateVoicesは、LessonのVoiceのうち教材が参照するものとその音声ファイルをuserIDのものとして複製し、複製元のIDから複製先のIDへの対応を返します。
+func (r *lessonDuplicateRepository) duplicateVoices(ctx context.Context, userID int64, sourceLessonID int64, lessonID int64, referencedIDs map[int64]bool) (map[int64]int64, error) {
+	var allVoices []Voice
+	query := infrastructure.NewQuery("Voice").Filter("LessonID =", sourceLessonID)
+	allKeys, err := r.store.GetAll(ctx, query, &allVoices)
+	if err != nil {
+		return nil, err
+	}
+
+	// 教材から参照されていないVoiceは、公開されていない可能性があるので複製しない
+	var voices []Voice
+	var sourceKeys []*datastore.Key
+	for i, voice := range allVoices {
+		if referencedIDs[allKeys[i].ID] {
+			voices = append(voices, voice)
+			sourceKeys = append(sourceKeys, allKeys[i])
+		}
+	}
+
+	ids := make(map[int64]int64, len(voices))
+	if len(voices) == 0 {
+		return ids, nil
+	}
+
+	keys := make([]*datastore.Key, len(voices))
+	for i := range voices {
+		keys[i] = datastore.IncompleteKey("Voice", nil)
+		voices[i].UserID = userID
+		voices[i].LessonID = lessonID
+	}
+
+	putKeys, err := r.store.PutMulti(ctx, keys, voices)
+	if err != nil {
+		return nil, err
+	}
+
+	bucketName := infrastructure.PublicBucketName()
+	for i, voice := range voices {
+		ids[sourceKeys[i].ID] = putKeys[i].ID
+
+		// 文字起こしのみで音声ファイルを持たないVoiceもある
+		srcPath := CloudStorageVoiceFilePath(sourceLessonID, sourceKeys[i].ID, voice.FileKey)
+		dstPath := CloudStorageVoiceFilePath(lessonID, putKeys[i].ID, voice.FileKey)
+		if err := infrastructure.CopyFile(ctx, bucketName, srcPath, bucketName, dstPath); err != nil && !errors.Is(err, infrastructure.ErrObjectNotFound) {
+			return nil, err
+		}
+	}
+
+	return ids, nil
+}
+
+// referencedGraphicIDsは、教材が参照するGraphicのIDを返します。
+func referencedGraphicIDs(material *LessonMaterial) map[int64]bool {
+	ids := make(map[int64]bool, len(material.Graphics))
+	for _, graphic := range material.Graphics {
+		ids[graphic.GraphicID] = true
+	}
+	return ids
+}
+
+// referencedVoiceIDsは、教材が参照するVoiceのIDを返します。
+func referencedVoiceIDs(material *LessonMaterial) map[int64]bool {
+	ids := make(map[int64]bool, len(material.Speeches))
+	for _, speech := range material.Speeches {
+		if speech.VoiceID != 0 {
+			ids[speech.VoiceID] = true
+		}
+	}
+	return ids
+}
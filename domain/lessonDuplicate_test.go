@@ -0,0 +1,118 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+func TestLessonDuplicate(t *testing.T) {
+	tests := []struct {
+		name        string
+		userID      int64
+		version     int32
+		saveVersion bool // LessonVersionに公開した教材を記録する
+		saveBody    bool // 公開した教材の圧縮ファイルを保存する
+		wantDraft   bool // 編集中の教材が複製される
+		wantErr     error
+	}{
+		{name: "owner duplicates draft", userID: 1, version: 1, saveVersion: true, wantDraft: true},
+		{name: "other user duplicates published version", userID: 2, version: 1, saveVersion: true},
+		{name: "other user duplicates published body", userID: 2, version: 1, saveBody: true},
+		{name: "other user cannot duplicate unpublished content", userID: 2, version: 1, wantErr: LessonVersionNotPublished},
+		{name: "other user cannot duplicate without version", userID: 2, wantErr: LessonVersionNotPublished},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestDatastore(t)
+			repository := NewLessonDuplicateRepository(store)
+
+			source := putTestLesson(t, store, Lesson{UserID: 1, Status: LessonStatusPublic, Version: tt.version})
+			draftGraphicID := putTestGraphic(t, store, source.ID, "png")
+			publishedGraphicID := putTestGraphic(t, store, source.ID, "jpg")
+
+			// 編集中の教材は、公開されていないGraphicを参照している
+			materialKey := datastore.IDKey("LessonMaterial", source.MaterialID, datastore.IDKey("Lesson", source.ID, nil))
+			draft := LessonMaterial{UserID: 1, Graphics: []LessonGraphic{{GraphicID: draftGraphicID}}}
+			if _, err := store.Put(ctx, materialKey, &draft); err != nil {
+				t.Fatal(err)
+			}
+
+			published := LessonMaterial{UserID: 1, Graphics: []LessonGraphic{{GraphicID: publishedGraphicID}}}
+			if tt.saveVersion {
+				err := store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+					return createLessonVersionInTransaction(tx, source.ID, tt.version, &LessonVersion{Material: published})
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.saveBody {
+				body, err := compressLessonMaterial(&published)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if err := infrastructure.CreateFile(ctx, LessonBucketName(source.Status), LessonBodyFilePath(source.ID, tt.version), "application/zstd", body); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var duplicated Lesson
+			err := repository.Duplicate(ctx, tt.userID, &source, &duplicated)
+			if err != tt.wantErr {
+				t.Fatalf("Duplicate() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if duplicated.ID != 0 {
+					t.Errorf("duplicated lesson was created: %d", duplicated.ID)
+				}
+				return
+			}
+
+			// 教材が参照するGraphicのみが複製される
+			var graphics []Graphic
+			keys, err := store.GetAll(ctx, infrastructure.NewQuery("Graphic").Filter("LessonID =", duplicated.ID), &graphics)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != 1 {
+				t.Fatalf("duplicated graphics = %d, want 1", len(keys))
+			}
+
+			var material LessonMaterial
+			materialKey = datastore.IDKey("LessonMaterial", duplicated.MaterialID, datastore.IDKey("Lesson", duplicated.ID, nil))
+			if err := store.Get(ctx, materialKey, &material); err != nil {
+				t.Fatal(err)
+			}
+			if len(material.Graphics) != 1 || material.Graphics[0].GraphicID != keys[0].ID {
+				t.Errorf("duplicated material graphics = %+v, want %d", material.Graphics, keys[0].ID)
+			}
+			if material.UserID != tt.userID {
+				t.Errorf("duplicated material user = %d, want %d", material.UserID, tt.userID)
+			}
+
+			wantFileType := "jpg"
+			if tt.wantDraft {
+				wantFileType = "png"
+			}
+			if graphics[0].FileType != wantFileType {
+				t.Errorf("duplicated graphic file type = %s, want %s", graphics[0].FileType, wantFileType)
+			}
+		})
+	}
+}
+
+func putTestGraphic(t *testing.T, store infrastructure.Datastore, lessonID int64, fileType string) int64 {
+	t.Helper()
+
+	key, err := store.Put(context.Background(), datastore.IncompleteKey("Graphic", datastore.IDKey("User", 1, nil)), &Graphic{LessonID: lessonID, FileType: fileType})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return key.ID
+}
@@ -0,0 +1,313 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+	"google.golang.org/api/iterator"
+)
+
+// LessonListOrderは、公開中のLessonの一覧の並び順です。
+type LessonListOrder string
+
+const (
+	LessonListOrderNewest  LessonListOrder = "newest"
+	LessonListOrderViews   LessonListOrder = "views"
+	LessonListOrderLongest LessonListOrder = "longest"
+)
+
+// OwnLessonOrderは、作者自身のLessonの一覧の並び順です。
+type OwnLessonOrder string
+
+const (
+	OwnLessonOrderCreated OwnLessonOrder = "created"
+	OwnLessonOrderViews   OwnLessonOrder = "views"
+	OwnLessonOrderLikes   OwnLessonOrder = "likes"
+	OwnLessonOrderRating  OwnLessonOrder = "rating"
+)
+
+const (
+	LessonListDefaultPageSize = 18
+	LessonListMaxPageSize     = 50
+	// 再生時間の範囲はDatastoreで絞り込めない並び順があるので、読み込んでから絞り込む。一度に読み込む件数はページの件数のこの倍数まで
+	lessonListScanFactor   = 10
+	lessonListPutBatchSize = 500
+)
+
+// LessonListFilterは、公開中のLessonの一覧の絞り込み条件と並び順です。ゼロ値の条件では絞り込みません。
+type LessonListFilter struct {
+	SubjectID      int64
+	CategoryID     int64 // 副カテゴリを含む
+	UserID         int64
+	Tag            string
+	MinDurationSec float32
+	MaxDurationSec float32
+	HasThumbnail   *bool
+	Order          LessonListOrder
+	PageSize       int
+}
+
+// LessonSummaryは、一覧表示用のLessonです。
+type LessonSummary struct {
+	ID                 int64     `json:"id"`
+	UserID             int64     `json:"userID"`
+	AuthorName         string    `json:"authorName"`
+	Title              string    `json:"title"`
+	Description        string    `json:"description"`
+	ThumbnailURL       string    `json:"thumbnailURL"`
+	DurationSec        float32   `json:"durationSec"`
+	ViewCount          int64     `json:"viewCount"`
+	LikeCount          int64     `json:"likeCount"`
+	RatingAverage      float32   `json:"ratingAverage"`
+	SubjectID          int64     `json:"subjectID"`
+	JapaneseCategoryID int64     `json:"japaneseCategoryID"`
+	Tags               []string  `json:"tags"`
+	Published          time.Time `json:"published"`
+}
+
+// NewLessonSummaryは、LessonとauthorNameから一覧表示用のLessonを作成します。ThumbnailURLは設定済みのものを使用します。
+func NewLessonSummary(lesson *Lesson, authorName string) LessonSummary {
+	return LessonSummary{
+		ID:                 lesson.ID,
+		UserID:             lesson.UserID,
+		AuthorName:         authorName,
+		Title:              lesson.Title,
+		Description:        lesson.Description,
+		ThumbnailURL:       lesson.ThumbnailURL,
+		DurationSec:        lesson.DurationSec,
+		ViewCount:          lesson.ViewCount,
+		LikeCount:          lesson.LikeCount,
+		RatingAverage:      lesson.RatingAverage,
+		SubjectID:          lesson.SubjectID,
+		JapaneseCategoryID: lesson.JapaneseCategoryID,
+		Tags:               lesson.Tags,
+		Published:          lesson.Published,
+	}
+}
+
+type LessonListErrorCode uint
+
+const (
+	InvalidLessonListFilter LessonListErrorCode = 1
+)
+
+func (e LessonListErrorCode) Error() string {
+	switch e {
+	case InvalidLessonListFilter:
+		return "invalid lesson list filter"
+	default:
+		return "unknown lesson list error"
+	}
+}
+
+// Validateは、並び順とページの件数を既定値で補い、範囲外の条件をエラーにします。
+func (f *LessonListFilter) Validate() error {
+	if f.Order == "" {
+		f.Order = LessonListOrderNewest
+	}
+	if f.Order != LessonListOrderNewest && f.Order != LessonListOrderViews && f.Order != LessonListOrderLongest {
+		return InvalidLessonListFilter
+	}
+
+	if f.PageSize == 0 {
+		f.PageSize = LessonListDefaultPageSize
+	}
+	if f.PageSize < 0 || f.PageSize > LessonListMaxPageSize {
+		return InvalidLessonListFilter
+	}
+
+	if f.MinDurationSec < 0 || f.MaxDurationSec < 0 || (f.MaxDurationSec > 0 && f.MaxDurationSec < f.MinDurationSec) {
+		return InvalidLessonListFilter
+	}
+
+	f.Tag = NormalizeTag(f.Tag)
+
+	return nil
+}
+
+// SortOwnLessonsは、作成日時の新しい順に並んだ作者自身のLessonをorderの順に並べ替えます。同じ値の場合は作成日時の新しい順のままです。
+func SortOwnLessons(lessons []Lesson, order OwnLessonOrder) error {
+	var less func(a *Lesson, b *Lesson) bool
+	switch order {
+	case "", OwnLessonOrderCreated:
+		return nil
+	case OwnLessonOrderViews:
+		less = func(a *Lesson, b *Lesson) bool { return a.ViewCount > b.ViewCount }
+	case OwnLessonOrderLikes:
+		less = func(a *Lesson, b *Lesson) bool { return a.LikeCount > b.LikeCount }
+	case OwnLessonOrderRating:
+		less = func(a *Lesson, b *Lesson) bool {
+			if a.RatingAverage != b.RatingAverage {
+				return a.RatingAverage > b.RatingAverage
+			}
+			return a.RatingCount > b.RatingCount
+		}
+	default:
+		return InvalidLessonListFilter
+	}
+
+	sort.SliceStable(lessons, func(i, j int) bool { return less(&lessons[i], &lessons[j]) })
+
+	return nil
+}
+
+// GetPublicListは、filterに一致する公開中のLessonを最大filter.PageSize件と、続きを取得するためのカーソルを返します。
+// 続きがない場合、カーソルは空文字列です。再生時間で絞り込む場合は、続きがあっても件数がfilter.PageSizeに満たないことがあります。
+func (r *lessonRepository) GetPublicList(ctx context.Context, filter *LessonListFilter, cursorStr string) ([]Lesson, string, error) {
+	if err := filter.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	query := infrastructure.NewQuery("Lesson").Filter("Status =", int32(LessonStatusPublic))
+	if filter.SubjectID != 0 {
+		query = query.Filter("SubjectID =", filter.SubjectID)
+	}
+	if filter.CategoryID != 0 {
+		query = query.Filter("JapaneseCategoryIDs =", filter.CategoryID)
+	}
+	if filter.UserID != 0 {
+		query = query.Filter("UserID =", filter.UserID)
+	}
+	if filter.Tag != "" {
+		query = query.Filter("Tags =", filter.Tag)
+	}
+	if filter.HasThumbnail != nil {
+		query = query.Filter("HasThumbnail =", *filter.HasThumbnail)
+	}
+
+	scanLimit := filter.PageSize
+	filtersDuration := filter.MinDurationSec > 0 || filter.MaxDurationSec > 0
+	switch filter.Order {
+	case LessonListOrderViews:
+		query = query.Order("-ViewCount").Order("-Published")
+	case LessonListOrderLongest:
+		// 再生時間の順の場合は、範囲もDatastoreで絞り込める
+		if filter.MinDurationSec > 0 {
+			query = query.Filter("DurationSec >=", filter.MinDurationSec)
+		}
+		if filter.MaxDurationSec > 0 {
+			query = query.Filter("DurationSec <=", filter.MaxDurationSec)
+		}
+		filtersDuration = false
+		query = query.Order("-DurationSec").Order("-Published")
+	default:
+		query = query.Order("-Published")
+	}
+	if filtersDuration {
+		scanLimit = filter.PageSize * lessonListScanFactor
+	}
+	query = query.Limit(scanLimit)
+
+	if cursorStr != "" {
+		query = query.Start(cursorStr)
+	}
+
+	var lessons []Lesson
+	scanned := 0
+	it := r.store.Run(ctx, query)
+	for len(lessons) < filter.PageSize {
+		var lesson Lesson
+		key, err := it.Next(&lesson)
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, "", err
+		}
+		scanned++
+
+		if filtersDuration && !lesson.inDurationRange(filter.MinDurationSec, filter.MaxDurationSec) {
+			continue
+		}
+		lesson.ID = key.ID
+		lessons = append(lessons, lesson)
+	}
+
+	// 読み込める件数を読み切らずに終わった場合は、続きがない
+	if scanned < scanLimit && len(lessons) < filter.PageSize {
+		return lessons, "", nil
+	}
+
+	nextCursor, err := it.Cursor()
+	if err != nil {
+		return nil, "", err
+	}
+
+	return lessons, nextCursor, nil
+}
+
+func (lesson *Lesson) inDurationRange(minDurationSec float32, maxDurationSec float32) bool {
+	if minDurationSec > 0 && lesson.DurationSec < minDurationSec {
+		return false
+	}
+	if maxDurationSec > 0 && lesson.DurationSec > maxDurationSec {
+		return false
+	}
+	return true
+}
+
+// RefreshPublicListは、公開中の全てのLessonを保存し直し、保存した件数を返します。
+// 一覧の絞り込みと並べ替えに使用するフィールドが、インデックスの追加前に保存されたLessonでも使用できるようになります。
+func (r *lessonRepository) RefreshPublicList(ctx context.Context) (int, error) {
+	var lessons []Lesson
+	query := infrastructure.NewQuery("Lesson").Filter("Status =", int32(LessonStatusPublic))
+	keys, err := r.store.GetAll(ctx, query, &lessons)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range lessons {
+		lessons[i].JapaneseCategoryIDs = lessons[i].allJapaneseCategoryIDs()
+	}
+
+	for start := 0; start < len(keys); start += lessonListPutBatchSize {
+		end := start + lessonListPutBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := r.fillDurationFromMaterials(ctx, keys[start:end], lessons[start:end]); err != nil {
+			return 0, err
+		}
+		if _, err := r.store.PutMulti(ctx, keys[start:end], lessons[start:end]); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(keys), nil
+}
+
+// fillDurationFromMaterialsは、DurationSecが0のLessonのみ、DurationSecをそれぞれのLessonMaterialの値で設定します。
+// DurationSecが保存されるようになる前に公開されたLessonも、再生時間で絞り込みと並べ替えができるようになります。
+// 公開時に保存されたDurationSecは、編集中の教材の値で上書きしません。
+func (r *lessonRepository) fillDurationFromMaterials(ctx context.Context, keys []*datastore.Key, lessons []Lesson) error {
+	var materialKeys []*datastore.Key
+	var indexes []int
+	for i, lesson := range lessons {
+		if lesson.MaterialID == 0 || lesson.DurationSec != 0 {
+			continue
+		}
+		materialKeys = append(materialKeys, datastore.IDKey("LessonMaterial", lesson.MaterialID, keys[i]))
+		indexes = append(indexes, i)
+	}
+
+	materials := make([]LessonMaterial, len(materialKeys))
+	if err := r.store.GetMulti(ctx, materialKeys, materials); err != nil {
+		multiErr, ok := err.(datastore.MultiError)
+		if !ok {
+			return err
+		}
+		for _, e := range multiErr {
+			if e != nil && e != datastore.ErrNoSuchEntity {
+				return e
+			}
+		}
+	}
+
+	for i, material := range materials {
+		lessons[indexes[i]].DurationSec = material.DurationSec
+	}
+
+	return nil
+}
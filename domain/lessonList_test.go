@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+)
+
+func TestFillDurationFromMaterials(t *testing.T) {
+	tests := []struct {
+		name             string
+		durationSec      float32
+		materialDuration float32
+		deletesMaterial  bool
+		want             float32
+	}{
+		{name: "fills missing duration", materialDuration: 30, want: 30},
+		{name: "keeps published duration", durationSec: 20, materialDuration: 30, want: 20},
+		{name: "ignores missing material", deletesMaterial: true, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestDatastore(t)
+			repository := &lessonRepository{store: store}
+
+			lesson := putTestLesson(t, store, Lesson{UserID: 1, Status: LessonStatusPublic, DurationSec: tt.durationSec})
+			lessonKey := datastore.IDKey("Lesson", lesson.ID, nil)
+			materialKey := datastore.IDKey("LessonMaterial", lesson.MaterialID, lessonKey)
+			if tt.deletesMaterial {
+				if err := store.Delete(ctx, materialKey); err != nil {
+					t.Fatal(err)
+				}
+			} else {
+				// 編集中の教材の再生時間は、公開済みの値と異なる
+				material := LessonMaterial{UserID: 1, DurationSec: tt.materialDuration}
+				if _, err := store.Put(ctx, materialKey, &material); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			lessons := []Lesson{lesson}
+			if err := repository.fillDurationFromMaterials(ctx, []*datastore.Key{lessonKey}, lessons); err != nil {
+				t.Fatal(err)
+			}
+			if lessons[0].DurationSec != tt.want {
+				t.Errorf("DurationSec = %v, want %v", lessons[0].DurationSec, tt.want)
+			}
+		})
+	}
+}
@@ -25,6 +25,7 @@ type LessonMaterial struct {
 	Embeddings           []LessonEmbedding    `json:"embeddings" datastore:",noindex"`
 	Musics               []LessonMusic        `json:"musics" datastore:",noindex"`
 	Speeches             []LessonSpeech       `json:"speeches" datastore:",noindex"`
+	Quizzes              []LessonQuiz         `json:"quizzes" datastore:",noindex"`
 	Created              time.Time            `json:"created" datastore:",noindex"`
 	Updated              time.Time            `json:"updated" datastore:",noindex"`
 }
@@ -90,6 +91,19 @@ type LessonSpeech struct {
 	SynthesisConfig VoiceSynthesisConfig `json:"synthesisConfig"`
 }
 
+// LessonQuizは、elapsedTimeで再生を一時停止して出題する問題です。
+// 正解と解説は公開用の教材には含めず、回答の送信時に返します。
+type LessonQuiz struct {
+	ElapsedTime    float32        `json:"elapsedTime"`
+	Type           LessonQuizType `json:"type"`
+	Question       string         `json:"question"`
+	Choices        []string       `json:"choices,omitempty"`        // multipleChoiceの選択肢
+	CorrectChoice  int32          `json:"correctChoice,omitempty"`  // multipleChoiceの正解の選択肢の位置
+	CorrectBoolean bool           `json:"correctBoolean,omitempty"` // trueFalseの正解
+	CorrectAnswers []string       `json:"correctAnswers,omitempty"` // shortAnswerで正解とする回答
+	Explanation    string         `json:"explanation,omitempty"`
+}
+
 type Caption struct {
 	Body            string `json:"body,omitempty"`
 	BodyColor       string `json:"bodyColor,omitempty"`
@@ -98,15 +112,27 @@ type Caption struct {
 	VerticalAlign   string `json:"verticalAlign,omitempty"`
 }
 
-func GetLessonMaterial(ctx context.Context, id int64, lessonID int64, lessonMaterial *LessonMaterial) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
+// LessonMaterialRepositoryは、Lessonを祖先に持つLessonMaterialの永続化を行います。
+type LessonMaterialRepository interface {
+	Get(ctx context.Context, id int64, lessonID int64, lessonMaterial *LessonMaterial) error
+	CreateInitial(ctx context.Context, userID int64, avatarID int64, backgroundImageID int64, lessonID int64) (int64, error)
+	Update(ctx context.Context, id int64, lessonID int64, jsonBody *map[string]interface{}, targetFields *[]string) error
+	Validate(ctx context.Context, lessonID int64, lessonMaterial *LessonMaterial) (LessonMaterialValidation, error)
+}
+
+type lessonMaterialRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonMaterialRepositoryは、storeを使用するLessonMaterialRepositoryを返します。
+func NewLessonMaterialRepository(store infrastructure.Datastore) LessonMaterialRepository {
+	return &lessonMaterialRepository{store: store}
+}
 
+func (r *lessonMaterialRepository) Get(ctx context.Context, id int64, lessonID int64, lessonMaterial *LessonMaterial) error {
 	ancestor := datastore.IDKey("Lesson", lessonID, nil)
 	key := datastore.IDKey("LessonMaterial", id, ancestor)
-	if err := client.Get(ctx, key, lessonMaterial); err != nil {
+	if err := r.store.Get(ctx, key, lessonMaterial); err != nil {
 		return err
 	}
 
@@ -116,14 +142,9 @@ func GetLessonMaterial(ctx context.Context, id int64, lessonID int64, lessonMate
 	return nil
 }
 
-func CreateInitialLessonMaterial(ctx context.Context, userID int64, avatarID int64, backgroundImageID int64, lessonID int64) (int64, error) {
+func (r *lessonMaterialRepository) CreateInitial(ctx context.Context, userID int64, avatarID int64, backgroundImageID int64, lessonID int64) (int64, error) {
 	var id int64
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return id, err
-	}
-
 	var lessonMaterial LessonMaterial
 	lessonMaterial.UserID = userID
 	lessonMaterial.AvatarID = avatarID
@@ -138,7 +159,7 @@ func CreateInitialLessonMaterial(ctx context.Context, userID int64, avatarID int
 	lessonMaterial.Updated = currentTime
 
 	ancestor := datastore.IDKey("Lesson", lessonID, nil)
-	key, err := client.Put(ctx, datastore.IncompleteKey("LessonMaterial", ancestor), &lessonMaterial)
+	key, err := r.store.Put(ctx, datastore.IncompleteKey("LessonMaterial", ancestor), &lessonMaterial)
 	if err != nil {
 		return id, err
 	}
@@ -147,15 +168,10 @@ func CreateInitialLessonMaterial(ctx context.Context, userID int64, avatarID int
 	return id, nil
 }
 
-func UpdateLessonMaterial(ctx context.Context, id int64, lessonID int64, jsonBody *map[string]interface{}, targetFields *[]string) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
-	_, err = client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+func (r *lessonMaterialRepository) Update(ctx context.Context, id int64, lessonID int64, jsonBody *map[string]interface{}, targetFields *[]string) error {
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
 		currentTime := time.Now()
-		if _, err := updateLessonMaterialInTransaction(tx, id, lessonID, jsonBody, targetFields, currentTime); err != nil {
+		if _, err := r.updateInTransaction(tx, id, lessonID, jsonBody, targetFields, currentTime); err != nil {
 			return err
 		}
 
@@ -169,7 +185,7 @@ func UpdateLessonMaterial(ctx context.Context, id int64, lessonID int64, jsonBod
 	return nil
 }
 
-func updateLessonMaterialInTransaction(tx *datastore.Transaction, id int64, lessonID int64, jsonBody *map[string]interface{}, targetFields *[]string, currentTime time.Time) (LessonMaterial, error) {
+func (r *lessonMaterialRepository) updateInTransaction(tx infrastructure.Transaction, id int64, lessonID int64, jsonBody *map[string]interface{}, targetFields *[]string, currentTime time.Time) (LessonMaterial, error) {
 	ancestor := datastore.IDKey("Lesson", lessonID, nil)
 	key := datastore.IDKey("LessonMaterial", id, ancestor)
 	lessonMaterial := new(LessonMaterial)
@@ -180,7 +196,7 @@ func updateLessonMaterialInTransaction(tx *datastore.Transaction, id int64, less
 	MergeJsonToStruct(jsonBody, lessonMaterial, targetFields)
 	lessonMaterial.Updated = currentTime
 
-	if _, err := tx.Put(key, lessonMaterial); err != nil {
+	if err := tx.Put(key, lessonMaterial); err != nil {
 		return *lessonMaterial, err
 	}
 
@@ -0,0 +1,268 @@
+package domain
+
+import (
+	"context"
+	"sort"
+
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// LessonMaterialIssueCodeは、LessonMaterialの検証で見つかった問題の種類です。
+type LessonMaterialIssueCode string
+
+const (
+	LessonMaterialIssueInvalidDuration          LessonMaterialIssueCode = "invalid_duration"
+	LessonMaterialIssueNegativeElapsedTime      LessonMaterialIssueCode = "negative_elapsed_time"
+	LessonMaterialIssueTimelineExceedsDuration  LessonMaterialIssueCode = "timeline_exceeds_duration"
+	LessonMaterialIssueDurationExceedsTimelines LessonMaterialIssueCode = "duration_exceeds_timelines"
+	LessonMaterialIssueGraphicNotFound          LessonMaterialIssueCode = "graphic_not_found"
+	LessonMaterialIssueGraphicAlreadyShown      LessonMaterialIssueCode = "graphic_already_shown"
+	LessonMaterialIssueGraphicNotShown          LessonMaterialIssueCode = "graphic_not_shown"
+	LessonMaterialIssueVoiceNotFound            LessonMaterialIssueCode = "voice_not_found"
+	LessonMaterialIssueMusicAlreadyPlaying      LessonMaterialIssueCode = "music_already_playing"
+	LessonMaterialIssueMusicNotPlaying          LessonMaterialIssueCode = "music_not_playing"
+	LessonMaterialIssueMusicNotStopped          LessonMaterialIssueCode = "music_not_stopped"
+	LessonMaterialIssueInvalidQuiz              LessonMaterialIssueCode = "invalid_quiz"
+)
+
+type LessonMaterialValidationErrorCode uint
+
+const (
+	LessonMaterialNotPublishable LessonMaterialValidationErrorCode = 1
+)
+
+func (e LessonMaterialValidationErrorCode) Error() string {
+	switch e {
+	case LessonMaterialNotPublishable:
+		return "lesson material has errors"
+	default:
+		return "unknown lesson material validation error"
+	}
+}
+
+// LessonMaterialのタイムラインの名前。LessonMaterialIssueの位置を示すために使用する
+const (
+	lessonTimelineAvatars    = "avatars"
+	lessonTimelineDrawings   = "drawings"
+	lessonTimelineEmbeddings = "embeddings"
+	lessonTimelineGraphics   = "graphics"
+	lessonTimelineMusics     = "musics"
+	lessonTimelineSpeeches   = "speeches"
+	lessonTimelineQuizzes    = "quizzes"
+)
+
+const (
+	// タイムラインの終了がDurationSecをこれより超えるとエラーにする。再生時間の丸め誤差を許容するため
+	lessonMaterialDurationTolerance float32 = 0.1
+	// DurationSecがタイムラインの終了をこれより超えると、末尾に何も起きない時間があるとして警告する
+	lessonMaterialTrailingSecLimit float32 = 1
+)
+
+// LessonMaterialIssueは、LessonMaterialの検証で見つかった問題とその位置です。
+// Timelineが空の場合は、LessonMaterial全体に関する問題です。
+type LessonMaterialIssue struct {
+	Code        LessonMaterialIssueCode `json:"code"`
+	Timeline    string                  `json:"timeline,omitempty"`
+	Index       int                     `json:"index"` // タイムライン中の要素の位置
+	ElapsedTime float32                 `json:"elapsedTime"`
+	ReferenceID int64                   `json:"referenceID,omitempty"` // 問題のあるGraphic、Voice、BackgroundMusicのID
+}
+
+// LessonMaterialValidationは、LessonMaterialの検証結果です。Errorsがある間はLessonを公開できません。
+type LessonMaterialValidation struct {
+	Errors   []LessonMaterialIssue `json:"errors"`
+	Warnings []LessonMaterialIssue `json:"warnings"`
+}
+
+// HasErrorsは、公開を妨げる問題があるかを返します。
+func (v *LessonMaterialValidation) HasErrors() bool {
+	return len(v.Errors) > 0
+}
+
+func (v *LessonMaterialValidation) addError(issue LessonMaterialIssue) {
+	v.Errors = append(v.Errors, issue)
+}
+
+func (v *LessonMaterialValidation) addWarning(issue LessonMaterialIssue) {
+	v.Warnings = append(v.Warnings, issue)
+}
+
+// Validateは、lessonIDのLessonのLessonMaterialが公開できる状態かを検証します。
+// GraphicとVoiceは、そのLessonのものとして存在している必要があります。
+func (r *lessonMaterialRepository) Validate(ctx context.Context, lessonID int64, lessonMaterial *LessonMaterial) (LessonMaterialValidation, error) {
+	graphicKeys, err := r.store.GetAll(ctx, infrastructure.NewQuery("Graphic").Filter("LessonID =", lessonID).KeysOnly(), nil)
+	if err != nil {
+		return LessonMaterialValidation{}, err
+	}
+	graphicIDs := make(map[int64]bool, len(graphicKeys))
+	for _, key := range graphicKeys {
+		graphicIDs[key.ID] = true
+	}
+
+	voiceKeys, err := r.store.GetAll(ctx, infrastructure.NewQuery("Voice").Filter("LessonID =", lessonID).KeysOnly(), nil)
+	if err != nil {
+		return LessonMaterialValidation{}, err
+	}
+	voiceIDs := make(map[int64]bool, len(voiceKeys))
+	for _, key := range voiceKeys {
+		voiceIDs[key.ID] = true
+	}
+
+	return validateLessonMaterial(lessonMaterial, graphicIDs, voiceIDs), nil
+}
+
+// validateLessonMaterialは、lessonMaterialのタイムラインと参照先を検証します。
+// graphicIDsとvoiceIDsは、LessonのGraphicとVoiceのIDです。
+func validateLessonMaterial(lessonMaterial *LessonMaterial, graphicIDs map[int64]bool, voiceIDs map[int64]bool) LessonMaterialValidation {
+	validation := LessonMaterialValidation{Errors: []LessonMaterialIssue{}, Warnings: []LessonMaterialIssue{}}
+
+	validateLessonGraphics(&validation, lessonMaterial.Graphics, graphicIDs)
+	validateLessonSpeeches(&validation, lessonMaterial.Speeches, voiceIDs)
+	validateLessonMusics(&validation, lessonMaterial.Musics)
+	validateLessonQuizzes(&validation, lessonMaterial.Quizzes)
+	validateLessonDuration(&validation, lessonMaterial)
+
+	return validation
+}
+
+func validateLessonGraphics(validation *LessonMaterialValidation, graphics []LessonGraphic, graphicIDs map[int64]bool) {
+	shown := make(map[int64]bool)
+	for _, i := range sortedTimelineIndexes(len(graphics), func(i int) float32 { return graphics[i].ElapsedTime }) {
+		graphic := graphics[i]
+		issue := LessonMaterialIssue{Timeline: lessonTimelineGraphics, Index: i, ElapsedTime: graphic.ElapsedTime, ReferenceID: graphic.GraphicID}
+
+		if !graphicIDs[graphic.GraphicID] {
+			issue.Code = LessonMaterialIssueGraphicNotFound
+			validation.addError(issue)
+			continue
+		}
+
+		switch graphic.Action {
+		case GraphicActionShow:
+			if shown[graphic.GraphicID] {
+				issue.Code = LessonMaterialIssueGraphicAlreadyShown
+				validation.addWarning(issue)
+			}
+			shown[graphic.GraphicID] = true
+		case GraphicActionHide:
+			if !shown[graphic.GraphicID] {
+				issue.Code = LessonMaterialIssueGraphicNotShown
+				validation.addWarning(issue)
+			}
+			shown[graphic.GraphicID] = false
+		}
+	}
+}
+
+func validateLessonSpeeches(validation *LessonMaterialValidation, speeches []LessonSpeech, voiceIDs map[int64]bool) {
+	for i, speech := range speeches {
+		// VoiceIDが0のものは字幕のみで音声を持たない
+		if speech.VoiceID != 0 && !voiceIDs[speech.VoiceID] {
+			validation.addError(LessonMaterialIssue{Code: LessonMaterialIssueVoiceNotFound, Timeline: lessonTimelineSpeeches, Index: i, ElapsedTime: speech.ElapsedTime, ReferenceID: speech.VoiceID})
+		}
+	}
+}
+
+// validateLessonMusicsは、BGMの開始と停止が対になっているかを検証します。BGMは同時に一つしか再生できません。
+func validateLessonMusics(validation *LessonMaterialValidation, musics []LessonMusic) {
+	playingIndex := -1
+	for _, i := range sortedTimelineIndexes(len(musics), func(i int) float32 { return musics[i].ElapsedTime }) {
+		music := musics[i]
+		issue := LessonMaterialIssue{Timeline: lessonTimelineMusics, Index: i, ElapsedTime: music.ElapsedTime, ReferenceID: music.BackgroundMusicID}
+
+		switch music.Action {
+		case MusicActionStart:
+			if playingIndex >= 0 {
+				issue.Code = LessonMaterialIssueMusicAlreadyPlaying
+				validation.addError(issue)
+			}
+			playingIndex = i
+		case MusicActionStop:
+			if playingIndex < 0 {
+				issue.Code = LessonMaterialIssueMusicNotPlaying
+				validation.addWarning(issue)
+			}
+			playingIndex = -1
+		}
+	}
+
+	if playingIndex >= 0 {
+		music := musics[playingIndex]
+		validation.addError(LessonMaterialIssue{Code: LessonMaterialIssueMusicNotStopped, Timeline: lessonTimelineMusics, Index: playingIndex, ElapsedTime: music.ElapsedTime, ReferenceID: music.BackgroundMusicID})
+	}
+}
+
+// validateLessonQuizzesは、LessonQuizの問題文と正解が出題形式に応じて揃っているかを検証します。
+func validateLessonQuizzes(validation *LessonMaterialValidation, quizzes []LessonQuiz) {
+	for i, quiz := range quizzes {
+		if !quiz.isValid() {
+			validation.addError(LessonMaterialIssue{Code: LessonMaterialIssueInvalidQuiz, Timeline: lessonTimelineQuizzes, Index: i, ElapsedTime: quiz.ElapsedTime})
+		}
+	}
+}
+
+// validateLessonDurationは、全てのタイムラインがDurationSecの範囲に収まっているかを検証します。
+func validateLessonDuration(validation *LessonMaterialValidation, lessonMaterial *LessonMaterial) {
+	if lessonMaterial.DurationSec <= 0 {
+		validation.addError(LessonMaterialIssue{Code: LessonMaterialIssueInvalidDuration})
+	}
+
+	var timelineEndSec float32
+	checkTimeline := func(timeline string, index int, elapsedTime float32, durationSec float32) {
+		issue := LessonMaterialIssue{Timeline: timeline, Index: index, ElapsedTime: elapsedTime}
+		if elapsedTime < 0 {
+			issue.Code = LessonMaterialIssueNegativeElapsedTime
+			validation.addError(issue)
+			return
+		}
+
+		endSec := elapsedTime + durationSec
+		if endSec > timelineEndSec {
+			timelineEndSec = endSec
+		}
+		if lessonMaterial.DurationSec > 0 && endSec > lessonMaterial.DurationSec+lessonMaterialDurationTolerance {
+			issue.Code = LessonMaterialIssueTimelineExceedsDuration
+			validation.addError(issue)
+		}
+	}
+
+	for i, avatar := range lessonMaterial.Avatars {
+		checkTimeline(lessonTimelineAvatars, i, avatar.ElapsedTime, avatar.DurationSec)
+	}
+	for i, drawing := range lessonMaterial.Drawings {
+		checkTimeline(lessonTimelineDrawings, i, drawing.ElapsedTime, drawing.DurationSec)
+	}
+	for i, embedding := range lessonMaterial.Embeddings {
+		checkTimeline(lessonTimelineEmbeddings, i, embedding.ElapsedTime, 0)
+	}
+	for i, graphic := range lessonMaterial.Graphics {
+		checkTimeline(lessonTimelineGraphics, i, graphic.ElapsedTime, 0)
+	}
+	for i, music := range lessonMaterial.Musics {
+		checkTimeline(lessonTimelineMusics, i, music.ElapsedTime, 0)
+	}
+	for i, speech := range lessonMaterial.Speeches {
+		checkTimeline(lessonTimelineSpeeches, i, speech.ElapsedTime, speech.DurationSec)
+	}
+	for i, quiz := range lessonMaterial.Quizzes {
+		checkTimeline(lessonTimelineQuizzes, i, quiz.ElapsedTime, 0)
+	}
+
+	if timelineEndSec > 0 && lessonMaterial.DurationSec > timelineEndSec+lessonMaterialTrailingSecLimit {
+		validation.addWarning(LessonMaterialIssue{Code: LessonMaterialIssueDurationExceedsTimelines, ElapsedTime: timelineEndSec})
+	}
+}
+
+// sortedTimelineIndexesは、タイムラインの要素の位置をElapsedTimeの順に並べて返します。
+func sortedTimelineIndexes(length int, elapsedTime func(i int) float32) []int {
+	indexes := make([]int, length)
+	for i := range indexes {
+		indexes[i] = i
+	}
+
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return elapsedTime(indexes[i]) < elapsedTime(indexes[j])
+	})
+
+	return indexes
+}
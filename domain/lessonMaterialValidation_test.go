@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidateLessonMaterial(t *testing.T) {
+	graphicIDs := map[int64]bool{1: true}
+	voiceIDs := map[int64]bool{2: true}
+	// 末尾に何も起きない時間があるという警告が出ないよう、DurationSecまで続く字幕を含める
+	subtitle := LessonSpeech{ElapsedTime: 0, DurationSec: 10}
+
+	tests := []struct {
+		name         string
+		material     LessonMaterial
+		wantErrors   []LessonMaterialIssueCode
+		wantWarnings []LessonMaterialIssueCode
+	}{
+		{
+			name: "valid material",
+			material: LessonMaterial{
+				DurationSec: 10,
+				Graphics:    []LessonGraphic{{ElapsedTime: 1, GraphicID: 1, Action: GraphicActionShow}, {ElapsedTime: 5, GraphicID: 1, Action: GraphicActionHide}},
+				Speeches:    []LessonSpeech{subtitle, {ElapsedTime: 1, DurationSec: 2, VoiceID: 2}},
+				Musics:      []LessonMusic{{ElapsedTime: 0, Action: MusicActionStart}, {ElapsedTime: 9, Action: MusicActionStop}},
+				Quizzes:     []LessonQuiz{{ElapsedTime: 8, Type: LessonQuizTypeTrueFalse, Question: "q"}},
+			},
+		},
+		{
+			name:       "invalid duration",
+			material:   LessonMaterial{DurationSec: 0},
+			wantErrors: []LessonMaterialIssueCode{LessonMaterialIssueInvalidDuration},
+		},
+		{
+			name:       "negative elapsed time",
+			material:   LessonMaterial{DurationSec: 10, Speeches: []LessonSpeech{subtitle, {ElapsedTime: -1, DurationSec: 1}}},
+			wantErrors: []LessonMaterialIssueCode{LessonMaterialIssueNegativeElapsedTime},
+		},
+		{
+			name:       "timeline exceeds duration",
+			material:   LessonMaterial{DurationSec: 10, Speeches: []LessonSpeech{{ElapsedTime: 5, DurationSec: 5.5}}},
+			wantErrors: []LessonMaterialIssueCode{LessonMaterialIssueTimelineExceedsDuration},
+		},
+		{
+			name:     "allows rounding error at the end",
+			material: LessonMaterial{DurationSec: 10, Speeches: []LessonSpeech{{ElapsedTime: 5, DurationSec: 5.05}}},
+		},
+		{
+			name:         "duration exceeds timelines",
+			material:     LessonMaterial{DurationSec: 10, Speeches: []LessonSpeech{{ElapsedTime: 0, DurationSec: 2}}},
+			wantWarnings: []LessonMaterialIssueCode{LessonMaterialIssueDurationExceedsTimelines},
+		},
+		{
+			name:       "graphic not found",
+			material:   LessonMaterial{DurationSec: 10, Speeches: []LessonSpeech{subtitle}, Graphics: []LessonGraphic{{GraphicID: 9}}},
+			wantErrors: []LessonMaterialIssueCode{LessonMaterialIssueGraphicNotFound},
+		},
+		{
+			name: "graphic shown twice and hidden twice",
+			material: LessonMaterial{DurationSec: 10, Speeches: []LessonSpeech{subtitle}, Graphics: []LessonGraphic{
+				{ElapsedTime: 1, GraphicID: 1, Action: GraphicActionShow},
+				{ElapsedTime: 2, GraphicID: 1, Action: GraphicActionShow},
+				{ElapsedTime: 3, GraphicID: 1, Action: GraphicActionHide},
+				{ElapsedTime: 4, GraphicID: 1, Action: GraphicActionHide},
+			}},
+			wantWarnings: []LessonMaterialIssueCode{LessonMaterialIssueGraphicAlreadyShown, LessonMaterialIssueGraphicNotShown},
+		},
+		{
+			name: "checks graphics in elapsed time order",
+			material: LessonMaterial{DurationSec: 10, Speeches: []LessonSpeech{subtitle}, Graphics: []LessonGraphic{
+				{ElapsedTime: 5, GraphicID: 1, Action: GraphicActionHide},
+				{ElapsedTime: 1, GraphicID: 1, Action: GraphicActionShow},
+			}},
+		},
+		{
+			name:       "voice not found",
+			material:   LessonMaterial{DurationSec: 10, Speeches: []LessonSpeech{subtitle, {ElapsedTime: 1, VoiceID: 9}}},
+			wantErrors: []LessonMaterialIssueCode{LessonMaterialIssueVoiceNotFound},
+		},
+		{
+			name: "music started twice and never stopped",
+			material: LessonMaterial{DurationSec: 10, Speeches: []LessonSpeech{subtitle}, Musics: []LessonMusic{
+				{ElapsedTime: 1, Action: MusicActionStart},
+				{ElapsedTime: 2, Action: MusicActionStart},
+			}},
+			wantErrors: []LessonMaterialIssueCode{LessonMaterialIssueMusicAlreadyPlaying, LessonMaterialIssueMusicNotStopped},
+		},
+		{
+			name:         "music stopped without playing",
+			material:     LessonMaterial{DurationSec: 10, Speeches: []LessonSpeech{subtitle}, Musics: []LessonMusic{{ElapsedTime: 1, Action: MusicActionStop}}},
+			wantWarnings: []LessonMaterialIssueCode{LessonMaterialIssueMusicNotPlaying},
+		},
+		{
+			name:       "invalid quiz",
+			material:   LessonMaterial{DurationSec: 10, Speeches: []LessonSpeech{subtitle}, Quizzes: []LessonQuiz{{ElapsedTime: 1, Type: LessonQuizTypeShortAnswer, Question: "q"}}},
+			wantErrors: []LessonMaterialIssueCode{LessonMaterialIssueInvalidQuiz},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validation := validateLessonMaterial(&tt.material, graphicIDs, voiceIDs)
+
+			if got := lessonMaterialIssueCodes(validation.Errors); !reflect.DeepEqual(got, tt.wantErrors) {
+				t.Errorf("errors = %v, want %v", got, tt.wantErrors)
+			}
+			if got := lessonMaterialIssueCodes(validation.Warnings); !reflect.DeepEqual(got, tt.wantWarnings) {
+				t.Errorf("warnings = %v, want %v", got, tt.wantWarnings)
+			}
+			if validation.HasErrors() != (len(tt.wantErrors) > 0) {
+				t.Errorf("HasErrors() = %v, want %v", validation.HasErrors(), len(tt.wantErrors) > 0)
+			}
+		})
+	}
+}
+
+func lessonMaterialIssueCodes(issues []LessonMaterialIssue) []LessonMaterialIssueCode {
+	var codes []LessonMaterialIssueCode
+	for _, issue := range issues {
+		codes = append(codes, issue.Code)
+	}
+	return codes
+}
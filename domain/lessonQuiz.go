@@ -0,0 +1,127 @@
+package domain
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+type LessonQuizErrorCode uint
+
+const (
+	LessonQuizNotFound      LessonQuizErrorCode = 1
+	InvalidLessonQuizAnswer LessonQuizErrorCode = 2
+)
+
+func (e LessonQuizErrorCode) Error() string {
+	switch e {
+	case LessonQuizNotFound:
+		return "lesson quiz not found"
+	case InvalidLessonQuizAnswer:
+		return "invalid lesson quiz answer"
+	default:
+		return "unknown lesson quiz error"
+	}
+}
+
+// shortAnswerの回答の最大文字数
+const lessonQuizAnswerMaxLength = 200
+
+// LessonQuizAnswerは、学習者のLessonQuizへの回答です。出題形式に応じたフィールドのみを使用します。
+type LessonQuizAnswer struct {
+	Choice  *int32 `json:"choice"`  // multipleChoiceで選んだ選択肢の位置
+	Boolean *bool  `json:"boolean"` // trueFalseの回答
+	Text    string `json:"text"`    // shortAnswerの回答
+}
+
+// LessonQuizResultは、LessonQuizへの回答の採点結果です。正誤に関わらず正解と解説を含みます。
+type LessonQuizResult struct {
+	Correct        bool           `json:"correct"`
+	Type           LessonQuizType `json:"type"`
+	CorrectChoice  int32          `json:"correctChoice"`
+	CorrectBoolean bool           `json:"correctBoolean"`
+	CorrectAnswers []string       `json:"correctAnswers,omitempty"`
+	Explanation    string         `json:"explanation"`
+}
+
+// Checkは、answerを採点します。answerが出題形式に合わない場合はInvalidLessonQuizAnswerを返します。
+// shortAnswerは、前後の空白と大文字小文字の違いを無視して正解のいずれかと一致すれば正解とします。
+func (quiz *LessonQuiz) Check(answer *LessonQuizAnswer) (LessonQuizResult, error) {
+	result := LessonQuizResult{Type: quiz.Type, Explanation: quiz.Explanation}
+
+	switch quiz.Type {
+	case LessonQuizTypeMultipleChoice:
+		if answer.Choice == nil || *answer.Choice < 0 || int(*answer.Choice) >= len(quiz.Choices) {
+			return result, InvalidLessonQuizAnswer
+		}
+		result.CorrectChoice = quiz.CorrectChoice
+		result.Correct = *answer.Choice == quiz.CorrectChoice
+	case LessonQuizTypeTrueFalse:
+		if answer.Boolean == nil {
+			return result, InvalidLessonQuizAnswer
+		}
+		result.CorrectBoolean = quiz.CorrectBoolean
+		result.Correct = *answer.Boolean == quiz.CorrectBoolean
+	case LessonQuizTypeShortAnswer:
+		text := normalizeLessonQuizAnswer(answer.Text)
+		if text == "" || utf8.RuneCountInString(text) > lessonQuizAnswerMaxLength {
+			return result, InvalidLessonQuizAnswer
+		}
+		result.CorrectAnswers = quiz.CorrectAnswers
+		for _, correctAnswer := range quiz.CorrectAnswers {
+			if text == normalizeLessonQuizAnswer(correctAnswer) {
+				result.Correct = true
+				break
+			}
+		}
+	default:
+		return result, InvalidLessonQuizAnswer
+	}
+
+	return result, nil
+}
+
+// isValidは、出題形式に応じて問題文と正解が揃っているかを返します。
+func (quiz *LessonQuiz) isValid() bool {
+	if strings.TrimSpace(quiz.Question) == "" {
+		return false
+	}
+
+	switch quiz.Type {
+	case LessonQuizTypeMultipleChoice:
+		return len(quiz.Choices) >= 2 && quiz.CorrectChoice >= 0 && int(quiz.CorrectChoice) < len(quiz.Choices)
+	case LessonQuizTypeTrueFalse:
+		return true
+	case LessonQuizTypeShortAnswer:
+		for _, correctAnswer := range quiz.CorrectAnswers {
+			if normalizeLessonQuizAnswer(correctAnswer) != "" {
+				return true
+			}
+		}
+		return false
+	default:
+		return false
+	}
+}
+
+// publicLessonQuizzesは、正解と解説を除いたquizzesを返します。公開用の教材に使用します。
+func publicLessonQuizzes(quizzes []LessonQuiz) []LessonQuiz {
+	if quizzes == nil {
+		return nil
+	}
+
+	publicQuizzes := make([]LessonQuiz, len(quizzes))
+	for i, quiz := range quizzes {
+		publicQuizzes[i] = LessonQuiz{
+			ElapsedTime: quiz.ElapsedTime,
+			Type:        quiz.Type,
+			Question:    quiz.Question,
+			Choices:     quiz.Choices,
+		}
+	}
+
+	return publicQuizzes
+}
+
+func normalizeLessonQuizAnswer(answer string) string {
+	return strings.ToLower(strings.TrimSpace(answer))
+}
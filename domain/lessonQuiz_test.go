@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLessonQuizCheck(t *testing.T) {
+	choice := func(i int32) *int32 { return &i }
+	boolean := func(b bool) *bool { return &b }
+
+	multipleChoice := LessonQuiz{Type: LessonQuizTypeMultipleChoice, Question: "q", Choices: []string{"a", "b", "c"}, CorrectChoice: 1}
+	trueFalse := LessonQuiz{Type: LessonQuizTypeTrueFalse, Question: "q", CorrectBoolean: true}
+	shortAnswer := LessonQuiz{Type: LessonQuizTypeShortAnswer, Question: "q", CorrectAnswers: []string{"Tokyo", "東京"}}
+
+	tests := []struct {
+		name        string
+		quiz        LessonQuiz
+		answer      LessonQuizAnswer
+		wantCorrect bool
+		wantErr     error
+	}{
+		{name: "correct choice", quiz: multipleChoice, answer: LessonQuizAnswer{Choice: choice(1)}, wantCorrect: true},
+		{name: "wrong choice", quiz: multipleChoice, answer: LessonQuizAnswer{Choice: choice(0)}},
+		{name: "missing choice", quiz: multipleChoice, answer: LessonQuizAnswer{}, wantErr: InvalidLessonQuizAnswer},
+		{name: "negative choice", quiz: multipleChoice, answer: LessonQuizAnswer{Choice: choice(-1)}, wantErr: InvalidLessonQuizAnswer},
+		{name: "choice out of range", quiz: multipleChoice, answer: LessonQuizAnswer{Choice: choice(3)}, wantErr: InvalidLessonQuizAnswer},
+		{name: "correct boolean", quiz: trueFalse, answer: LessonQuizAnswer{Boolean: boolean(true)}, wantCorrect: true},
+		{name: "wrong boolean", quiz: trueFalse, answer: LessonQuizAnswer{Boolean: boolean(false)}},
+		{name: "missing boolean", quiz: trueFalse, answer: LessonQuizAnswer{}, wantErr: InvalidLessonQuizAnswer},
+		{name: "correct text", quiz: shortAnswer, answer: LessonQuizAnswer{Text: "東京"}, wantCorrect: true},
+		{name: "ignores case and spaces", quiz: shortAnswer, answer: LessonQuizAnswer{Text: "  tokyo "}, wantCorrect: true},
+		{name: "wrong text", quiz: shortAnswer, answer: LessonQuizAnswer{Text: "Kyoto"}},
+		{name: "blank text", quiz: shortAnswer, answer: LessonQuizAnswer{Text: " "}, wantErr: InvalidLessonQuizAnswer},
+		{name: "too long text", quiz: shortAnswer, answer: LessonQuizAnswer{Text: strings.Repeat("あ", lessonQuizAnswerMaxLength+1)}, wantErr: InvalidLessonQuizAnswer},
+		{name: "unknown type", quiz: LessonQuiz{Type: LessonQuizType(99), Question: "q"}, answer: LessonQuizAnswer{Text: "a"}, wantErr: InvalidLessonQuizAnswer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := tt.quiz.Check(&tt.answer)
+			if err != tt.wantErr {
+				t.Fatalf("Check() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			if result.Correct != tt.wantCorrect {
+				t.Errorf("Correct = %v, want %v", result.Correct, tt.wantCorrect)
+			}
+			// 正誤に関わらず正解を返す
+			if result.CorrectChoice != tt.quiz.CorrectChoice || result.CorrectBoolean != tt.quiz.CorrectBoolean || len(result.CorrectAnswers) != len(tt.quiz.CorrectAnswers) {
+				t.Errorf("result = %+v, want correct answers of %+v", result, tt.quiz)
+			}
+		})
+	}
+}
+
+func TestLessonQuizIsValid(t *testing.T) {
+	tests := []struct {
+		name string
+		quiz LessonQuiz
+		want bool
+	}{
+		{name: "multiple choice", quiz: LessonQuiz{Type: LessonQuizTypeMultipleChoice, Question: "q", Choices: []string{"a", "b"}, CorrectChoice: 1}, want: true},
+		{name: "multiple choice with one choice", quiz: LessonQuiz{Type: LessonQuizTypeMultipleChoice, Question: "q", Choices: []string{"a"}}},
+		{name: "multiple choice with correct choice out of range", quiz: LessonQuiz{Type: LessonQuizTypeMultipleChoice, Question: "q", Choices: []string{"a", "b"}, CorrectChoice: 2}},
+		{name: "multiple choice with negative correct choice", quiz: LessonQuiz{Type: LessonQuizTypeMultipleChoice, Question: "q", Choices: []string{"a", "b"}, CorrectChoice: -1}},
+		{name: "true false", quiz: LessonQuiz{Type: LessonQuizTypeTrueFalse, Question: "q"}, want: true},
+		{name: "short answer", quiz: LessonQuiz{Type: LessonQuizTypeShortAnswer, Question: "q", CorrectAnswers: []string{" ", "a"}}, want: true},
+		{name: "short answer without correct answers", quiz: LessonQuiz{Type: LessonQuizTypeShortAnswer, Question: "q", CorrectAnswers: []string{" "}}},
+		{name: "blank question", quiz: LessonQuiz{Type: LessonQuizTypeTrueFalse, Question: "  "}},
+		{name: "unknown type", quiz: LessonQuiz{Type: LessonQuizType(99), Question: "q"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.quiz.isValid(); got != tt.want {
+				t.Errorf("isValid() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,399 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+	"google.golang.org/api/iterator"
+)
+
+// LessonRankingPeriodは、ランキングの集計期間です。
+type LessonRankingPeriod string
+
+const (
+	LessonRankingPeriodDaily   LessonRankingPeriod = "daily"
+	LessonRankingPeriodWeekly  LessonRankingPeriod = "weekly"
+	LessonRankingPeriodAllTime LessonRankingPeriod = "all_time"
+)
+
+// LessonRankingScopeは、ランキングの対象とするLessonの範囲です。
+type LessonRankingScope string
+
+const (
+	LessonRankingScopeOverall  LessonRankingScope = "overall"
+	LessonRankingScopeSubject  LessonRankingScope = "subject"
+	LessonRankingScopeCategory LessonRankingScope = "category" // 副カテゴリを含む
+)
+
+// LessonRankingMaxSizeは、一つのランキングに含めるLessonの上限です。
+const LessonRankingMaxSize = 50
+
+// lessonRankingDecayは、日毎の参照回数の重み付けです。経過日数がhalfLifeDays増える毎に重みが半分になり、windowDaysより前の参照回数は含めません。
+type lessonRankingDecay struct {
+	windowDays   int
+	halfLifeDays float64
+}
+
+// 日間は前日分も半分の重みで含め、日付が変わった直後でも順位が空にならないようにする。通算はLesson.ViewCountをそのまま使用する
+var lessonRankingDecays = map[LessonRankingPeriod]lessonRankingDecay{
+	LessonRankingPeriodDaily:  {windowDays: 2, halfLifeDays: 1},
+	LessonRankingPeriodWeekly: {windowDays: 7, halfLifeDays: 3},
+}
+
+var lessonRankingPeriods = []LessonRankingPeriod{LessonRankingPeriodDaily, LessonRankingPeriodWeekly, LessonRankingPeriodAllTime}
+
+// LessonRankingは、定時バッチで作成される公開中のLessonのランキングです。キーの名前は期間と範囲から作成されます。
+// 参照時にDatastoreを検索しなくて済むよう、一覧表示用のLessonを順位の順に保持します。
+type LessonRanking struct {
+	Period   LessonRankingPeriod  `json:"period"`
+	Scope    LessonRankingScope   `json:"scope"`
+	ScopeID  int64                `json:"scopeID"`
+	Lessons  []LessonRankingEntry `json:"lessons" datastore:",noindex"`
+	Computed time.Time            `json:"computed" datastore:",noindex"`
+}
+
+// LessonRankingEntryは、ランキング中のLessonです。
+type LessonRankingEntry struct {
+	Rank  int     `json:"rank"`
+	Score float64 `json:"score"`
+	LessonSummary
+}
+
+type LessonRankingErrorCode uint
+
+const (
+	InvalidLessonRanking LessonRankingErrorCode = 1
+)
+
+func (e LessonRankingErrorCode) Error() string {
+	switch e {
+	case InvalidLessonRanking:
+		return "invalid lesson ranking"
+	default:
+		return "unknown lesson ranking error"
+	}
+}
+
+// LessonRankingRepositoryは、LessonRankingの作成と取得を行います。
+type LessonRankingRepository interface {
+	Get(ctx context.Context, period LessonRankingPeriod, scope LessonRankingScope, scopeID int64) (LessonRanking, error)
+	Compute(ctx context.Context, currentTime time.Time) (int, error)
+}
+
+type lessonRankingRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonRankingRepositoryは、storeを使用するLessonRankingRepositoryを返します。
+func NewLessonRankingRepository(store infrastructure.Datastore) LessonRankingRepository {
+	return &lessonRankingRepository{store: store}
+}
+
+// lessonRankingCandidateは、ランキングの作成中のLessonと期間毎のスコアです。
+type lessonRankingCandidate struct {
+	lesson     *Lesson
+	authorName string
+	scores     map[LessonRankingPeriod]float64
+}
+
+type lessonRankingGroup struct {
+	scope   LessonRankingScope
+	scopeID int64
+}
+
+// Getは、期間と範囲のLessonRankingを返します。まだ作成されていない場合は、Lessonを含まないLessonRankingを返します。
+func (r *lessonRankingRepository) Get(ctx context.Context, period LessonRankingPeriod, scope LessonRankingScope, scopeID int64) (LessonRanking, error) {
+	ranking := LessonRanking{Period: period, Scope: scope, ScopeID: scopeID}
+
+	if !containsLessonRankingPeriod(lessonRankingPeriods, period) {
+		return ranking, InvalidLessonRanking
+	}
+	if (scope == LessonRankingScopeOverall) != (scopeID == 0) {
+		return ranking, InvalidLessonRanking
+	}
+	if scope != LessonRankingScopeOverall && scope != LessonRankingScopeSubject && scope != LessonRankingScopeCategory {
+		return ranking, InvalidLessonRanking
+	}
+
+	if err := r.store.Get(ctx, lessonRankingKey(period, scope, scopeID), &ranking); err != nil && err != datastore.ErrNoSuchEntity {
+		return ranking, err
+	}
+	if ranking.Lessons == nil {
+		ranking.Lessons = []LessonRankingEntry{}
+	}
+
+	return ranking, nil
+}
+
+// Computeは、LessonViewCountHistoryとLesson.ViewCountから全ての期間と範囲のLessonRankingを作成し直し、作成した数を返します。
+// 日間と週間は、日毎の参照回数に経過日数に応じた重みを付けて合計したスコアの順です。対象のLessonがなくなった範囲のLessonRankingは削除されます。
+func (r *lessonRankingRepository) Compute(ctx context.Context, currentTime time.Time) (int, error) {
+	jst, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		panic(err)
+	}
+	today := currentTime.In(jst)
+
+	recentScores, err := r.recentScores(ctx, today)
+	if err != nil {
+		return 0, err
+	}
+
+	candidates, err := r.candidates(ctx, recentScores)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := r.setAuthorNames(ctx, candidates); err != nil {
+		return 0, err
+	}
+
+	// 範囲毎にLessonをまとめる
+	groups := map[lessonRankingGroup][]*lessonRankingCandidate{}
+	addToGroup := func(scope LessonRankingScope, scopeID int64, candidate *lessonRankingCandidate) {
+		group := lessonRankingGroup{scope: scope, scopeID: scopeID}
+		groups[group] = append(groups[group], candidate)
+	}
+	for _, candidate := range candidates {
+		lesson := candidate.lesson
+		addToGroup(LessonRankingScopeOverall, 0, candidate)
+		if lesson.SubjectID != 0 {
+			addToGroup(LessonRankingScopeSubject, lesson.SubjectID, candidate)
+		}
+		categoryIDs := lesson.JapaneseCategoryIDs
+		if len(categoryIDs) == 0 { // 副カテゴリの導入前に公開され、保存し直されていないLesson
+			categoryIDs = lesson.allJapaneseCategoryIDs()
+		}
+		for _, categoryID := range categoryIDs {
+			if categoryID != 0 {
+				addToGroup(LessonRankingScopeCategory, categoryID, candidate)
+			}
+		}
+	}
+
+	var rankingKeys []*datastore.Key
+	var rankings []LessonRanking
+	for group, groupCandidates := range groups {
+		for _, period := range lessonRankingPeriods {
+			ranking := LessonRanking{
+				Period:   period,
+				Scope:    group.scope,
+				ScopeID:  group.scopeID,
+				Lessons:  rankLessonCandidates(groupCandidates, period),
+				Computed: currentTime,
+			}
+			if len(ranking.Lessons) == 0 {
+				continue
+			}
+			rankingKeys = append(rankingKeys, lessonRankingKey(period, group.scope, group.scopeID))
+			rankings = append(rankings, ranking)
+		}
+	}
+
+	for start := 0; start < len(rankingKeys); start += lessonListPutBatchSize {
+		end := start + lessonListPutBatchSize
+		if end > len(rankingKeys) {
+			end = len(rankingKeys)
+		}
+		if _, err := r.store.PutMulti(ctx, rankingKeys[start:end], rankings[start:end]); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := r.deleteStaleRankings(ctx, rankingKeys); err != nil {
+		return len(rankings), err
+	}
+
+	return len(rankings), nil
+}
+
+// recentScoresは、日間と週間の集計期間内のLessonViewCountHistoryから、LessonのID毎に重み付けしたスコアを返します。
+func (r *lessonRankingRepository) recentScores(ctx context.Context, today time.Time) (map[int64]map[LessonRankingPeriod]float64, error) {
+	maxWindowDays := 0
+	for _, decay := range lessonRankingDecays {
+		if decay.windowDays > maxWindowDays {
+			maxWindowDays = decay.windowDays
+		}
+	}
+
+	// Dateの文字列から経過日数を求める
+	ages := make(map[string]int)
+	for age := 0; age < maxWindowDays; age++ {
+		ages[today.AddDate(0, 0, -age).Format("20060102")] = age
+	}
+	startDate := today.AddDate(0, 0, -(maxWindowDays - 1)).Format("20060102")
+
+	var histories []LessonViewCountHistory
+	query := infrastructure.NewQuery("LessonViewCountHistory").Filter("Date >=", startDate)
+	if _, err := r.store.GetAll(ctx, query, &histories); err != nil {
+		return nil, err
+	}
+
+	scores := make(map[int64]map[LessonRankingPeriod]float64)
+	for _, history := range histories {
+		age, ok := ages[history.Date]
+		if !ok || history.Count <= 0 {
+			continue // 日本時間で未来の日付
+		}
+
+		for period, decay := range lessonRankingDecays {
+			if age >= decay.windowDays {
+				continue
+			}
+			if scores[history.LessonID] == nil {
+				scores[history.LessonID] = make(map[LessonRankingPeriod]float64)
+			}
+			scores[history.LessonID][period] += float64(history.Count) * math.Pow(0.5, float64(age)/decay.halfLifeDays)
+		}
+	}
+
+	return scores, nil
+}
+
+// candidatesは、全ての公開中のLessonを、期間毎のスコアと共に返します。
+func (r *lessonRankingRepository) candidates(ctx context.Context, recentScores map[int64]map[LessonRankingPeriod]float64) ([]*lessonRankingCandidate, error) {
+	var candidates []*lessonRankingCandidate
+
+	query := infrastructure.NewQuery("Lesson").Filter("Status =", int32(LessonStatusPublic))
+	it := r.store.Run(ctx, query)
+	for {
+		lesson := new(Lesson)
+		key, err := it.Next(lesson)
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if lesson.IsIntroduction {
+			continue
+		}
+		lesson.ID = key.ID
+
+		scores := make(map[LessonRankingPeriod]float64)
+		for period, score := range recentScores[lesson.ID] {
+			scores[period] = score
+		}
+		scores[LessonRankingPeriodAllTime] = float64(lesson.ViewCount)
+
+		if err := SetLessonThumbnailURL(ctx, lesson); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, &lessonRankingCandidate{lesson: lesson, scores: scores})
+	}
+
+	return candidates, nil
+}
+
+func (r *lessonRankingRepository) setAuthorNames(ctx context.Context, candidates []*lessonRankingCandidate) error {
+	var userIDs []int64
+	for _, candidate := range candidates {
+		if !containsInt64(userIDs, candidate.lesson.UserID) {
+			userIDs = append(userIDs, candidate.lesson.UserID)
+		}
+	}
+
+	authors, err := NewUserRepository(r.store).GetByIDs(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, candidate := range candidates {
+		candidate.authorName = authors[candidate.lesson.UserID].Name
+	}
+
+	return nil
+}
+
+// deleteStaleRankingsは、今回作成しなかったLessonRankingを削除します。
+func (r *lessonRankingRepository) deleteStaleRankings(ctx context.Context, computedKeys []*datastore.Key) error {
+	computed := make(map[string]bool, len(computedKeys))
+	for _, key := range computedKeys {
+		computed[key.Name] = true
+	}
+
+	keys, err := r.store.GetAll(ctx, infrastructure.NewQuery("LessonRanking").KeysOnly(), nil)
+	if err != nil {
+		return err
+	}
+
+	var staleKeys []*datastore.Key
+	for _, key := range keys {
+		if !computed[key.Name] {
+			staleKeys = append(staleKeys, key)
+		}
+	}
+	for start := 0; start < len(staleKeys); start += lessonListPutBatchSize {
+		end := start + lessonListPutBatchSize
+		if end > len(staleKeys) {
+			end = len(staleKeys)
+		}
+		if err := r.store.DeleteMulti(ctx, staleKeys[start:end]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// rankLessonCandidatesは、periodのスコアが0より大きいLessonを、スコアの高い順に最大LessonRankingMaxSize件返します。
+// 同じスコアの場合は通算の参照回数が多い順、公開日時の新しい順で、同じスコアのLessonは同じ順位です。
+func rankLessonCandidates(candidates []*lessonRankingCandidate, period LessonRankingPeriod) []LessonRankingEntry {
+	var ranked []*lessonRankingCandidate
+	for _, candidate := range candidates {
+		if candidate.scores[period] > 0 {
+			ranked = append(ranked, candidate)
+		}
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		a, b := ranked[i], ranked[j]
+		if a.scores[period] != b.scores[period] {
+			return a.scores[period] > b.scores[period]
+		}
+		if a.lesson.ViewCount != b.lesson.ViewCount {
+			return a.lesson.ViewCount > b.lesson.ViewCount
+		}
+		return a.lesson.Published.After(b.lesson.Published)
+	})
+
+	if len(ranked) > LessonRankingMaxSize {
+		ranked = ranked[:LessonRankingMaxSize]
+	}
+
+	entries := make([]LessonRankingEntry, len(ranked))
+	for i, candidate := range ranked {
+		rank := i + 1
+		if i > 0 && candidate.scores[period] == ranked[i-1].scores[period] {
+			rank = entries[i-1].Rank
+		}
+		entries[i] = LessonRankingEntry{
+			Rank:          rank,
+			Score:         candidate.scores[period],
+			LessonSummary: NewLessonSummary(candidate.lesson, candidate.authorName),
+		}
+	}
+
+	return entries
+}
+
+func lessonRankingKey(period LessonRankingPeriod, scope LessonRankingScope, scopeID int64) *datastore.Key {
+	return datastore.NameKey("LessonRanking", lessonRankingKeyName(period, scope, scopeID), nil)
+}
+
+func lessonRankingKeyName(period LessonRankingPeriod, scope LessonRankingScope, scopeID int64) string {
+	return fmt.Sprintf("%s_%s_%d", period, scope, scopeID)
+}
+
+func containsLessonRankingPeriod(periods []LessonRankingPeriod, target LessonRankingPeriod) bool {
+	for _, period := range periods {
+		if period == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"context"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRankLessonCandidates(t *testing.T) {
+	published := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	candidate := func(id int64, score float64, viewCount int64, publishedDays int) *lessonRankingCandidate {
+		lesson := &Lesson{ID: id, ViewCount: viewCount, Published: published.AddDate(0, 0, publishedDays)}
+		return &lessonRankingCandidate{lesson: lesson, scores: map[LessonRankingPeriod]float64{LessonRankingPeriodDaily: score}}
+	}
+
+	manyCandidates := make([]*lessonRankingCandidate, LessonRankingMaxSize+1)
+	for i := range manyCandidates {
+		manyCandidates[i] = candidate(int64(i+1), float64(len(manyCandidates)-i), 0, 0)
+	}
+
+	tests := []struct {
+		name       string
+		candidates []*lessonRankingCandidate
+		wantIDs    []int64
+		wantRanks  []int
+	}{
+		{
+			name:       "orders by score",
+			candidates: []*lessonRankingCandidate{candidate(1, 1, 0, 0), candidate(2, 3, 0, 0), candidate(3, 2, 0, 0)},
+			wantIDs:    []int64{2, 3, 1},
+			wantRanks:  []int{1, 2, 3},
+		},
+		{
+			name:       "excludes lessons without score",
+			candidates: []*lessonRankingCandidate{candidate(1, 0, 100, 0), candidate(2, 1, 0, 0)},
+			wantIDs:    []int64{2},
+			wantRanks:  []int{1},
+		},
+		{
+			name:       "breaks ties by view count and then published",
+			candidates: []*lessonRankingCandidate{candidate(1, 2, 10, 0), candidate(2, 2, 20, 0), candidate(3, 2, 10, 1)},
+			wantIDs:    []int64{2, 3, 1},
+			wantRanks:  []int{1, 1, 1},
+		},
+		{
+			name:       "skips ranks after ties",
+			candidates: []*lessonRankingCandidate{candidate(1, 2, 0, 0), candidate(2, 2, 0, 1), candidate(3, 1, 0, 0)},
+			wantIDs:    []int64{2, 1, 3},
+			wantRanks:  []int{1, 1, 3},
+		},
+		{
+			name:       "no candidates",
+			candidates: nil,
+			wantIDs:    []int64{},
+			wantRanks:  []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries := rankLessonCandidates(tt.candidates, LessonRankingPeriodDaily)
+
+			ids := []int64{}
+			ranks := []int{}
+			for _, entry := range entries {
+				ids = append(ids, entry.ID)
+				ranks = append(ranks, entry.Rank)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			if !reflect.DeepEqual(ranks, tt.wantRanks) {
+				t.Errorf("ranks = %v, want %v", ranks, tt.wantRanks)
+			}
+		})
+	}
+
+	t.Run("limits size", func(t *testing.T) {
+		if entries := rankLessonCandidates(manyCandidates, LessonRankingPeriodDaily); len(entries) != LessonRankingMaxSize {
+			t.Errorf("len(entries) = %d, want %d", len(entries), LessonRankingMaxSize)
+		}
+	})
+}
+
+func TestRecentScores(t *testing.T) {
+	today := time.Date(2021, 6, 10, 12, 0, 0, 0, time.UTC)
+	date := func(days int) string {
+		return today.AddDate(0, 0, -days).Format("20060102")
+	}
+
+	tests := []struct {
+		name        string
+		date        string
+		count       int64
+		wantDaily   float64
+		wantWeekly  float64
+		wantMissing bool // スコアが計算されない
+	}{
+		{name: "today", date: date(0), count: 4, wantDaily: 4, wantWeekly: 4},
+		{name: "yesterday is halved for daily", date: date(1), count: 4, wantDaily: 2, wantWeekly: 4 * math.Pow(0.5, 1.0/3)},
+		{name: "outside daily window", date: date(3), count: 8, wantWeekly: 4},
+		{name: "last day of weekly window", date: date(6), count: 4, wantWeekly: 1},
+		{name: "outside weekly window", date: date(7), count: 4, wantMissing: true},
+		{name: "future date", date: date(-1), count: 4, wantMissing: true},
+		{name: "no views", date: date(0), count: 0, wantMissing: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestDatastore(t)
+			repository := &lessonRankingRepository{store: store}
+
+			var lessonID int64 = 1
+			history := LessonViewCountHistory{LessonID: lessonID, Date: tt.date, Count: tt.count}
+			if _, err := store.Put(ctx, lessonViewCountHistoryKey(lessonID, tt.date), &history); err != nil {
+				t.Fatal(err)
+			}
+
+			scores, err := repository.recentScores(ctx, today)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got, ok := scores[lessonID]
+			if ok == tt.wantMissing {
+				t.Fatalf("scores = %v, want missing %v", scores, tt.wantMissing)
+			}
+			if tt.wantMissing {
+				return
+			}
+			if math.Abs(got[LessonRankingPeriodDaily]-tt.wantDaily) > 1e-9 {
+				t.Errorf("daily score = %v, want %v", got[LessonRankingPeriodDaily], tt.wantDaily)
+			}
+			if math.Abs(got[LessonRankingPeriodWeekly]-tt.wantWeekly) > 1e-9 {
+				t.Errorf("weekly score = %v, want %v", got[LessonRankingPeriodWeekly], tt.wantWeekly)
+			}
+		})
+	}
+}
@@ -0,0 +1,464 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/go-redis/redis/v8"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+const (
+	lessonReactionRatingMin = 1
+	lessonReactionRatingMax = 5
+	// LessonReactionHistoryに記録する集計済みのキーの上限。集計済みのキーは直後に削除されるので、直近の分のみ保持する
+	lessonReactionAppliedKeysMaxCount = 20
+)
+
+// 集計中のキーのプリフィックス。集計対象のキーはこのプリフィックスに改名してから集計するので、集計中の増分は次回の集計に回される
+const lessonReactionProcessingKeyPrefix = "lessonReactionProcessing"
+
+// LessonReactionは、公開中のLessonに対するUserのいいねと評価です。Lessonを祖先に持ち、キーのIDはUserのIDです。
+// 変更による集計値の増分は、LessonReactionと同じトランザクションでPendingに記録し、Lessonへ加算するかRedisへ格納する際に取り除きます。
+type LessonReaction struct {
+	LessonID   int64               `json:"lessonID" datastore:"-"`
+	UserID     int64               `json:"userID"`
+	Liked      bool                `json:"liked" datastore:",noindex"`
+	Rating     int                 `json:"rating" datastore:",noindex"` // 1〜5。0は未評価
+	Pending    LessonReactionDelta `json:"-" datastore:",noindex"`      // まだ集計値に加算されていない増分
+	HasPending bool                `json:"-"`
+	Created    time.Time           `json:"created" datastore:",noindex"`
+	Updated    time.Time           `json:"updated" datastore:",noindex"`
+}
+
+// LessonReactionDeltaは、LessonReactionの変更によるLessonの集計値の増分です。
+type LessonReactionDelta struct {
+	Likes       int64
+	Ratings     int64
+	RatingTotal int64
+}
+
+// LessonReactionHistoryは、Lessonへ反映済みのRedisのキーを記録し、同じキーが二重に加算されることを防ぎます。キーのIDはLessonのIDです。
+type LessonReactionHistory struct {
+	AppliedKeys []string  `datastore:",noindex"`
+	Updated     time.Time `datastore:",noindex"`
+}
+
+type LessonReactionErrorCode uint
+
+const (
+	InvalidLessonReaction LessonReactionErrorCode = 1
+)
+
+func (e LessonReactionErrorCode) Error() string {
+	switch e {
+	case InvalidLessonReaction:
+		return "invalid lesson reaction"
+	default:
+		return "unknown lesson reaction error"
+	}
+}
+
+// LessonReactionRepositoryは、LessonReactionの永続化と、Redisに格納された集計値の増分のLessonへの反映を行います。
+type LessonReactionRepository interface {
+	Get(ctx context.Context, lessonID int64, userID int64) (LessonReaction, error)
+	Like(ctx context.Context, lesson *Lesson, userID int64, liked bool) (LessonReaction, error)
+	Rate(ctx context.Context, lesson *Lesson, userID int64, rating int) (LessonReaction, error)
+	ApplyPending(ctx context.Context, reaction *LessonReaction, buffered bool) error
+	ApplyAllPending(ctx context.Context, buffered bool) (int, error)
+	Aggregate(ctx context.Context) (int, error)
+}
+
+type lessonReactionRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonReactionRepositoryは、storeを使用するLessonReactionRepositoryを返します。
+func NewLessonReactionRepository(store infrastructure.Datastore) LessonReactionRepository {
+	return &lessonReactionRepository{store: store}
+}
+
+// IsZeroは、増分がない場合にtrueを返します。
+func (d LessonReactionDelta) IsZero() bool {
+	return d.Likes == 0 && d.Ratings == 0 && d.RatingTotal == 0
+}
+
+func (d LessonReactionDelta) add(other LessonReactionDelta) LessonReactionDelta {
+	return LessonReactionDelta{
+		Likes:       d.Likes + other.Likes,
+		Ratings:     d.Ratings + other.Ratings,
+		RatingTotal: d.RatingTotal + other.RatingTotal,
+	}
+}
+
+func (d LessonReactionDelta) negate() LessonReactionDelta {
+	return LessonReactionDelta{Likes: -d.Likes, Ratings: -d.Ratings, RatingTotal: -d.RatingTotal}
+}
+
+// Getは、userIDのUserのLessonReactionを返します。未作成の場合は、いいねも評価もしていないLessonReactionを返します。
+func (r *lessonReactionRepository) Get(ctx context.Context, lessonID int64, userID int64) (LessonReaction, error) {
+	var reaction LessonReaction
+	if err := r.store.Get(ctx, lessonReactionKey(lessonID, userID), &reaction); err != nil && err != datastore.ErrNoSuchEntity {
+		return reaction, err
+	}
+
+	reaction.LessonID = lessonID
+	reaction.UserID = userID
+
+	return reaction, nil
+}
+
+// Likeは、userIDのUserのいいねを設定または解除します。Lessonの集計値の増分はPendingに記録されます。
+func (r *lessonReactionRepository) Like(ctx context.Context, lesson *Lesson, userID int64, liked bool) (LessonReaction, error) {
+	return r.update(ctx, lesson, userID, func(reaction *LessonReaction) {
+		reaction.Liked = liked
+	})
+}
+
+// Rateは、userIDのUserの評価を設定します。ratingが0の場合は評価を取り消します。Lessonの集計値の増分はPendingに記録されます。
+func (r *lessonReactionRepository) Rate(ctx context.Context, lesson *Lesson, userID int64, rating int) (LessonReaction, error) {
+	if rating != 0 && (rating < lessonReactionRatingMin || rating > lessonReactionRatingMax) {
+		return LessonReaction{}, InvalidLessonReaction
+	}
+
+	return r.update(ctx, lesson, userID, func(reaction *LessonReaction) {
+		reaction.Rating = rating
+	})
+}
+
+// updateは、トランザクションでLessonReactionを更新し、更新前後の差分をPendingに加えます。作者は自身のLessonにいいねや評価はできません。
+func (r *lessonReactionRepository) update(ctx context.Context, lesson *Lesson, userID int64, f func(reaction *LessonReaction)) (LessonReaction, error) {
+	var reaction LessonReaction
+
+	if lesson.UserID == userID || lesson.IsIntroduction {
+		return reaction, InvalidLessonReaction
+	}
+
+	key := lessonReactionKey(lesson.ID, userID)
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		currentTime := time.Now()
+		reaction = LessonReaction{}
+		if err := tx.Get(key, &reaction); err == datastore.ErrNoSuchEntity {
+			reaction = LessonReaction{UserID: userID, Created: currentTime}
+		} else if err != nil {
+			return err
+		}
+
+		before := reaction
+		f(&reaction)
+		delta := lessonReactionDelta(before, reaction)
+		if delta.IsZero() {
+			return nil
+		}
+
+		reaction.Pending = reaction.Pending.add(delta)
+		reaction.HasPending = !reaction.Pending.IsZero()
+		reaction.Updated = currentTime
+		return tx.Put(key, &reaction)
+	})
+
+	if err != nil {
+		return reaction, err
+	}
+	reaction.LessonID = lesson.ID
+
+	return reaction, nil
+}
+
+// ApplyPendingは、reactionのPendingをLessonの集計値に加算し、LessonReactionから取り除きます。
+// bufferedがtrueの場合はRedisに格納して定時バッチで反映し、falseの場合はトランザクションでLessonへ直接加算します。
+// Redisへは、二重に格納しないようにPendingから取り除いてから格納し、格納に失敗した場合はPendingに戻すので、次回の変更時かApplyAllPendingで改めて加算されます。
+func (r *lessonReactionRepository) ApplyPending(ctx context.Context, reaction *LessonReaction, buffered bool) error {
+	if reaction.Pending.IsZero() {
+		return nil
+	}
+
+	if !buffered {
+		return r.updatePending(ctx, reaction, func(tx infrastructure.Transaction, current *LessonReaction) error {
+			lessonKey := datastore.IDKey("Lesson", reaction.LessonID, nil)
+			var lesson Lesson
+			if err := tx.Get(lessonKey, &lesson); err != nil && err != datastore.ErrNoSuchEntity {
+				return err
+			} else if err == nil {
+				lesson.applyReactionDelta(current.Pending)
+				if err := tx.Put(lessonKey, &lesson); err != nil {
+					return err
+				}
+			}
+			current.Pending = LessonReactionDelta{}
+			return nil
+		})
+	}
+
+	var pushing LessonReactionDelta
+	err := r.updatePending(ctx, reaction, func(tx infrastructure.Transaction, current *LessonReaction) error {
+		pushing = current.Pending
+		current.Pending = LessonReactionDelta{}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := IncrementLessonReactionCounts(ctx, reaction.LessonID, pushing); err != nil {
+		// 取り除いた後に並行して加わった増分は残したまま、格納できなかった増分を戻す
+		restoreErr := r.updatePending(ctx, reaction, func(tx infrastructure.Transaction, current *LessonReaction) error {
+			current.Pending = current.Pending.add(pushing)
+			return nil
+		})
+		if restoreErr != nil {
+			return fmt.Errorf("%v. failed to restore pending reaction. %w", err, restoreErr)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// updatePendingは、トランザクションで保存済みのLessonReactionをfで更新し、reactionを更新後の内容にします。
+func (r *lessonReactionRepository) updatePending(ctx context.Context, reaction *LessonReaction, f func(tx infrastructure.Transaction, current *LessonReaction) error) error {
+	key := lessonReactionKey(reaction.LessonID, reaction.UserID)
+	return r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var current LessonReaction
+		if err := tx.Get(key, &current); err != nil {
+			return err
+		}
+
+		if err := f(tx, &current); err != nil {
+			return err
+		}
+		current.HasPending = !current.Pending.IsZero()
+		if err := tx.Put(key, &current); err != nil {
+			return err
+		}
+
+		current.LessonID = reaction.LessonID
+		*reaction = current
+		return nil
+	})
+}
+
+// ApplyAllPendingは、Pendingが残っている全てのLessonReactionについてApplyPendingを行い、加算したLessonReactionの数を返します。
+func (r *lessonReactionRepository) ApplyAllPending(ctx context.Context, buffered bool) (int, error) {
+	var reactions []LessonReaction
+	query := infrastructure.NewQuery("LessonReaction").Filter("HasPending =", true)
+	keys, err := r.store.GetAll(ctx, query, &reactions)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range reactions {
+		reactions[i].LessonID = keys[i].Parent.ID
+		if err := r.ApplyPending(ctx, &reactions[i], buffered); err != nil {
+			return i, err
+		}
+	}
+
+	return len(reactions), nil
+}
+
+// IncrementLessonReactionCountsは、lessonIDのLessonの集計値にdeltaを加算します。
+// 加算は即座に行われず、Redisに格納されます。その後、定時バッチでLessonのLikeCount、RatingCount、RatingAverageに反映されます。
+func IncrementLessonReactionCounts(ctx context.Context, lessonID int64, delta LessonReactionDelta) error {
+	if delta.IsZero() {
+		return nil
+	}
+
+	rdb := newRedisClient()
+	defer rdb.Close()
+
+	key := lessonReactionCountKey(lessonID)
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, key, "likes", delta.Likes)
+		pipe.HIncrBy(ctx, key, "ratings", delta.Ratings)
+		pipe.HIncrBy(ctx, key, "ratingTotal", delta.RatingTotal)
+		return nil
+	})
+
+	return err
+}
+
+// Aggregateは、Redisに格納された集計値の増分をLessonへ反映し、反映したLessonの数を返します。
+// LessonViewCountと同様に、キーは集計用の名前へ改名してから読み取り、反映と同じトランザクションで集計済みのキーを記録します。
+func (r *lessonReactionRepository) Aggregate(ctx context.Context) (int, error) {
+	rdb := newRedisClient()
+	defer rdb.Close()
+
+	runID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := renameLessonReactionCountKeys(ctx, rdb, runID); err != nil {
+		return 0, err
+	}
+
+	// 以前の実行で反映できなかったキーも含めて集計する
+	iter := rdb.Scan(ctx, 0, lessonReactionProcessingKeyPrefix+"_*", 0).Iterator()
+	updated := 0
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		// lessonReactionProcessing_{runID}_{lessonID}
+		parts := strings.Split(key, "_")
+		if len(parts) != 3 {
+			continue
+		}
+		lessonID, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		values, err := rdb.HGetAll(ctx, key).Result()
+		if err != nil {
+			return updated, err
+		}
+
+		delta := LessonReactionDelta{
+			Likes:       parseRedisInt64(values["likes"]),
+			Ratings:     parseRedisInt64(values["ratings"]),
+			RatingTotal: parseRedisInt64(values["ratingTotal"]),
+		}
+		applied, err := r.applyLessonReactionDelta(ctx, lessonID, key, delta)
+		if err != nil {
+			return updated, err
+		}
+		if applied {
+			updated++
+		}
+
+		if err := rdb.Del(ctx, key).Err(); err != nil {
+			return updated, err
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return updated, err
+	}
+
+	return updated, nil
+}
+
+// applyLessonReactionDeltaは、トランザクションでLessonの集計値にdeltaを加算します。Lessonが削除済みか、反映済みの場合はfalseを返します。
+func (r *lessonReactionRepository) applyLessonReactionDelta(ctx context.Context, lessonID int64, redisKey string, delta LessonReactionDelta) (bool, error) {
+	applied := false
+
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		applied = false
+
+		lessonKey := datastore.IDKey("Lesson", lessonID, nil)
+		var lesson Lesson
+		if err := tx.Get(lessonKey, &lesson); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+
+		historyKey := lessonReactionHistoryKey(lessonID)
+		var history LessonReactionHistory
+		if err := tx.Get(historyKey, &history); err != nil && err != datastore.ErrNoSuchEntity {
+			return err
+		}
+		if containsString(history.AppliedKeys, redisKey) {
+			return nil
+		}
+
+		history.AppliedKeys = append(history.AppliedKeys, redisKey)
+		if len(history.AppliedKeys) > lessonReactionAppliedKeysMaxCount {
+			history.AppliedKeys = history.AppliedKeys[len(history.AppliedKeys)-lessonReactionAppliedKeysMaxCount:]
+		}
+		history.Updated = time.Now()
+		if err := tx.Put(historyKey, &history); err != nil {
+			return err
+		}
+
+		lesson.applyReactionDelta(delta)
+		if err := tx.Put(lessonKey, &lesson); err != nil {
+			return err
+		}
+
+		applied = true
+		return nil
+	})
+
+	return applied, err
+}
+
+// applyReactionDeltaは、集計値にdeltaを加算し、平均評価を計算し直します。
+func (l *Lesson) applyReactionDelta(delta LessonReactionDelta) {
+	l.LikeCount = maxInt64(l.LikeCount+delta.Likes, 0)
+	l.RatingCount = maxInt64(l.RatingCount+delta.Ratings, 0)
+	l.RatingTotal = maxInt64(l.RatingTotal+delta.RatingTotal, 0)
+
+	if l.RatingCount == 0 {
+		l.RatingAverage = 0
+	} else {
+		l.RatingAverage = float32(l.RatingTotal) / float32(l.RatingCount)
+	}
+}
+
+// lessonReactionDeltaは、LessonReactionの変更前後の差分を返します。
+func lessonReactionDelta(before LessonReaction, after LessonReaction) LessonReactionDelta {
+	var delta LessonReactionDelta
+
+	if before.Liked != after.Liked {
+		if after.Liked {
+			delta.Likes = 1
+		} else {
+			delta.Likes = -1
+		}
+	}
+
+	if before.Rating == 0 && after.Rating != 0 {
+		delta.Ratings = 1
+	} else if before.Rating != 0 && after.Rating == 0 {
+		delta.Ratings = -1
+	}
+	delta.RatingTotal = int64(after.Rating - before.Rating)
+
+	return delta
+}
+
+// renameLessonReactionCountKeysは、集計対象のキーを集計用の名前に改名します。改名はアトミックなので、改名後の増分は元の名前のキーに加算されます。
+func renameLessonReactionCountKeys(ctx context.Context, rdb *redis.Client, runID string) error {
+	iter := rdb.Scan(ctx, 0, "lessonReaction_*", 0).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		suffix := strings.TrimPrefix(key, "lessonReaction_")
+		processingKey := fmt.Sprintf("%s_%s_%s", lessonReactionProcessingKeyPrefix, runID, suffix)
+		if err := rdb.Rename(ctx, key, processingKey).Err(); err != nil && err.Error() != "ERR no such key" {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
+
+// lessonReactionCountKeyは、Redis内で使用されるLessonの集計値の増分保持用のキーをstringで返します。
+func lessonReactionCountKey(lessonID int64) string {
+	return fmt.Sprintf("lessonReaction_%d", lessonID)
+}
+
+func lessonReactionKey(lessonID int64, userID int64) *datastore.Key {
+	return datastore.IDKey("LessonReaction", userID, datastore.IDKey("Lesson", lessonID, nil))
+}
+
+func lessonReactionHistoryKey(lessonID int64) *datastore.Key {
+	return datastore.IDKey("LessonReactionHistory", lessonID, nil)
+}
+
+func parseRedisInt64(value string) int64 {
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n
+}
+
+func maxInt64(a int64, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
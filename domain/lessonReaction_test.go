@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"context"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+)
+
+func TestLessonReactionApplyPending(t *testing.T) {
+	tests := []struct {
+		name          string
+		react         func(r LessonReactionRepository, lesson *Lesson) (LessonReaction, error)
+		wantLikes     int64
+		wantRatings   int64
+		wantAverage   float32
+		wantReactions int // ApplyAllPendingで加算されるLessonReactionの数
+	}{
+		{
+			name: "applies like",
+			react: func(r LessonReactionRepository, lesson *Lesson) (LessonReaction, error) {
+				return r.Like(context.Background(), lesson, 2, true)
+			},
+			wantLikes:     1,
+			wantReactions: 1,
+		},
+		{
+			name: "applies rating",
+			react: func(r LessonReactionRepository, lesson *Lesson) (LessonReaction, error) {
+				return r.Rate(context.Background(), lesson, 2, 4)
+			},
+			wantRatings:   1,
+			wantAverage:   4,
+			wantReactions: 1,
+		},
+		{
+			name: "applies nothing for unchanged reaction",
+			react: func(r LessonReactionRepository, lesson *Lesson) (LessonReaction, error) {
+				return r.Like(context.Background(), lesson, 2, false)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestDatastore(t)
+			repository := NewLessonReactionRepository(store)
+
+			lesson := putTestLesson(t, store, Lesson{UserID: 1, Status: LessonStatusPublic})
+			reaction, err := tt.react(repository, &lesson)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// 加算する前に失敗した場合も、Pendingは保存されたままになる
+			var saved LessonReaction
+			if err := store.Get(ctx, lessonReactionKey(lesson.ID, 2), &saved); err != nil && err != datastore.ErrNoSuchEntity {
+				t.Fatal(err)
+			}
+			if saved.Pending != reaction.Pending || saved.HasPending != !reaction.Pending.IsZero() {
+				t.Errorf("saved pending = %+v, want %+v", saved.Pending, reaction.Pending)
+			}
+
+			count, err := repository.ApplyAllPending(ctx, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != tt.wantReactions {
+				t.Errorf("ApplyAllPending() = %d, want %d", count, tt.wantReactions)
+			}
+
+			// 加算済みの増分は二重に加算されない
+			if count, err := repository.ApplyAllPending(ctx, false); err != nil || count != 0 {
+				t.Errorf("ApplyAllPending() again = %d, %v, want 0", count, err)
+			}
+
+			var got Lesson
+			if err := store.Get(ctx, datastore.IDKey("Lesson", lesson.ID, nil), &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.LikeCount != tt.wantLikes || got.RatingCount != tt.wantRatings || got.RatingAverage != tt.wantAverage {
+				t.Errorf("lesson counts = %d, %d, %v, want %d, %d, %v",
+					got.LikeCount, got.RatingCount, got.RatingAverage, tt.wantLikes, tt.wantRatings, tt.wantAverage)
+			}
+		})
+	}
+}
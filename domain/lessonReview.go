@@ -0,0 +1,237 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// LessonReviewLinkは、レビュアーに共有する下書きの参照用のキーです。作者にのみ返されます。
+type LessonReviewLink struct {
+	ReviewerUserID int64  `json:"userID"`
+	ReviewKey      string `json:"reviewKey"`
+}
+
+type LessonReviewErrorCode uint
+
+const (
+	LessonReviewNotFound      LessonReviewErrorCode = 1
+	InvalidLessonReviewer     LessonReviewErrorCode = 2
+	InvalidLessonReviewStatus LessonReviewErrorCode = 3
+)
+
+func (e LessonReviewErrorCode) Error() string {
+	switch e {
+	case LessonReviewNotFound:
+		return "lesson review not found"
+	case InvalidLessonReviewer:
+		return "invalid lesson reviewer"
+	case InvalidLessonReviewStatus:
+		return "invalid lesson review status"
+	default:
+		return "unknown lesson review error"
+	}
+}
+
+// LessonReviewRepositoryは、Lesson.Reviewsへのレビューの依頼と、レビュアーによるコメントや判定を記録します。
+type LessonReviewRepository interface {
+	Request(ctx context.Context, lessonID int64, reviewerUserIDs []int64) ([]LessonReviewLink, error)
+	Cancel(ctx context.Context, lessonID int64, reviewerUserID int64) error
+	GetForReviewer(ctx context.Context, lessonID int64, reviewerUserID int64, reviewKey string) (Lesson, LessonReview, error)
+	AddComment(ctx context.Context, lessonID int64, reviewerUserID int64, reviewKey string, comment LessonReviewComment) (LessonReview, error)
+	Submit(ctx context.Context, lessonID int64, reviewerUserID int64, reviewKey string, status LessonReviewStatus, comment string) (LessonReview, error)
+}
+
+type lessonReviewRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonReviewRepositoryは、storeを使用するLessonReviewRepositoryを返します。
+func NewLessonReviewRepository(store infrastructure.Datastore) LessonReviewRepository {
+	return &lessonReviewRepository{store: store}
+}
+
+// Requestは、reviewerUserIDsのUserにレビューを依頼し、レビュアー毎の参照用のキーを返します。
+// 依頼済みのレビュアーに再度依頼した場合は、キーとコメントを維持したまま状態を依頼中に戻します。
+func (r *lessonReviewRepository) Request(ctx context.Context, lessonID int64, reviewerUserIDs []int64) ([]LessonReviewLink, error) {
+	if len(reviewerUserIDs) == 0 {
+		return nil, InvalidLessonReviewer
+	}
+
+	var links []LessonReviewLink
+	err := r.updateLessonInTransaction(ctx, lessonID, func(tx infrastructure.Transaction, lesson *Lesson) error {
+		links = nil
+		currentTime := time.Now()
+
+		for _, reviewerUserID := range reviewerUserIDs {
+			if reviewerUserID == lesson.UserID {
+				return InvalidLessonReviewer
+			}
+
+			var reviewer User
+			if err := tx.Get(datastore.IDKey("User", reviewerUserID, nil), &reviewer); err != nil {
+				if err == datastore.ErrNoSuchEntity {
+					return InvalidLessonReviewer
+				}
+				return err
+			}
+
+			review := findLessonReview(lesson, reviewerUserID)
+			if review == nil {
+				reviewKey, err := UUIDWithoutHypen()
+				if err != nil {
+					return err
+				}
+				lesson.Reviews = append(lesson.Reviews, LessonReview{ReviewerUserID: reviewerUserID, ReviewKey: reviewKey, Created: currentTime})
+				review = &lesson.Reviews[len(lesson.Reviews)-1]
+			}
+
+			review.Status = LessonReviewStatusRequested
+			review.Updated = currentTime
+			links = append(links, LessonReviewLink{ReviewerUserID: reviewerUserID, ReviewKey: review.ReviewKey})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// Cancelは、reviewerUserIDのUserへのレビューの依頼を取り消します。参照用のキーも無効になります。
+func (r *lessonReviewRepository) Cancel(ctx context.Context, lessonID int64, reviewerUserID int64) error {
+	return r.updateLessonInTransaction(ctx, lessonID, func(tx infrastructure.Transaction, lesson *Lesson) error {
+		reviews := make([]LessonReview, 0, len(lesson.Reviews))
+		for _, review := range lesson.Reviews {
+			if review.ReviewerUserID != reviewerUserID {
+				reviews = append(reviews, review)
+			}
+		}
+
+		if len(reviews) == len(lesson.Reviews) {
+			return LessonReviewNotFound
+		}
+
+		lesson.Reviews = reviews
+		return nil
+	})
+}
+
+// GetForReviewerは、レビュアーが参照できる下書きのLessonと、そのレビュアーのレビューを返します。
+// レビューを依頼されていないか、キーが一致しない場合はLessonReviewNotFoundを返します。
+func (r *lessonReviewRepository) GetForReviewer(ctx context.Context, lessonID int64, reviewerUserID int64, reviewKey string) (Lesson, LessonReview, error) {
+	var lesson Lesson
+	if err := r.store.Get(ctx, datastore.IDKey("Lesson", lessonID, nil), &lesson); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return lesson, LessonReview{}, LessonReviewNotFound
+		}
+		return lesson, LessonReview{}, err
+	}
+	if lesson.Status == LessonStatusDeleted {
+		return Lesson{}, LessonReview{}, LessonReviewNotFound
+	}
+	lesson.ID = lessonID
+
+	review := findLessonReview(&lesson, reviewerUserID)
+	if review == nil || review.ReviewKey != reviewKey {
+		return lesson, LessonReview{}, LessonReviewNotFound
+	}
+
+	return lesson, *review, nil
+}
+
+// AddCommentは、レビュアーのコメントを追加します。
+func (r *lessonReviewRepository) AddComment(ctx context.Context, lessonID int64, reviewerUserID int64, reviewKey string, comment LessonReviewComment) (LessonReview, error) {
+	var updated LessonReview
+	err := r.updateLessonInTransaction(ctx, lessonID, func(tx infrastructure.Transaction, lesson *Lesson) error {
+		review := findLessonReview(lesson, reviewerUserID)
+		if review == nil || review.ReviewKey != reviewKey {
+			return LessonReviewNotFound
+		}
+
+		comment.Created = time.Now()
+		review.Comments = append(review.Comments, comment)
+		review.Updated = comment.Created
+		updated = *review
+		return nil
+	})
+
+	return updated, err
+}
+
+// Submitは、レビュアーによる承認または修正依頼を記録します。
+func (r *lessonReviewRepository) Submit(ctx context.Context, lessonID int64, reviewerUserID int64, reviewKey string, status LessonReviewStatus, comment string) (LessonReview, error) {
+	if status != LessonReviewStatusApproved && status != LessonReviewStatusChangesRequested {
+		return LessonReview{}, InvalidLessonReviewStatus
+	}
+
+	var updated LessonReview
+	err := r.updateLessonInTransaction(ctx, lessonID, func(tx infrastructure.Transaction, lesson *Lesson) error {
+		review := findLessonReview(lesson, reviewerUserID)
+		if review == nil || review.ReviewKey != reviewKey {
+			return LessonReviewNotFound
+		}
+
+		review.Status = status
+		review.Comment = comment
+		review.Updated = time.Now()
+		updated = *review
+		return nil
+	})
+
+	return updated, err
+}
+
+// updateLessonInTransactionは、トランザクション中でLessonを取得してfで変更し、ReviewStatusを集計し直して保存します。
+func (r *lessonReviewRepository) updateLessonInTransaction(ctx context.Context, lessonID int64, f func(tx infrastructure.Transaction, lesson *Lesson) error) error {
+	key := datastore.IDKey("Lesson", lessonID, nil)
+	return r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var lesson Lesson
+		if err := tx.Get(key, &lesson); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return LessonReviewNotFound
+			}
+			return err
+		}
+
+		if err := f(tx, &lesson); err != nil {
+			return err
+		}
+
+		lesson.ReviewStatus = summarizeLessonReviews(lesson.Reviews)
+		return tx.Put(key, &lesson)
+	})
+}
+
+func findLessonReview(lesson *Lesson, reviewerUserID int64) *LessonReview {
+	for i := range lesson.Reviews {
+		if lesson.Reviews[i].ReviewerUserID == reviewerUserID {
+			return &lesson.Reviews[i]
+		}
+	}
+	return nil
+}
+
+// summarizeLessonReviewsは、一人でも修正を依頼していれば修正依頼、全員が承認していれば承認、それ以外は依頼中とします。
+func summarizeLessonReviews(reviews []LessonReview) LessonReviewStatus {
+	if len(reviews) == 0 {
+		return LessonReviewStatusNone
+	}
+
+	status := LessonReviewStatusApproved
+	for _, review := range reviews {
+		switch review.Status {
+		case LessonReviewStatusChangesRequested:
+			return LessonReviewStatusChangesRequested
+		case LessonReviewStatusRequested:
+			status = LessonReviewStatusRequested
+		}
+	}
+
+	return status
+}
@@ -0,0 +1,256 @@
+package domain
+
+import (
+	"context"
+	"log"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// LessonScheduleは、Lessonの公開と公開終了の予約です。Lessonごとに一つだけ作成され、キーのIDはLessonのIDです。
+type LessonSchedule struct {
+	LessonID    int64     `json:"lessonID" datastore:"-"`
+	UserID      int64     `json:"userID"`
+	PublishAt   time.Time `json:"publishAt" datastore:",noindex"`   // ゼロ値の場合は予約なし
+	UnpublishAt time.Time `json:"unpublishAt" datastore:",noindex"` // ゼロ値の場合は予約なし
+	NextRunAt   time.Time `json:"nextRunAt"`                        // PublishAtとUnpublishAtのうち、未実行で早い方
+	LastError   string    `json:"lastError" datastore:",noindex"`
+	Created     time.Time `json:"created" datastore:",noindex"`
+	Updated     time.Time `json:"updated" datastore:",noindex"`
+}
+
+type LessonScheduleErrorCode uint
+
+const (
+	LessonScheduleNotFound LessonScheduleErrorCode = 1
+	InvalidLessonSchedule  LessonScheduleErrorCode = 2
+)
+
+func (e LessonScheduleErrorCode) Error() string {
+	switch e {
+	case LessonScheduleNotFound:
+		return "lesson schedule not found"
+	case InvalidLessonSchedule:
+		return "invalid lesson schedule"
+	default:
+		return "unknown lesson schedule error"
+	}
+}
+
+// LessonScheduleQueueは、予約日時にLessonScheduleを処理するタスクのキューです。
+var LessonScheduleQueue = infrastructure.QueueConfig{
+	Name:        "lessonSchedule",
+	RelativeURI: "/lesson_schedules",
+	Retry:       infrastructure.RetryPolicy{MaxAttempts: 5, MinBackoff: time.Minute, MaxBackoff: 30 * time.Minute},
+}
+
+// Cloud Tasksに登録できるETAの上限。これより先の予約は定期実行で処理する
+const lessonScheduleTaskMaxDelay = 30 * 24 * time.Hour
+
+// LessonScheduleRepositoryは、LessonScheduleの永続化と、予約日時に処理するタスクの登録を行います。
+type LessonScheduleRepository interface {
+	GetByLessonID(ctx context.Context, lessonID int64) (LessonSchedule, error)
+	GetByUserID(ctx context.Context, userID int64) ([]LessonSchedule, error)
+	GetDue(ctx context.Context, currentTime time.Time, limit int) ([]LessonSchedule, error)
+	Set(ctx context.Context, schedule *LessonSchedule) error
+	Complete(ctx context.Context, lessonID int64, runAt time.Time) error
+	RecordError(ctx context.Context, lessonID int64, err error) error
+	Delete(ctx context.Context, lessonID int64) error
+}
+
+type lessonScheduleRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonScheduleRepositoryは、storeを使用するLessonScheduleRepositoryを返します。
+func NewLessonScheduleRepository(store infrastructure.Datastore) LessonScheduleRepository {
+	return &lessonScheduleRepository{store: store}
+}
+
+func (r *lessonScheduleRepository) GetByLessonID(ctx context.Context, lessonID int64) (LessonSchedule, error) {
+	schedule := new(LessonSchedule)
+
+	if err := r.store.Get(ctx, lessonScheduleKey(lessonID), schedule); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return *schedule, LessonScheduleNotFound
+		}
+		return *schedule, err
+	}
+	schedule.LessonID = lessonID
+
+	return *schedule, nil
+}
+
+// GetByUserIDは、Userの未実行の予約を実行日時の早い順に返します。
+func (r *lessonScheduleRepository) GetByUserID(ctx context.Context, userID int64) ([]LessonSchedule, error) {
+	var schedules []LessonSchedule
+
+	query := infrastructure.NewQuery("LessonSchedule").Filter("UserID =", userID)
+	keys, err := r.store.GetAll(ctx, query, &schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		schedules[i].LessonID = key.ID
+	}
+
+	// 複合インデックスを増やさないよう、並べ替えはここで行う
+	sort.Slice(schedules, func(i, j int) bool { return schedules[i].NextRunAt.Before(schedules[j].NextRunAt) })
+
+	return schedules, nil
+}
+
+// GetDueは、currentTimeまでに実行すべき予約を実行日時の早い順に最大limit件返します。
+func (r *lessonScheduleRepository) GetDue(ctx context.Context, currentTime time.Time, limit int) ([]LessonSchedule, error) {
+	var schedules []LessonSchedule
+
+	query := infrastructure.NewQuery("LessonSchedule").Filter("NextRunAt <=", currentTime).Order("NextRunAt").Limit(limit)
+	keys, err := r.store.GetAll(ctx, query, &schedules)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		schedules[i].LessonID = key.ID
+	}
+
+	return schedules, nil
+}
+
+// Setは、Lessonの予約を作成するか置き換え、予約日時に処理するタスクを登録します。
+// 予約日時は未来である必要があり、両方を指定する場合は公開終了を公開より後にします。
+func (r *lessonScheduleRepository) Set(ctx context.Context, schedule *LessonSchedule) error {
+	currentTime := time.Now()
+	if schedule.PublishAt.IsZero() && schedule.UnpublishAt.IsZero() {
+		return InvalidLessonSchedule
+	}
+	for _, runAt := range []time.Time{schedule.PublishAt, schedule.UnpublishAt} {
+		if !runAt.IsZero() && !runAt.After(currentTime) {
+			return InvalidLessonSchedule
+		}
+	}
+	if !schedule.PublishAt.IsZero() && !schedule.UnpublishAt.IsZero() && !schedule.UnpublishAt.After(schedule.PublishAt) {
+		return InvalidLessonSchedule
+	}
+
+	key := lessonScheduleKey(schedule.LessonID)
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var current LessonSchedule
+		if err := tx.Get(key, &current); err == nil {
+			schedule.Created = current.Created
+		} else if err == datastore.ErrNoSuchEntity {
+			schedule.Created = currentTime
+		} else {
+			return err
+		}
+
+		schedule.NextRunAt = schedule.nextRunAt()
+		schedule.LastError = ""
+		schedule.Updated = currentTime
+		return tx.Put(key, schedule)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	for _, runAt := range []time.Time{schedule.PublishAt, schedule.UnpublishAt} {
+		if !runAt.IsZero() {
+			enqueueLessonScheduleTask(ctx, runAt)
+		}
+	}
+
+	return nil
+}
+
+// Completeは、runAtの予約を実行済みにします。実行中に予約が変更されていた場合は、変更後の予約を残します。
+// 未実行の予約がなくなった場合はLessonScheduleを削除します。
+func (r *lessonScheduleRepository) Complete(ctx context.Context, lessonID int64, runAt time.Time) error {
+	key := lessonScheduleKey(lessonID)
+	return r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var schedule LessonSchedule
+		if err := tx.Get(key, &schedule); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+
+		if schedule.PublishAt.Equal(runAt) {
+			schedule.PublishAt = time.Time{}
+		} else if schedule.UnpublishAt.Equal(runAt) {
+			schedule.UnpublishAt = time.Time{}
+		}
+
+		if schedule.PublishAt.IsZero() && schedule.UnpublishAt.IsZero() {
+			return tx.Delete(key)
+		}
+
+		schedule.NextRunAt = schedule.nextRunAt()
+		schedule.LastError = ""
+		schedule.Updated = time.Now()
+		return tx.Put(key, &schedule)
+	})
+}
+
+// RecordErrorは、予約の実行に失敗した理由を記録します。予約は残るので、次の実行で再試行されます。
+func (r *lessonScheduleRepository) RecordError(ctx context.Context, lessonID int64, err error) error {
+	key := lessonScheduleKey(lessonID)
+	return r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var schedule LessonSchedule
+		if getErr := tx.Get(key, &schedule); getErr != nil {
+			if getErr == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return getErr
+		}
+
+		schedule.LastError = err.Error()
+		schedule.Updated = time.Now()
+		return tx.Put(key, &schedule)
+	})
+}
+
+// Deleteは、Lessonの予約を取り消します。登録済みのタスクは実行されても何もしません。
+func (r *lessonScheduleRepository) Delete(ctx context.Context, lessonID int64) error {
+	return r.store.Delete(ctx, lessonScheduleKey(lessonID))
+}
+
+// Dueは、currentTimeまでに実行すべき予約について、変更後のLessonの状態と予約日時を返します。公開を公開終了より先に実行します。
+func (s *LessonSchedule) Due(currentTime time.Time) (LessonStatus, time.Time, bool) {
+	if !s.PublishAt.IsZero() && !s.PublishAt.After(currentTime) {
+		return LessonStatusPublic, s.PublishAt, true
+	}
+	if !s.UnpublishAt.IsZero() && !s.UnpublishAt.After(currentTime) {
+		return LessonStatusDraft, s.UnpublishAt, true
+	}
+	return LessonStatusDraft, time.Time{}, false
+}
+
+func (s *LessonSchedule) nextRunAt() time.Time {
+	if !s.PublishAt.IsZero() {
+		return s.PublishAt
+	}
+	return s.UnpublishAt
+}
+
+func lessonScheduleKey(lessonID int64) *datastore.Key {
+	return datastore.IDKey("LessonSchedule", lessonID, nil)
+}
+
+// enqueueLessonScheduleTaskは、runAtに予約を処理するタスクを登録します。
+// 失敗しても定期実行で予約は処理されるので、エラーは記録のみ行います。
+func enqueueLessonScheduleTask(ctx context.Context, runAt time.Time) {
+	if time.Until(runAt) > lessonScheduleTaskMaxDelay {
+		return
+	}
+
+	task := infrastructure.Task{Queue: LessonScheduleQueue, ETA: runAt}
+	if err := infrastructure.EnqueueTask(ctx, task); err != nil {
+		log.Printf("failed to enqueue lesson schedule task. %v\n", err)
+	}
+}
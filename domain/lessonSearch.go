@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// LessonSearchRepositoryは、公開中のLessonの検索インデックスを管理します。
+type LessonSearchRepository interface {
+	Upsert(ctx context.Context, lesson *Lesson) error
+	Remove(ctx context.Context, lessonID int64) error
+	Reindex(ctx context.Context) (int, error)
+}
+
+type lessonSearchRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonSearchRepositoryは、storeを使用するLessonSearchRepositoryを返します。
+func NewLessonSearchRepository(store infrastructure.Datastore) LessonSearchRepository {
+	return &lessonSearchRepository{store: store}
+}
+
+// Upsertは、公開中のLessonを検索インデックスに登録します。公開中でないLessonは検索インデックスから削除します。
+func (r *lessonSearchRepository) Upsert(ctx context.Context, lesson *Lesson) error {
+	if !isSearchableLesson(lesson) {
+		return r.Remove(ctx, lesson.ID)
+	}
+
+	return infrastructure.SearchIndex().Upsert(ctx, lessonSearchRecord(lesson))
+}
+
+func (r *lessonSearchRepository) Remove(ctx context.Context, lessonID int64) error {
+	return infrastructure.SearchIndex().Delete(ctx, strconv.FormatInt(lessonID, 10))
+}
+
+// Reindexは、公開中の全てのLessonで検索インデックスを作り直し、登録した件数を返します。
+func (r *lessonSearchRepository) Reindex(ctx context.Context) (int, error) {
+	var lessons []Lesson
+	query := infrastructure.NewQuery("Lesson").Filter("Status =", int32(LessonStatusPublic)).Filter("IsIntroduction =", false)
+	keys, err := r.store.GetAll(ctx, query, &lessons)
+	if err != nil {
+		return 0, err
+	}
+
+	records := make([]infrastructure.SearchRecord, len(lessons))
+	for i := range lessons {
+		lessons[i].ID = keys[i].ID
+		records[i] = lessonSearchRecord(&lessons[i])
+	}
+
+	if err := infrastructure.SearchIndex().ReplaceAll(ctx, records); err != nil {
+		return 0, err
+	}
+
+	return len(records), nil
+}
+
+// isSearchableLessonは、検索インデックスに登録するLessonかを返します。自己紹介の授業は検索対象にしません。
+func isSearchableLesson(lesson *Lesson) bool {
+	return lesson.Status == LessonStatusPublic && !lesson.IsIntroduction
+}
+
+func lessonSearchRecord(lesson *Lesson) infrastructure.SearchRecord {
+	return infrastructure.SearchRecord{
+		ObjectID: strconv.FormatInt(lesson.ID, 10),
+		Fields: map[string]interface{}{
+			"userID":               lesson.UserID,
+			"title":                lesson.Title,
+			"description":          lesson.Description,
+			"subjectID":            lesson.SubjectID,
+			"subjectName":          lesson.SubjectName,
+			"japaneseCategoryID":   lesson.JapaneseCategoryID,
+			"japaneseCategoryName": lesson.JapaneseCategoryName,
+			"tags":                 lesson.Tags,
+			"durationSec":          lesson.DurationSec,
+			"hasThumbnail":         lesson.HasThumbnail,
+			"viewCount":            lesson.ViewCount,
+			"published":            lesson.Published.Unix(),
+		},
+	}
+}
@@ -0,0 +1,101 @@
+package domain
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+func TestLessonSearchUpsert(t *testing.T) {
+	tests := []struct {
+		name   string
+		lesson Lesson
+		want   []int64
+	}{
+		{name: "public lesson", lesson: Lesson{Status: LessonStatusPublic, Title: "算数"}, want: []int64{1}},
+		{name: "limited lesson", lesson: Lesson{Status: LessonStatusLimited, Title: "算数"}, want: []int64{}},
+		{name: "draft lesson", lesson: Lesson{Status: LessonStatusDraft, Title: "算数"}, want: []int64{}},
+		{name: "trashed lesson", lesson: Lesson{Status: LessonStatusDeleted, Title: "算数"}, want: []int64{}},
+		{name: "introduction", lesson: Lesson{Status: LessonStatusPublic, Title: "算数", IsIntroduction: true}, want: []int64{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repository := NewLessonSearchRepository(newTestDatastore(t))
+
+			// 公開中に登録された後に状態が変わった場合も、検索インデックスから削除される
+			indexed := Lesson{ID: 1, Status: LessonStatusPublic, Title: "算数"}
+			if err := repository.Upsert(ctx, &indexed); err != nil {
+				t.Fatal(err)
+			}
+
+			lesson := tt.lesson
+			lesson.ID = 1
+			if err := repository.Upsert(ctx, &lesson); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := searchTestIndex(ctx, "算数")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("indexed lessons = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLessonSearchReindex(t *testing.T) {
+	ctx := context.Background()
+	store := newTestDatastore(t)
+	repository := NewLessonSearchRepository(store)
+
+	public := putTestLesson(t, store, Lesson{Status: LessonStatusPublic, Title: "理科の実験"})
+	putTestLesson(t, store, Lesson{Status: LessonStatusDraft, Title: "理科の下書き"})
+	putTestLesson(t, store, Lesson{Status: LessonStatusPublic, Title: "理科の自己紹介", IsIntroduction: true})
+
+	stale := Lesson{ID: 999, Status: LessonStatusPublic, Title: "理科の古い授業"}
+	if err := repository.Upsert(ctx, &stale); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := repository.Reindex(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Errorf("Reindex() = %d, want 1", count)
+	}
+
+	got, err := searchTestIndex(ctx, "理科")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int64{public.ID}; !reflect.DeepEqual(got, want) {
+		t.Errorf("indexed lessons = %v, want %v", got, want)
+	}
+}
+
+// searchTestIndexは、テスト用のメモリ上の検索インデックスでqueryに一致するLessonのIDを返します。
+func searchTestIndex(ctx context.Context, query string) ([]int64, error) {
+	objectIDs, err := infrastructure.SearchIndex().(*infrastructure.MemorySearchIndexer).Search(ctx, query, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(objectIDs))
+	for _, objectID := range objectIDs {
+		id, err := strconv.ParseInt(objectID, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -5,7 +5,6 @@ import (
 	"strconv"
 	"time"
 
-	"cloud.google.com/go/storage"
 	"github.com/super-dog-human/teraconnectgo/infrastructure"
 )
 
@@ -39,12 +38,12 @@ func CreateLessonThumbnailBlankFile(ctx context.Context, id int64, isPublic bool
 	var err error
 
 	if isPublic {
-		url, err = infrastructure.CreateBlankFileToPublicGCS(ctx, fileName, fileDir, fileRequest)
+		url, err = infrastructure.CreateBlankFileToPublic(ctx, fileName, fileDir, fileRequest)
 		if err != nil {
 			return "", err
 		}
 	} else {
-		url, err = infrastructure.CreateBlankFileToGCS(ctx, fileName, fileDir, fileRequest)
+		url, err = infrastructure.CreateBlankFile(ctx, fileName, fileDir, fileRequest)
 		if err != nil {
 			return "", err
 		}
@@ -54,12 +53,6 @@ func CreateLessonThumbnailBlankFile(ctx context.Context, id int64, isPublic bool
 }
 
 func CopyLessonThumbnail(ctx context.Context, id int64, currentStatus LessonStatus, newStatus LessonStatus) error {
-	client, err := storage.NewClient(ctx)
-	if err != nil {
-		return err
-	}
-	defer client.Close()
-
 	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
 	defer cancel()
 
@@ -83,10 +76,7 @@ func CopyLessonThumbnail(ctx context.Context, id int64, currentStatus LessonStat
 
 	fileID := strconv.FormatInt(id, 10)
 	objectPath := thumbnailFilePath(fileID)
-	src := client.Bucket(srcBucket).Object(objectPath)
-	dst := client.Bucket(destBucket).Object(objectPath)
-
-	if _, err := dst.CopierFrom(src).Run(ctx); err != nil {
+	if err := infrastructure.CopyFile(ctx, srcBucket, objectPath, destBucket, objectPath); err != nil {
 		return err
 	}
 
@@ -99,14 +89,14 @@ func thumbnailFilePath(id string) string {
 
 func createPublicURL(id int64) string {
 	idStr := strconv.FormatInt(id, 10)
-	return infrastructure.CloudStorageURL + infrastructure.PublicBucketName() + "/" + thumbnailFilePath(idStr)
+	return infrastructure.PublicURL(infrastructure.PublicBucketName(), thumbnailFilePath(idStr))
 }
 
 func createSignedURL(ctx context.Context, id int64) (string, error) {
 	idStr := strconv.FormatInt(id, 10)
 	fileType := "" // this is unnecessary when GET request
 	bucketName := infrastructure.MaterialBucketName()
-	url, err := infrastructure.GetGCSSignedURL(ctx, bucketName, thumbnailFilePath(idStr), "GET", fileType)
+	url, err := infrastructure.GetSignedURL(ctx, bucketName, thumbnailFilePath(idStr), "GET", fileType)
 
 	if err != nil {
 		return "", err
@@ -0,0 +1,177 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+type LessonTrashErrorCode uint
+
+const (
+	LessonNotInTrash    LessonTrashErrorCode = 1
+	LessonNotRestorable LessonTrashErrorCode = 2
+)
+
+func (e LessonTrashErrorCode) Error() string {
+	switch e {
+	case LessonNotInTrash:
+		return "lesson is not in trash"
+	case LessonNotRestorable:
+		return "lesson is no longer restorable"
+	default:
+		return "unknown lesson trash error"
+	}
+}
+
+// LessonTrashRepositoryは、ゴミ箱に移動したLessonの管理を行います。
+// ゴミ箱のLessonは復元できる期間が過ぎると、DeleteOrderによって関連データと共に完全に削除されます。
+type LessonTrashRepository interface {
+	GetByID(ctx context.Context, id int64) (Lesson, error)
+	GetByUserID(ctx context.Context, userID int64) ([]Lesson, error)
+	MoveToTrashInTransaction(tx infrastructure.Transaction, id int64, currentTime time.Time) (Lesson, error)
+	Restore(ctx context.Context, id int64, currentTime time.Time) (Lesson, error)
+}
+
+type lessonTrashRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonTrashRepositoryは、storeを使用するLessonTrashRepositoryを返します。
+func NewLessonTrashRepository(store infrastructure.Datastore) LessonTrashRepository {
+	return &lessonTrashRepository{store: store}
+}
+
+// GetByIDは、ゴミ箱にあるLessonを返します。Lessonが存在しないか、ゴミ箱にない場合はLessonNotInTrashを返します。
+func (r *lessonTrashRepository) GetByID(ctx context.Context, id int64) (Lesson, error) {
+	var lesson Lesson
+	if err := r.store.Get(ctx, datastore.IDKey("Lesson", id, nil), &lesson); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return Lesson{}, LessonNotInTrash
+		}
+		return Lesson{}, err
+	}
+
+	if lesson.Status != LessonStatusDeleted {
+		return Lesson{}, LessonNotInTrash
+	}
+
+	lesson.ID = id
+	lesson.RestorableUntil = lessonRestorableUntil(&lesson)
+
+	return lesson, nil
+}
+
+// GetByUserIDは、userIDのUserのゴミ箱にあるLessonを、ゴミ箱に移動した日時の新しい順に返します。
+func (r *lessonTrashRepository) GetByUserID(ctx context.Context, userID int64) ([]Lesson, error) {
+	var lessons []Lesson
+
+	query := infrastructure.NewQuery("Lesson").Filter("UserID =", userID).Filter("Status =", int32(LessonStatusDeleted))
+	keys, err := r.store.GetAll(ctx, query, &lessons)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		lessons[i].ID = key.ID
+		lessons[i].RestorableUntil = lessonRestorableUntil(&lessons[i])
+		if err := SetLessonThumbnailURL(ctx, &lessons[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	// Deletedはインデックスを作成していないので、取得後に並べ替える
+	sort.SliceStable(lessons, func(i, j int) bool {
+		return lessons[i].Deleted.After(lessons[j].Deleted)
+	})
+
+	return lessons, nil
+}
+
+// MoveToTrashInTransactionは、トランザクション中で最新のLessonを読み込んでゴミ箱に移動し、復元できる期間が過ぎた後に処理されるDeleteOrderを作成します。
+// Seriesに含まれている場合はSeriesから取り除きます。移動前の状態は復元のために保存します。
+// Lessonが存在しないか、既にゴミ箱にある場合はdatastore.ErrNoSuchEntityを返します。
+func (r *lessonTrashRepository) MoveToTrashInTransaction(tx infrastructure.Transaction, id int64, currentTime time.Time) (Lesson, error) {
+	var lesson Lesson
+	key := datastore.IDKey("Lesson", id, nil)
+	if err := tx.Get(key, &lesson); err != nil {
+		return Lesson{}, err
+	}
+
+	if lesson.Status == LessonStatusDeleted {
+		return Lesson{}, datastore.ErrNoSuchEntity
+	}
+
+	if lesson.SeriesID != 0 {
+		if err := NewSeriesRepository(r.store).RemoveLessonInTransaction(tx, lesson.SeriesID, id); err != nil {
+			return Lesson{}, err
+		}
+	}
+
+	lesson.ID = id
+	lesson.StatusBeforeDeletion = lesson.Status
+	lesson.Status = LessonStatusDeleted
+	lesson.SeriesID = 0
+	lesson.Deleted = currentTime
+	lesson.Updated = currentTime
+	lesson.RestorableUntil = lessonRestorableUntil(&lesson)
+
+	if err := tx.Put(key, &lesson); err != nil {
+		return Lesson{}, err
+	}
+
+	if err := createDeleteOrderInTransaction(tx, "Lesson", id, lesson.RestorableUntil); err != nil {
+		return Lesson{}, err
+	}
+
+	return lesson, nil
+}
+
+// Restoreは、ゴミ箱にあるLessonをゴミ箱に移動する前の状態に戻します。
+// 復元できる期間が過ぎている場合はLessonNotRestorableを返します。
+func (r *lessonTrashRepository) Restore(ctx context.Context, id int64, currentTime time.Time) (Lesson, error) {
+	var lesson Lesson
+	key := datastore.IDKey("Lesson", id, nil)
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		if err := tx.Get(key, &lesson); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return LessonNotInTrash
+			}
+			return err
+		}
+
+		if lesson.Status != LessonStatusDeleted {
+			return LessonNotInTrash
+		}
+		if !currentTime.Before(lessonRestorableUntil(&lesson)) {
+			return LessonNotRestorable
+		}
+
+		// ゴミ箱に移動した際のDeleteOrderは、処理時にLessonが復元済みであれば何もせずに削除される
+		lesson.Status = lesson.StatusBeforeDeletion
+		lesson.StatusBeforeDeletion = LessonStatusDraft
+		lesson.Deleted = time.Time{}
+		lesson.Updated = currentTime
+
+		return tx.Put(key, &lesson)
+	})
+
+	if err != nil {
+		return Lesson{}, err
+	}
+
+	lesson.ID = id
+	if err := SetLessonThumbnailURL(ctx, &lesson); err != nil {
+		return lesson, err
+	}
+
+	return lesson, nil
+}
+
+// lessonRestorableUntilは、ゴミ箱にあるLessonを復元できる期限を返します。
+func lessonRestorableUntil(lesson *Lesson) time.Time {
+	return lesson.Deleted.Add(infrastructure.CurrentConfig().LessonTrashRetention)
+}
@@ -0,0 +1,73 @@
+package domain
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+func TestMoveToTrashInTransaction(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     LessonStatus
+		staleTitle bool // ゴミ箱に移動する前に、他の処理でタイトルが変更されている
+		wantErr    error
+	}{
+		{name: "public lesson", status: LessonStatusPublic},
+		{name: "draft lesson", status: LessonStatusDraft},
+		{name: "keeps concurrent update", status: LessonStatusLimited, staleTitle: true},
+		{name: "already trashed", status: LessonStatusDeleted, wantErr: datastore.ErrNoSuchEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestDatastore(t)
+			repository := NewLessonTrashRepository(store)
+
+			lesson := putTestLesson(t, store, Lesson{UserID: 1, Status: tt.status, Title: "title"})
+			key := datastore.IDKey("Lesson", lesson.ID, nil)
+			if tt.staleTitle {
+				lesson.Title = "renamed"
+				if _, err := store.Put(ctx, key, &lesson); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var trashed Lesson
+			err := store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+				var err error
+				trashed, err = repository.MoveToTrashInTransaction(tx, lesson.ID, time.Now())
+				return err
+			})
+			if err != tt.wantErr {
+				t.Fatalf("MoveToTrashInTransaction() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			var got Lesson
+			if err := store.Get(ctx, key, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != LessonStatusDeleted || got.StatusBeforeDeletion != tt.status || got.Title != lesson.Title {
+				t.Errorf("saved lesson = %+v", got)
+			}
+			if trashed.StatusBeforeDeletion != tt.status {
+				t.Errorf("trashed.StatusBeforeDeletion = %v, want %v", trashed.StatusBeforeDeletion, tt.status)
+			}
+
+			var orders []DeleteOrder
+			if _, err := store.GetAll(ctx, infrastructure.NewQuery("DeleteOrder"), &orders); err != nil {
+				t.Fatal(err)
+			}
+			if len(orders) != 1 || orders[0].TargetID != lesson.ID || !orders[0].NotBefore.Equal(trashed.RestorableUntil) {
+				t.Errorf("delete orders = %+v, want one order not before %v", orders, trashed.RestorableUntil)
+			}
+		})
+	}
+}
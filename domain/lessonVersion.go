@@ -0,0 +1,235 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// LessonVersionは、公開処理毎に記録されるLessonの履歴です。Lessonを祖先に持ち、バージョン番号をIDにします。
+type LessonVersion struct {
+	LessonID       int64          `json:"lessonID" datastore:"-"`
+	Version        int32          `json:"version" datastore:"-"`
+	UserID         int64          `json:"userID"`
+	Title          string         `json:"title" datastore:",noindex"`
+	Description    string         `json:"description" datastore:",noindex"`
+	Status         LessonStatus   `json:"status" datastore:",noindex"` // 公開時の状態。ファイルはこの状態に応じたバケットに保存されている
+	DurationSec    float32        `json:"durationSec" datastore:",noindex"`
+	RolledBackFrom int32          `json:"rolledBackFrom" datastore:",noindex"` // ロールバックで作成された場合は元のバージョン
+	Material       LessonMaterial `json:"material" datastore:",noindex"`
+	SpeechURL      string         `json:"speechURL" datastore:"-"`
+	BodyURL        string         `json:"bodyURL" datastore:"-"`
+	Published      time.Time      `json:"published" datastore:",noindex"`
+}
+
+// ShortLessonVersionは、一覧表示用のLessonVersionです。
+type ShortLessonVersion struct {
+	Version        int32        `json:"version"`
+	Title          string       `json:"title"`
+	Description    string       `json:"description"`
+	Status         LessonStatus `json:"status"`
+	DurationSec    float32      `json:"durationSec"`
+	RolledBackFrom int32        `json:"rolledBackFrom"`
+	Published      time.Time    `json:"published"`
+}
+
+type LessonVersionErrorCode uint
+
+const (
+	LessonVersionNotFound     LessonVersionErrorCode = 1
+	LessonVersionIsCurrent    LessonVersionErrorCode = 2
+	LessonVersionNotPublished LessonVersionErrorCode = 3
+)
+
+func (e LessonVersionErrorCode) Error() string {
+	switch e {
+	case LessonVersionNotFound:
+		return "lesson version not found"
+	case LessonVersionIsCurrent:
+		return "lesson version is already current"
+	case LessonVersionNotPublished:
+		return "lesson is not published"
+	default:
+		return "unknown lesson version error"
+	}
+}
+
+// LessonVersionRepositoryは、LessonVersionの永続化と、過去のバージョンへのロールバックを行います。
+type LessonVersionRepository interface {
+	GetByLessonID(ctx context.Context, lessonID int64) ([]ShortLessonVersion, error)
+	Get(ctx context.Context, lessonID int64, version int32) (LessonVersion, error)
+	Rollback(ctx context.Context, lesson *Lesson, version int32) error
+}
+
+type lessonVersionRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonVersionRepositoryは、storeを使用するLessonVersionRepositoryを返します。
+func NewLessonVersionRepository(store infrastructure.Datastore) LessonVersionRepository {
+	return &lessonVersionRepository{store: store}
+}
+
+// LessonSpeechFilePathは、Lessonの音声のファイルパスを返します。
+func LessonSpeechFilePath(lessonID int64, version int32) string {
+	return fmt.Sprintf("lesson/%d/speech-%d.mp3", lessonID, version)
+}
+
+// LessonBucketNameは、statusのLessonのファイルが保存されるバケット名を返します。
+func LessonBucketName(status LessonStatus) string {
+	if status == LessonStatusPublic {
+		return infrastructure.PublicBucketName()
+	}
+	return infrastructure.MaterialBucketName()
+}
+
+// GetByLessonIDは、LessonのバージョンをMaterialを除いて新しい順に返します。
+func (r *lessonVersionRepository) GetByLessonID(ctx context.Context, lessonID int64) ([]ShortLessonVersion, error) {
+	var versions []LessonVersion
+
+	ancestor := datastore.IDKey("Lesson", lessonID, nil)
+	query := infrastructure.NewQuery("LessonVersion").Ancestor(ancestor)
+	keys, err := r.store.GetAll(ctx, query, &versions)
+	if err != nil {
+		return nil, err
+	}
+
+	shortVersions := make([]ShortLessonVersion, len(versions))
+	for i, key := range keys {
+		version := versions[i]
+		shortVersions[i] = ShortLessonVersion{
+			Version:        int32(key.ID),
+			Title:          version.Title,
+			Description:    version.Description,
+			Status:         version.Status,
+			DurationSec:    version.DurationSec,
+			RolledBackFrom: version.RolledBackFrom,
+			Published:      version.Published,
+		}
+	}
+
+	// 祖先クエリで並べ替えるには複合インデックスが必要になるので、取得後に並べ替える
+	sort.Slice(shortVersions, func(i, j int) bool { return shortVersions[i].Version > shortVersions[j].Version })
+
+	return shortVersions, nil
+}
+
+func (r *lessonVersionRepository) Get(ctx context.Context, lessonID int64, version int32) (LessonVersion, error) {
+	lessonVersion := new(LessonVersion)
+
+	if err := r.store.Get(ctx, lessonVersionKey(lessonID, version), lessonVersion); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return *lessonVersion, LessonVersionNotFound
+		}
+		return *lessonVersion, err
+	}
+
+	lessonVersion.LessonID = lessonID
+	lessonVersion.Version = version
+
+	return *lessonVersion, nil
+}
+
+// Rollbackは、versionのファイルと教材を新しいバージョンとして公開し直します。収録済みのファイルを複製するので、再収録や再圧縮は行いません。
+// 履歴を遡れるように、ロールバックも元のバージョンを記録したLessonVersionとして残します。
+func (r *lessonVersionRepository) Rollback(ctx context.Context, lesson *Lesson, version int32) error {
+	if lesson.Status == LessonStatusDraft {
+		return LessonVersionNotPublished
+	}
+	if lesson.Version == version {
+		return LessonVersionIsCurrent
+	}
+
+	target, err := r.Get(ctx, lesson.ID, version)
+	if err != nil {
+		return err
+	}
+
+	newVersion := lesson.Version + 1
+	srcBucket := LessonBucketName(target.Status)
+	dstBucket := LessonBucketName(lesson.Status)
+	if err := infrastructure.CopyFile(ctx, srcBucket, LessonBodyFilePath(lesson.ID, version), dstBucket, LessonBodyFilePath(lesson.ID, newVersion)); err != nil {
+		return err
+	}
+	// 音声のないLessonもあるので、音声ファイルがなくてもエラーにしない
+	if err := infrastructure.CopyFile(ctx, srcBucket, LessonSpeechFilePath(lesson.ID, version), dstBucket, LessonSpeechFilePath(lesson.ID, newVersion)); err != nil && !errors.Is(err, infrastructure.ErrObjectNotFound) {
+		return err
+	}
+
+	currentTime := time.Now()
+	lessonKey := datastore.IDKey("Lesson", lesson.ID, nil)
+	err = r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var current Lesson
+		if err := tx.Get(lessonKey, &current); err != nil {
+			return err
+		}
+
+		if current.Version != lesson.Version {
+			return LessonVersionConflicted
+		}
+
+		materialKey := datastore.IDKey("LessonMaterial", current.MaterialID, lessonKey)
+		var material LessonMaterial
+		if err := tx.Get(materialKey, &material); err != nil {
+			return err
+		}
+
+		restored := target.Material
+		restored.Created = material.Created
+		restored.Updated = currentTime
+		if err := tx.Put(materialKey, &restored); err != nil {
+			return err
+		}
+
+		current.Title = target.Title
+		current.Description = target.Description
+		current.AvatarID = restored.AvatarID
+		current.AvatarLightColor = restored.AvatarLightColor
+		current.DurationSec = restored.DurationSec
+		current.Version = newVersion
+		// 公開日時を進めることで、ロールバック前に予約された圧縮タスクが古い内容で上書きしないようにする
+		current.Published = currentTime
+		current.Updated = currentTime
+		if err := tx.Put(lessonKey, &current); err != nil {
+			return err
+		}
+
+		lessonVersion := LessonVersion{
+			UserID:         current.UserID,
+			Title:          current.Title,
+			Description:    current.Description,
+			Status:         current.Status,
+			DurationSec:    restored.DurationSec,
+			RolledBackFrom: version,
+			Material:       restored,
+			Published:      currentTime,
+		}
+		if err := createLessonVersionInTransaction(tx, lesson.ID, newVersion, &lessonVersion); err != nil {
+			return err
+		}
+
+		current.ID = lesson.ID
+		*lesson = current
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return NewLessonSearchRepository(r.store).Upsert(ctx, lesson)
+}
+
+func createLessonVersionInTransaction(tx infrastructure.Transaction, lessonID int64, version int32, lessonVersion *LessonVersion) error {
+	return tx.Put(lessonVersionKey(lessonID, version), lessonVersion)
+}
+
+func lessonVersionKey(lessonID int64, version int32) *datastore.Key {
+	ancestor := datastore.IDKey("Lesson", lessonID, nil)
+	return datastore.IDKey("LessonVersion", int64(version), ancestor)
+}
@@ -3,12 +3,48 @@ package domain
 import (
 	"context"
 	"fmt"
-	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"cloud.google.com/go/datastore"
 	"github.com/go-redis/redis/v8"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
 )
 
+// 集計中のキーのプリフィックス。集計対象のキーはこのプリフィックスに改名してから集計するので、集計中の増分は次回の集計に回される
+const lessonViewCountProcessingKeyPrefix = "lessonViewCountProcessing"
+
+// LessonViewCountHistoryは、Lessonの日毎の参照回数です。集計済みのRedisのキーを記録し、同じキーが二重に加算されることを防ぎます。
+type LessonViewCountHistory struct {
+	LessonID    int64     `json:"lessonID"`
+	UserID      int64     `json:"userID"`
+	Date        string    `json:"date"` // JSTでのYYYYMMDD
+	Count       int64     `json:"count" datastore:",noindex"`
+	AppliedKeys []string  `json:"-" datastore:",noindex"`
+	Updated     time.Time `json:"updated" datastore:",noindex"`
+}
+
+type lessonViewCountEntry struct {
+	key   string
+	date  string
+	count int64
+}
+
+// LessonViewCountRepositoryは、Redisに格納された参照回数をLessonとUserへ反映します。
+type LessonViewCountRepository interface {
+	Aggregate(ctx context.Context) (int, error)
+}
+
+type lessonViewCountRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewLessonViewCountRepositoryは、storeを使用するLessonViewCountRepositoryを返します。
+func NewLessonViewCountRepository(store infrastructure.Datastore) LessonViewCountRepository {
+	return &lessonViewCountRepository{store: store}
+}
+
 // LessonViewCountKeyは、Redis内で使用されるLesson参照回数保持用のキーのプリフィックスをstringで返します。
 func LessonViewCountKeyPrefix(currentTime time.Time) string {
 	return fmt.Sprintf("lessonViewCount_%s", currentTime.Format("20060102"))
@@ -22,11 +58,8 @@ func lessonViewCountKey(lessonID int64, currentTime time.Time) string {
 // IncrementLessonViewCountは、lessonIDのLessonのViewCountを1つ増分します。
 // 増分は即座に行われず、Redisに格納されます。その後、定時バッチでLesson.ViewCountとUser.TotalLessonViewCountに反映されます。
 func IncrementLessonViewCount(ctx context.Context, lessonID int64) error {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     os.Getenv("REDIS_ENDPOINT"),
-		Password: os.Getenv("REDIS_PASSWORD"),
-		DB:       0,
-	})
+	rdb := newRedisClient()
+	defer rdb.Close()
 
 	jst, err := time.LoadLocation("Asia/Tokyo")
 	if err != nil {
@@ -42,3 +75,191 @@ func IncrementLessonViewCount(ctx context.Context, lessonID int64) error {
 
 	return nil
 }
+
+// Aggregateは、Redisの参照回数をLesson.ViewCount、User.TotalLessonViewCount、LessonViewCountHistoryへ反映し、反映したLessonの数を返します。
+// キーは集計用の名前へ改名してから読み取り、反映と同じトランザクションで集計済みのキーを記録するので、途中で失敗して再実行しても二重に加算されません。
+func (r *lessonViewCountRepository) Aggregate(ctx context.Context) (int, error) {
+	rdb := newRedisClient()
+	defer rdb.Close()
+
+	runID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := renameLessonViewCountKeys(ctx, rdb, runID); err != nil {
+		return 0, err
+	}
+
+	// 以前の実行で反映できなかったキーも含めて集計する
+	entries, err := loadLessonViewCountEntries(ctx, rdb)
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for lessonID, lessonEntries := range entries {
+		applied, err := r.applyLessonViewCount(ctx, lessonID, lessonEntries)
+		if err != nil {
+			return updated, err
+		}
+		if applied {
+			updated++
+		}
+
+		keys := make([]string, len(lessonEntries))
+		for i, entry := range lessonEntries {
+			keys[i] = entry.key
+		}
+		if err := rdb.Del(ctx, keys...).Err(); err != nil {
+			return updated, err
+		}
+	}
+
+	return updated, nil
+}
+
+// applyLessonViewCountは、トランザクションでLessonとその作者、日毎の履歴に参照回数を加算します。Lessonが削除済みの場合はfalseを返します。
+func (r *lessonViewCountRepository) applyLessonViewCount(ctx context.Context, lessonID int64, entries []lessonViewCountEntry) (bool, error) {
+	applied := false
+
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		applied = false
+
+		lessonKey := datastore.IDKey("Lesson", lessonID, nil)
+		var lesson Lesson
+		if err := tx.Get(lessonKey, &lesson); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+
+		currentTime := time.Now()
+		histories := make(map[string]*LessonViewCountHistory)
+		var total int64
+		for _, entry := range entries {
+			history, ok := histories[entry.date]
+			if !ok {
+				history = new(LessonViewCountHistory)
+				historyKey := lessonViewCountHistoryKey(lessonID, entry.date)
+				if err := tx.Get(historyKey, history); err != nil && err != datastore.ErrNoSuchEntity {
+					return err
+				}
+				history.LessonID = lessonID
+				history.UserID = lesson.UserID
+				history.Date = entry.date
+				histories[entry.date] = history
+			}
+
+			if containsString(history.AppliedKeys, entry.key) {
+				continue
+			}
+
+			history.Count += entry.count
+			history.AppliedKeys = append(history.AppliedKeys, entry.key)
+			history.Updated = currentTime
+			total += entry.count
+		}
+
+		if total == 0 {
+			return nil
+		}
+
+		for date, history := range histories {
+			if err := tx.Put(lessonViewCountHistoryKey(lessonID, date), history); err != nil {
+				return err
+			}
+		}
+
+		lesson.ViewCount += total
+		if err := tx.Put(lessonKey, &lesson); err != nil {
+			return err
+		}
+
+		userKey := datastore.IDKey("User", lesson.UserID, nil)
+		var user User
+		if err := tx.Get(userKey, &user); err != nil {
+			if err != datastore.ErrNoSuchEntity {
+				return err
+			}
+		} else {
+			user.TotalLessonViewCount += total
+			if err := tx.Put(userKey, &user); err != nil {
+				return err
+			}
+		}
+
+		applied = true
+		return nil
+	})
+
+	return applied, err
+}
+
+// renameLessonViewCountKeysは、集計対象のキーを集計用の名前に改名します。改名はアトミックなので、改名後の増分は元の名前のキーに加算されます。
+func renameLessonViewCountKeys(ctx context.Context, rdb *redis.Client, runID string) error {
+	iter := rdb.Scan(ctx, 0, "lessonViewCount_*", 0).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		suffix := strings.TrimPrefix(key, "lessonViewCount_")
+		processingKey := fmt.Sprintf("%s_%s_%s", lessonViewCountProcessingKeyPrefix, runID, suffix)
+		if err := rdb.Rename(ctx, key, processingKey).Err(); err != nil && err.Error() != "ERR no such key" {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
+
+// loadLessonViewCountEntriesは、集計用の名前のキーをLessonのID毎にまとめて返します。
+func loadLessonViewCountEntries(ctx context.Context, rdb *redis.Client) (map[int64][]lessonViewCountEntry, error) {
+	entries := make(map[int64][]lessonViewCountEntry)
+
+	iter := rdb.Scan(ctx, 0, lessonViewCountProcessingKeyPrefix+"_*", 0).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+
+		// lessonViewCountProcessing_{runID}_{YYYYMMDD}_{lessonID}
+		parts := strings.Split(key, "_")
+		if len(parts) != 4 {
+			continue
+		}
+		lessonID, err := strconv.ParseInt(parts[3], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		count, err := rdb.Get(ctx, key).Int64()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		entries[lessonID] = append(entries[lessonID], lessonViewCountEntry{key: key, date: parts[2], count: count})
+	}
+
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func lessonViewCountHistoryKey(lessonID int64, date string) *datastore.Key {
+	return datastore.NameKey("LessonViewCountHistory", fmt.Sprintf("%s_%d", date, lessonID), nil)
+}
+
+func newRedisClient() *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     infrastructure.CurrentConfig().RedisEndpoint,
+		Password: infrastructure.CurrentConfig().RedisPassword,
+		DB:       0,
+	})
+}
+
+func containsString(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,212 @@
+package domain
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+func TestUpdateWithMaterial(t *testing.T) {
+	lessonFields := []string{"Tags", "Status", "Title", "Description"}
+	lessonMaterialFields := []string{"AvatarID"}
+
+	tests := []struct {
+		name             string
+		jsonBody         map[string]interface{}
+		concurrentUpdate func(lesson *Lesson) // 更新対象のLessonを読み込んだ後に、他の処理で行われた更新
+		wantErr          error
+		wantLesson       func(lesson *Lesson) // 更新後に保存されているべきLesson
+		wantAvatarID     int64
+	}{
+		{
+			name:     "updates only the fields in json",
+			jsonBody: map[string]interface{}{"title": "new title"},
+			wantLesson: func(lesson *Lesson) {
+				lesson.Title = "new title"
+			},
+		},
+		{
+			name:     "normalizes tags",
+			jsonBody: map[string]interface{}{"tags": []interface{}{"Ｇｏ", "#go", "Web  API"}},
+			wantLesson: func(lesson *Lesson) {
+				lesson.Tags = []string{"go", "web api"}
+			},
+		},
+		{
+			name:     "ignores fields not allowed",
+			jsonBody: map[string]interface{}{"viewCount": float64(100), "userID": float64(2)},
+		},
+		{
+			name:     "keeps fields updated concurrently",
+			jsonBody: map[string]interface{}{"description": "new description"},
+			concurrentUpdate: func(lesson *Lesson) {
+				lesson.ViewCount = 10
+				lesson.Title = "renamed"
+			},
+			wantLesson: func(lesson *Lesson) {
+				lesson.ViewCount = 10
+				lesson.Title = "renamed"
+				lesson.Description = "new description"
+			},
+		},
+		{
+			name:         "updates lesson material",
+			jsonBody:     map[string]interface{}{"avatarID": float64(3)},
+			wantAvatarID: 3,
+		},
+		{
+			name:     "rejects trashed lesson",
+			jsonBody: map[string]interface{}{"title": "new title"},
+			concurrentUpdate: func(lesson *Lesson) {
+				lesson.Status = LessonStatusDeleted
+			},
+			wantErr: datastore.ErrNoSuchEntity,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestDatastore(t)
+			repository := NewLessonRepository(store)
+
+			lesson := putTestLesson(t, store, Lesson{UserID: 1, Status: LessonStatusDraft, Title: "title", Description: "description"})
+			key := datastore.IDKey("Lesson", lesson.ID, nil)
+
+			stale := lesson
+			if tt.concurrentUpdate != nil {
+				tt.concurrentUpdate(&lesson)
+				if _, err := store.Put(ctx, key, &lesson); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			user := User{ID: 1}
+			err := repository.UpdateWithMaterial(ctx, &user, &stale, false, true, "request", &tt.jsonBody, &lessonFields, &lessonMaterialFields)
+			if err != tt.wantErr {
+				t.Fatalf("UpdateWithMaterial() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			want := lesson
+			if tt.wantLesson != nil {
+				tt.wantLesson(&want)
+			}
+
+			var got Lesson
+			if err := store.Get(ctx, key, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Title != want.Title || got.Description != want.Description || got.ViewCount != want.ViewCount ||
+				got.UserID != want.UserID || got.Status != want.Status || !reflect.DeepEqual(got.Tags, want.Tags) {
+				t.Errorf("saved lesson = %+v, want %+v", got, want)
+			}
+			if stale.Title != want.Title || stale.ViewCount != want.ViewCount {
+				t.Errorf("updated lesson = %+v, want %+v", stale, want)
+			}
+
+			var material LessonMaterial
+			materialKey := datastore.IDKey("LessonMaterial", lesson.MaterialID, key)
+			if err := store.Get(ctx, materialKey, &material); err != nil {
+				t.Fatal(err)
+			}
+			if material.AvatarID != tt.wantAvatarID {
+				t.Errorf("saved material avatarID = %d, want %d", material.AvatarID, tt.wantAvatarID)
+			}
+		})
+	}
+}
+
+func TestUpdateWithMaterialRepublish(t *testing.T) {
+	lessonFields := []string{"Status", "Description"}
+	lessonMaterialFields := []string{"AvatarID"}
+
+	tests := []struct {
+		name          string
+		status        LessonStatus
+		durationSec   float32 // 0の場合、LessonMaterialは公開できない
+		jsonBody      map[string]interface{}
+		byEditor      bool // 作成者以外の編集者による更新
+		wantErr       error
+		wantSnapshots int // 作成される圧縮用のLessonMaterialの数
+	}{
+		{
+			name:          "republishes valid material on metadata edit",
+			status:        LessonStatusPublic,
+			durationSec:   10,
+			jsonBody:      map[string]interface{}{"description": "new description"},
+			wantSnapshots: 1,
+		},
+		{
+			name:     "rejects broken material on metadata edit",
+			status:   LessonStatusPublic,
+			jsonBody: map[string]interface{}{"description": "new description"},
+			wantErr:  LessonMaterialNotPublishable,
+		},
+		{
+			name:     "rejects broken material on publish",
+			status:   LessonStatusDraft,
+			jsonBody: map[string]interface{}{"status": "limited"},
+			wantErr:  LessonMaterialNotPublishable,
+		},
+		{
+			name:     "does not republish for editors",
+			status:   LessonStatusPublic,
+			jsonBody: map[string]interface{}{"description": "new description"},
+			byEditor: true,
+		},
+		{
+			name:     "does not validate draft",
+			status:   LessonStatusDraft,
+			jsonBody: map[string]interface{}{"description": "new description"},
+		},
+		{
+			name:     "does not validate unpublishing",
+			status:   LessonStatusPublic,
+			jsonBody: map[string]interface{}{"status": "draft"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestDatastore(t)
+			repository := NewLessonRepository(store)
+
+			lesson := putTestLesson(t, store, Lesson{UserID: 1, Status: tt.status, Description: "description"})
+			key := datastore.IDKey("Lesson", lesson.ID, nil)
+			materialKey := datastore.IDKey("LessonMaterial", lesson.MaterialID, key)
+			material := LessonMaterial{UserID: 1, DurationSec: tt.durationSec}
+			if _, err := store.Put(ctx, materialKey, &material); err != nil {
+				t.Fatal(err)
+			}
+
+			user := User{ID: 1}
+			err := repository.UpdateWithMaterial(ctx, &user, &lesson, false, !tt.byEditor, "request", &tt.jsonBody, &lessonFields, &lessonMaterialFields)
+			if err != tt.wantErr {
+				t.Fatalf("UpdateWithMaterial() error = %v, want %v", err, tt.wantErr)
+			}
+
+			var got Lesson
+			if err := store.Get(ctx, key, &got); err != nil {
+				t.Fatal(err)
+			}
+			if updated := got.Description != "description" || got.Status != tt.status; updated != (tt.wantErr == nil) {
+				t.Errorf("lesson updated = %v, want %v", updated, tt.wantErr == nil)
+			}
+
+			snapshots, err := store.GetAll(ctx, infrastructure.NewQuery("LessonMaterialForCompressing").KeysOnly(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(snapshots) != tt.wantSnapshots {
+				t.Errorf("snapshots = %d, want %d", len(snapshots), tt.wantSnapshots)
+			}
+		})
+	}
+}
@@ -3,7 +3,6 @@ package domain
 import (
 	"context"
 
-	"cloud.google.com/go/datastore"
 	"github.com/super-dog-human/teraconnectgo/infrastructure"
 )
 
@@ -15,17 +14,26 @@ type PublicGraphic struct {
 	SortID          int64
 }
 
-func GetPublicGraphicsForIntroduction(ctx context.Context) ([]PublicGraphic, error) {
-	var graphics []PublicGraphic
+// PublicGraphicRepositoryは、PublicGraphicの参照を行います。
+type PublicGraphicRepository interface {
+	GetForIntroduction(ctx context.Context) ([]PublicGraphic, error)
+}
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, err
-	}
+type publicGraphicRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewPublicGraphicRepositoryは、storeを使用するPublicGraphicRepositoryを返します。
+func NewPublicGraphicRepository(store infrastructure.Datastore) PublicGraphicRepository {
+	return &publicGraphicRepository{store: store}
+}
+
+func (r *publicGraphicRepository) GetForIntroduction(ctx context.Context) ([]PublicGraphic, error) {
+	var graphics []PublicGraphic
 
-	query := datastore.NewQuery("PublicGraphic").Filter("ForIntroduction =", true).Order("SortID")
+	query := infrastructure.NewQuery("PublicGraphic").Filter("ForIntroduction =", true).Order("SortID")
 
-	keys, err := client.GetAll(ctx, query, &graphics)
+	keys, err := r.store.GetAll(ctx, query, &graphics)
 	if err != nil {
 		return graphics, err
 	}
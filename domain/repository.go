@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"context"
+
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// TransactionRunnerは、複数のリポジトリにまたがる更新を一つのトランザクションで実行します。
+type TransactionRunner interface {
+	RunInTransaction(ctx context.Context, f func(tx infrastructure.Transaction) error) error
+}
+
+// Repositoriesは、usecaseが使用する全てのリポジトリをまとめたものです。起動時にusecaseへ注入されます。
+type Repositories struct {
+	Transaction        TransactionRunner
+	Lesson             LessonRepository
+	LessonMaterial     LessonMaterialRepository
+	LessonCompressing  LessonCompressingRepository
+	LessonViewCount    LessonViewCountRepository
+	LessonReaction     LessonReactionRepository
+	LessonComment      LessonCommentRepository
+	LessonRanking      LessonRankingRepository
+	LessonTrash        LessonTrashRepository
+	LessonSearch       LessonSearchRepository
+	LessonVersion      LessonVersionRepository
+	LessonReview       LessonReviewRepository
+	LessonDuplicate    LessonDuplicateRepository
+	LessonBundle       LessonBundleRepository
+	LessonSchedule     LessonScheduleRepository
+	LessonCollaborator LessonCollaboratorRepository
+	ShareLink          ShareLinkRepository
+	Series             SeriesRepository
+	DeleteOrder        DeleteOrderRepository
+	User               UserRepository
+	Graphic            GraphicRepository
+	PublicGraphic      PublicGraphicRepository
+	Voice              VoiceRepository
+	Avatar             AvatarRepository
+	BackgroundImage    BackgroundImageRepository
+	BackgroundMusic    BackgroundMusicRepository
+	Category           CategoryRepository
+	Subject            SubjectRepository
+	Tag                TagRepository
+}
+
+// NewRepositoriesは、storeを使用する全てのリポジトリを作成します。
+func NewRepositories(store infrastructure.Datastore) Repositories {
+	return Repositories{
+		Transaction:        store,
+		Lesson:             NewLessonRepository(store),
+		LessonMaterial:     NewLessonMaterialRepository(store),
+		LessonCompressing:  NewLessonCompressingRepository(store),
+		LessonViewCount:    NewLessonViewCountRepository(store),
+		LessonReaction:     NewLessonReactionRepository(store),
+		LessonComment:      NewLessonCommentRepository(store),
+		LessonRanking:      NewLessonRankingRepository(store),
+		LessonTrash:        NewLessonTrashRepository(store),
+		LessonSearch:       NewLessonSearchRepository(store),
+		LessonVersion:      NewLessonVersionRepository(store),
+		LessonReview:       NewLessonReviewRepository(store),
+		LessonDuplicate:    NewLessonDuplicateRepository(store),
+		LessonBundle:       NewLessonBundleRepository(store),
+		LessonSchedule:     NewLessonScheduleRepository(store),
+		LessonCollaborator: NewLessonCollaboratorRepository(store),
+		ShareLink:          NewShareLinkRepository(store),
+		Series:             NewSeriesRepository(store),
+		DeleteOrder:        NewDeleteOrderRepository(store),
+		User:               NewUserRepository(store),
+		Graphic:            NewGraphicRepository(store),
+		PublicGraphic:      NewPublicGraphicRepository(store),
+		Voice:              NewVoiceRepository(store),
+		Avatar:             NewAvatarRepository(store),
+		BackgroundImage:    NewBackgroundImageRepository(store),
+		BackgroundMusic:    NewBackgroundMusicRepository(store),
+		Category:           NewCategoryRepository(store),
+		Subject:            NewSubjectRepository(store),
+		Tag:                NewTagRepository(store),
+	}
+}
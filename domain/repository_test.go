@@ -0,0 +1,72 @@
+package domain
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// newTestDatastoreは、テスト用のメモリ上のDatastoreを返します。
+// 検索インデックスとオブジェクトストレージ、タスクキューもテスト毎に作り直し、設定はテスト用の値にします。
+func newTestDatastore(t *testing.T) infrastructure.Datastore {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "teraconnect-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	objectStore, err := infrastructure.NewLocalObjectStore(dir, "https://localhost", "test-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	infrastructure.SetConfig(infrastructure.Config{
+		MaterialBucketName:   "material",
+		PublicBucketName:     "public",
+		LessonTrashRetention: 30 * 24 * time.Hour,
+	})
+	infrastructure.SetObjectStore(objectStore)
+	infrastructure.SetSearchIndexer(infrastructure.NewMemorySearchIndexer())
+
+	// 登録したタスクは実行せず、ファイルに保存されるのみとする
+	taskQueue, err := infrastructure.NewLocalTaskQueue(filepath.Join(dir, "tasks.json"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infrastructure.SetTaskQueue(taskQueue)
+
+	return infrastructure.NewMemoryDatastore()
+}
+
+// putTestLessonは、LessonとそのLessonMaterialを保存し、IDを設定したlessonを返します。
+func putTestLesson(t *testing.T, store infrastructure.Datastore, lesson Lesson) Lesson {
+	t.Helper()
+
+	ctx := context.Background()
+	key, err := store.Put(ctx, datastore.IncompleteKey("Lesson", nil), &lesson)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lesson.ID = key.ID
+
+	material := LessonMaterial{UserID: lesson.UserID, Created: time.Now()}
+	materialKey, err := store.Put(ctx, datastore.IncompleteKey("LessonMaterial", key), &material)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lesson.MaterialID = materialKey.ID
+	if _, err := store.Put(ctx, key, &lesson); err != nil {
+		t.Fatal(err)
+	}
+
+	return lesson
+}
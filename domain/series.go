@@ -0,0 +1,373 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// Seriesは、Userが作成する順序付きのLessonのまとまりです。LessonはLesson.SeriesIDで一つのSeriesにのみ所属します。
+type Series struct {
+	ID           int64         `json:"id" datastore:"-"`
+	UserID       int64         `json:"userID"`
+	Title        string        `json:"title" datastore:",noindex"`
+	Description  string        `json:"description" datastore:",noindex"`
+	LessonIDs    []int64       `json:"lessonIDs" datastore:",noindex"` // 並び順で格納
+	Lessons      []ShortLesson `json:"lessons,omitempty" datastore:"-"`
+	HasThumbnail bool          `json:"hasThumbnail" datastore:",noindex"`
+	ThumbnailURL string        `json:"thumbnailURL" datastore:"-"`
+	Created      time.Time     `json:"created"`
+	Updated      time.Time     `json:"updated" datastore:",noindex"`
+}
+
+// LessonSeriesPositionは、Seriesの中でのLessonの位置です。公開中のLessonのみで数えます。
+type LessonSeriesPosition struct {
+	ID          int64  `json:"id"`
+	Title       string `json:"title"`
+	Position    int    `json:"position"` // 1始まり
+	LessonCount int    `json:"lessonCount"`
+}
+
+type SeriesErrorCode uint
+
+const (
+	SeriesNotFound        SeriesErrorCode = 1
+	InvalidSeriesLessons  SeriesErrorCode = 2
+	LessonAlreadyInSeries SeriesErrorCode = 3
+)
+
+func (e SeriesErrorCode) Error() string {
+	switch e {
+	case SeriesNotFound:
+		return "series not found"
+	case InvalidSeriesLessons:
+		return "invalid lessons for series"
+	case LessonAlreadyInSeries:
+		return "lesson already belongs to another series"
+	default:
+		return "unknown series error"
+	}
+}
+
+// SeriesRepositoryは、Seriesの永続化と、Seriesに所属するLessonの管理を行います。
+type SeriesRepository interface {
+	GetByID(ctx context.Context, id int64) (Series, error)
+	GetByUserID(ctx context.Context, userID int64) ([]Series, error)
+	GetPublicLessons(ctx context.Context, series *Series) ([]Lesson, error)
+	Create(ctx context.Context, series *Series) error
+	Update(ctx context.Context, series *Series) error
+	SetLessons(ctx context.Context, series *Series, lessonIDs []int64) error
+	Delete(ctx context.Context, series *Series) error
+	RemoveLessonInTransaction(tx infrastructure.Transaction, seriesID int64, lessonID int64) error
+	DeleteByUserID(ctx context.Context, userID int64) error
+}
+
+type seriesRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewSeriesRepositoryは、storeを使用するSeriesRepositoryを返します。
+func NewSeriesRepository(store infrastructure.Datastore) SeriesRepository {
+	return &seriesRepository{store: store}
+}
+
+func (r *seriesRepository) GetByID(ctx context.Context, id int64) (Series, error) {
+	series := new(Series)
+
+	key := datastore.IDKey("Series", id, nil)
+	if err := r.store.Get(ctx, key, series); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return *series, SeriesNotFound
+		}
+		return *series, err
+	}
+
+	series.ID = id
+	setSeriesThumbnailURL(series)
+
+	return *series, nil
+}
+
+func (r *seriesRepository) GetByUserID(ctx context.Context, userID int64) ([]Series, error) {
+	var seriesList []Series
+
+	query := infrastructure.NewQuery("Series").Filter("UserID =", userID).Order("-Created")
+	keys, err := r.store.GetAll(ctx, query, &seriesList)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		seriesList[i].ID = key.ID
+		setSeriesThumbnailURL(&seriesList[i])
+	}
+
+	return seriesList, nil
+}
+
+// GetPublicLessonsは、seriesのLessonのうち公開中のものを並び順で返します。削除済みのLessonは無視します。
+func (r *seriesRepository) GetPublicLessons(ctx context.Context, series *Series) ([]Lesson, error) {
+	if len(series.LessonIDs) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]*datastore.Key, len(series.LessonIDs))
+	for i, id := range series.LessonIDs {
+		keys[i] = datastore.IDKey("Lesson", id, nil)
+	}
+
+	lessons := make([]Lesson, len(keys))
+	if err := r.store.GetMulti(ctx, keys, lessons); err != nil {
+		multiErr, ok := err.(datastore.MultiError)
+		if !ok {
+			return nil, err
+		}
+		for i, e := range multiErr {
+			if e != nil && e != datastore.ErrNoSuchEntity {
+				return nil, multiErr[i]
+			}
+		}
+		for i, e := range multiErr {
+			if e == datastore.ErrNoSuchEntity {
+				lessons[i].Status = LessonStatusDraft
+			}
+		}
+	}
+
+	var publicLessons []Lesson
+	for i, lesson := range lessons {
+		if lesson.Status != LessonStatusPublic || lesson.SeriesID != series.ID {
+			continue
+		}
+		lesson.ID = keys[i].ID
+		publicLessons = append(publicLessons, lesson)
+	}
+
+	return publicLessons, nil
+}
+
+func (r *seriesRepository) Create(ctx context.Context, series *Series) error {
+	currentTime := time.Now()
+	series.LessonIDs = nil
+	series.Created = currentTime
+	series.Updated = currentTime
+
+	key, err := r.store.Put(ctx, datastore.IncompleteKey("Series", nil), series)
+	if err != nil {
+		return err
+	}
+
+	series.ID = key.ID
+	setSeriesThumbnailURL(series)
+
+	return nil
+}
+
+// Updateは、Seriesのタイトルなどを更新します。LessonIDsの変更はSetLessonsで行うので、ここでは保存済みの値を維持します。
+func (r *seriesRepository) Update(ctx context.Context, series *Series) error {
+	key := datastore.IDKey("Series", series.ID, nil)
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var current Series
+		if err := tx.Get(key, &current); err != nil {
+			return err
+		}
+
+		series.LessonIDs = current.LessonIDs
+		series.Created = current.Created
+		series.Updated = time.Now()
+		return tx.Put(key, series)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	setSeriesThumbnailURL(series)
+
+	return nil
+}
+
+// SetLessonsは、seriesのLessonをlessonIDsの順序で置き換えます。追加、削除、並べ替えを一つのトランザクションで行います。
+// LessonはSeriesと同じUserのもので、他のSeriesに所属していない必要があります。
+func (r *seriesRepository) SetLessons(ctx context.Context, series *Series, lessonIDs []int64) error {
+	seen := make(map[int64]bool, len(lessonIDs))
+	for _, id := range lessonIDs {
+		if id == 0 || seen[id] {
+			return InvalidSeriesLessons
+		}
+		seen[id] = true
+	}
+
+	seriesKey := datastore.IDKey("Series", series.ID, nil)
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var current Series
+		if err := tx.Get(seriesKey, &current); err != nil {
+			return err
+		}
+		current.ID = series.ID
+
+		for _, id := range current.LessonIDs {
+			if seen[id] {
+				continue
+			}
+			if err := r.setLessonSeriesInTransaction(tx, id, current.ID, 0); err != nil {
+				return err
+			}
+		}
+
+		for _, id := range lessonIDs {
+			lessonKey := datastore.IDKey("Lesson", id, nil)
+			var lesson Lesson
+			if err := tx.Get(lessonKey, &lesson); err != nil {
+				if err == datastore.ErrNoSuchEntity {
+					return InvalidSeriesLessons
+				}
+				return err
+			}
+
+			if lesson.UserID != current.UserID || lesson.IsIntroduction || lesson.Status == LessonStatusDeleted {
+				return InvalidSeriesLessons
+			}
+			if lesson.SeriesID != 0 && lesson.SeriesID != current.ID {
+				return LessonAlreadyInSeries
+			}
+			if lesson.SeriesID == current.ID {
+				continue
+			}
+
+			lesson.SeriesID = current.ID
+			if err := tx.Put(lessonKey, &lesson); err != nil {
+				return err
+			}
+		}
+
+		current.LessonIDs = lessonIDs
+		current.Updated = time.Now()
+		if err := tx.Put(seriesKey, &current); err != nil {
+			return err
+		}
+
+		*series = current
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	setSeriesThumbnailURL(series)
+
+	return nil
+}
+
+// Deleteは、Seriesを削除し、所属していたLessonをSeriesから外します。Lesson自体は削除しません。
+func (r *seriesRepository) Delete(ctx context.Context, series *Series) error {
+	seriesKey := datastore.IDKey("Series", series.ID, nil)
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var current Series
+		if err := tx.Get(seriesKey, &current); err != nil {
+			return err
+		}
+
+		for _, id := range current.LessonIDs {
+			if err := r.setLessonSeriesInTransaction(tx, id, series.ID, 0); err != nil {
+				return err
+			}
+		}
+
+		return tx.Delete(seriesKey)
+	})
+
+	if err != nil {
+		return err
+	}
+
+	return infrastructure.DeleteFile(ctx, infrastructure.PublicBucketName(), seriesThumbnailFilePath(series.ID))
+}
+
+// RemoveLessonInTransactionは、トランザクションでSeriesの並びからLessonを取り除きます。Seriesが削除済みの場合は何もしません。
+func (r *seriesRepository) RemoveLessonInTransaction(tx infrastructure.Transaction, seriesID int64, lessonID int64) error {
+	seriesKey := datastore.IDKey("Series", seriesID, nil)
+	var series Series
+	if err := tx.Get(seriesKey, &series); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return nil
+		}
+		return err
+	}
+
+	lessonIDs := make([]int64, 0, len(series.LessonIDs))
+	for _, id := range series.LessonIDs {
+		if id != lessonID {
+			lessonIDs = append(lessonIDs, id)
+		}
+	}
+	series.LessonIDs = lessonIDs
+	series.Updated = time.Now()
+
+	return tx.Put(seriesKey, &series)
+}
+
+// DeleteByUserIDは、Userの全てのSeriesとサムネイルを削除します。Userの削除時に使用されることを想定しています。
+func (r *seriesRepository) DeleteByUserID(ctx context.Context, userID int64) error {
+	query := infrastructure.NewQuery("Series").Filter("UserID =", userID).KeysOnly()
+	keys, err := r.store.GetAll(ctx, query, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, key := range keys {
+		if err := infrastructure.DeleteFile(ctx, infrastructure.PublicBucketName(), seriesThumbnailFilePath(key.ID)); err != nil {
+			return err
+		}
+	}
+
+	if len(keys) == 0 {
+		return nil
+	}
+
+	return r.store.DeleteMulti(ctx, keys)
+}
+
+// CreateSeriesThumbnailBlankFileは、Seriesのサムネイルをアップロードするための署名付きURLを返します。Seriesは公開されるので、常に公開用のバケットに保存します。
+func CreateSeriesThumbnailBlankFile(ctx context.Context, id int64) (string, error) {
+	fileRequest := infrastructure.FileRequest{
+		Extension:   "png",
+		ContentType: "image/png",
+	}
+
+	return infrastructure.CreateBlankFileToPublic(ctx, "thumbnail", fmt.Sprintf("series/%d", id), fileRequest)
+}
+
+// setLessonSeriesInTransactionは、LessonがseriesIDのSeriesに所属している場合に、所属先をnewSeriesIDに変更します。
+func (r *seriesRepository) setLessonSeriesInTransaction(tx infrastructure.Transaction, lessonID int64, seriesID int64, newSeriesID int64) error {
+	lessonKey := datastore.IDKey("Lesson", lessonID, nil)
+	var lesson Lesson
+	if err := tx.Get(lessonKey, &lesson); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return nil
+		}
+		return err
+	}
+
+	if lesson.SeriesID != seriesID {
+		return nil
+	}
+
+	lesson.SeriesID = newSeriesID
+	return tx.Put(lessonKey, &lesson)
+}
+
+func setSeriesThumbnailURL(series *Series) {
+	series.ThumbnailURL = ""
+	if series.HasThumbnail {
+		series.ThumbnailURL = infrastructure.PublicURL(infrastructure.PublicBucketName(), seriesThumbnailFilePath(series.ID))
+	}
+}
+
+func seriesThumbnailFilePath(id int64) string {
+	return fmt.Sprintf("series/%d/thumbnail.png", id)
+}
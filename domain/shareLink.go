@@ -0,0 +1,402 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"cloud.google.com/go/datastore"
+	"github.com/go-redis/redis/v8"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// ShareLinkは、限定公開のLessonを参照するためのリンクです。Lessonを祖先に持ち、キーの名前はview_keyとして使用されます。
+// Lessonごとに複数作成でき、有効期限と参照回数の上限、取り消しをリンクごとに設定できます。
+// 上限のないリンクの参照回数は即座に更新されず、Redisに格納してから定時バッチで反映します。
+type ShareLink struct {
+	LessonID     int64     `json:"lessonID" datastore:"-"`
+	Key          string    `json:"key" datastore:"-"`
+	Label        string    `json:"label" datastore:",noindex"`
+	ExpiresAt    time.Time `json:"expiresAt" datastore:",noindex"` // ゼロ値の場合は無期限
+	MaxViews     int64     `json:"maxViews" datastore:",noindex"`  // 0の場合は無制限
+	AccessCount  int64     `json:"accessCount" datastore:",noindex"`
+	LastAccessed time.Time `json:"lastAccessed" datastore:",noindex"`
+	Revoked      time.Time `json:"revoked" datastore:",noindex"` // ゼロ値の場合は有効
+	AppliedKeys  []string  `json:"-" datastore:",noindex"`       // 反映済みのRedisのキー。同じキーが二重に加算されることを防ぐ
+	Created      time.Time `json:"created" datastore:",noindex"`
+	Updated      time.Time `json:"updated" datastore:",noindex"`
+}
+
+type ShareLinkErrorCode uint
+
+const (
+	ShareLinkNotFound    ShareLinkErrorCode = 1
+	InvalidShareLink     ShareLinkErrorCode = 2
+	ShareLinkUnavailable ShareLinkErrorCode = 3
+)
+
+func (e ShareLinkErrorCode) Error() string {
+	switch e {
+	case ShareLinkNotFound:
+		return "share link not found"
+	case InvalidShareLink:
+		return "invalid share link"
+	case ShareLinkUnavailable:
+		return "share link is revoked, expired or reached the view limit"
+	default:
+		return "unknown share link error"
+	}
+}
+
+const (
+	shareLinkLabelMaxLength = 100
+	// ShareLinkに記録する反映済みのキーの上限。反映済みのキーは直後に削除されるので、直近の分のみ保持する
+	shareLinkAppliedKeysMaxCount = 20
+)
+
+// 集計中のキーのプリフィックス。集計対象のキーはこのプリフィックスに改名してから集計するので、集計中の参照は次回の集計に回される
+const shareLinkAccessProcessingKeyPrefix = "shareLinkAccessProcessing"
+
+// ShareLinkRepositoryは、ShareLinkの永続化と、リンクによる限定公開のLessonの参照の確認を行います。
+type ShareLinkRepository interface {
+	GetByLesson(ctx context.Context, lesson *Lesson) ([]ShareLink, error)
+	Create(ctx context.Context, lessonID int64, link *ShareLink) error
+	Revoke(ctx context.Context, lessonID int64, key string) (ShareLink, error)
+	Use(ctx context.Context, lesson *Lesson, key string, buffered bool) error
+	Validate(ctx context.Context, lesson *Lesson, key string) error
+	AggregateAccess(ctx context.Context) (int, error)
+}
+
+type shareLinkRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewShareLinkRepositoryは、storeを使用するShareLinkRepositoryを返します。
+func NewShareLinkRepository(store infrastructure.Datastore) ShareLinkRepository {
+	return &shareLinkRepository{store: store}
+}
+
+// GetByLessonは、Lessonの取り消し済みを含む全てのShareLinkを作成日時の新しい順に返します。
+func (r *shareLinkRepository) GetByLesson(ctx context.Context, lesson *Lesson) ([]ShareLink, error) {
+	if err := r.migrateViewKey(ctx, lesson); err != nil {
+		return nil, err
+	}
+
+	var links []ShareLink
+	ancestor := datastore.IDKey("Lesson", lesson.ID, nil)
+	query := infrastructure.NewQuery("ShareLink").Ancestor(ancestor)
+	keys, err := r.store.GetAll(ctx, query, &links)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, key := range keys {
+		links[i].LessonID = lesson.ID
+		links[i].Key = key.Name
+	}
+
+	sort.Slice(links, func(i, j int) bool { return links[i].Created.After(links[j].Created) })
+
+	return links, nil
+}
+
+// Createは、LessonにShareLinkを作成します。キーは新たに生成され、参照回数は0から数えます。
+func (r *shareLinkRepository) Create(ctx context.Context, lessonID int64, link *ShareLink) error {
+	currentTime := time.Now()
+	if utf8.RuneCountInString(link.Label) > shareLinkLabelMaxLength || link.MaxViews < 0 {
+		return InvalidShareLink
+	}
+	if !link.ExpiresAt.IsZero() && !link.ExpiresAt.After(currentTime) {
+		return InvalidShareLink
+	}
+
+	key, err := UUIDWithoutHypen()
+	if err != nil {
+		return err
+	}
+
+	link.LessonID = lessonID
+	link.Key = key
+	link.AccessCount = 0
+	link.LastAccessed = time.Time{}
+	link.Revoked = time.Time{}
+	link.Created = currentTime
+	link.Updated = currentTime
+
+	_, err = r.store.Put(ctx, shareLinkKey(lessonID, key), link)
+	return err
+}
+
+// Revokeは、ShareLinkを取り消します。参照回数を残すため、ShareLinkは削除しません。
+func (r *shareLinkRepository) Revoke(ctx context.Context, lessonID int64, key string) (ShareLink, error) {
+	var link ShareLink
+
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		link = ShareLink{}
+		if err := tx.Get(shareLinkKey(lessonID, key), &link); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return ShareLinkNotFound
+			}
+			return err
+		}
+
+		if link.Revoked.IsZero() {
+			link.Revoked = time.Now()
+			link.Updated = link.Revoked
+		}
+		return tx.Put(shareLinkKey(lessonID, key), &link)
+	})
+
+	if err != nil {
+		return link, err
+	}
+	link.LessonID = lessonID
+	link.Key = key
+
+	return link, nil
+}
+
+// Useは、keyのShareLinkでLessonを参照できる場合に、参照回数を増分します。
+// 上限のあるリンクは上限を超えないようトランザクションで増分します。上限のないリンクは、bufferedがtrueの場合、
+// クラス全体に共有されたリンクの同時の参照で書き込みが競合しないよう、増分をRedisに格納してAggregateAccessで反映します。
+func (r *shareLinkRepository) Use(ctx context.Context, lesson *Lesson, key string, buffered bool) error {
+	if err := r.prepare(ctx, lesson, key); err != nil {
+		return err
+	}
+
+	linkKey := shareLinkKey(lesson.ID, key)
+	var link ShareLink
+	if err := r.store.Get(ctx, linkKey, &link); err != nil {
+		return err
+	}
+
+	currentTime := time.Now()
+	if !link.available(currentTime) {
+		return ShareLinkUnavailable
+	}
+
+	if link.MaxViews == 0 && buffered {
+		return incrementShareLinkAccess(ctx, lesson.ID, key, currentTime)
+	}
+
+	return r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var link ShareLink
+		if err := tx.Get(linkKey, &link); err != nil {
+			return err
+		}
+
+		currentTime := time.Now()
+		if !link.available(currentTime) || (link.MaxViews > 0 && link.AccessCount >= link.MaxViews) {
+			return ShareLinkUnavailable
+		}
+
+		link.AccessCount++
+		link.LastAccessed = currentTime
+		return tx.Put(linkKey, &link)
+	})
+}
+
+// Validateは、keyのShareLinkでLessonを参照できるかを確認します。参照回数は増分も確認もしないので、
+// 最後の参照で上限に達したリンクでも、再生中の教材が使うGraphicなどは取得できます。
+func (r *shareLinkRepository) Validate(ctx context.Context, lesson *Lesson, key string) error {
+	if err := r.prepare(ctx, lesson, key); err != nil {
+		return err
+	}
+
+	var link ShareLink
+	if err := r.store.Get(ctx, shareLinkKey(lesson.ID, key), &link); err != nil {
+		return err
+	}
+
+	if !link.available(time.Now()) {
+		return ShareLinkUnavailable
+	}
+
+	return nil
+}
+
+// prepareは、keyのShareLinkが存在するかを確認します。ShareLinkの導入前のViewKeyの場合は、ShareLinkを作成します。
+func (r *shareLinkRepository) prepare(ctx context.Context, lesson *Lesson, key string) error {
+	if key == "" {
+		return ShareLinkUnavailable
+	}
+
+	var link ShareLink
+	err := r.store.Get(ctx, shareLinkKey(lesson.ID, key), &link)
+	if err == nil {
+		return nil
+	} else if err != datastore.ErrNoSuchEntity {
+		return err
+	}
+
+	if key != lesson.ViewKey {
+		return ShareLinkUnavailable
+	}
+
+	return r.migrateViewKey(ctx, lesson)
+}
+
+// migrateViewKeyは、ShareLinkの導入前に限定公開されたLessonのViewKeyを、期限のないShareLinkとして作成します。
+// 作成済みの場合は、取り消されていても作り直しません。
+func (r *shareLinkRepository) migrateViewKey(ctx context.Context, lesson *Lesson) error {
+	if lesson.ViewKey == "" {
+		return nil
+	}
+
+	key := shareLinkKey(lesson.ID, lesson.ViewKey)
+	return r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var link ShareLink
+		if err := tx.Get(key, &link); err != datastore.ErrNoSuchEntity {
+			return err
+		}
+
+		currentTime := time.Now()
+		link = ShareLink{Created: currentTime, Updated: currentTime}
+		return tx.Put(key, &link)
+	})
+}
+
+// AggregateAccessは、Redisに格納された参照回数と最終参照日時をShareLinkへ反映し、反映したShareLinkの数を返します。
+// LessonViewCountと同様に、キーは集計用の名前へ改名してから読み取り、反映と同じトランザクションで集計済みのキーを記録します。
+func (r *shareLinkRepository) AggregateAccess(ctx context.Context) (int, error) {
+	rdb := newRedisClient()
+	defer rdb.Close()
+
+	runID := strconv.FormatInt(time.Now().UnixNano(), 10)
+	if err := renameShareLinkAccessKeys(ctx, rdb, runID); err != nil {
+		return 0, err
+	}
+
+	// 以前の実行で反映できなかったキーも含めて集計する
+	iter := rdb.Scan(ctx, 0, shareLinkAccessProcessingKeyPrefix+"_*", 0).Iterator()
+	updated := 0
+	for iter.Next(ctx) {
+		redisKey := iter.Val()
+
+		// shareLinkAccessProcessing_{runID}_{lessonID}_{key}
+		parts := strings.SplitN(redisKey, "_", 4)
+		if len(parts) != 4 {
+			continue
+		}
+		lessonID, err := strconv.ParseInt(parts[2], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		values, err := rdb.HGetAll(ctx, redisKey).Result()
+		if err != nil {
+			return updated, err
+		}
+
+		lastAccessed := time.Unix(0, parseRedisInt64(values["lastAccessed"]))
+		applied, err := r.applyAccess(ctx, shareLinkKey(lessonID, parts[3]), redisKey, parseRedisInt64(values["count"]), lastAccessed)
+		if err != nil {
+			return updated, err
+		}
+		if applied {
+			updated++
+		}
+
+		if err := rdb.Del(ctx, redisKey).Err(); err != nil {
+			return updated, err
+		}
+	}
+
+	if err := iter.Err(); err != nil {
+		return updated, err
+	}
+
+	return updated, nil
+}
+
+// applyAccessは、トランザクションでShareLinkに参照回数を加算します。ShareLinkが削除済みか、反映済みの場合はfalseを返します。
+func (r *shareLinkRepository) applyAccess(ctx context.Context, linkKey *datastore.Key, redisKey string, count int64, lastAccessed time.Time) (bool, error) {
+	applied := false
+
+	err := r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		applied = false
+
+		var link ShareLink
+		if err := tx.Get(linkKey, &link); err != nil {
+			if err == datastore.ErrNoSuchEntity {
+				return nil
+			}
+			return err
+		}
+
+		if containsString(link.AppliedKeys, redisKey) {
+			return nil
+		}
+
+		link.AppliedKeys = append(link.AppliedKeys, redisKey)
+		if len(link.AppliedKeys) > shareLinkAppliedKeysMaxCount {
+			link.AppliedKeys = link.AppliedKeys[len(link.AppliedKeys)-shareLinkAppliedKeysMaxCount:]
+		}
+		link.AccessCount += count
+		if lastAccessed.After(link.LastAccessed) {
+			link.LastAccessed = lastAccessed
+		}
+		if err := tx.Put(linkKey, &link); err != nil {
+			return err
+		}
+
+		applied = true
+		return nil
+	})
+
+	return applied, err
+}
+
+// incrementShareLinkAccessは、ShareLinkの参照回数の増分と最終参照日時をRedisに格納します。
+func incrementShareLinkAccess(ctx context.Context, lessonID int64, key string, currentTime time.Time) error {
+	rdb := newRedisClient()
+	defer rdb.Close()
+
+	redisKey := shareLinkAccessKey(lessonID, key)
+	_, err := rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HIncrBy(ctx, redisKey, "count", 1)
+		pipe.HSet(ctx, redisKey, "lastAccessed", currentTime.UnixNano())
+		return nil
+	})
+
+	return err
+}
+
+// renameShareLinkAccessKeysは、集計対象のキーを集計用の名前に改名します。改名はアトミックなので、改名後の参照は元の名前のキーに加算されます。
+func renameShareLinkAccessKeys(ctx context.Context, rdb *redis.Client, runID string) error {
+	iter := rdb.Scan(ctx, 0, "shareLinkAccess_*", 0).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
+		suffix := strings.TrimPrefix(key, "shareLinkAccess_")
+		processingKey := fmt.Sprintf("%s_%s_%s", shareLinkAccessProcessingKeyPrefix, runID, suffix)
+		if err := rdb.Rename(ctx, key, processingKey).Err(); err != nil && err.Error() != "ERR no such key" {
+			return err
+		}
+	}
+
+	return iter.Err()
+}
+
+// shareLinkAccessKeyは、Redis内で使用されるShareLinkの参照回数保持用のキーをstringで返します。
+func shareLinkAccessKey(lessonID int64, key string) string {
+	return fmt.Sprintf("shareLinkAccess_%d_%s", lessonID, key)
+}
+
+// availableは、取り消されておらず、有効期限内であればtrueを返します。
+func (l *ShareLink) available(currentTime time.Time) bool {
+	if !l.Revoked.IsZero() {
+		return false
+	}
+	if !l.ExpiresAt.IsZero() && !l.ExpiresAt.After(currentTime) {
+		return false
+	}
+	return true
+}
+
+func shareLinkKey(lessonID int64, key string) *datastore.Key {
+	return datastore.NameKey("ShareLink", key, datastore.IDKey("Lesson", lessonID, nil))
+}
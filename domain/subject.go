@@ -15,16 +15,26 @@ type Subject struct {
 	SortID       int64  `json:"-"`
 }
 
-// GetAllSubjects is return all sorted subjects.
-func GetAllSubjects(ctx context.Context) ([]Subject, error) {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, err
-	}
+// SubjectRepository is reference of subjects.
+type SubjectRepository interface {
+	GetAll(ctx context.Context) ([]Subject, error)
+	GetByID(ctx context.Context, id int64) (Subject, error)
+}
+
+type subjectRepository struct {
+	store infrastructure.Datastore
+}
 
+// NewSubjectRepository returns SubjectRepository uses the store.
+func NewSubjectRepository(store infrastructure.Datastore) SubjectRepository {
+	return &subjectRepository{store: store}
+}
+
+// GetAll is return all sorted subjects.
+func (r *subjectRepository) GetAll(ctx context.Context) ([]Subject, error) {
 	var subjects []Subject
-	query := datastore.NewQuery("Subject").Order("SortID")
-	keys, err := client.GetAll(ctx, query, &subjects)
+	query := infrastructure.NewQuery("Subject").Order("SortID")
+	keys, err := r.store.GetAll(ctx, query, &subjects)
 	if err != nil {
 		return nil, err
 	}
@@ -36,17 +46,12 @@ func GetAllSubjects(ctx context.Context) ([]Subject, error) {
 	return subjects, nil
 }
 
-// GetSubject is return a subject from id.
-func GetSubject(ctx context.Context, id int64) (Subject, error) {
+// GetByID is return a subject from id.
+func (r *subjectRepository) GetByID(ctx context.Context, id int64) (Subject, error) {
 	subject := new(Subject)
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return *subject, err
-	}
-
 	key := datastore.IDKey("Subject", id, nil)
-	if err := client.Get(ctx, key, subject); err != nil {
+	if err := r.store.Get(ctx, key, subject); err != nil {
 		return *subject, err
 	}
 	subject.ID = id
@@ -4,9 +4,7 @@ import (
 	"context"
 	"fmt"
 
-	texttospeech "cloud.google.com/go/texttospeech/apiv1"
 	"github.com/super-dog-human/teraconnectgo/infrastructure"
-	texttospeechpb "google.golang.org/genproto/googleapis/cloud/texttospeech/v1"
 )
 
 type CreateSynthesisVoiceParam struct {
@@ -27,40 +25,31 @@ func CreateSynthesisVoice(ctx context.Context, params *CreateSynthesisVoiceParam
 	return nil
 }
 
+// CreateSynthesizedVoiceは、VoiceSynthesisConfigに従って音声を合成します。filePathが空でなければbucketNameのバケットに保存します。
 func CreateSynthesizedVoice(ctx context.Context, params *CreateSynthesisVoiceParam, bucketName, filePath string) ([]byte, error) {
-	client, err := texttospeech.NewClient(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	req := texttospeechpb.SynthesizeSpeechRequest{
-		Input: &texttospeechpb.SynthesisInput{
-			InputSource: &texttospeechpb.SynthesisInput_Text{Text: params.Text},
-		},
-		Voice: &texttospeechpb.VoiceSelectionParams{
-			LanguageCode: params.LanguageCode,
-			Name:         params.Name,
-		},
-		AudioConfig: &texttospeechpb.AudioConfig{
-			AudioEncoding: texttospeechpb.AudioEncoding_MP3,
-			SpeakingRate:  params.SpeakingRate,
-			Pitch:         params.Pitch,
-			VolumeGainDb:  params.VolumeGainDb,
-		},
-	}
-
-	resp, err := client.SynthesizeSpeech(ctx, &req)
+	audio, err := infrastructure.SynthesizeSpeech(ctx, params.VoiceSynthesisConfig.speechSynthesisRequest(params.Text))
 	if err != nil {
 		return nil, err
 	}
 
 	if filePath != "" {
-		if err := infrastructure.CreateFileToGCS(ctx, bucketName, filePath, "audio/mpeg", resp.AudioContent); err != nil {
+		if err := infrastructure.CreateFile(ctx, bucketName, filePath, "audio/mpeg", audio); err != nil {
 			return nil, err
 		}
 	}
 
-	return resp.AudioContent, nil
+	return audio, nil
+}
+
+func (c VoiceSynthesisConfig) speechSynthesisRequest(text string) infrastructure.SpeechSynthesisRequest {
+	return infrastructure.SpeechSynthesisRequest{
+		Text:         text,
+		LanguageCode: c.LanguageCode,
+		VoiceName:    c.Name,
+		SpeakingRate: c.SpeakingRate,
+		Pitch:        c.Pitch,
+		VolumeGainDb: c.VolumeGainDb,
+	}
 }
 
 func CloudStorageVoiceFilePath(lessonID, voiceID int64, voiceFileKey string) string {
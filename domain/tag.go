@@ -0,0 +1,214 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// Tagは、公開中のLessonに付けられたタグです。キーの名前は正規化したタグ名で、入力補完に使用します。
+type Tag struct {
+	Name        string    `json:"name"`
+	LessonCount int64     `json:"lessonCount" datastore:",noindex"` // このタグが付いた公開中のLessonの数
+	Updated     time.Time `json:"updated" datastore:",noindex"`
+}
+
+type TagErrorCode uint
+
+const (
+	InvalidTag  TagErrorCode = 1
+	TooManyTags TagErrorCode = 2
+)
+
+func (e TagErrorCode) Error() string {
+	switch e {
+	case InvalidTag:
+		return "invalid tag"
+	case TooManyTags:
+		return "too many tags"
+	default:
+		return "unknown tag error"
+	}
+}
+
+const (
+	lessonTagsMaxCount = 10
+	tagNameMaxLength   = 30
+	tagSearchMaxCount  = 100 // 前方一致で取得する候補の上限。この中からLessonの多い順に返す
+	tagPutBatchSize    = 500
+)
+
+// TagRepositoryは、Tagの入力補完と、公開中のLessonのタグの件数の管理を行います。
+type TagRepository interface {
+	Search(ctx context.Context, prefix string, limit int) ([]Tag, error)
+	Apply(ctx context.Context, before []string, after []string) error
+	Rebuild(ctx context.Context) (int, error)
+}
+
+type tagRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewTagRepositoryは、storeを使用するTagRepositoryを返します。
+func NewTagRepository(store infrastructure.Datastore) TagRepository {
+	return &tagRepository{store: store}
+}
+
+// Searchは、prefixで始まるTagを公開中のLessonの多い順に最大limit件返します。
+func (r *tagRepository) Search(ctx context.Context, prefix string, limit int) ([]Tag, error) {
+	prefix = NormalizeTag(prefix)
+	if prefix == "" {
+		return []Tag{}, nil
+	}
+
+	var tags []Tag
+	query := infrastructure.NewQuery("Tag").Filter("Name >=", prefix).Filter("Name <", prefix+"\uffff").Order("Name").Limit(tagSearchMaxCount)
+	if _, err := r.store.GetAll(ctx, query, &tags); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].LessonCount > tags[j].LessonCount })
+	if len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	return tags, nil
+}
+
+// Applyは、Lessonのタグがbeforeからafterに変わった分だけ、Tagの件数を増減します。公開中でないLessonのタグは空として渡します。
+func (r *tagRepository) Apply(ctx context.Context, before []string, after []string) error {
+	diffs := make(map[string]int64)
+	for _, name := range before {
+		diffs[name]--
+	}
+	for _, name := range after {
+		diffs[name]++
+	}
+	for name, diff := range diffs {
+		if diff == 0 {
+			delete(diffs, name)
+		}
+	}
+	if len(diffs) == 0 {
+		return nil
+	}
+
+	return r.store.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		currentTime := time.Now()
+		for name, diff := range diffs {
+			key := tagKey(name)
+			tag := Tag{Name: name}
+			if err := tx.Get(key, &tag); err != nil && err != datastore.ErrNoSuchEntity {
+				return err
+			}
+
+			tag.LessonCount += diff
+			tag.Updated = currentTime
+			if tag.LessonCount <= 0 {
+				if err := tx.Delete(key); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Put(key, &tag); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Rebuildは、公開中の全てのLessonからTagを作り直し、Tagの件数を返します。
+func (r *tagRepository) Rebuild(ctx context.Context) (int, error) {
+	var lessons []Lesson
+	query := infrastructure.NewQuery("Lesson").Filter("Status =", int32(LessonStatusPublic))
+	if _, err := r.store.GetAll(ctx, query, &lessons); err != nil {
+		return 0, err
+	}
+
+	counts := make(map[string]int64)
+	for i := range lessons {
+		for _, name := range lessons[i].Tags {
+			counts[name]++
+		}
+	}
+
+	currentKeys, err := r.store.GetAll(ctx, infrastructure.NewQuery("Tag").KeysOnly(), nil)
+	if err != nil {
+		return 0, err
+	}
+	for start := 0; start < len(currentKeys); start += tagPutBatchSize {
+		end := start + tagPutBatchSize
+		if end > len(currentKeys) {
+			end = len(currentKeys)
+		}
+		if err := r.store.DeleteMulti(ctx, currentKeys[start:end]); err != nil {
+			return 0, err
+		}
+	}
+
+	currentTime := time.Now()
+	tagKeys := make([]*datastore.Key, 0, len(counts))
+	tags := make([]Tag, 0, len(counts))
+	for name, count := range counts {
+		tagKeys = append(tagKeys, tagKey(name))
+		tags = append(tags, Tag{Name: name, LessonCount: count, Updated: currentTime})
+	}
+	for start := 0; start < len(tagKeys); start += tagPutBatchSize {
+		end := start + tagPutBatchSize
+		if end > len(tagKeys) {
+			end = len(tagKeys)
+		}
+		if _, err := r.store.PutMulti(ctx, tagKeys[start:end], tags[start:end]); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(tags), nil
+}
+
+// NormalizeTagは、全角英数字と記号を半角に、英字を小文字にし、先頭の#と前後の空白を取り除きます。連続する空白は一つにまとめます。
+func NormalizeTag(name string) string {
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case r == '　':
+			return ' '
+		case r >= '！' && r <= '～':
+			r = r - '！' + '!'
+		}
+		return unicode.ToLower(r)
+	}, name)
+
+	name = strings.Join(strings.Fields(name), " ")
+	return strings.TrimSpace(strings.TrimLeft(name, "#"))
+}
+
+// NormalizeTagsは、tagsを正規化して重複を取り除きます。空のタグ、長すぎるタグ、多すぎるタグはエラーになります。
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		name := NormalizeTag(tag)
+		if name == "" || utf8.RuneCountInString(name) > tagNameMaxLength {
+			return nil, InvalidTag
+		}
+		if !Contains(&normalized, name) {
+			normalized = append(normalized, name)
+		}
+	}
+
+	if len(normalized) > lessonTagsMaxCount {
+		return nil, TooManyTags
+	}
+
+	return normalized, nil
+}
+
+func tagKey(name string) *datastore.Key {
+	return datastore.NameKey("Tag", name, nil)
+}
@@ -0,0 +1,14 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// LessonCompressingQueueは、LessonMaterialの圧縮タスクのキューです。タスク名がLessonMaterialForCompressingのIDを兼ねます。
+var LessonCompressingQueue = infrastructure.QueueConfig{
+	Name:        "compressLesson",
+	RelativeURI: "/lesson_compressing",
+	Retry:       infrastructure.RetryPolicy{MaxAttempts: 5, MinBackoff: 10 * time.Second, MaxBackoff: 10 * time.Minute},
+}
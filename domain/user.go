@@ -48,8 +48,31 @@ func (e UserErrorCode) Error() string {
 	}
 }
 
-// GetCurrentUser returns user from valid token.
-func GetCurrentUser(request *http.Request) (User, error) {
+// UserRepository is persistence of User.
+type UserRepository interface {
+	GetCurrent(request *http.Request) (User, error)
+	GetByID(ctx context.Context, id int64) (User, error)
+	GetByIDs(ctx context.Context, ids []int64) (map[int64]User, error)
+	GetList(ctx context.Context, cursorStr string) ([]User, string, error)
+	ReserveProviderIDInTransaction(tx infrastructure.Transaction, providerID string) error
+	CreateInTransaction(tx infrastructure.Transaction, user *User) error
+	UpdateByJson(ctx context.Context, user *User, jsonBody *map[string]interface{}, targetFields *[]string) error
+	Update(ctx context.Context, user *User) error
+	Delete(ctx context.Context, id int64) error
+	DeleteInTransaction(tx infrastructure.Transaction, id int64) error
+}
+
+type userRepository struct {
+	store infrastructure.Datastore
+}
+
+// NewUserRepository returns UserRepository uses the store.
+func NewUserRepository(store infrastructure.Datastore) UserRepository {
+	return &userRepository{store: store}
+}
+
+// GetCurrent returns user from valid token.
+func (r *userRepository) GetCurrent(request *http.Request) (User, error) {
 	user := new(User) // for return blank user when error
 
 	providerID, err := ProviderID(request)
@@ -59,13 +82,9 @@ func GetCurrentUser(request *http.Request) (User, error) {
 
 	var users []User
 	ctx := request.Context()
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return *user, FailedDatastoreInitialize
-	}
 
-	query := datastore.NewQuery("User").Filter("ProviderID =", providerID).Limit(1)
-	keys, err := client.GetAll(ctx, query, &users)
+	query := infrastructure.NewQuery("User").Filter("ProviderID =", providerID).Limit(1)
+	keys, err := r.store.GetAll(ctx, query, &users)
 	if err != nil {
 		return *user, FailedGettingUser
 	}
@@ -80,17 +99,12 @@ func GetCurrentUser(request *http.Request) (User, error) {
 	return *user, nil
 }
 
-// GetUserByIDはidからユーザーを取得して返します。Emailは必ず空文字列になり、json出力時はフィールドごとなくなります。
-func GetUserByID(ctx context.Context, id int64) (User, error) {
+// GetByIDはidからユーザーを取得して返します。Emailは必ず空文字列になり、json出力時はフィールドごとなくなります。
+func (r *userRepository) GetByID(ctx context.Context, id int64) (User, error) {
 	user := new(User)
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return *user, err
-	}
-
 	key := datastore.IDKey("User", id, nil)
-	if err := client.Get(ctx, key, user); err != nil {
+	if err := r.store.Get(ctx, key, user); err != nil {
 		if err == datastore.ErrNoSuchEntity {
 			return *user, UserNotFound
 		}
@@ -104,25 +118,57 @@ func GetUserByID(ctx context.Context, id int64) (User, error) {
 	return *user, nil
 }
 
-func GetUsers(ctx context.Context, cursorStr string) ([]User, string, error) {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return nil, "", err
+// GetByIDsは、idsのUserをIDをキーとしたマップで返します。存在しないUserは含めません。GetByIDと同じく非公開の値は返しません。
+func (r *userRepository) GetByIDs(ctx context.Context, ids []int64) (map[int64]User, error) {
+	keys := make([]*datastore.Key, len(ids))
+	for i, id := range ids {
+		keys[i] = datastore.IDKey("User", id, nil)
+	}
+
+	users := make([]User, len(keys))
+	found := make([]bool, len(keys))
+	for i := range found {
+		found[i] = true
+	}
+	if err := r.store.GetMulti(ctx, keys, users); err != nil {
+		multiErr, ok := err.(datastore.MultiError)
+		if !ok {
+			return nil, err
+		}
+		for i, e := range multiErr {
+			if e == datastore.ErrNoSuchEntity {
+				found[i] = false
+			} else if e != nil {
+				return nil, e
+			}
+		}
 	}
 
+	usersByID := make(map[int64]User, len(users))
+	for i, user := range users {
+		if !found[i] {
+			continue
+		}
+		user.ID = ids[i]
+		user.BackgroundImageURL = infrastructure.GetPublicBackgroundImageURL(strconv.FormatInt(user.BackgroundImageID, 10))
+		user.Email = ""
+		user.TotalLessonViewCount = 0
+		usersByID[user.ID] = user
+	}
+
+	return usersByID, nil
+}
+
+func (r *userRepository) GetList(ctx context.Context, cursorStr string) ([]User, string, error) {
 	const userPageSize = 20
-	query := datastore.NewQuery("User").Order("-Created").Limit(userPageSize)
+	query := infrastructure.NewQuery("User").Order("-Created").Limit(userPageSize)
 
 	if cursorStr != "" {
-		cursor, err := datastore.DecodeCursor(cursorStr)
-		if err != nil {
-			return nil, "", err
-		}
-		query = query.Start(cursor)
+		query = query.Start(cursorStr)
 	}
 
 	var users []User
-	it := client.Run(ctx, query)
+	it := r.store.Run(ctx, query)
 	for {
 		var user User
 		key, err := it.Next(&user)
@@ -142,11 +188,11 @@ func GetUsers(ctx context.Context, cursorStr string) ([]User, string, error) {
 		return nil, "", err
 	}
 
-	return users, nextCursor.String(), nil
+	return users, nextCursor, nil
 }
 
-// ReserveUserProviderIDInTransaction creates user's ProviderID for exclusion control.
-func ReserveUserProviderIDInTransaction(tx *datastore.Transaction, providerID string) error {
+// ReserveProviderIDInTransaction creates user's ProviderID for exclusion control.
+func (r *userRepository) ReserveProviderIDInTransaction(tx infrastructure.Transaction, providerID string) error {
 	key := datastore.NameKey("UserProviderID", providerID, nil)
 	userProviderID := new(UserProviderID)
 
@@ -159,62 +205,60 @@ func ReserveUserProviderIDInTransaction(tx *datastore.Transaction, providerID st
 	}
 
 	// Put only when ErrNoSuchEntity
-	_, err = tx.Put(key, userProviderID)
-	return err
+	return tx.Put(key, userProviderID)
 }
 
-// CreateUserInTransaction creates new user.
-func CreateUserInTransaction(tx *datastore.Transaction, user *User) (*datastore.PendingKey, error) {
+// CreateInTransaction creates new user.
+func (r *userRepository) CreateInTransaction(tx infrastructure.Transaction, user *User) error {
 	key := datastore.IncompleteKey("User", nil)
 
 	currentTime := time.Now()
 	user.Created = currentTime
 	user.Updated = currentTime
 
-	pendingKey, err := tx.Put(key, user)
-	if err != nil {
-		return nil, err
+	if err := tx.Put(key, user); err != nil {
+		return err
 	}
 
-	return pendingKey, nil
+	return nil
 }
 
-// UpdateUserByJsonは、json構造のinterfaceを受け取り、Userを更新します。
-func UpdateUserByJson(ctx context.Context, user *User, jsonBody *map[string]interface{}, targetFields *[]string) error {
+// UpdateByJsonは、json構造のinterfaceを受け取り、Userを更新します。
+func (r *userRepository) UpdateByJson(ctx context.Context, user *User, jsonBody *map[string]interface{}, targetFields *[]string) error {
 	MergeJsonToStruct(jsonBody, user, targetFields)
 
-	if err := UpdateUser(ctx, user); err != nil {
+	if err := r.Update(ctx, user); err != nil {
 		return err
 	}
 	return nil
 }
 
-// UpdateUserは、受け取ったUserでエンティティを更新します。
-func UpdateUser(ctx context.Context, user *User) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
+// Updateは、受け取ったUserでエンティティを更新します。
+func (r *userRepository) Update(ctx context.Context, user *User) error {
 	key := datastore.IDKey("User", user.ID, nil)
 	user.Updated = time.Now()
 
-	if _, err := client.Put(ctx, key, user); err != nil {
+	if _, err := r.store.Put(ctx, key, user); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-// DeleteUser deletes user.
-func DeleteUser(ctx context.Context, id int64) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
+// Delete deletes user.
+func (r *userRepository) Delete(ctx context.Context, id int64) error {
+	key := datastore.IDKey("User", id, nil)
+	if err := r.store.Delete(ctx, key); err != nil {
 		return err
 	}
 
+	return nil
+}
+
+// DeleteInTransaction deletes user in the transaction.
+func (r *userRepository) DeleteInTransaction(tx infrastructure.Transaction, id int64) error {
 	key := datastore.IDKey("User", id, nil)
-	if err := client.Delete(ctx, key); err != nil {
+	if err := tx.Delete(key); err != nil {
 		return err
 	}
 
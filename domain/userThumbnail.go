@@ -19,7 +19,7 @@ func CreateUserThumbnailBlankFile(ctx context.Context, id int64) (string, error)
 	var url string
 	var err error
 
-	url, err = infrastructure.CreateBlankFileToPublicGCS(ctx, fileName, fileDir, fileRequest)
+	url, err = infrastructure.CreateBlankFileToPublic(ctx, fileName, fileDir, fileRequest)
 	if err != nil {
 		return "", err
 	}
@@ -127,6 +127,16 @@ func MergeJsonToStruct(jsonDiff *map[string]interface{}, origin interface{}, all
 					targets = append(targets, targetBlankStruct)
 				}
 				targetField.Set(reflect.ValueOf(&targets).Elem())
+			case []LessonQuiz:
+				targets = nil
+				for _, v := range jsonValue.([]interface{}) {
+					var targetBlankStruct LessonQuiz
+					allowChildFields := TopLevelStructKeys(&targetBlankStruct)
+					child := v.(map[string]interface{})
+					MergeJsonToStruct(&child, &targetBlankStruct, &allowChildFields)
+					targets = append(targets, targetBlankStruct)
+				}
+				targetField.Set(reflect.ValueOf(&targets).Elem())
 			case []LessonDrawingUnit:
 				targets = nil
 				for _, v := range jsonValue.([]interface{}) {
@@ -154,6 +164,22 @@ func MergeJsonToStruct(jsonDiff *map[string]interface{}, origin interface{}, all
 					targets = append(targets, value)
 				}
 				targetField.Set(reflect.ValueOf(&targets).Elem())
+			case []string:
+				targets = nil
+				for _, v := range jsonValue.([]interface{}) {
+					if value, ok := v.(string); ok {
+						targets = append(targets, value)
+					}
+				}
+				targetField.Set(reflect.ValueOf(&targets).Elem())
+			case []int64:
+				targets = nil
+				for _, v := range jsonValue.([]interface{}) {
+					if value, ok := v.(float64); ok {
+						targets = append(targets, int64(value))
+					}
+				}
+				targetField.Set(reflect.ValueOf(&targets).Elem())
 			}
 		} else {
 			setValueToField(jsonValue, targetField)
@@ -195,6 +221,49 @@ func TopLevelStructKeys(target interface{}) []string {
 	return keys
 }
 
+// copyStructFieldsは、srcのfieldsに含まれるフィールドの値をdstへコピーします。dstとsrcは同じ型の構造体のポインタです。
+// 構造体に存在しないフィールド名は無視します。
+func copyStructFields(dst interface{}, src interface{}, fields []string) {
+	d := reflect.ValueOf(dst).Elem()
+	s := reflect.ValueOf(src).Elem()
+
+	for _, name := range fields {
+		targetField := d.FieldByName(name)
+		if !targetField.IsValid() || !targetField.CanSet() {
+			continue
+		}
+		targetField.Set(s.FieldByName(name))
+	}
+}
+
+// mergedFieldNamesは、MergeJsonToStructでjsonからマージされるフィールドの名前を返します。
+func mergedFieldNames(jsonDiff *map[string]interface{}, allowFields *[]string) []string {
+	var names []string
+	for rawName := range *jsonDiff {
+		fieldName := strings.Title(rawName)
+		if Contains(allowFields, fieldName) {
+			names = append(names, fieldName)
+		}
+	}
+
+	return names
+}
+
+// datastoreFieldNamesは、targetのフィールドのうちdatastoreに保存されるものの名前を返します。
+func datastoreFieldNames(target interface{}) []string {
+	v := reflect.ValueOf(target).Elem().Type()
+
+	var names []string
+	for i := 0; i < v.NumField(); i++ {
+		if strings.Split(v.Field(i).Tag.Get("datastore"), ",")[0] == "-" {
+			continue
+		}
+		names = append(names, v.Field(i).Name)
+	}
+
+	return names
+}
+
 func Contains(s *[]string, e string) bool {
 	for _, a := range *s {
 		if a == e {
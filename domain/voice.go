@@ -38,16 +38,26 @@ type Voice struct {
 	Updated     time.Time `json:"updated" datastore:",noindex"`
 }
 
-// GetVoices is get voice entities belongs to lesson.
-func GetVoices(ctx context.Context, lessonID int64, voices *[]Voice) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
+// VoiceRepository is persistence of Voice.
+type VoiceRepository interface {
+	GetByLessonID(ctx context.Context, lessonID int64, voices *[]Voice) error
+	Create(ctx context.Context, voice *Voice) error
+}
+
+type voiceRepository struct {
+	store infrastructure.Datastore
+}
 
-	query := datastore.NewQuery("Voice").Filter("LessonID = ", lessonID).Filter("IsSynthesis =", false).Order("ElapsedTime")
+// NewVoiceRepository returns VoiceRepository uses the store.
+func NewVoiceRepository(store infrastructure.Datastore) VoiceRepository {
+	return &voiceRepository{store: store}
+}
+
+// GetByLessonID is get voice entities belongs to lesson.
+func (r *voiceRepository) GetByLessonID(ctx context.Context, lessonID int64, voices *[]Voice) error {
+	query := infrastructure.NewQuery("Voice").Filter("LessonID = ", lessonID).Filter("IsSynthesis =", false).Order("ElapsedTime")
 
-	keys, err := client.GetAll(ctx, query, voices)
+	keys, err := r.store.GetAll(ctx, query, voices)
 	if err != nil {
 		return err
 	}
@@ -65,13 +75,8 @@ func GetVoices(ctx context.Context, lessonID int64, voices *[]Voice) error {
 	return nil
 }
 
-// CreateVoice is creates new voice.
-func CreateVoice(ctx context.Context, voice *Voice) error {
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
+// Create is creates new voice.
+func (r *voiceRepository) Create(ctx context.Context, voice *Voice) error {
 	uuid, err := UUIDWithoutHypen()
 	if err != nil {
 		return err
@@ -81,7 +86,7 @@ func CreateVoice(ctx context.Context, voice *Voice) error {
 	voice.Created = time.Now()
 
 	key := datastore.IncompleteKey("Voice", nil)
-	putKey, err := client.Put(ctx, key, voice)
+	putKey, err := r.store.Put(ctx, key, voice)
 	if err != nil {
 		return err
 	}
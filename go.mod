@@ -14,6 +14,7 @@ require (
 	github.com/gorilla/csrf v1.7.1
 	github.com/jinzhu/copier v0.3.2
 	github.com/joho/godotenv v1.3.0
+	github.com/klauspost/compress v1.13.6
 	github.com/labstack/echo/v4 v4.5.0
 	github.com/mattn/go-isatty v0.0.13 // indirect
 	github.com/pkg/errors v0.9.1
@@ -25,6 +26,6 @@ require (
 	golang.org/x/time v0.0.0-20210723032227-1f47c861a9ac // indirect
 	google.golang.org/api v0.52.0
 	google.golang.org/genproto v0.0.0-20210805201207-89edb61ffb67
-	google.golang.org/grpc v1.39.1 // indirect
+	google.golang.org/grpc v1.39.1
 	google.golang.org/protobuf v1.27.1
 )
@@ -0,0 +1,48 @@
+package infrastructure
+
+import (
+	"context"
+
+	"github.com/algolia/algoliasearch-client-go/v3/algolia/search"
+)
+
+type algoliaSearchIndexer struct {
+	index *search.Index
+}
+
+// NewAlgoliaSearchIndexerは、Algoliaのインデックスを使用するSearchIndexerを返します。
+func NewAlgoliaSearchIndexer(applicationID, apiKey, indexName string) SearchIndexer {
+	client := search.NewClient(applicationID, apiKey)
+	return &algoliaSearchIndexer{index: client.InitIndex(indexName)}
+}
+
+func (s *algoliaSearchIndexer) Upsert(ctx context.Context, record SearchRecord) error {
+	_, err := s.index.SaveObject(algoliaObject(record))
+	return err
+}
+
+func (s *algoliaSearchIndexer) Delete(ctx context.Context, objectID string) error {
+	_, err := s.index.DeleteObject(objectID)
+	return err
+}
+
+// ReplaceAllは、一時インデックスに全てのレコードを登録してから置き換えるので、処理中も検索が途切れません。
+func (s *algoliaSearchIndexer) ReplaceAll(ctx context.Context, records []SearchRecord) error {
+	objects := make([]map[string]interface{}, len(records))
+	for i, record := range records {
+		objects[i] = algoliaObject(record)
+	}
+
+	_, err := s.index.ReplaceAllObjects(objects)
+	return err
+}
+
+func algoliaObject(record SearchRecord) map[string]interface{} {
+	object := make(map[string]interface{}, len(record.Fields)+1)
+	for name, value := range record.Fields {
+		object[name] = value
+	}
+	object["objectID"] = record.ObjectID
+
+	return object
+}
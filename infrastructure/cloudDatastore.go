@@ -0,0 +1,118 @@
+package infrastructure
+
+import (
+	"context"
+
+	"cloud.google.com/go/datastore"
+)
+
+type cloudDatastore struct {
+	client *datastore.Client
+}
+
+type cloudTransaction struct {
+	tx *datastore.Transaction
+}
+
+type cloudIterator struct {
+	it  *datastore.Iterator
+	err error
+}
+
+// NewCloudDatastoreは、Cloud Datastoreを使用するDatastoreを返します。
+func NewCloudDatastore(ctx context.Context) (Datastore, error) {
+	client, err := datastore.NewClient(ctx, ProjectID())
+	if err != nil {
+		return nil, err
+	}
+
+	return &cloudDatastore{client: client}, nil
+}
+
+func (s *cloudDatastore) Get(ctx context.Context, key *datastore.Key, dst interface{}) error {
+	return s.client.Get(ctx, key, dst)
+}
+
+func (s *cloudDatastore) GetMulti(ctx context.Context, keys []*datastore.Key, dst interface{}) error {
+	return s.client.GetMulti(ctx, keys, dst)
+}
+
+func (s *cloudDatastore) GetAll(ctx context.Context, query *Query, dst interface{}) ([]*datastore.Key, error) {
+	q, err := query.toDatastoreQuery()
+	if err != nil {
+		return nil, err
+	}
+
+	return s.client.GetAll(ctx, q, dst)
+}
+
+func (s *cloudDatastore) Run(ctx context.Context, query *Query) Iterator {
+	q, err := query.toDatastoreQuery()
+	if err != nil {
+		return &cloudIterator{err: err}
+	}
+
+	return &cloudIterator{it: s.client.Run(ctx, q)}
+}
+
+func (s *cloudDatastore) Put(ctx context.Context, key *datastore.Key, src interface{}) (*datastore.Key, error) {
+	return s.client.Put(ctx, key, src)
+}
+
+func (s *cloudDatastore) PutMulti(ctx context.Context, keys []*datastore.Key, src interface{}) ([]*datastore.Key, error) {
+	return s.client.PutMulti(ctx, keys, src)
+}
+
+func (s *cloudDatastore) Delete(ctx context.Context, key *datastore.Key) error {
+	return s.client.Delete(ctx, key)
+}
+
+func (s *cloudDatastore) DeleteMulti(ctx context.Context, keys []*datastore.Key) error {
+	return s.client.DeleteMulti(ctx, keys)
+}
+
+func (s *cloudDatastore) RunInTransaction(ctx context.Context, f func(tx Transaction) error) error {
+	_, err := s.client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
+		return f(&cloudTransaction{tx: tx})
+	})
+
+	return err
+}
+
+func (s *cloudDatastore) Close() error {
+	return s.client.Close()
+}
+
+func (t *cloudTransaction) Get(key *datastore.Key, dst interface{}) error {
+	return t.tx.Get(key, dst)
+}
+
+func (t *cloudTransaction) Put(key *datastore.Key, src interface{}) error {
+	_, err := t.tx.Put(key, src)
+	return err
+}
+
+func (t *cloudTransaction) Delete(key *datastore.Key) error {
+	return t.tx.Delete(key)
+}
+
+func (i *cloudIterator) Next(dst interface{}) (*datastore.Key, error) {
+	if i.err != nil {
+		return nil, i.err
+	}
+
+	return i.it.Next(dst)
+}
+
+func (i *cloudIterator) Cursor() (string, error) {
+	if i.err != nil {
+		return "", i.err
+	}
+
+	cursor, err := i.it.Cursor()
+	if err != nil {
+		return "", err
+	}
+
+	return cursor.String(), nil
+}
@@ -4,71 +4,43 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
-	"fmt"
-	"log"
-	"strings"
 	"time"
 
 	"cloud.google.com/go/storage"
-	"github.com/pkg/errors"
 	"golang.org/x/oauth2/google"
 	iam "google.golang.org/api/iam/v1"
+	"google.golang.org/api/iterator"
 )
 
-type SignedURL struct {
-	FileID    string `json:"fileID"`
-	SignedURL string `json:"signedURL"`
-}
-
-type SignedURLs struct {
-	SignedURLs []SignedURL `json:"signedURLs"`
-}
-
-type StorageObjectRequest struct {
-	LessonID     int64         `json:"lessonID"`
-	FileRequests []FileRequest `json:"fileRequests"`
-}
-
-type FileRequest struct {
-	ID          string `json:"id"`
-	Entity      string `json:"entity"`
-	Extension   string `json:"extension"`
-	ContentType string `json:"contentType"`
-}
+const CloudStorageURL string = "https://storage.googleapis.com/"
 
-type EntityBelongToFile struct {
-	UserID int64
+type cloudStorageObjectStore struct {
+	client     *storage.Client
+	iamService *iam.Service
 }
 
-var iamService *iam.Service
-
-const CloudStorageURL string = "https://storage.googleapis.com/"
-
-func init() {
-	cred, err := google.DefaultClient(context.Background(), iam.CloudPlatformScope)
+// NewCloudStorageObjectStoreは、GCSを使用するObjectStoreを返します。署名付きURLの発行にはIAMのSignBlobを使用します。
+func NewCloudStorageObjectStore(ctx context.Context) (ObjectStore, error) {
+	cred, err := google.DefaultClient(ctx, iam.CloudPlatformScope)
 	if err != nil {
-		log.Printf("failed to initialize the Google client.\n")
-		log.Printf("%v\n", errors.WithStack(err.(error)).Error())
-		return
+		return nil, err
 	}
 
-	iamService, err = iam.New(cred)
+	iamService, err := iam.New(cred)
 	if err != nil {
-		log.Printf("failed to initialize the IAM.\n")
-		log.Printf("%v\n", errors.WithStack(err.(error)).Error())
-		return
+		return nil, err
 	}
-}
 
-// CreateFileToGCS creates object to GCS.
-func CreateFileToGCS(ctx context.Context, bucketName, filePath, contentType string, contents []byte) error {
 	client, err := storage.NewClient(ctx)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer client.Close()
 
-	w := client.Bucket(bucketName).Object(filePath).NewWriter(ctx)
+	return &cloudStorageObjectStore{client: client, iamService: iamService}, nil
+}
+
+func (s *cloudStorageObjectStore) Put(ctx context.Context, bucket, path, contentType string, contents []byte) error {
+	w := s.client.Bucket(bucket).Object(path).NewWriter(ctx)
 	w.ContentType = contentType
 	defer w.Close()
 
@@ -85,67 +57,69 @@ func CreateFileToGCS(ctx context.Context, bucketName, filePath, contentType stri
 	return nil
 }
 
-func CreateBlankFileToGCS(ctx context.Context, fileID string, fileEntity string, fileRequest FileRequest) (string, error) {
-	filePath := StorageObjectFilePath(fileEntity, fileID, fileRequest.Extension)
-	bucketName := MaterialBucketName()
-
-	if err := CreateFileToGCS(ctx, bucketName, filePath, fileRequest.ContentType, nil); err != nil {
-		return "", err
+func (s *cloudStorageObjectStore) Get(ctx context.Context, bucket, path string) ([]byte, error) {
+	r, err := s.client.Bucket(bucket).Object(path).NewReader(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil, ErrObjectNotFound
+	} else if err != nil {
+		return nil, err
 	}
+	defer r.Close()
 
-	url, err := GetGCSSignedURL(ctx, bucketName, filePath, "PUT", fileRequest.ContentType)
-	if err != nil {
-		return "", err
+	var buffer bytes.Buffer
+	if _, err := buffer.ReadFrom(r); err != nil {
+		return nil, err
 	}
 
-	return url, err
+	return buffer.Bytes(), nil
 }
 
-func CreateBlankFileToPublicGCS(ctx context.Context, fileID string, fileEntity string, fileRequest FileRequest) (string, error) {
-	filePath := StorageObjectFilePath(fileEntity, fileID, fileRequest.Extension)
-	bucketName := PublicBucketName()
+func (s *cloudStorageObjectStore) Copy(ctx context.Context, srcBucket, srcPath, dstBucket, dstPath string) error {
+	src := s.client.Bucket(srcBucket).Object(srcPath)
+	dst := s.client.Bucket(dstBucket).Object(dstPath)
 
-	if err := CreateFileToGCS(ctx, bucketName, filePath, fileRequest.ContentType, nil); err != nil {
-		return "", err
-	}
-
-	url, err := GetGCSSignedURL(ctx, bucketName, filePath, "PUT", fileRequest.ContentType)
-	if err != nil {
-		return "", err
+	if _, err := dst.CopierFrom(src).Run(ctx); err != nil {
+		if err == storage.ErrObjectNotExist {
+			return ErrObjectNotFound
+		}
+		return err
 	}
 
-	return url, err
+	return nil
 }
 
-// GetFileFromGCS gets object from GCS.
-func GetFileFromGCS(ctx context.Context, bucketName, filePath string) ([]byte, error) {
-	client, err := storage.NewClient(ctx)
-	if err != nil {
-		return nil, err
+func (s *cloudStorageObjectStore) Delete(ctx context.Context, bucket, path string) error {
+	err := s.client.Bucket(bucket).Object(path).Delete(ctx)
+	if err != nil && err != storage.ErrObjectNotExist {
+		return err
 	}
-	defer client.Close()
 
-	r, err := client.Bucket(bucketName).Object(filePath).NewReader(ctx)
-	if err != nil {
-		return nil, err
-	}
-	defer r.Close()
+	return nil
+}
 
-	var buffer bytes.Buffer
-	if _, err := buffer.ReadFrom(r); err != nil {
-		return nil, err
+func (s *cloudStorageObjectStore) List(ctx context.Context, bucket, prefix string) ([]string, error) {
+	var paths []string
+
+	it := s.client.Bucket(bucket).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		paths = append(paths, attrs.Name)
 	}
 
-	return buffer.Bytes(), nil
+	return paths, nil
 }
 
-// GetGCSSignedURL generates signed-URL for GCS object.
-func GetGCSSignedURL(ctx context.Context, bucket string, key string, method string, contentType string) (string, error) {
+func (s *cloudStorageObjectStore) SignedURL(ctx context.Context, bucket, path, method, contentType string) (string, error) {
 	expire := time.Now().AddDate(0, 0, 3) // expire after 3 days.
-	url, err := storage.SignedURL(bucket, key, &storage.SignedURLOptions{
+	url, err := storage.SignedURL(bucket, path, &storage.SignedURLOptions{
 		GoogleAccessID: ServiceAccountName(),
 		SignBytes: func(b []byte) ([]byte, error) {
-			resp, err := iamService.Projects.ServiceAccounts.SignBlob(
+			resp, err := s.iamService.Projects.ServiceAccounts.SignBlob(
 				ServiceAccountID(),
 				&iam.SignBlobRequest{BytesToSign: base64.StdEncoding.EncodeToString(b)},
 			).Context(ctx).Do()
@@ -166,16 +140,6 @@ func GetGCSSignedURL(ctx context.Context, bucket string, key string, method stri
 	return url, nil
 }
 
-// GetPublicBackgroundImageURL returns public image file URL in GCS.
-func GetPublicBackgroundImageURL(id string) string {
-	return CloudStorageURL + PublicBucketName() + "/background/" + id + ".webp"
-}
-
-// GetPublicBackgroundMusicURL returns public audio file URL in GCS.
-func GetPublicBackgroundMusicURL(id string) string {
-	return CloudStorageURL + PublicBucketName() + "/bgm/" + id + ".mp3"
-}
-
-func StorageObjectFilePath(entity string, id string, extension string) string {
-	return fmt.Sprintf("%s/%s.%s", strings.ToLower(entity), id, extension)
+func (s *cloudStorageObjectStore) PublicURL(bucket, path string) string {
+	return CloudStorageURL + bucket + "/" + path
 }
@@ -8,50 +8,69 @@ import (
 
 	cloudtasks "cloud.google.com/go/cloudtasks/apiv2"
 	taskspb "google.golang.org/genproto/googleapis/cloud/tasks/v2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-// 現状ではLessonMaterialの圧縮にしか使用していないので固定値
-const (
-	queueID     string = "compressLesson"
-	relativeUri string = "/lesson_compressing"
-)
+type cloudTaskQueue struct {
+	client *cloudtasks.Client
+}
 
 func LessonCompressingTaskName(lessonID int64, currentTime time.Time, requestID string) string {
 	// シーケンシャルな値を避けるため、ランダム文字列であるリクエストIDを先頭に付与する
 	return requestID + "-" + strconv.FormatInt(lessonID, 10) + "-" + strconv.FormatInt(currentTime.UnixNano(), 10)
 }
 
-func CreateTask(ctx context.Context, name string, eta time.Time, message string) (*taskspb.Task, error) {
+// NewCloudTaskQueueは、Cloud Tasksを使用するTaskQueueを返します。
+// タスクはApp EngineのQueueConfig.RelativeURIへPOSTで配送されます。
+func NewCloudTaskQueue(ctx context.Context) (TaskQueue, error) {
 	client, err := cloudtasks.NewClient(ctx)
 	if err != nil {
 		return nil, err
 	}
-	defer client.Close()
 
-	scheduleTime := timestamppb.New(eta)
-	queuePath := fmt.Sprintf("projects/%s/locations/%s/queues/%s", ProjectID(), LocationID(), queueID)
-	taskName := fmt.Sprintf("%s/tasks/%s", queuePath, name)
+	return &cloudTaskQueue{client: client}, nil
+}
+
+func (q *cloudTaskQueue) Enqueue(ctx context.Context, task Task) error {
+	queuePath := fmt.Sprintf("projects/%s/locations/%s/queues/%s", ProjectID(), LocationID(), task.Queue.Name)
 	req := &taskspb.CreateTaskRequest{
 		Parent: queuePath,
 		Task: &taskspb.Task{
 			MessageType: &taskspb.Task_AppEngineHttpRequest{
 				AppEngineHttpRequest: &taskspb.AppEngineHttpRequest{
 					HttpMethod:  taskspb.HttpMethod_POST,
-					RelativeUri: relativeUri,
+					RelativeUri: task.Queue.RelativeURI,
+					Body:        task.Payload,
 				},
 			},
-			Name:         taskName,
-			ScheduleTime: scheduleTime,
 		},
 	}
 
-	req.Task.GetAppEngineHttpRequest().Body = []byte(message)
+	if task.Name != "" {
+		req.Task.Name = fmt.Sprintf("%s/tasks/%s", queuePath, task.Name)
+	}
 
-	createdTask, err := client.CreateTask(ctx, req)
-	if err != nil {
-		return nil, err
+	if !task.ETA.IsZero() {
+		req.Task.ScheduleTime = timestamppb.New(task.ETA)
 	}
 
-	return createdTask, nil
+	if _, err := q.client.CreateTask(ctx, req); err != nil {
+		if status.Code(err) == codes.AlreadyExists {
+			return ErrTaskAlreadyExists
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Startは何もしません。タスクの実行はCloud TasksからのHTTPリクエストで行われます。
+func (q *cloudTaskQueue) Start() error {
+	return nil
+}
+
+func (q *cloudTaskQueue) Close() error {
+	return q.client.Close()
 }
@@ -0,0 +1,275 @@
+package infrastructure
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Configは、アプリケーションの設定です。起動時にLoadConfigで読み込み、SetConfigで設定します。
+// 各フィールドの値は、環境変数、.env.{環境名}のファイル、環境毎の既定値、全環境共通の既定値の順に優先されます。
+type Config struct {
+	AppEnv string `env:"-"`
+
+	ProjectID          string `env:"PROJECT_ID"` // Google Cloudのサービスを使用する場合は必須
+	LocationID         string `env:"LOCATION_ID"`
+	MaterialBucketName string `env:"MATERIAL_BUCKET_NAME" required:"true"`
+	PublicBucketName   string `env:"PUBLIC_BUCKET_NAME" required:"true"`
+	OriginURL          string `env:"ORIGIN_URL" required:"true"`
+	CookieSecret       string `env:"COOKIE_SECRET" required:"true"`
+
+	Port        string `env:"PORT" required:"true"`
+	TLSCertFile string `env:"TLS_CERT_FILE"` // 指定した場合はHTTPSで待ち受ける
+	TLSKeyFile  string `env:"TLS_KEY_FILE"`
+
+	AllowsUnverifiedInternalRequests bool          `env:"ALLOW_UNVERIFIED_INTERNAL_REQUESTS"` // cronやタスク以外からの内部APIの呼び出しを許可する
+	CountsLessonViews                bool          `env:"COUNT_LESSON_VIEWS"`
+	CountsLessonReactions            bool          `env:"COUNT_LESSON_REACTIONS"`
+	LessonCompressingDelay           time.Duration `env:"LESSON_COMPRESSING_DELAY"`
+	LessonTrashRetention             time.Duration `env:"LESSON_TRASH_RETENTION"` // 削除したLessonを復元できる期間。過ぎると完全に削除される
+
+	RedisEndpoint string `env:"REDIS_ENDPOINT"`
+	RedisPassword string `env:"REDIS_PASSWORD"`
+
+	AlgoliaApplicationID string `env:"ALGOLIA_APPLICATION_ID"`
+	AlgoliaAdminAPIKey   string `env:"ALGOLIA_ADMIN_API_KEY"`
+	AlgoliaIndexName     string `env:"ALGOLIA_INDEX_NAME"`
+
+	DatastoreBackend       string `env:"DATASTORE_BACKEND" required:"true"`        // cloud/memory
+	ObjectStoreBackend     string `env:"OBJECT_STORE_BACKEND" required:"true"`     // gcs/local
+	TaskQueueBackend       string `env:"TASK_QUEUE_BACKEND" required:"true"`       // cloud/local
+	SearchBackend          string `env:"SEARCH_BACKEND" required:"true"`           // algolia/memory
+	SpeechSynthesisBackend string `env:"SPEECH_SYNTHESIS_BACKEND" required:"true"` // google/offline
+
+	LocalStorageDir    string `env:"LOCAL_STORAGE_DIR"`
+	LocalStorageURL    string `env:"LOCAL_STORAGE_URL"`
+	LocalStorageSecret string `env:"LOCAL_STORAGE_SECRET"`
+	LocalTaskQueueFile string `env:"LOCAL_TASK_QUEUE_FILE"`
+}
+
+// 全環境共通の既定値
+var defaultConfigValues = map[string]string{
+	"LOCATION_ID":              "asia-northeast1", // Tokyo
+	"PORT":                     "8080",
+	"LESSON_COMPRESSING_DELAY": "1m",
+	"LESSON_TRASH_RETENTION":   "720h", // 30日
+	"DATASTORE_BACKEND":        "cloud",
+	"OBJECT_STORE_BACKEND":     "gcs",
+	"TASK_QUEUE_BACKEND":       "cloud",
+	"SEARCH_BACKEND":           "algolia",
+	"SPEECH_SYNTHESIS_BACKEND": "google",
+}
+
+// 環境毎の既定値。ここにない環境は、.env.{環境名}のファイルか環境変数で必須の値を全て指定する必要がある
+var environmentConfigValues = map[string]map[string]string{
+	"production": {
+		"PROJECT_ID":               "teraconnect-209509",
+		"MATERIAL_BUCKET_NAME":     "teraconn_material",
+		"PUBLIC_BUCKET_NAME":       "teraconn_public",
+		"ORIGIN_URL":               "https://teraconnect.org",
+		"COUNT_LESSON_VIEWS":       "true",
+		"COUNT_LESSON_REACTIONS":   "true",
+		"LESSON_COMPRESSING_DELAY": "5m",
+	},
+	"staging": {
+		"PROJECT_ID":           "teraconnect-staging",
+		"MATERIAL_BUCKET_NAME": "teraconn_material_staging",
+		"PUBLIC_BUCKET_NAME":   "teraconn_public_staging_2",
+		"ORIGIN_URL":           "https://staging.teraconnect.org",
+	},
+	"development": {
+		"PROJECT_ID":                         "teraconnect-development",
+		"MATERIAL_BUCKET_NAME":               "teraconn_material_development",
+		"PUBLIC_BUCKET_NAME":                 "teraconn_public_development",
+		"ORIGIN_URL":                         "https://dev.teraconnect.org:3000",
+		"COOKIE_SECRET":                      "32-byte-long-auth-key",
+		"PORT":                               "443",
+		"TLS_CERT_FILE":                      "localhost.crt",
+		"TLS_KEY_FILE":                       "localhost.key",
+		"ALLOW_UNVERIFIED_INTERNAL_REQUESTS": "true",
+	},
+}
+
+var currentConfig Config
+
+// LoadConfigは、appEnvの設定を読み込んで検証します。必須の値がない場合や、値が不正な場合はエラーを返します。
+func LoadConfig(appEnv string) (Config, error) {
+	config := Config{AppEnv: appEnv}
+	if appEnv == "" {
+		return config, fmt.Errorf("config: environment name is empty")
+	}
+
+	// godotenvは既に設定されている環境変数を上書きしないので、環境変数の値が優先される
+	if err := godotenv.Load(".env." + appEnv); err != nil && !os.IsNotExist(err) {
+		return config, err
+	}
+
+	var invalid []string
+	value := reflect.ValueOf(&config).Elem()
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		key := field.Tag.Get("env")
+		if key == "-" {
+			continue
+		}
+
+		raw := configValue(appEnv, key)
+		if raw == "" {
+			continue
+		}
+
+		if err := setConfigField(value.Field(i), raw); err != nil {
+			invalid = append(invalid, fmt.Sprintf("%s(%v)", key, err))
+		}
+	}
+
+	if len(invalid) > 0 {
+		return config, fmt.Errorf("config: invalid values for %s: %s", appEnv, strings.Join(invalid, ", "))
+	}
+
+	if err := config.validate(); err != nil {
+		return config, err
+	}
+
+	return config, nil
+}
+
+// SetConfigは、使用する設定を設定します。起動時に一度だけ呼び出されることを想定しています。
+func SetConfig(config Config) {
+	currentConfig = config
+}
+
+// CurrentConfigは、設定済みの設定を返します。
+func CurrentConfig() Config {
+	return currentConfig
+}
+
+// AppEnv returns application envirionment of 'staging', 'production'.
+func AppEnv() string {
+	return currentConfig.AppEnv
+}
+
+// validateは、必須の値と、使用するバックエンドに応じて必要になる値が設定されているかを検証します。
+func (c Config) validate() error {
+	missing := make(map[string]bool)
+
+	value := reflect.ValueOf(c)
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if field.Tag.Get("required") == "true" && value.Field(i).IsZero() {
+			missing[field.Tag.Get("env")] = true
+		}
+	}
+
+	if c.usesGoogleCloud() && c.ProjectID == "" {
+		missing["PROJECT_ID"] = true
+	}
+	if c.usesGoogleCloud() && c.LocationID == "" {
+		missing["LOCATION_ID"] = true
+	}
+	if c.SearchBackend == "algolia" {
+		for key, v := range map[string]string{"ALGOLIA_APPLICATION_ID": c.AlgoliaApplicationID, "ALGOLIA_ADMIN_API_KEY": c.AlgoliaAdminAPIKey, "ALGOLIA_INDEX_NAME": c.AlgoliaIndexName} {
+			if v == "" {
+				missing[key] = true
+			}
+		}
+	}
+	if (c.CountsLessonViews || c.CountsLessonReactions) && c.RedisEndpoint == "" {
+		missing["REDIS_ENDPOINT"] = true
+	}
+	if (c.TLSCertFile == "") != (c.TLSKeyFile == "") {
+		missing["TLS_CERT_FILE"] = c.TLSCertFile == ""
+		missing["TLS_KEY_FILE"] = c.TLSKeyFile == ""
+	}
+
+	var errs []string
+	if keys := trueKeys(missing); len(keys) > 0 {
+		errs = append(errs, "missing "+strings.Join(keys, ", "))
+	}
+
+	for key, choice := range map[string]struct {
+		value   string
+		options []string
+	}{
+		"DATASTORE_BACKEND":        {c.DatastoreBackend, []string{"cloud", "memory"}},
+		"OBJECT_STORE_BACKEND":     {c.ObjectStoreBackend, []string{"gcs", "local"}},
+		"TASK_QUEUE_BACKEND":       {c.TaskQueueBackend, []string{"cloud", "local"}},
+		"SEARCH_BACKEND":           {c.SearchBackend, []string{"algolia", "memory"}},
+		"SPEECH_SYNTHESIS_BACKEND": {c.SpeechSynthesisBackend, []string{"google", "offline"}},
+	} {
+		if choice.value != "" && !containsConfigOption(choice.options, choice.value) {
+			errs = append(errs, fmt.Sprintf("%s must be one of %s", key, strings.Join(choice.options, "/")))
+		}
+	}
+
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return fmt.Errorf("config: invalid config for %s: %s", c.AppEnv, strings.Join(errs, "; "))
+	}
+
+	return nil
+}
+
+func (c Config) usesGoogleCloud() bool {
+	return c.DatastoreBackend == "cloud" || c.ObjectStoreBackend == "gcs" || c.TaskQueueBackend == "cloud" || c.SpeechSynthesisBackend == "google"
+}
+
+func configValue(appEnv, key string) string {
+	if v, ok := os.LookupEnv(key); ok {
+		return v
+	}
+	if v, ok := environmentConfigValues[appEnv][key]; ok {
+		return v
+	}
+	return defaultConfigValues[key]
+}
+
+func setConfigField(field reflect.Value, raw string) error {
+	switch field.Interface().(type) {
+	case string:
+		field.SetString(raw)
+	case bool:
+		v, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		field.SetBool(v)
+	case time.Duration:
+		v, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		field.SetInt(int64(v))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+
+	return nil
+}
+
+func trueKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for key, ok := range m {
+		if ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+func containsConfigOption(options []string, value string) bool {
+	for _, option := range options {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
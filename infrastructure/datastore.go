@@ -0,0 +1,179 @@
+package infrastructure
+
+import (
+	"context"
+	"strings"
+
+	"cloud.google.com/go/datastore"
+)
+
+// Datastoreは、リポジトリが使用するデータストア操作の抽象です。Cloud Datastoreとインメモリの実装があります。
+type Datastore interface {
+	Get(ctx context.Context, key *datastore.Key, dst interface{}) error
+	GetMulti(ctx context.Context, keys []*datastore.Key, dst interface{}) error
+	GetAll(ctx context.Context, query *Query, dst interface{}) ([]*datastore.Key, error)
+	Run(ctx context.Context, query *Query) Iterator
+	Put(ctx context.Context, key *datastore.Key, src interface{}) (*datastore.Key, error)
+	PutMulti(ctx context.Context, keys []*datastore.Key, src interface{}) ([]*datastore.Key, error)
+	Delete(ctx context.Context, key *datastore.Key) error
+	DeleteMulti(ctx context.Context, keys []*datastore.Key) error
+	RunInTransaction(ctx context.Context, f func(tx Transaction) error) error
+	Close() error
+}
+
+// Transactionは、RunInTransaction中で使用するトランザクションです。
+// 不完全キーでPutした場合の採番結果は、コミット前には取得できません。
+type Transaction interface {
+	Get(key *datastore.Key, dst interface{}) error
+	Put(key *datastore.Key, src interface{}) error
+	Delete(key *datastore.Key) error
+}
+
+// Iteratorは、Runで得られるクエリ結果を一件ずつ返します。終端ではiterator.Doneを返します。
+type Iterator interface {
+	Next(dst interface{}) (*datastore.Key, error)
+	Cursor() (string, error)
+}
+
+// NewDatastoreは、設定のDatastoreBackendに応じたDatastoreを返します。"memory"の場合はインメモリ、それ以外はCloud Datastoreを使用します。
+func NewDatastore(ctx context.Context) (Datastore, error) {
+	if currentConfig.DatastoreBackend == "memory" {
+		return NewMemoryDatastore(), nil
+	}
+
+	return NewCloudDatastore(ctx)
+}
+
+// Queryは、どちらのDatastore実装でも解釈できるクエリです。datastore.Queryと同じくメソッドはコピーを返します。
+type Query struct {
+	kind       string
+	ancestor   *datastore.Key
+	filters    []queryFilter
+	orders     []queryOrder
+	projection []string
+	keysOnly   bool
+	limit      int
+	cursor     string
+}
+
+type queryFilter struct {
+	field    string
+	operator string
+	value    interface{}
+}
+
+type queryOrder struct {
+	field      string
+	descending bool
+}
+
+// NewQueryは、kindを対象とする新しいQueryを返します。
+func NewQuery(kind string) *Query {
+	return &Query{kind: kind}
+}
+
+func (q *Query) clone() *Query {
+	c := *q
+	c.filters = append([]queryFilter(nil), q.filters...)
+	c.orders = append([]queryOrder(nil), q.orders...)
+	c.projection = append([]string(nil), q.projection...)
+	return &c
+}
+
+// Ancestorは、ancestorの子孫のみを対象にします。
+func (q *Query) Ancestor(ancestor *datastore.Key) *Query {
+	q = q.clone()
+	q.ancestor = ancestor
+	return q
+}
+
+// Filterは、"UserID ="のようなフィールド名と演算子の組み合わせで絞り込みます。
+func (q *Query) Filter(filterStr string, value interface{}) *Query {
+	q = q.clone()
+	filterStr = strings.TrimSpace(filterStr)
+	operator := "="
+	for _, op := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasSuffix(filterStr, op) {
+			operator = op
+			filterStr = strings.TrimSuffix(filterStr, op)
+			break
+		}
+	}
+	q.filters = append(q.filters, queryFilter{field: strings.TrimSpace(filterStr), operator: operator, value: value})
+	return q
+}
+
+// Orderは、フィールド名で並び替えます。先頭に"-"があれば降順です。
+func (q *Query) Order(fieldName string) *Query {
+	q = q.clone()
+	order := queryOrder{field: strings.TrimSpace(fieldName)}
+	if strings.HasPrefix(order.field, "-") {
+		order.field = strings.TrimSpace(order.field[1:])
+		order.descending = true
+	}
+	q.orders = append(q.orders, order)
+	return q
+}
+
+// Projectは、取得するフィールドを限定します。
+func (q *Query) Project(fieldNames ...string) *Query {
+	q = q.clone()
+	q.projection = append([]string(nil), fieldNames...)
+	return q
+}
+
+// KeysOnlyは、キーのみを取得します。
+func (q *Query) KeysOnly() *Query {
+	q = q.clone()
+	q.keysOnly = true
+	return q
+}
+
+// Limitは、取得件数の上限を設定します。
+func (q *Query) Limit(limit int) *Query {
+	q = q.clone()
+	q.limit = limit
+	return q
+}
+
+// Startは、Iterator.Cursorで得られた文字列の位置から取得を開始します。
+func (q *Query) Start(cursor string) *Query {
+	q = q.clone()
+	q.cursor = cursor
+	return q
+}
+
+func (q *Query) toDatastoreQuery() (*datastore.Query, error) {
+	query := datastore.NewQuery(q.kind)
+	if q.ancestor != nil {
+		query = query.Ancestor(q.ancestor)
+	}
+	for _, filter := range q.filters {
+		query = query.Filter(filter.field+" "+filter.operator, filter.value)
+	}
+	for _, order := range q.orders {
+		if order.descending {
+			query = query.Order("-" + order.field)
+		} else {
+			query = query.Order(order.field)
+		}
+	}
+	if len(q.projection) > 0 {
+		query = query.Project(q.projection...)
+	}
+	if q.keysOnly {
+		query = query.KeysOnly()
+	}
+	if q.limit > 0 {
+		query = query.Limit(q.limit)
+	}
+	if q.cursor != "" {
+		cursor, err := datastore.DecodeCursor(q.cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Start(cursor)
+	}
+
+	return query, nil
+}
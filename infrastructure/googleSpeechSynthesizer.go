@@ -0,0 +1,51 @@
+package infrastructure
+
+import (
+	"context"
+
+	texttospeech "cloud.google.com/go/texttospeech/apiv1"
+	texttospeechpb "google.golang.org/genproto/googleapis/cloud/texttospeech/v1"
+)
+
+type googleSpeechSynthesizer struct {
+	client *texttospeech.Client
+}
+
+// NewGoogleSpeechSynthesizerは、Google Text-to-Speechを使用するSpeechSynthesizerを返します。
+func NewGoogleSpeechSynthesizer(ctx context.Context) (SpeechSynthesizer, error) {
+	client, err := texttospeech.NewClient(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &googleSpeechSynthesizer{client: client}, nil
+}
+
+func (s *googleSpeechSynthesizer) Synthesize(ctx context.Context, req SpeechSynthesisRequest) ([]byte, error) {
+	synthesizeReq := texttospeechpb.SynthesizeSpeechRequest{
+		Input: &texttospeechpb.SynthesisInput{
+			InputSource: &texttospeechpb.SynthesisInput_Text{Text: req.Text},
+		},
+		Voice: &texttospeechpb.VoiceSelectionParams{
+			LanguageCode: req.LanguageCode,
+			Name:         req.VoiceName,
+		},
+		AudioConfig: &texttospeechpb.AudioConfig{
+			AudioEncoding: texttospeechpb.AudioEncoding_MP3,
+			SpeakingRate:  req.SpeakingRate,
+			Pitch:         req.Pitch,
+			VolumeGainDb:  req.VolumeGainDb,
+		},
+	}
+
+	resp, err := s.client.SynthesizeSpeech(ctx, &synthesizeReq)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.AudioContent, nil
+}
+
+func (s *googleSpeechSynthesizer) Close() error {
+	return s.client.Close()
+}
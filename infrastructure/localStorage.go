@@ -0,0 +1,211 @@
+package infrastructure
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LocalStoragePathは、LocalObjectStoreのオブジェクトを配信するルートのパスです。
+const LocalStoragePath string = "/local_storage"
+
+// LocalObjectStoreの署名付きURLの検証に失敗した場合のエラー。
+var (
+	ErrInvalidSignature = errors.New("storage: invalid signature")
+	ErrSignatureExpired = errors.New("storage: signature expired")
+	ErrInvalidPath      = errors.New("storage: invalid object path")
+)
+
+// LocalObjectStoreは、ローカルディスクにオブジェクトを保存するObjectStoreです。
+// 署名付きURLはHMAC-SHA256で署名され、LocalStoragePath以下のルートで配信されます。
+type LocalObjectStore struct {
+	rootDir string
+	baseURL string
+	secret  []byte
+}
+
+// NewLocalObjectStoreは、rootDir以下にオブジェクトを保存するLocalObjectStoreを返します。
+// 空文字列の引数には開発用の既定値を使用します。
+func NewLocalObjectStore(rootDir, baseURL, secret string) (*LocalObjectStore, error) {
+	if rootDir == "" {
+		rootDir = ".storage"
+	}
+	if baseURL == "" {
+		baseURL = "https://localhost"
+	}
+	if secret == "" {
+		secret = "local-storage-secret"
+	}
+
+	absDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return nil, err
+	}
+
+	return &LocalObjectStore{rootDir: absDir, baseURL: strings.TrimSuffix(baseURL, "/"), secret: []byte(secret)}, nil
+}
+
+func (s *LocalObjectStore) Put(ctx context.Context, bucket, path, contentType string, contents []byte) error {
+	filePath, err := s.filePath(bucket, path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filePath, contents, 0644)
+}
+
+func (s *LocalObjectStore) Get(ctx context.Context, bucket, path string) ([]byte, error) {
+	filePath, err := s.filePath(bucket, path)
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := ioutil.ReadFile(filePath)
+	if os.IsNotExist(err) {
+		return nil, ErrObjectNotFound
+	}
+
+	return contents, err
+}
+
+func (s *LocalObjectStore) Copy(ctx context.Context, srcBucket, srcPath, dstBucket, dstPath string) error {
+	contents, err := s.Get(ctx, srcBucket, srcPath)
+	if err != nil {
+		return err
+	}
+
+	return s.Put(ctx, dstBucket, dstPath, "", contents)
+}
+
+func (s *LocalObjectStore) Delete(ctx context.Context, bucket, path string) error {
+	filePath, err := s.filePath(bucket, path)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *LocalObjectStore) List(ctx context.Context, bucket, prefix string) ([]string, error) {
+	bucketDir, err := s.filePath(bucket, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	err = filepath.Walk(bucketDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(bucketDir, filePath)
+		if err != nil {
+			return err
+		}
+		objectPath := filepath.ToSlash(relPath)
+		if strings.HasPrefix(objectPath, prefix) {
+			paths = append(paths, objectPath)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return paths, nil
+}
+
+// SignedURLは、3日間有効なHMAC署名付きのURLを返します。PUTの場合はリクエストのContent-TypeもcontentTypeと一致する必要があります。
+func (s *LocalObjectStore) SignedURL(ctx context.Context, bucket, path, method, contentType string) (string, error) {
+	if _, err := s.filePath(bucket, path); err != nil {
+		return "", err
+	}
+
+	expires := strconv.FormatInt(time.Now().AddDate(0, 0, 3).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.signature(method, bucket, path, contentType, expires))
+
+	return s.PublicURL(bucket, path) + "?" + query.Encode(), nil
+}
+
+// PublicURLは、署名なしのURLを返します。公開用のバケット以外は、このURLでは取得できません。
+func (s *LocalObjectStore) PublicURL(bucket, path string) string {
+	return s.baseURL + LocalStoragePath + "/" + bucket + "/" + path
+}
+
+// VerifySignatureは、SignedURLで発行したURLのリクエストかを検証します。公開用のバケットへのGETは署名を必要としません。
+func (s *LocalObjectStore) VerifySignature(method, bucket, path, contentType, expires, signature string) error {
+	if method == "GET" && bucket == PublicBucketName() {
+		return nil
+	}
+
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	expected := s.signature(method, bucket, path, contentType, expires)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return ErrInvalidSignature
+	}
+
+	if time.Now().Unix() > expiresUnix {
+		return ErrSignatureExpired
+	}
+
+	return nil
+}
+
+func (s *LocalObjectStore) signature(method, bucket, path, contentType, expires string) string {
+	if method == "GET" {
+		contentType = "" // GETの署名にはContent-Typeを含めない
+	}
+
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(strings.Join([]string{method, bucket, path, contentType, expires}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// filePathは、バケットとパスからディスク上のファイルパスを返します。rootDirの外を指すパスはエラーにします。
+func (s *LocalObjectStore) filePath(bucket, path string) (string, error) {
+	if bucket == "" || strings.Contains(bucket, "/") || strings.Contains(bucket, "..") {
+		return "", ErrInvalidPath
+	}
+
+	bucketDir := filepath.Join(s.rootDir, bucket)
+	filePath := filepath.Join(bucketDir, filepath.FromSlash(path))
+	if filePath != bucketDir && !strings.HasPrefix(filePath, bucketDir+string(filepath.Separator)) {
+		return "", ErrInvalidPath
+	}
+
+	return filePath, nil
+}
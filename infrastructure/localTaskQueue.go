@@ -0,0 +1,433 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	localTaskQueueWorkers      = 4
+	localTaskQueuePollInterval = time.Second
+	localTaskTimeout           = 10 * time.Minute
+	localTaskRetention         = 24 * time.Hour // 完了したタスクの名前を重複防止のために保持する期間
+	localTaskLockInterval      = 10 * time.Millisecond
+	localTaskLockStaleAfter    = 10 * time.Second // 異常終了したプロセスのロックファイルを削除するまでの時間
+)
+
+const (
+	localTaskPending = "pending"
+	localTaskRunning = "running"
+	localTaskDone    = "done"
+	localTaskFailed  = "failed"
+)
+
+var errLocalTaskQueueClosed = errors.New("task queue: local task queue is closed")
+
+type localTaskRecord struct {
+	Task      Task      `json:"task"`
+	Status    string    `json:"status"`
+	Attempts  int       `json:"attempts"`
+	NextRunAt time.Time `json:"nextRunAt"`
+	LastError string    `json:"lastError,omitempty"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// LocalTaskQueueは、プロセス内のgoroutineでタスクを実行するTaskQueueです。
+// タスクの状態はファイルに保存され、再起動後も未完了のタスクから再開します。
+// コマンドとサーバーのように複数のプロセスが同じファイルを使用する場合は、ロックファイルで排他してファイルの内容と統合してから保存し、
+// 他のプロセスが登録したタスクは、Startしたプロセスがファイルから読み込んで実行します。
+type LocalTaskQueue struct {
+	mu       sync.Mutex
+	filePath string
+	workers  int
+	records  map[string]*localTaskRecord
+	sequence int64
+	wake     chan struct{}
+	stop     chan struct{}
+	wg       sync.WaitGroup
+	started  bool
+	closed   bool
+}
+
+// NewLocalTaskQueueは、filePathに状態を保存するLocalTaskQueueを返します。filePathが空の場合は".tasks.json"を使用します。
+func NewLocalTaskQueue(filePath string, workers int) (*LocalTaskQueue, error) {
+	if filePath == "" {
+		filePath = ".tasks.json"
+	}
+	if workers <= 0 {
+		workers = 1
+	}
+
+	q := &LocalTaskQueue{
+		filePath: filePath,
+		workers:  workers,
+		records:  make(map[string]*localTaskRecord),
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+	}
+
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+
+	return q, nil
+}
+
+func (q *LocalTaskQueue) Enqueue(ctx context.Context, task Task) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return errLocalTaskQueueClosed
+	}
+
+	if task.Name == "" {
+		q.sequence++
+		task.Name = "local-" + strconv.FormatInt(time.Now().UnixNano(), 10) + "-" + strconv.FormatInt(q.sequence, 10)
+	}
+
+	unlock, err := q.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// 他のプロセスが登録したタスクとも重複しないようにする
+	if err := q.merge(); err != nil {
+		return err
+	}
+
+	key := localTaskKey(task.Queue.Name, task.Name)
+	if _, ok := q.records[key]; ok {
+		return ErrTaskAlreadyExists
+	}
+
+	now := time.Now()
+	nextRunAt := task.ETA
+	if nextRunAt.IsZero() {
+		nextRunAt = now
+	}
+
+	q.records[key] = &localTaskRecord{Task: task, Status: localTaskPending, NextRunAt: nextRunAt, UpdatedAt: now}
+	if err := q.write(); err != nil {
+		delete(q.records, key)
+		return err
+	}
+
+	q.notify()
+	return nil
+}
+
+// Startは、ワーカーのgoroutineを起動します。handlerを登録した後に呼び出してください。
+func (q *LocalTaskQueue) Start() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return errLocalTaskQueueClosed
+	}
+	if q.started {
+		return nil
+	}
+	q.started = true
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+
+	return nil
+}
+
+// Closeは、ワーカーを停止し、実行中のタスクの終了を待ちます。
+func (q *LocalTaskQueue) Close() error {
+	q.mu.Lock()
+	if q.closed {
+		q.mu.Unlock()
+		return nil
+	}
+	q.closed = true
+	close(q.stop)
+	q.mu.Unlock()
+
+	q.wg.Wait()
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.save()
+}
+
+func (q *LocalTaskQueue) work() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(localTaskQueuePollInterval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			record, ok := q.next()
+			if !ok {
+				break
+			}
+			q.run(record)
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+// nextは、実行時刻を過ぎた最も古い待機中のタスクを実行中にして返します。
+func (q *LocalTaskQueue) next() (localTaskRecord, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return localTaskRecord{}, false
+	}
+
+	// 他のプロセスが登録したタスクを読み込む
+	if err := q.merge(); err != nil {
+		log.Printf("task queue: failed to read state. %v\n", err)
+	}
+
+	now := time.Now()
+	var found *localTaskRecord
+	for _, record := range q.records {
+		if record.Status != localTaskPending || record.NextRunAt.After(now) {
+			continue
+		}
+		if found == nil || record.NextRunAt.Before(found.NextRunAt) {
+			found = record
+		}
+	}
+
+	if found == nil {
+		q.prune(now)
+		return localTaskRecord{}, false
+	}
+
+	found.Status = localTaskRunning
+	found.Attempts++
+	found.UpdatedAt = now
+	if err := q.save(); err != nil {
+		log.Printf("task queue: failed to save state. %v\n", err)
+	}
+
+	return *found, true
+}
+
+func (q *LocalTaskQueue) run(record localTaskRecord) {
+	task := record.Task
+	retry := task.Queue.Retry
+
+	var err error
+	if queue, handler, ok := TaskHandlerFor(task.Queue.Name); ok {
+		retry = queue.Retry
+		err = q.invoke(handler, task)
+	} else {
+		err = fmt.Errorf("task queue: no handler for queue %s", task.Queue.Name)
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	current, ok := q.records[localTaskKey(task.Queue.Name, task.Name)]
+	if !ok {
+		return
+	}
+
+	current.UpdatedAt = time.Now()
+	if err == nil {
+		current.Status = localTaskDone
+		current.LastError = ""
+	} else if retry.Exhausted(current.Attempts) {
+		current.Status = localTaskFailed
+		current.LastError = err.Error()
+		log.Printf("task queue: %s/%s failed after %d attempts. %v\n", task.Queue.Name, task.Name, current.Attempts, err)
+	} else {
+		current.Status = localTaskPending
+		current.LastError = err.Error()
+		current.NextRunAt = current.UpdatedAt.Add(retry.Backoff(current.Attempts))
+		log.Printf("task queue: %s/%s will be retried at %s. %v\n", task.Queue.Name, task.Name, current.NextRunAt.Format(time.RFC3339), err)
+	}
+
+	if err := q.save(); err != nil {
+		log.Printf("task queue: failed to save state. %v\n", err)
+	}
+}
+
+func (q *LocalTaskQueue) invoke(handler TaskHandler, task Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("task queue: handler panicked. %v", r)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), localTaskTimeout)
+	defer cancel()
+
+	return handler(ctx, task)
+}
+
+// pruneは、保持期間を過ぎた完了済みのタスクを削除します。q.muを取得した状態で呼び出してください。
+func (q *LocalTaskQueue) prune(now time.Time) {
+	pruned := false
+	for key, record := range q.records {
+		if (record.Status == localTaskDone || record.Status == localTaskFailed) && now.Sub(record.UpdatedAt) > localTaskRetention {
+			delete(q.records, key)
+			pruned = true
+		}
+	}
+
+	if pruned {
+		if err := q.save(); err != nil {
+			log.Printf("task queue: failed to save state. %v\n", err)
+		}
+	}
+}
+
+func (q *LocalTaskQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// loadは、保存された状態を読み込みます。前回の終了時に実行中だったタスクは待機中に戻します。
+func (q *LocalTaskQueue) load() error {
+	contents, err := ioutil.ReadFile(q.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var records []*localTaskRecord
+	if err := json.Unmarshal(contents, &records); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		if record.Status == localTaskRunning {
+			record.Status = localTaskPending
+		}
+		q.records[localTaskKey(record.Task.Queue.Name, record.Task.Name)] = record
+	}
+
+	return nil
+}
+
+// mergeは、ファイルに保存された状態を読み込み、他のプロセスが登録したタスクと、より新しく更新されたタスクを取り込みます。q.muを取得した状態で呼び出してください。
+func (q *LocalTaskQueue) merge() error {
+	contents, err := ioutil.ReadFile(q.filePath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	var records []*localTaskRecord
+	if err := json.Unmarshal(contents, &records); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, record := range records {
+		key := localTaskKey(record.Task.Queue.Name, record.Task.Name)
+		if current, ok := q.records[key]; ok {
+			if record.UpdatedAt.After(current.UpdatedAt) {
+				*current = *record
+			}
+			continue
+		}
+
+		// 保持期間を過ぎて削除済みのタスクは取り込まない
+		if (record.Status == localTaskDone || record.Status == localTaskFailed) && now.Sub(record.UpdatedAt) > localTaskRetention {
+			continue
+		}
+		q.records[key] = record
+	}
+
+	return nil
+}
+
+// saveは、ファイルをロックし、他のプロセスが保存した状態と統合してから書き込みます。q.muを取得した状態で呼び出してください。
+func (q *LocalTaskQueue) save() error {
+	unlock, err := q.lockFile()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := q.merge(); err != nil {
+		return err
+	}
+
+	return q.write()
+}
+
+// writeは、状態を一時ファイルに書き込んでから置き換えます。q.muとファイルのロックを取得した状態で呼び出してください。
+func (q *LocalTaskQueue) write() error {
+	records := make([]*localTaskRecord, 0, len(q.records))
+	for _, record := range q.records {
+		records = append(records, record)
+	}
+
+	contents, err := json.Marshal(records)
+	if err != nil {
+		return err
+	}
+
+	tmpPath := q.filePath + ".tmp"
+	if err := ioutil.WriteFile(tmpPath, contents, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, q.filePath)
+}
+
+// lockFileは、ロックファイルを作成して他のプロセスによる保存を排他し、ロックを解放する関数を返します。
+func (q *LocalTaskQueue) lockFile() (func(), error) {
+	if dir := filepath.Dir(q.filePath); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	lockPath := q.filePath + ".lock"
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		} else if !os.IsExist(err) {
+			return nil, err
+		}
+
+		// ロックしたまま異常終了したプロセスのロックファイルは削除する
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > localTaskLockStaleAfter {
+			os.Remove(lockPath)
+			continue
+		}
+
+		time.Sleep(localTaskLockInterval)
+	}
+}
+
+func localTaskKey(queueName, taskName string) string {
+	return queueName + "/" + taskName
+}
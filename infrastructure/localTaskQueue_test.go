@@ -0,0 +1,89 @@
+package infrastructure
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+func TestLocalTaskQueueSharedFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "teraconnect-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filePath := filepath.Join(dir, "tasks.json")
+
+	queue := QueueConfig{Name: "test"}
+	tests := []struct {
+		name      string
+		queueName string // タスクを登録するプロセス
+		taskName  string
+		wantErr   error
+	}{
+		{name: "server enqueues", queueName: "server", taskName: "a"},
+		{name: "command enqueues", queueName: "command", taskName: "b"},
+		{name: "server keeps command task", queueName: "server", taskName: "c"},
+		{name: "server rejects command task name", queueName: "server", taskName: "b", wantErr: ErrTaskAlreadyExists},
+		{name: "command keeps server task", queueName: "command", taskName: "d"},
+	}
+
+	// サーバーとコマンドが、同じファイルを使用する
+	queues := make(map[string]*LocalTaskQueue)
+	for _, name := range []string{"server", "command"} {
+		q, err := NewLocalTaskQueue(filePath, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		queues[name] = q
+	}
+
+	ctx := context.Background()
+	var want []string
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := queues[tt.queueName].Enqueue(ctx, Task{Queue: queue, Name: tt.taskName})
+			if err != tt.wantErr {
+				t.Fatalf("Enqueue() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				want = append(want, tt.taskName)
+			}
+
+			saved, err := NewLocalTaskQueue(filePath, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, record := range saved.records {
+				got = append(got, record.Task.Name)
+			}
+			sort.Strings(got)
+			if len(got) != len(want) {
+				t.Fatalf("saved tasks = %v, want %v", got, want)
+			}
+			for i := range want {
+				if got[i] != want[i] {
+					t.Fatalf("saved tasks = %v, want %v", got, want)
+				}
+			}
+		})
+	}
+
+	// サーバーは、コマンドが登録したタスクを実行対象として読み込む
+	var names []string
+	for {
+		record, ok := queues["server"].next()
+		if !ok {
+			break
+		}
+		names = append(names, record.Task.Name)
+	}
+	sort.Strings(names)
+	if len(names) != len(want) {
+		t.Errorf("server runs %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,630 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"google.golang.org/api/iterator"
+)
+
+// インメモリ実装で、競合したトランザクションを再試行する回数。Cloud Datastoreクライアントの既定値に合わせている。
+const memoryTransactionAttempts = 3
+
+var errMemoryTransactionFinished = errors.New("datastore: transaction has already finished")
+
+type memoryDatastore struct {
+	mu       sync.RWMutex
+	entities map[string]*memoryEntity
+	nextID   int64
+	version  int64
+}
+
+type memoryEntity struct {
+	key        *datastore.Key
+	properties []datastore.Property
+	version    int64
+}
+
+type memoryTransaction struct {
+	store    *memoryDatastore
+	reads    map[string]int64
+	writes   []memoryMutation
+	finished bool
+}
+
+type memoryMutation struct {
+	key        *datastore.Key
+	properties []datastore.Property // nilの場合は削除
+}
+
+type memoryIterator struct {
+	results  []*memoryEntity
+	query    *Query
+	position int
+	offset   int
+	err      error
+}
+
+// NewMemoryDatastoreは、プロセス内のメモリにエンティティを保持するDatastoreを返します。
+// ローカルでの実行やテストでの使用を想定しており、祖先キー、フィルタ、並び替え、トランザクションをサポートします。
+// Cloud Datastoreと同様に、noindexのプロパティで絞り込みや並び替えをしたクエリの結果には、そのエンティティは含まれません。
+func NewMemoryDatastore() Datastore {
+	return &memoryDatastore{entities: make(map[string]*memoryEntity), nextID: 1}
+}
+
+func (s *memoryDatastore) Get(ctx context.Context, key *datastore.Key, dst interface{}) error {
+	if key == nil || key.Incomplete() {
+		return datastore.ErrInvalidKey
+	}
+
+	s.mu.RLock()
+	entity, ok := s.entities[key.String()]
+	s.mu.RUnlock()
+	if !ok {
+		return datastore.ErrNoSuchEntity
+	}
+
+	return datastore.LoadStruct(dst, entity.properties)
+}
+
+func (s *memoryDatastore) GetMulti(ctx context.Context, keys []*datastore.Key, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Slice {
+		return errors.New("datastore: dst has invalid type")
+	}
+	if v.Len() != len(keys) {
+		return errors.New("datastore: keys and dst slices have different length")
+	}
+
+	multiErr, any := make(datastore.MultiError, len(keys)), false
+	for i, key := range keys {
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				elem.Set(reflect.New(elem.Type().Elem()))
+			}
+		} else {
+			elem = elem.Addr()
+		}
+
+		if err := s.Get(ctx, key, elem.Interface()); err != nil {
+			multiErr[i] = err
+			any = true
+		}
+	}
+
+	if any {
+		return multiErr
+	}
+
+	return nil
+}
+
+func (s *memoryDatastore) GetAll(ctx context.Context, query *Query, dst interface{}) ([]*datastore.Key, error) {
+	var slice reflect.Value
+	if dst != nil {
+		v := reflect.ValueOf(dst)
+		if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Slice {
+			return nil, errors.New("datastore: dst must be a pointer to a slice")
+		}
+		slice = v.Elem()
+	}
+
+	it := s.Run(ctx, query)
+	var keys []*datastore.Key
+	for {
+		var elem reflect.Value
+		var target interface{}
+		if slice.IsValid() && !query.keysOnly {
+			elemType := slice.Type().Elem()
+			if elemType.Kind() == reflect.Ptr {
+				elem = reflect.New(elemType.Elem())
+				target = elem.Interface()
+			} else {
+				elem = reflect.New(elemType)
+				target = elem.Interface()
+				elem = elem.Elem()
+			}
+		}
+
+		key, err := it.Next(target)
+		if err == iterator.Done {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+		if elem.IsValid() {
+			slice.Set(reflect.Append(slice, elem))
+		}
+	}
+
+	return keys, nil
+}
+
+func (s *memoryDatastore) Run(ctx context.Context, query *Query) Iterator {
+	offset := 0
+	if query.cursor != "" {
+		decoded, err := decodeMemoryCursor(query.cursor)
+		if err != nil {
+			return &memoryIterator{err: err}
+		}
+		offset = decoded
+	}
+
+	s.mu.RLock()
+	var results []*memoryEntity
+	for _, entity := range s.entities {
+		if entity.key.Kind != query.kind || !hasAncestor(entity.key, query.ancestor) {
+			continue
+		}
+		if !hasIndexedOrders(entity, query.orders) || !matchesFilters(entity, query.filters) {
+			continue
+		}
+		results = append(results, entity)
+	}
+	s.mu.RUnlock()
+
+	sort.SliceStable(results, func(i, j int) bool {
+		for _, order := range query.orders {
+			c := compareValues(propertyValue(results[i], order.field), propertyValue(results[j], order.field))
+			if c == 0 {
+				continue
+			}
+			if order.descending {
+				return c > 0
+			}
+			return c < 0
+		}
+		return compareKeys(results[i].key, results[j].key) < 0
+	})
+
+	if offset > len(results) {
+		offset = len(results)
+	}
+	results = results[offset:]
+	if query.limit > 0 && len(results) > query.limit {
+		results = results[:query.limit]
+	}
+
+	return &memoryIterator{results: results, query: query, offset: offset}
+}
+
+func (s *memoryDatastore) Put(ctx context.Context, key *datastore.Key, src interface{}) (*datastore.Key, error) {
+	properties, err := datastore.SaveStruct(src)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.put(key, properties), nil
+}
+
+func (s *memoryDatastore) PutMulti(ctx context.Context, keys []*datastore.Key, src interface{}) ([]*datastore.Key, error) {
+	v := reflect.ValueOf(src)
+	if v.Kind() != reflect.Slice {
+		return nil, errors.New("datastore: src has invalid type")
+	}
+	if v.Len() != len(keys) {
+		return nil, errors.New("datastore: key and src slices have different length")
+	}
+
+	propertiesList := make([][]datastore.Property, len(keys))
+	for i := range keys {
+		elem := v.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr()
+		}
+		properties, err := datastore.SaveStruct(elem.Interface())
+		if err != nil {
+			return nil, err
+		}
+		propertiesList[i] = properties
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	putKeys := make([]*datastore.Key, len(keys))
+	for i, key := range keys {
+		putKeys[i] = s.put(key, propertiesList[i])
+	}
+
+	return putKeys, nil
+}
+
+func (s *memoryDatastore) Delete(ctx context.Context, key *datastore.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entities, key.String())
+	return nil
+}
+
+func (s *memoryDatastore) DeleteMulti(ctx context.Context, keys []*datastore.Key) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, key := range keys {
+		delete(s.entities, key.String())
+	}
+	return nil
+}
+
+// RunInTransactionは、読み込んだエンティティがコミット時までに他から更新されていなければ書き込みを反映します。
+// 更新されていた場合はCloud Datastoreと同様にfを再実行します。
+func (s *memoryDatastore) RunInTransaction(ctx context.Context, f func(tx Transaction) error) error {
+	for i := 0; i < memoryTransactionAttempts; i++ {
+		tx := &memoryTransaction{store: s, reads: make(map[string]int64)}
+		if err := f(tx); err != nil {
+			tx.finished = true
+			return err
+		}
+
+		err := tx.commit()
+		if err == datastore.ErrConcurrentTransaction {
+			continue
+		}
+		return err
+	}
+
+	return datastore.ErrConcurrentTransaction
+}
+
+func (s *memoryDatastore) Close() error {
+	return nil
+}
+
+// putは、ロックを取得した状態で呼び出します。
+func (s *memoryDatastore) put(key *datastore.Key, properties []datastore.Property) *datastore.Key {
+	if key.Incomplete() {
+		completeKey := *key
+		completeKey.ID = s.nextID
+		s.nextID++
+		key = &completeKey
+	} else if key.ID >= s.nextID {
+		s.nextID = key.ID + 1
+	}
+
+	s.version++
+	s.entities[key.String()] = &memoryEntity{key: key, properties: properties, version: s.version}
+
+	return key
+}
+
+func (t *memoryTransaction) Get(key *datastore.Key, dst interface{}) error {
+	if t.finished {
+		return errMemoryTransactionFinished
+	}
+
+	t.store.mu.RLock()
+	entity, ok := t.store.entities[key.String()]
+	t.store.mu.RUnlock()
+
+	if !ok {
+		t.reads[key.String()] = 0
+		return datastore.ErrNoSuchEntity
+	}
+
+	t.reads[key.String()] = entity.version
+	return datastore.LoadStruct(dst, entity.properties)
+}
+
+func (t *memoryTransaction) Put(key *datastore.Key, src interface{}) error {
+	properties, err := datastore.SaveStruct(src)
+	if err != nil {
+		return err
+	}
+
+	t.writes = append(t.writes, memoryMutation{key: key, properties: properties})
+	return nil
+}
+
+func (t *memoryTransaction) Delete(key *datastore.Key) error {
+	t.writes = append(t.writes, memoryMutation{key: key})
+	return nil
+}
+
+func (t *memoryTransaction) commit() error {
+	t.finished = true
+
+	t.store.mu.Lock()
+	defer t.store.mu.Unlock()
+
+	for keyStr, version := range t.reads {
+		var currentVersion int64
+		if entity, ok := t.store.entities[keyStr]; ok {
+			currentVersion = entity.version
+		}
+		if currentVersion != version {
+			return datastore.ErrConcurrentTransaction
+		}
+	}
+
+	for _, mutation := range t.writes {
+		if mutation.properties == nil {
+			delete(t.store.entities, mutation.key.String())
+		} else {
+			t.store.put(mutation.key, mutation.properties)
+		}
+	}
+
+	return nil
+}
+
+func (i *memoryIterator) Next(dst interface{}) (*datastore.Key, error) {
+	if i.err != nil {
+		return nil, i.err
+	}
+	if i.position >= len(i.results) {
+		return nil, iterator.Done
+	}
+
+	entity := i.results[i.position]
+	i.position++
+
+	if dst != nil && !i.query.keysOnly {
+		properties := entity.properties
+		if len(i.query.projection) > 0 {
+			properties = nil
+			for _, property := range entity.properties {
+				for _, name := range i.query.projection {
+					if property.Name == name {
+						properties = append(properties, property)
+					}
+				}
+			}
+		}
+		if err := datastore.LoadStruct(dst, properties); err != nil {
+			return entity.key, err
+		}
+	}
+
+	return entity.key, nil
+}
+
+func (i *memoryIterator) Cursor() (string, error) {
+	if i.err != nil {
+		return "", i.err
+	}
+
+	return encodeMemoryCursor(i.offset + i.position), nil
+}
+
+func encodeMemoryCursor(offset int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte("memory:" + strconv.Itoa(offset)))
+}
+
+func decodeMemoryCursor(cursor string) (int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, err
+	}
+
+	value := string(decoded)
+	if !strings.HasPrefix(value, "memory:") {
+		return 0, fmt.Errorf("invalid cursor %s", cursor)
+	}
+
+	return strconv.Atoi(strings.TrimPrefix(value, "memory:"))
+}
+
+func hasAncestor(key *datastore.Key, ancestor *datastore.Key) bool {
+	if ancestor == nil {
+		return true
+	}
+
+	for k := key; k != nil; k = k.Parent {
+		if k.Equal(ancestor) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// matchesFiltersは、エンティティが全てのフィルタに一致するかを返します。
+// Cloud Datastoreと同様に、noindexか存在しないプロパティはインデックスに含まれないので、フィルタに一致しません。
+func matchesFilters(entity *memoryEntity, filters []queryFilter) bool {
+	for _, filter := range filters {
+		value, ok := indexedPropertyValue(entity, filter.field)
+		if !ok {
+			return false
+		}
+		values, isList := value.([]interface{})
+		if !isList {
+			values = []interface{}{value}
+		}
+
+		matched := false
+		for _, v := range values {
+			if matchesOperator(compareValues(v, filter.value), filter.operator) {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hasIndexedOrdersは、並び替えに使用する全てのプロパティがインデックスに含まれるかを返します。含まれないエンティティは結果から除かれます。
+func hasIndexedOrders(entity *memoryEntity, orders []queryOrder) bool {
+	for _, order := range orders {
+		if _, ok := indexedPropertyValue(entity, order.field); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchesOperator(c int, operator string) bool {
+	switch operator {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	default:
+		return c == 0
+	}
+}
+
+// indexedPropertyValueは、インデックスに含まれるプロパティの値を返します。noindexか存在しないプロパティの場合はfalseを返します。
+func indexedPropertyValue(entity *memoryEntity, name string) (interface{}, bool) {
+	for _, property := range entity.properties {
+		if property.Name == name {
+			return property.Value, !property.NoIndex
+		}
+	}
+
+	return nil, false
+}
+
+func propertyValue(entity *memoryEntity, name string) interface{} {
+	for _, property := range entity.properties {
+		if property.Name == name {
+			return property.Value
+		}
+	}
+
+	return nil
+}
+
+// compareValuesは、Cloud Datastoreと同じく型ごとの順序を優先して二つの値を比較します。
+func compareValues(a, b interface{}) int {
+	a, b = normalizeValue(a), normalizeValue(b)
+
+	if rankA, rankB := valueTypeRank(a), valueTypeRank(b); rankA != rankB {
+		return rankA - rankB
+	}
+
+	switch x := a.(type) {
+	case bool:
+		y := b.(bool)
+		if x == y {
+			return 0
+		} else if !x {
+			return -1
+		}
+		return 1
+	case int64:
+		y := b.(int64)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case float64:
+		y := b.(float64)
+		if x < y {
+			return -1
+		} else if x > y {
+			return 1
+		}
+		return 0
+	case string:
+		return strings.Compare(x, b.(string))
+	case time.Time:
+		y := b.(time.Time)
+		if x.Before(y) {
+			return -1
+		} else if x.After(y) {
+			return 1
+		}
+		return 0
+	case *datastore.Key:
+		return compareKeys(x, b.(*datastore.Key))
+	default:
+		return 0
+	}
+}
+
+func normalizeValue(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	default:
+		return value
+	}
+}
+
+func valueTypeRank(value interface{}) int {
+	switch value.(type) {
+	case nil:
+		return 0
+	case int64:
+		return 1
+	case time.Time:
+		return 2
+	case bool:
+		return 3
+	case string:
+		return 4
+	case float64:
+		return 5
+	case *datastore.Key:
+		return 6
+	default:
+		return 7
+	}
+}
+
+// compareKeysは、ルートからパスの各要素をKind、ID、Nameの順に比較します。
+func compareKeys(a, b *datastore.Key) int {
+	pathA, pathB := keyPath(a), keyPath(b)
+	for i := 0; i < len(pathA) && i < len(pathB); i++ {
+		x, y := pathA[i], pathB[i]
+		if c := strings.Compare(x.Kind, y.Kind); c != 0 {
+			return c
+		}
+		if x.ID != y.ID {
+			if x.ID < y.ID {
+				return -1
+			}
+			return 1
+		}
+		if c := strings.Compare(x.Name, y.Name); c != 0 {
+			return c
+		}
+	}
+
+	return len(pathA) - len(pathB)
+}
+
+func keyPath(key *datastore.Key) []*datastore.Key {
+	var path []*datastore.Key
+	for k := key; k != nil; k = k.Parent {
+		path = append([]*datastore.Key{k}, path...)
+	}
+
+	return path
+}
@@ -0,0 +1,201 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"cloud.google.com/go/datastore"
+	"google.golang.org/api/iterator"
+)
+
+type memoryTestEntity struct {
+	Name  string
+	Count int64
+	Tags  []string
+	Note  string `datastore:",noindex"`
+}
+
+func TestMemoryDatastoreRunInTransaction(t *testing.T) {
+	errTest := errors.New("test error")
+
+	tests := []struct {
+		name         string
+		conflicts    int  // 他からエンティティが更新される試行の回数
+		fails        bool // fがエラーを返す
+		wantErr      error
+		wantAttempts int
+		wantCount    int64
+	}{
+		{name: "commits writes", wantAttempts: 1, wantCount: 1},
+		{name: "retries on conflict", conflicts: 1, wantAttempts: 2, wantCount: 11},
+		{name: "gives up after repeated conflicts", conflicts: memoryTransactionAttempts, wantErr: datastore.ErrConcurrentTransaction, wantAttempts: memoryTransactionAttempts, wantCount: 10},
+		{name: "discards writes on error", fails: true, wantErr: errTest, wantAttempts: 1, wantCount: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := NewMemoryDatastore()
+			key := datastore.NameKey("Item", "a", nil)
+			if _, err := store.Put(ctx, key, &memoryTestEntity{Name: "a"}); err != nil {
+				t.Fatal(err)
+			}
+
+			attempts := 0
+			err := store.RunInTransaction(ctx, func(tx Transaction) error {
+				attempts++
+
+				var entity memoryTestEntity
+				if err := tx.Get(key, &entity); err != nil {
+					return err
+				}
+				if attempts <= tt.conflicts {
+					// 読み込んだ後、コミットまでに他から更新される
+					if _, err := store.Put(ctx, key, &memoryTestEntity{Name: "a", Count: 10}); err != nil {
+						return err
+					}
+				}
+
+				entity.Count++
+				if err := tx.Put(key, &entity); err != nil {
+					return err
+				}
+				if tt.fails {
+					return errTest
+				}
+				return nil
+			})
+
+			if err != tt.wantErr {
+				t.Errorf("RunInTransaction() error = %v, want %v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+
+			var got memoryTestEntity
+			if err := store.Get(ctx, key, &got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Count != tt.wantCount {
+				t.Errorf("Count = %d, want %d", got.Count, tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestMemoryDatastoreGetAll(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDatastore()
+
+	parent1 := datastore.IDKey("Parent", 1, nil)
+	parent2 := datastore.IDKey("Parent", 2, nil)
+	entities := []struct {
+		key    *datastore.Key
+		entity memoryTestEntity
+	}{
+		{key: datastore.NameKey("Item", "a", parent1), entity: memoryTestEntity{Name: "a", Count: 3, Tags: []string{"go", "web"}, Note: "x"}},
+		{key: datastore.NameKey("Item", "b", parent1), entity: memoryTestEntity{Name: "b", Count: 1, Tags: []string{"go"}, Note: "x"}},
+		{key: datastore.NameKey("Item", "c", parent2), entity: memoryTestEntity{Name: "c", Count: 2, Tags: []string{"web"}}},
+		{key: datastore.NameKey("Item", "d", nil), entity: memoryTestEntity{Name: "d", Count: 2}},
+	}
+	for _, e := range entities {
+		if _, err := store.Put(ctx, e.key, &e.entity); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		query *Query
+		want  []string
+	}{
+		{name: "orders by key without order", query: NewQuery("Item"), want: []string{"d", "a", "b", "c"}},
+		{name: "filters by equality", query: NewQuery("Item").Filter("Count =", 2).Order("Name"), want: []string{"c", "d"}},
+		{name: "filters by range", query: NewQuery("Item").Filter("Count >=", 2).Order("Count").Order("Name"), want: []string{"c", "d", "a"}},
+		{name: "filters by ancestor", query: NewQuery("Item").Ancestor(parent1).Order("Count"), want: []string{"b", "a"}},
+		{name: "matches any value of list property", query: NewQuery("Item").Filter("Tags =", "web").Order("Name"), want: []string{"a", "c"}},
+		{name: "matches every filter on list property", query: NewQuery("Item").Filter("Tags =", "go").Filter("Tags =", "web"), want: []string{"a"}},
+		{name: "orders descending", query: NewQuery("Item").Order("-Count").Order("Name"), want: []string{"a", "c", "d", "b"}},
+		{name: "limits results", query: NewQuery("Item").Order("Name").Limit(2), want: []string{"a", "b"}},
+		{name: "ignores other kinds", query: NewQuery("Other"), want: []string{}},
+		{name: "excludes noindex filter", query: NewQuery("Item").Filter("Note =", "x"), want: []string{}},
+		{name: "excludes noindex order", query: NewQuery("Item").Order("Note"), want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []memoryTestEntity
+			keys, err := store.GetAll(ctx, tt.query, &got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(keys) != len(got) {
+				t.Fatalf("GetAll() = %d keys and %d entities", len(keys), len(got))
+			}
+
+			names := []string{}
+			for _, entity := range got {
+				names = append(names, entity.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("GetAll() = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryDatastoreCursor(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryDatastore()
+
+	for _, name := range []string{"e", "d", "c", "b", "a"} {
+		if _, err := store.Put(ctx, datastore.IncompleteKey("Item", nil), &memoryTestEntity{Name: name}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{name: "first page", want: []string{"a", "b"}},
+		{name: "second page", want: []string{"c", "d"}},
+		{name: "last page", want: []string{"e"}},
+		{name: "after last page", want: []string{}},
+	}
+
+	// 前のページのカーソルから、続きのページを取得する
+	cursor := ""
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := NewQuery("Item").Order("Name").Limit(2)
+			if cursor != "" {
+				query = query.Start(cursor)
+			}
+
+			it := store.Run(ctx, query)
+			names := []string{}
+			for {
+				var entity memoryTestEntity
+				_, err := it.Next(&entity)
+				if err == iterator.Done {
+					break
+				} else if err != nil {
+					t.Fatal(err)
+				}
+				names = append(names, entity.Name)
+			}
+			if !reflect.DeepEqual(names, tt.want) {
+				t.Errorf("page = %v, want %v", names, tt.want)
+			}
+
+			var err error
+			if cursor, err = it.Cursor(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package infrastructure
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// MemorySearchIndexerは、メモリ上の転置インデックスを使用するSearchIndexerです。
+// 英数字は単語、それ以外の文字は2文字ずつのN-gramに分割するので、分かち書きされていない日本語も検索できます。
+type MemorySearchIndexer struct {
+	mu        sync.RWMutex
+	postings  map[string]map[string]int // トークン -> objectID -> 出現回数
+	documents map[string][]string       // objectID -> トークン
+}
+
+// NewMemorySearchIndexerは、空のMemorySearchIndexerを返します。
+func NewMemorySearchIndexer() *MemorySearchIndexer {
+	return &MemorySearchIndexer{
+		postings:  make(map[string]map[string]int),
+		documents: make(map[string][]string),
+	}
+}
+
+func (s *MemorySearchIndexer) Upsert(ctx context.Context, record SearchRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(record.ObjectID)
+	s.add(record)
+
+	return nil
+}
+
+func (s *MemorySearchIndexer) Delete(ctx context.Context, objectID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.remove(objectID)
+
+	return nil
+}
+
+func (s *MemorySearchIndexer) ReplaceAll(ctx context.Context, records []SearchRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.postings = make(map[string]map[string]int)
+	s.documents = make(map[string][]string)
+	for _, record := range records {
+		s.add(record)
+	}
+
+	return nil
+}
+
+// Searchは、queryの全てのトークンを含むレコードを、トークンの出現回数の多い順に最大limit件返します。
+// 検索はクライアントからAlgoliaへ直接行うので、インデックスの内容の確認に使用します。
+func (s *MemorySearchIndexer) Search(ctx context.Context, query string, limit int) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := searchTokens(query)
+	if len(tokens) == 0 {
+		return []string{}, nil
+	}
+
+	scores := make(map[string]int)
+	for i, token := range tokens {
+		postings := s.postings[token]
+		if i == 0 {
+			for objectID, count := range postings {
+				scores[objectID] = count
+			}
+			continue
+		}
+
+		for objectID := range scores {
+			count, ok := postings[objectID]
+			if !ok {
+				delete(scores, objectID)
+				continue
+			}
+			scores[objectID] += count
+		}
+	}
+
+	objectIDs := make([]string, 0, len(scores))
+	for objectID := range scores {
+		objectIDs = append(objectIDs, objectID)
+	}
+	sort.Slice(objectIDs, func(i, j int) bool {
+		if scores[objectIDs[i]] != scores[objectIDs[j]] {
+			return scores[objectIDs[i]] > scores[objectIDs[j]]
+		}
+		return objectIDs[i] < objectIDs[j]
+	})
+
+	if limit > 0 && len(objectIDs) > limit {
+		objectIDs = objectIDs[:limit]
+	}
+
+	return objectIDs, nil
+}
+
+// addは、recordをインデックスに追加します。s.muを取得した状態で呼び出してください。
+func (s *MemorySearchIndexer) add(record SearchRecord) {
+	var tokens []string
+	for _, value := range record.Fields {
+		for _, text := range searchableTexts(value) {
+			tokens = append(tokens, searchTokens(text)...)
+		}
+	}
+
+	for _, token := range tokens {
+		postings, ok := s.postings[token]
+		if !ok {
+			postings = make(map[string]int)
+			s.postings[token] = postings
+		}
+		postings[record.ObjectID]++
+	}
+	s.documents[record.ObjectID] = tokens
+}
+
+// removeは、objectIDのレコードをインデックスから削除します。s.muを取得した状態で呼び出してください。
+func (s *MemorySearchIndexer) remove(objectID string) {
+	for _, token := range s.documents[objectID] {
+		postings := s.postings[token]
+		delete(postings, objectID)
+		if len(postings) == 0 {
+			delete(s.postings, token)
+		}
+	}
+	delete(s.documents, objectID)
+}
+
+func searchableTexts(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	default:
+		return nil
+	}
+}
+
+// searchTokensは、textを検索用のトークンに分割します。
+func searchTokens(text string) []string {
+	var tokens []string
+	var word []rune
+	var ngram []rune
+
+	flushWord := func() {
+		if len(word) > 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	flushNgram := func() {
+		if len(ngram) == 1 {
+			tokens = append(tokens, string(ngram))
+		}
+		for i := 0; i+1 < len(ngram); i++ {
+			tokens = append(tokens, string(ngram[i:i+2]))
+		}
+		ngram = ngram[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			flushNgram()
+			word = append(word, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushWord()
+			ngram = append(ngram, r)
+		default:
+			flushWord()
+			flushNgram()
+		}
+	}
+	flushWord()
+	flushNgram()
+
+	return tokens
+}
@@ -0,0 +1,114 @@
+package infrastructure
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestMemorySearchIndexerSearch(t *testing.T) {
+	records := []SearchRecord{
+		{ObjectID: "1", Fields: map[string]interface{}{"title": "Go言語入門", "tags": []string{"go", "programming"}}},
+		{ObjectID: "2", Fields: map[string]interface{}{"title": "はじめての英語", "description": "英語の発音を学ぶ"}},
+		{ObjectID: "3", Fields: map[string]interface{}{"title": "Go Web Programming", "viewCount": int64(100)}},
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		limit int
+		want  []string
+	}{
+		{name: "ascii word", query: "go", want: []string{"1", "3"}},
+		{name: "ascii words are case insensitive", query: "GO programming", want: []string{"1", "3"}},
+		{name: "japanese ngram", query: "英語", want: []string{"2"}},
+		{name: "ranks by occurrences", query: "英語 発音", want: []string{"2"}},
+		{name: "mixed japanese and ascii", query: "Go言語", want: []string{"1"}},
+		{name: "all tokens must match", query: "go 英語", want: []string{}},
+		{name: "non string fields are not searched", query: "100", want: []string{}},
+		{name: "limits results", query: "go", limit: 1, want: []string{"1"}},
+		{name: "empty query", query: "  ", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			indexer := NewMemorySearchIndexer()
+			if err := indexer.ReplaceAll(ctx, records); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := indexer.Search(ctx, tt.query, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemorySearchIndexerUpdate(t *testing.T) {
+	tests := []struct {
+		name   string
+		update func(ctx context.Context, indexer *MemorySearchIndexer) error
+		query  string
+		want   []string
+	}{
+		{
+			name: "upsert replaces the record",
+			update: func(ctx context.Context, indexer *MemorySearchIndexer) error {
+				return indexer.Upsert(ctx, SearchRecord{ObjectID: "1", Fields: map[string]interface{}{"title": "数学"}})
+			},
+			query: "理科",
+			want:  []string{},
+		},
+		{
+			name: "upsert adds a record",
+			update: func(ctx context.Context, indexer *MemorySearchIndexer) error {
+				return indexer.Upsert(ctx, SearchRecord{ObjectID: "2", Fields: map[string]interface{}{"title": "理科の実験"}})
+			},
+			query: "理科",
+			want:  []string{"1", "2"},
+		},
+		{
+			name: "delete removes the record",
+			update: func(ctx context.Context, indexer *MemorySearchIndexer) error {
+				return indexer.Delete(ctx, "1")
+			},
+			query: "理科",
+			want:  []string{},
+		},
+		{
+			name: "delete ignores unknown record",
+			update: func(ctx context.Context, indexer *MemorySearchIndexer) error {
+				return indexer.Delete(ctx, "unknown")
+			},
+			query: "理科",
+			want:  []string{"1"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			indexer := NewMemorySearchIndexer()
+			if err := indexer.Upsert(ctx, SearchRecord{ObjectID: "1", Fields: map[string]interface{}{"title": "理科"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := tt.update(ctx, indexer); err != nil {
+				t.Fatal(err)
+			}
+
+			got, err := indexer.Search(ctx, tt.query, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
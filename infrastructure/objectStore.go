@@ -0,0 +1,158 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type SignedURL struct {
+	FileID    string `json:"fileID"`
+	SignedURL string `json:"signedURL"`
+}
+
+type SignedURLs struct {
+	SignedURLs []SignedURL `json:"signedURLs"`
+}
+
+type StorageObjectRequest struct {
+	LessonID     int64         `json:"lessonID"`
+	FileRequests []FileRequest `json:"fileRequests"`
+}
+
+type FileRequest struct {
+	ID          string `json:"id"`
+	Entity      string `json:"entity"`
+	Extension   string `json:"extension"`
+	ContentType string `json:"contentType"`
+}
+
+type EntityBelongToFile struct {
+	UserID int64
+}
+
+// ErrObjectNotFoundは、存在しないオブジェクトを取得しようとした場合に返されます。
+var ErrObjectNotFound = errors.New("storage: object doesn't exist")
+
+// ObjectStoreは、バケットとパスで同定するオブジェクトの保存先の抽象です。GCSとローカルディスクの実装があります。
+type ObjectStore interface {
+	Put(ctx context.Context, bucket, path, contentType string, contents []byte) error
+	Get(ctx context.Context, bucket, path string) ([]byte, error)
+	Copy(ctx context.Context, srcBucket, srcPath, dstBucket, dstPath string) error
+	Delete(ctx context.Context, bucket, path string) error // 存在しないオブジェクトの削除はエラーにしない
+	List(ctx context.Context, bucket, prefix string) ([]string, error)
+	SignedURL(ctx context.Context, bucket, path, method, contentType string) (string, error)
+	PublicURL(bucket, path string) string
+}
+
+var objectStore ObjectStore
+
+// NewObjectStoreは、設定のObjectStoreBackendに応じたObjectStoreを返します。"local"の場合はローカルディスク、それ以外はGCSを使用します。
+func NewObjectStore(ctx context.Context) (ObjectStore, error) {
+	if currentConfig.ObjectStoreBackend == "local" {
+		return NewLocalObjectStore(currentConfig.LocalStorageDir, currentConfig.LocalStorageURL, currentConfig.LocalStorageSecret)
+	}
+
+	return NewCloudStorageObjectStore(ctx)
+}
+
+// SetObjectStoreは、ファイル操作に使用するObjectStoreを設定します。起動時に一度だけ呼び出されることを想定しています。
+func SetObjectStore(store ObjectStore) {
+	objectStore = store
+}
+
+// ObjectStorageは、設定済みのObjectStoreを返します。
+func ObjectStorage() ObjectStore {
+	return objectStore
+}
+
+// CreateFile creates object to the bucket.
+func CreateFile(ctx context.Context, bucketName, filePath, contentType string, contents []byte) error {
+	return objectStore.Put(ctx, bucketName, filePath, contentType, contents)
+}
+
+// CreateBlankFileは、マテリアル用のバケットに空のファイルを作成し、アップロード用の署名付きURLを返します。
+func CreateBlankFile(ctx context.Context, fileID string, fileEntity string, fileRequest FileRequest) (string, error) {
+	return createBlankFile(ctx, MaterialBucketName(), fileID, fileEntity, fileRequest)
+}
+
+// CreateBlankFileToPublicは、公開用のバケットに空のファイルを作成し、アップロード用の署名付きURLを返します。
+func CreateBlankFileToPublic(ctx context.Context, fileID string, fileEntity string, fileRequest FileRequest) (string, error) {
+	return createBlankFile(ctx, PublicBucketName(), fileID, fileEntity, fileRequest)
+}
+
+func createBlankFile(ctx context.Context, bucketName string, fileID string, fileEntity string, fileRequest FileRequest) (string, error) {
+	filePath := StorageObjectFilePath(fileEntity, fileID, fileRequest.Extension)
+
+	if err := CreateFile(ctx, bucketName, filePath, fileRequest.ContentType, nil); err != nil {
+		return "", err
+	}
+
+	url, err := GetSignedURL(ctx, bucketName, filePath, "PUT", fileRequest.ContentType)
+	if err != nil {
+		return "", err
+	}
+
+	return url, err
+}
+
+// GetFile gets object from the bucket.
+func GetFile(ctx context.Context, bucketName, filePath string) ([]byte, error) {
+	return objectStore.Get(ctx, bucketName, filePath)
+}
+
+// CopyFile copies object between buckets.
+func CopyFile(ctx context.Context, srcBucket, srcPath, dstBucket, dstPath string) error {
+	return objectStore.Copy(ctx, srcBucket, srcPath, dstBucket, dstPath)
+}
+
+// DeleteFile deletes object. it is not error when the object doesn't exist.
+func DeleteFile(ctx context.Context, bucketName, filePath string) error {
+	return objectStore.Delete(ctx, bucketName, filePath)
+}
+
+// ListFiles returns object paths that start with prefix.
+func ListFiles(ctx context.Context, bucketName, prefix string) ([]string, error) {
+	return objectStore.List(ctx, bucketName, prefix)
+}
+
+// FileExistsは、オブジェクトが存在するかを返します。
+func FileExists(ctx context.Context, bucketName, filePath string) (bool, error) {
+	paths, err := objectStore.List(ctx, bucketName, filePath)
+	if err != nil {
+		return false, err
+	}
+
+	for _, path := range paths {
+		if path == filePath {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetSignedURL generates signed-URL for the object.
+func GetSignedURL(ctx context.Context, bucket string, key string, method string, contentType string) (string, error) {
+	return objectStore.SignedURL(ctx, bucket, key, method, contentType)
+}
+
+// PublicURL returns URL of the object in public bucket.
+func PublicURL(bucket string, path string) string {
+	return objectStore.PublicURL(bucket, path)
+}
+
+// GetPublicBackgroundImageURL returns public image file URL.
+func GetPublicBackgroundImageURL(id string) string {
+	return PublicURL(PublicBucketName(), "background/"+id+".webp")
+}
+
+// GetPublicBackgroundMusicURL returns public audio file URL.
+func GetPublicBackgroundMusicURL(id string) string {
+	return PublicURL(PublicBucketName(), "bgm/"+id+".mp3")
+}
+
+func StorageObjectFilePath(entity string, id string, extension string) string {
+	return fmt.Sprintf("%s/%s.%s", strings.ToLower(entity), id, extension)
+}
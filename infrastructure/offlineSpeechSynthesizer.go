@@ -0,0 +1,60 @@
+package infrastructure
+
+import (
+	"context"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MPEG-1 Layer III、32kbps、48kHz、モノラルのフレームヘッダ。1フレームは144*32000/48000=96バイトで、パディングは不要
+	offlineMP3FrameHeader = "\xff\xfb\x14\xc0"
+	offlineMP3FrameSize   = 96
+	offlineMP3FrameSec    = 1152.0 / 48000.0
+
+	offlineMinDurationSec = 0.5
+)
+
+type offlineSpeechSynthesizer struct{}
+
+// NewOfflineSpeechSynthesizerは、外部サービスを使用せずに無音のMP3を生成するSpeechSynthesizerを返します。
+// 音声の長さはテキストの文字数と話速から推定するので、同じリクエストには常に同じ内容を返します。
+func NewOfflineSpeechSynthesizer() SpeechSynthesizer {
+	return &offlineSpeechSynthesizer{}
+}
+
+func (s *offlineSpeechSynthesizer) Synthesize(ctx context.Context, req SpeechSynthesisRequest) ([]byte, error) {
+	frameCount := int(math.Ceil(EstimateSpeechDurationSec(req) / offlineMP3FrameSec))
+
+	// サイド情報とメインデータが全て0のフレームは、デコードすると無音になる
+	frame := make([]byte, offlineMP3FrameSize)
+	copy(frame, offlineMP3FrameHeader)
+
+	audio := make([]byte, 0, frameCount*offlineMP3FrameSize)
+	for i := 0; i < frameCount; i++ {
+		audio = append(audio, frame...)
+	}
+
+	return audio, nil
+}
+
+func (s *offlineSpeechSynthesizer) Close() error {
+	return nil
+}
+
+// EstimateSpeechDurationSecは、テキストを読み上げる秒数を推定します。日本語は1秒に8文字、それ以外は1秒に15文字を読み上げるものとします。
+func EstimateSpeechDurationSec(req SpeechSynthesisRequest) float64 {
+	charsPerSec := 15.0
+	if strings.HasPrefix(req.LanguageCode, "ja") {
+		charsPerSec = 8.0
+	}
+
+	speakingRate := req.SpeakingRate
+	if speakingRate <= 0 {
+		speakingRate = 1.0
+	}
+
+	durationSec := float64(utf8.RuneCountInString(strings.TrimSpace(req.Text))) / charsPerSec / speakingRate
+	return math.Max(durationSec, offlineMinDurationSec)
+}
@@ -0,0 +1,39 @@
+package infrastructure
+
+import (
+	"context"
+)
+
+// SearchRecordは、検索インデックスに登録するレコードです。Fieldsの文字列の値が検索の対象になります。
+type SearchRecord struct {
+	ObjectID string
+	Fields   map[string]interface{}
+}
+
+// SearchIndexerは、検索インデックスの抽象です。Algoliaとメモリ上の転置インデックスの実装があります。
+type SearchIndexer interface {
+	Upsert(ctx context.Context, record SearchRecord) error
+	Delete(ctx context.Context, objectID string) error // 存在しないレコードの削除はエラーにしない
+	ReplaceAll(ctx context.Context, records []SearchRecord) error
+}
+
+var searchIndexer SearchIndexer
+
+// NewSearchIndexerは、設定のSearchBackendに応じたSearchIndexerを返します。"memory"の場合はメモリ上のインデックス、それ以外はAlgoliaを使用します。
+func NewSearchIndexer() SearchIndexer {
+	if currentConfig.SearchBackend == "memory" {
+		return NewMemorySearchIndexer()
+	}
+
+	return NewAlgoliaSearchIndexer(currentConfig.AlgoliaApplicationID, currentConfig.AlgoliaAdminAPIKey, currentConfig.AlgoliaIndexName)
+}
+
+// SetSearchIndexerは、使用するSearchIndexerを設定します。起動時に一度だけ呼び出されることを想定しています。
+func SetSearchIndexer(indexer SearchIndexer) {
+	searchIndexer = indexer
+}
+
+// SearchIndexは、設定済みのSearchIndexerを返します。
+func SearchIndex() SearchIndexer {
+	return searchIndexer
+}
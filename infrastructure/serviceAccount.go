@@ -11,5 +11,5 @@ func ServiceAccountID() string {
 }
 
 func LocationID() string {
-	return "asia-northeast1" // Tokyo
+	return currentConfig.LocationID
 }
@@ -0,0 +1,42 @@
+package infrastructure
+
+import (
+	"context"
+)
+
+// SpeechSynthesisRequestは、音声合成のパラメータです。
+type SpeechSynthesisRequest struct {
+	Text         string
+	LanguageCode string
+	VoiceName    string
+	SpeakingRate float64
+	Pitch        float64
+	VolumeGainDb float64
+}
+
+// SpeechSynthesizerは、テキストからMP3の音声を合成します。Google Text-to-Speechとオフラインの実装があります。
+type SpeechSynthesizer interface {
+	Synthesize(ctx context.Context, req SpeechSynthesisRequest) ([]byte, error)
+	Close() error
+}
+
+var speechSynthesizer SpeechSynthesizer
+
+// NewSpeechSynthesizerは、設定のSpeechSynthesisBackendに応じたSpeechSynthesizerを返します。"offline"の場合は無音のMP3を生成し、それ以外はGoogle Text-to-Speechを使用します。
+func NewSpeechSynthesizer(ctx context.Context) (SpeechSynthesizer, error) {
+	if currentConfig.SpeechSynthesisBackend == "offline" {
+		return NewOfflineSpeechSynthesizer(), nil
+	}
+
+	return NewGoogleSpeechSynthesizer(ctx)
+}
+
+// SetSpeechSynthesizerは、音声合成に使用するSpeechSynthesizerを設定します。起動時に一度だけ呼び出されることを想定しています。
+func SetSpeechSynthesizer(synthesizer SpeechSynthesizer) {
+	speechSynthesizer = synthesizer
+}
+
+// SynthesizeSpeechは、設定済みのSpeechSynthesizerで音声を合成します。
+func SynthesizeSpeech(ctx context.Context, req SpeechSynthesisRequest) ([]byte, error) {
+	return speechSynthesizer.Synthesize(ctx, req)
+}
@@ -0,0 +1,147 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrTaskAlreadyExistsは、同じ名前のタスクが既に登録されている場合に返されます。
+var ErrTaskAlreadyExists = errors.New("task queue: task already exists")
+
+// RetryPolicyは、タスクが失敗した際の再試行の方針です。
+type RetryPolicy struct {
+	MaxAttempts int           `json:"maxAttempts"` // 0の場合は無制限
+	MinBackoff  time.Duration `json:"minBackoff"`
+	MaxBackoff  time.Duration `json:"maxBackoff"`
+}
+
+// QueueConfigは、名前付きのキューの設定です。RelativeURIはCloud Tasksがタスクを配送するパスです。
+type QueueConfig struct {
+	Name        string      `json:"name"`
+	RelativeURI string      `json:"relativeURI"`
+	Retry       RetryPolicy `json:"retry"`
+}
+
+// Taskは、キューに登録する非同期処理です。Nameが空でなければ重複登録の防止に使用されます。
+type Task struct {
+	Queue   QueueConfig `json:"queue"`
+	Name    string      `json:"name"`
+	Payload []byte      `json:"payload"`
+	ETA     time.Time   `json:"eta"`
+}
+
+// TaskHandlerは、キューから配送されたタスクを処理します。エラーを返すとRetryPolicyに従って再試行されます。
+type TaskHandler func(ctx context.Context, task Task) error
+
+// TaskQueueは、タスクの登録先の抽象です。Cloud Tasksとプロセス内のワーカーの実装があります。
+type TaskQueue interface {
+	Enqueue(ctx context.Context, task Task) error
+	Start() error
+	Close() error
+}
+
+type registeredTaskHandler struct {
+	queue   QueueConfig
+	handler TaskHandler
+}
+
+var (
+	taskQueue        TaskQueue
+	taskHandlersLock sync.RWMutex
+	taskHandlers     = make(map[string]registeredTaskHandler)
+)
+
+// NewTaskQueueは、設定のTaskQueueBackendに応じたTaskQueueを返します。"local"の場合はプロセス内のワーカー、それ以外はCloud Tasksを使用します。
+func NewTaskQueue(ctx context.Context) (TaskQueue, error) {
+	if currentConfig.TaskQueueBackend == "local" {
+		return NewLocalTaskQueue(currentConfig.LocalTaskQueueFile, localTaskQueueWorkers)
+	}
+
+	return NewCloudTaskQueue(ctx)
+}
+
+// SetTaskQueueは、タスクの登録に使用するTaskQueueを設定します。起動時に一度だけ呼び出されることを想定しています。
+func SetTaskQueue(queue TaskQueue) {
+	taskQueue = queue
+}
+
+// EnqueueTaskは、設定済みのTaskQueueにタスクを登録します。
+func EnqueueTask(ctx context.Context, task Task) error {
+	return taskQueue.Enqueue(ctx, task)
+}
+
+// NewJSONTaskは、payloadをjsonにしたTaskを返します。
+func NewJSONTask(queue QueueConfig, name string, eta time.Time, payload interface{}) (Task, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return Task{}, err
+	}
+
+	return Task{Queue: queue, Name: name, Payload: body, ETA: eta}, nil
+}
+
+// DecodePayloadは、jsonのPayloadをvに読み込みます。Payloadが空の場合は何もしません。
+func (t Task) DecodePayload(v interface{}) error {
+	if len(t.Payload) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(t.Payload, v)
+}
+
+// RegisterTaskHandlerは、queueに登録されたタスクを処理するhandlerを登録します。
+func RegisterTaskHandler(queue QueueConfig, handler TaskHandler) {
+	taskHandlersLock.Lock()
+	defer taskHandlersLock.Unlock()
+
+	taskHandlers[queue.Name] = registeredTaskHandler{queue: queue, handler: handler}
+}
+
+// TaskHandlerForは、キュー名に対応する設定とhandlerを返します。
+func TaskHandlerFor(queueName string) (QueueConfig, TaskHandler, bool) {
+	taskHandlersLock.RLock()
+	defer taskHandlersLock.RUnlock()
+
+	registered, ok := taskHandlers[queueName]
+	return registered.queue, registered.handler, ok
+}
+
+// RegisteredTaskQueuesは、handlerが登録された全てのキューの設定を名前順で返します。
+func RegisteredTaskQueues() []QueueConfig {
+	taskHandlersLock.RLock()
+	defer taskHandlersLock.RUnlock()
+
+	queues := make([]QueueConfig, 0, len(taskHandlers))
+	for _, registered := range taskHandlers {
+		queues = append(queues, registered.queue)
+	}
+	sort.Slice(queues, func(i, j int) bool { return queues[i].Name < queues[j].Name })
+
+	return queues
+}
+
+// Backoffは、attempts回失敗した後に次の実行まで待つ時間を返します。
+func (p RetryPolicy) Backoff(attempts int) time.Duration {
+	backoff := p.MinBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+
+	for i := 1; i < attempts; i++ {
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+
+	return backoff
+}
+
+// Exhaustedは、attempts回の実行で再試行の上限に達したかを返します。
+func (p RetryPolicy) Exhausted(attempts int) bool {
+	return p.MaxAttempts > 0 && attempts >= p.MaxAttempts
+}
@@ -0,0 +1,146 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+type CommandErrorCode uint
+
+const (
+	UnknownCommand CommandErrorCode = 1
+)
+
+func (e CommandErrorCode) Error() string {
+	switch e {
+	case UnknownCommand:
+		return "unknown command"
+	default:
+		return "unknown command error"
+	}
+}
+
+// Mainは、バッチ処理をコマンドラインから実行します。argsの先頭がコマンド名です。
+func Main(appEnv string, args []string) error {
+	config, err := infrastructure.LoadConfig(appEnv)
+	if err != nil {
+		return err
+	}
+	infrastructure.SetConfig(config)
+
+	ctx := context.Background()
+
+	store, err := infrastructure.NewDatastore(ctx)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+	usecase.SetRepositories(domain.NewRepositories(store))
+
+	objectStore, err := infrastructure.NewObjectStore(ctx)
+	if err != nil {
+		return err
+	}
+	infrastructure.SetObjectStore(objectStore)
+	infrastructure.SetSearchIndexer(infrastructure.NewSearchIndexer())
+
+	// 予約の実行で圧縮タスクを登録するので、TaskQueueも設定する。ローカルの場合は同じファイルを使用するサーバーが読み込んで処理し、
+	// サーバーが起動していなければ次の起動時に処理される
+	taskQueue, err := infrastructure.NewTaskQueue(ctx)
+	if err != nil {
+		return err
+	}
+	defer taskQueue.Close()
+	infrastructure.SetTaskQueue(taskQueue)
+
+	switch args[0] {
+	case "delete-orders":
+		return processDeleteOrders(ctx)
+	case "rebuild-delete-orders":
+		return rebuildDeleteOrders(ctx)
+	case "lesson-view-counts":
+		return aggregateLessonViewCounts(ctx)
+	case "lesson-reactions":
+		return aggregateLessonReactions(ctx)
+	case "lesson-rankings":
+		return computeLessonRankings(ctx)
+	case "reindex-lessons":
+		return reindexLessons(ctx)
+	case "lesson-schedules":
+		return processLessonSchedules(ctx)
+	case "rebuild-lesson-lists":
+		return rebuildLessonLists(ctx)
+	default:
+		return fmt.Errorf("%w: %s", UnknownCommand, args[0])
+	}
+}
+
+func processDeleteOrders(ctx context.Context) error {
+	processed, err := usecase.ProcessDeleteOrders(ctx)
+	log.Printf("processed %d delete orders.\n", processed)
+
+	return err
+}
+
+func rebuildDeleteOrders(ctx context.Context) error {
+	refreshed, err := usecase.RefreshDeleteOrders(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("refreshed %d delete orders.\n", refreshed)
+
+	return nil
+}
+
+func aggregateLessonViewCounts(ctx context.Context) error {
+	updated, updatedShareLinks, err := usecase.AggregateLessonViewCounts(ctx)
+	log.Printf("updated view counts of %d lessons and %d share links.\n", updated, updatedShareLinks)
+
+	return err
+}
+
+func aggregateLessonReactions(ctx context.Context) error {
+	updated, err := usecase.AggregateLessonReactions(ctx)
+	log.Printf("updated reactions of %d lessons.\n", updated)
+
+	return err
+}
+
+func computeLessonRankings(ctx context.Context) error {
+	computed, err := usecase.ComputeLessonRankings(ctx)
+	log.Printf("computed %d lesson rankings.\n", computed)
+
+	return err
+}
+
+func processLessonSchedules(ctx context.Context) error {
+	processed, err := usecase.ProcessLessonSchedules(ctx)
+	log.Printf("processed %d lesson schedules.\n", processed)
+
+	return err
+}
+
+func reindexLessons(ctx context.Context) error {
+	indexed, err := usecase.ReindexLessons(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("indexed %d lessons.\n", indexed)
+
+	return nil
+}
+
+func rebuildLessonLists(ctx context.Context) error {
+	lessons, tags, err := usecase.RebuildLessonLists(ctx)
+	if err != nil {
+		return err
+	}
+	log.Printf("refreshed %d lessons and rebuilt %d tags.\n", lessons, tags)
+
+	return nil
+}
@@ -3,7 +3,6 @@ package handler
 import (
 	"log"
 	"net/http"
-	"os"
 
 	"github.com/gorilla/csrf"
 	"github.com/labstack/echo/v4"
@@ -47,9 +46,19 @@ func CSRFTokenHeader() echo.MiddlewareFunc {
 }
 
 func csrfInitialString() []byte {
-	if infrastructure.AppEnv() == "development" {
-		return []byte("32-byte-long-auth-key")
-	} else {
-		return []byte(os.Getenv("COOKIE_SECRET"))
+	return []byte(infrastructure.CurrentConfig().CookieSecret)
+}
+
+// InternalRequestは、App EngineのcronまたはCloud Tasksからのリクエストのみを許可します。
+// App Engineは外部からのリクエストのX-Appengine-*ヘッダを除去するので、ヘッダの有無で判定できます。
+func InternalRequest() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			header := c.Request().Header
+			if header.Get("X-Appengine-Cron") != "true" && header.Get("X-AppEngine-QueueName") == "" && !infrastructure.CurrentConfig().AllowsUnverifiedInternalRequests {
+				return echo.NewHTTPError(http.StatusForbidden, "internal request only.")
+			}
+			return next(c)
+		}
 	}
 }
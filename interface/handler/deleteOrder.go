@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func processDeleteOrders(c echo.Context) error {
+	processed, err := usecase.ProcessDeleteOrders(c.Request().Context())
+	if err != nil {
+		fatalLog(err)
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]int{"processed": processed})
+}
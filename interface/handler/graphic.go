@@ -19,14 +19,20 @@ func getGraphic(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, errMessage)
 	}
 
-	graphic, err := usecase.GetGraphicByID(c.Request(), id)
+	lessonID, err := optionalLessonIDParam(c)
+	if err != nil {
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	graphic, err := usecase.GetGraphicByID(c.Request(), id, lessonID)
 	if err != nil {
 		if ok := errors.Is(err, domain.GraphicNotFound); ok {
 			warnLog(err)
 			return c.JSON(http.StatusNotFound, err.Error())
 		}
-		fatalLog(err)
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return lessonAccessErrorResponse(c, err)
 	}
 
 	return c.JSON(http.StatusOK, graphic)
@@ -43,12 +49,12 @@ func getGraphics(c echo.Context) error {
 	graphics, err := usecase.GetGraphicsByLessonID(c.Request(), lessonID)
 
 	if err != nil {
-		fatalLog(err)
 		graphicErr, ok := err.(domain.GraphicErrorCode)
 		if ok && graphicErr == domain.GraphicNotFound {
+			fatalLog(err)
 			return c.JSON(http.StatusNotFound, err.Error())
 		}
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return lessonAccessErrorResponse(c, err)
 	}
 
 	if len(graphics) == 0 {
@@ -73,8 +79,7 @@ func postGraphics(c echo.Context) error {
 
 	signedURLs, err := usecase.CreateGraphicsAndBlankFiles(c.Request(), *objectRequest)
 	if err != nil {
-		fatalLog(err)
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return lessonAccessErrorResponse(c, err)
 	}
 
 	return c.JSON(http.StatusOK, signedURLs)
@@ -88,16 +93,31 @@ func deleteGraphic(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, errMessage)
 	}
 
-	err = usecase.DeleteGraphic(c.Request(), id)
+	lessonID, err := optionalLessonIDParam(c)
 	if err != nil {
-		fatalLog(err)
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
 
+	err = usecase.DeleteGraphic(c.Request(), id, lessonID)
+	if err != nil {
 		if ok := errors.Is(err, domain.GraphicNotFound); ok {
+			fatalLog(err)
 			return c.JSON(http.StatusNotFound, err.Error())
 		}
 
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return lessonAccessErrorResponse(c, err)
 	}
 
 	return c.JSON(http.StatusOK, "the graphic has deleted.")
 }
+
+// optionalLessonIDParamは、共同編集者として他のユーザーのLessonのGraphicを扱う場合に指定されるlesson_idを返します。
+// 指定がない場合は0を返し、現在のユーザー自身のGraphicを扱います。
+func optionalLessonIDParam(c echo.Context) (int64, error) {
+	if c.QueryParam("lesson_id") == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(c.QueryParam("lesson_id"), 10, 64)
+}
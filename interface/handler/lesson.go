@@ -11,23 +11,21 @@ import (
 	"github.com/super-dog-human/teraconnectgo/usecase"
 )
 
-type getLessonShortResponse struct {
-	NextCursor string               `json:"nextCursor"`
-	Lessons    []domain.ShortLesson `json:"lessons"`
+type getLessonsResponse struct {
+	NextCursor string                 `json:"nextCursor"`
+	Lessons    []domain.LessonSummary `json:"lessons"`
 }
 
 func getLessons(c echo.Context) error {
-	categoryID, err := strconv.ParseInt(c.QueryParam("category_id"), 10, 64)
+	filter, err := lessonListFilterParams(c)
 	if err != nil {
+		warnLog(err)
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
-	if categoryID == 0 {
-		return c.JSON(http.StatusNotFound, "category_id is blank.")
-	}
 
 	cursorStr := c.QueryParam("next_cursor")
 
-	lessons, nextCursorStr, err := usecase.GetLessonsByCategoryID(c.Request(), categoryID, cursorStr)
+	lessons, nextCursorStr, err := usecase.GetLessons(c.Request(), filter, cursorStr)
 	if err != nil {
 		lessonErr, ok := err.(usecase.LessonErrorCode)
 		if ok && lessonErr == usecase.LessonNotFound {
@@ -37,14 +35,65 @@ func getLessons(c echo.Context) error {
 			return c.JSON(http.StatusInternalServerError, err.Error())
 		}
 
+		if listErr, ok := err.(domain.LessonListErrorCode); ok {
+			warnLog(listErr)
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
 		fatalLog(err)
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
 
-	response := getLessonShortResponse{Lessons: lessons, NextCursor: nextCursorStr}
+	response := getLessonsResponse{Lessons: lessons, NextCursor: nextCursorStr}
 	return c.JSON(http.StatusOK, response)
 }
 
+// lessonListFilterParamsは、クエリパラメータから公開中のLessonの一覧の絞り込み条件を作成します。指定のないパラメータは絞り込みに使用しません。
+func lessonListFilterParams(c echo.Context) (*domain.LessonListFilter, error) {
+	filter := &domain.LessonListFilter{
+		Tag:   c.QueryParam("tag"),
+		Order: domain.LessonListOrder(c.QueryParam("order")),
+	}
+
+	for name, dst := range map[string]*int64{"subject_id": &filter.SubjectID, "category_id": &filter.CategoryID, "user_id": &filter.UserID} {
+		if value := c.QueryParam(name); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return nil, errors.Errorf("invalid %s", name)
+			}
+			*dst = id
+		}
+	}
+
+	for name, dst := range map[string]*float32{"min_duration": &filter.MinDurationSec, "max_duration": &filter.MaxDurationSec} {
+		if value := c.QueryParam(name); value != "" {
+			sec, err := strconv.ParseFloat(value, 32)
+			if err != nil {
+				return nil, errors.Errorf("invalid %s", name)
+			}
+			*dst = float32(sec)
+		}
+	}
+
+	if value := c.QueryParam("has_thumbnail"); value != "" {
+		hasThumbnail, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, errors.New("invalid has_thumbnail")
+		}
+		filter.HasThumbnail = &hasThumbnail
+	}
+
+	if value := c.QueryParam("page_size"); value != "" {
+		pageSize, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, errors.New("invalid page_size")
+		}
+		filter.PageSize = pageSize
+	}
+
+	return filter, nil
+}
+
 func getLesson(c echo.Context) error {
 	var lesson domain.Lesson
 	var err error
@@ -107,7 +156,8 @@ func getUserLessons(c echo.Context) error {
 }
 
 func getCurrentUserLessons(c echo.Context) error {
-	lessons, err := usecase.GetCurrentUserLessons(c.Request())
+	order := domain.OwnLessonOrder(c.QueryParam("order"))
+	lessons, err := usecase.GetCurrentUserLessons(c.Request(), order)
 
 	if err != nil {
 		fatalLog(err)
@@ -182,6 +232,22 @@ func patchLesson(c echo.Context) error {
 			return c.JSON(http.StatusNotFound, err.Error())
 		} else if ok && LessonErr == usecase.InvalidLessonParams {
 			return c.JSON(http.StatusBadRequest, err.Error())
+		} else if ok && LessonErr == usecase.LessonNotAvailable {
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
+		if _, ok := err.(domain.TagErrorCode); ok {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		if _, ok := err.(domain.CategoryErrorCode); ok {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+		if err == usecase.LessonMaterialHasErrors {
+			// 公開できない理由を編集画面で示せるよう、検証結果を返す
+			validation, validationErr := usecase.ValidateLessonMaterial(c.Request(), id)
+			if validationErr != nil {
+				return c.JSON(http.StatusUnprocessableEntity, err.Error())
+			}
+			return c.JSON(http.StatusUnprocessableEntity, validation)
 		}
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
@@ -189,6 +255,37 @@ func patchLesson(c echo.Context) error {
 	return c.JSON(http.StatusOK, "succeeded")
 }
 
+func postLessonDuplicate(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	lesson, err := usecase.DuplicateLesson(id, c.Request())
+	if err != nil {
+		lessonErr, ok := err.(usecase.LessonErrorCode)
+		if ok && lessonErr == usecase.LessonNotFound {
+			return c.JSON(http.StatusNotFound, err.Error())
+		} else if ok && lessonErr == usecase.LessonNotAvailable {
+			warnLog(lessonErr)
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
+
+		authErr, ok := err.(domain.AuthErrorCode)
+		if ok {
+			warnLog(authErr)
+			return c.JSON(http.StatusUnauthorized, err.Error())
+		}
+
+		fatalLog(err)
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, lesson)
+}
+
 func deleteLesson(c echo.Context) error {
 	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
 	if err != nil {
@@ -198,7 +295,24 @@ func deleteLesson(c echo.Context) error {
 	}
 
 	if err := usecase.DeleteLessonAndResources(id, c.Request()); err != nil {
-
+		return lessonAccessErrorResponse(c, err)
 	}
 	return c.JSON(http.StatusOK, "succeeded")
 }
+
+// lessonAccessErrorResponseは、Lessonに対する権限の確認で発生したエラーをレスポンスにします。
+func lessonAccessErrorResponse(c echo.Context, err error) error {
+	if lessonErr, ok := err.(usecase.LessonErrorCode); ok {
+		switch lessonErr {
+		case usecase.LessonNotFound:
+			warnLog(lessonErr)
+			return c.JSON(http.StatusNotFound, err.Error())
+		case usecase.LessonNotAvailable:
+			warnLog(lessonErr)
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
+	}
+
+	fatalLog(err)
+	return c.JSON(http.StatusInternalServerError, err.Error())
+}
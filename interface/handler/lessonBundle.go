@@ -0,0 +1,68 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func getLessonExport(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	data, err := usecase.ExportLesson(c.Request(), id)
+	if err != nil {
+		lessonErr, ok := err.(usecase.LessonErrorCode)
+		if ok && lessonErr == usecase.LessonNotFound {
+			return c.JSON(http.StatusNotFound, err.Error())
+		} else if ok && lessonErr == usecase.LessonNotAvailable {
+			warnLog(lessonErr)
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
+
+		authErr, ok := err.(domain.AuthErrorCode)
+		if ok {
+			warnLog(authErr)
+			return c.JSON(http.StatusUnauthorized, err.Error())
+		}
+
+		fatalLog(err)
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=\"lesson-%d.zip\"", id))
+	return c.Blob(http.StatusOK, "application/zip", data)
+}
+
+func postLessonImport(c echo.Context) error {
+	lesson, err := usecase.ImportLesson(c.Request())
+	if err != nil {
+		bundleErr, ok := err.(domain.LessonBundleErrorCode)
+		if ok && bundleErr == domain.LessonBundleTooLarge {
+			warnLog(bundleErr)
+			return c.JSON(http.StatusRequestEntityTooLarge, err.Error())
+		} else if ok {
+			warnLog(bundleErr)
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		authErr, ok := err.(domain.AuthErrorCode)
+		if ok {
+			warnLog(authErr)
+			return c.JSON(http.StatusUnauthorized, err.Error())
+		}
+
+		fatalLog(err)
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusCreated, lesson)
+}
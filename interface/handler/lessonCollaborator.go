@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func getLessonCollaborators(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	collaborators, err := usecase.GetLessonCollaborators(c.Request(), lessonID)
+	if err != nil {
+		return lessonCollaboratorErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, collaborators)
+}
+
+func postLessonCollaborator(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	params := new(usecase.LessonCollaboratorParams)
+	if err := c.Bind(params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	collaborator, err := usecase.InviteLessonCollaborator(c.Request(), lessonID, params)
+	if err != nil {
+		return lessonCollaboratorErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, collaborator)
+}
+
+func deleteLessonCollaborator(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	userID, err := strconv.ParseInt(c.Param("userID"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid userID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	if err := usecase.RemoveLessonCollaborator(c.Request(), lessonID, userID); err != nil {
+		return lessonCollaboratorErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, "succeeded")
+}
+
+func postLessonCollaboration(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	collaborator, err := usecase.AcceptLessonCollaboration(c.Request(), lessonID)
+	if err != nil {
+		return lessonCollaboratorErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, collaborator)
+}
+
+func deleteLessonCollaboration(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	if err := usecase.LeaveLessonCollaboration(c.Request(), lessonID); err != nil {
+		return lessonCollaboratorErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, "succeeded")
+}
+
+func getCurrentUserCollaborations(c echo.Context) error {
+	collaborations, err := usecase.GetCurrentUserCollaborations(c.Request())
+	if err != nil {
+		return lessonCollaboratorErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, collaborations)
+}
+
+func lessonCollaboratorErrorResponse(c echo.Context, err error) error {
+	if collaboratorErr, ok := err.(domain.LessonCollaboratorErrorCode); ok {
+		warnLog(collaboratorErr)
+		switch collaboratorErr {
+		case domain.LessonCollaboratorNotFound:
+			return c.JSON(http.StatusNotFound, err.Error())
+		case domain.InvalidLessonCollaborator:
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	if authErr, ok := err.(domain.AuthErrorCode); ok {
+		warnLog(authErr)
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+
+	return lessonAccessErrorResponse(c, err)
+}
@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+type getLessonCommentsResponse struct {
+	NextCursor string                 `json:"nextCursor"`
+	Comments   []domain.LessonComment `json:"comments"`
+}
+
+func getLessonComments(c echo.Context) error {
+	return getLessonCommentList(c, false)
+}
+
+func getLessonCommentReplies(c echo.Context) error {
+	return getLessonCommentList(c, true)
+}
+
+func getLessonCommentList(c echo.Context, isReplies bool) error {
+	lessonID, commentID, err := lessonCommentIDParams(c, isReplies)
+	if err != nil {
+		warnLog(err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	var pageSize int
+	if value := c.QueryParam("page_size"); value != "" {
+		if pageSize, err = strconv.Atoi(value); err != nil {
+			warnLog(err)
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+	includesHidden := c.QueryParam("include_hidden") == "true"
+	cursorStr := c.QueryParam("next_cursor")
+
+	comments, nextCursorStr, err := usecase.GetLessonComments(c.Request(), lessonID, commentID, includesHidden, pageSize, cursorStr)
+	if err != nil {
+		return lessonCommentErrorResponse(c, err)
+	}
+
+	response := getLessonCommentsResponse{Comments: comments, NextCursor: nextCursorStr}
+	return c.JSON(http.StatusOK, response)
+}
+
+func postLessonComment(c echo.Context) error {
+	return createLessonComment(c, false)
+}
+
+func postLessonCommentReply(c echo.Context) error {
+	return createLessonComment(c, true)
+}
+
+func createLessonComment(c echo.Context, isReply bool) error {
+	lessonID, parentID, err := lessonCommentIDParams(c, isReply)
+	if err != nil {
+		warnLog(err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	params := new(usecase.LessonCommentParams)
+	if err := c.Bind(params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	comment, err := usecase.CreateLessonComment(c.Request(), lessonID, parentID, params)
+	if err != nil {
+		return lessonCommentErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, comment)
+}
+
+func patchLessonComment(c echo.Context) error {
+	lessonID, id, err := lessonCommentIDParams(c, true)
+	if err != nil {
+		warnLog(err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	params := new(usecase.LessonCommentParams)
+	if err := c.Bind(params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	comment, err := usecase.UpdateLessonComment(c.Request(), lessonID, id, params)
+	if err != nil {
+		return lessonCommentErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, comment)
+}
+
+func deleteLessonComment(c echo.Context) error {
+	lessonID, id, err := lessonCommentIDParams(c, true)
+	if err != nil {
+		warnLog(err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if err := usecase.DeleteLessonComment(c.Request(), lessonID, id); err != nil {
+		return lessonCommentErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, "succeeded")
+}
+
+func putLessonCommentHidden(c echo.Context) error {
+	return updateLessonCommentHidden(c, true)
+}
+
+func deleteLessonCommentHidden(c echo.Context) error {
+	return updateLessonCommentHidden(c, false)
+}
+
+func updateLessonCommentHidden(c echo.Context, hidden bool) error {
+	lessonID, id, err := lessonCommentIDParams(c, true)
+	if err != nil {
+		warnLog(err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	comment, err := usecase.HideLessonComment(c.Request(), lessonID, id, hidden)
+	if err != nil {
+		return lessonCommentErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, comment)
+}
+
+func putLessonCommentAnswer(c echo.Context) error {
+	lessonID, id, err := lessonCommentIDParams(c, true)
+	if err != nil {
+		warnLog(err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	params := new(usecase.LessonCommentAnswerParams)
+	if err := c.Bind(params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if params.AnswerID == 0 {
+		return c.JSON(http.StatusBadRequest, "answerID is blank")
+	}
+
+	comment, err := usecase.SetLessonCommentAnswer(c.Request(), lessonID, id, params.AnswerID)
+	if err != nil {
+		return lessonCommentErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, comment)
+}
+
+func deleteLessonCommentAnswer(c echo.Context) error {
+	lessonID, id, err := lessonCommentIDParams(c, true)
+	if err != nil {
+		warnLog(err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	comment, err := usecase.SetLessonCommentAnswer(c.Request(), lessonID, id, 0)
+	if err != nil {
+		return lessonCommentErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, comment)
+}
+
+// lessonCommentIDParamsは、パスからLessonのIDと、hasCommentIDがtrueの場合はLessonCommentのIDを返します。
+func lessonCommentIDParams(c echo.Context, hasCommentID bool) (int64, int64, error) {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if !hasCommentID {
+		return lessonID, 0, nil
+	}
+
+	commentID, err := strconv.ParseInt(c.Param("commentID"), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return lessonID, commentID, nil
+}
+
+func lessonCommentErrorResponse(c echo.Context, err error) error {
+	if commentErr, ok := err.(domain.LessonCommentErrorCode); ok {
+		warnLog(commentErr)
+		switch commentErr {
+		case domain.LessonCommentNotFound:
+			return c.JSON(http.StatusNotFound, err.Error())
+		case domain.InvalidLessonComment:
+			return c.JSON(http.StatusBadRequest, err.Error())
+		case domain.LessonCommentsClosed:
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
+	}
+
+	if authErr, ok := err.(domain.AuthErrorCode); ok {
+		warnLog(authErr)
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+
+	return lessonAccessErrorResponse(c, err)
+}
@@ -18,27 +18,22 @@ func getLessonGraphics(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, errMessage)
 	}
 
-	viewKey := c.QueryParam("view_key")
-	lesson, err := usecase.GetPublicLesson(c.Request(), lessonID, viewKey)
-	if err != nil {
-		if err == usecase.LessonNotFound {
-			return c.JSON(http.StatusNotFound, err.Error())
-		} else if err == usecase.LessonNotAvailable {
-			warnLog(err)
-			return c.JSON(http.StatusForbidden, err.Error())
-		}
-		return c.JSON(http.StatusBadRequest, err.Error())
-	}
-
 	ids := c.Request().URL.Query()["ids"]
 
 	if len(ids) == 0 {
 		return c.JSON(http.StatusNotFound, "invalid params.")
 	}
 
-	urls, err := usecase.GetGraphicsByLessonIDAndIDs(c.Request(), lessonID, lesson.UserID, ids)
+	viewKey := c.QueryParam("view_key")
+	urls, err := usecase.GetPublicLessonGraphics(c.Request(), lessonID, viewKey, ids)
 
 	if err != nil {
+		if err == usecase.LessonNotFound {
+			return c.JSON(http.StatusNotFound, err.Error())
+		} else if err == usecase.LessonNotAvailable {
+			warnLog(err)
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
 		fatalLog(err)
 		if err == domain.GraphicNotFound {
 			// idsパラメータを持つ全部または一部のGraphicが見つからなかった場合
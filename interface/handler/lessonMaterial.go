@@ -99,3 +99,23 @@ func patchLessonMaterial(c echo.Context) error {
 
 	return c.JSON(http.StatusCreated, "succeeded")
 }
+
+func getLessonValidation(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	validation, err := usecase.ValidateLessonMaterial(c.Request(), lessonID)
+	if err != nil {
+		if err == usecase.LessonMaterialNotFound {
+			warnLog(err)
+			return c.JSON(http.StatusNotFound, err.Error())
+		}
+		return lessonAccessErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, validation)
+}
@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func postLessonQuizAnswer(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	index, err := strconv.Atoi(c.Param("index"))
+	if err != nil {
+		errMessage := "Invalid quiz index error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	params := new(usecase.LessonQuizAnswerParams)
+	if err := c.Bind(params); err != nil {
+		warnLog(err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	result, err := usecase.AnswerLessonQuiz(c.Request(), lessonID, index, c.QueryParam("view_key"), params)
+	if err != nil {
+		if quizErr, ok := err.(domain.LessonQuizErrorCode); ok {
+			switch quizErr {
+			case domain.LessonQuizNotFound:
+				warnLog(quizErr)
+				return c.JSON(http.StatusNotFound, err.Error())
+			case domain.InvalidLessonQuizAnswer:
+				warnLog(quizErr)
+				return c.JSON(http.StatusBadRequest, err.Error())
+			}
+		}
+		return lessonAccessErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
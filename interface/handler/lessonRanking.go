@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func getRankings(c echo.Context) error {
+	var subjectID, categoryID int64
+	for name, dst := range map[string]*int64{"subject_id": &subjectID, "category_id": &categoryID} {
+		if value := c.QueryParam(name); value != "" {
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				warnLog(err)
+				return c.JSON(http.StatusBadRequest, err.Error())
+			}
+			*dst = id
+		}
+	}
+	period := domain.LessonRankingPeriod(c.QueryParam("period"))
+
+	ranking, err := usecase.GetLessonRanking(c.Request(), period, subjectID, categoryID)
+	if err != nil {
+		if rankingErr, ok := err.(domain.LessonRankingErrorCode); ok {
+			warnLog(rankingErr)
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		fatalLog(err)
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, ranking)
+}
+
+func computeLessonRankings(c echo.Context) error {
+	computed, err := usecase.ComputeLessonRankings(c.Request().Context())
+	if err != nil {
+		fatalLog(err)
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]int{"computed": computed})
+}
@@ -0,0 +1,114 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func getLessonReaction(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	reaction, err := usecase.GetLessonReaction(c.Request(), lessonID)
+	if err != nil {
+		return lessonReactionErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, reaction)
+}
+
+func putLessonLike(c echo.Context) error {
+	return updateLessonLike(c, true)
+}
+
+func deleteLessonLike(c echo.Context) error {
+	return updateLessonLike(c, false)
+}
+
+func updateLessonLike(c echo.Context, liked bool) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	reaction, err := usecase.LikeLesson(c.Request(), lessonID, liked)
+	if err != nil {
+		return lessonReactionErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, reaction)
+}
+
+func putLessonRating(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	params := new(usecase.LessonRatingParams)
+	if err := c.Bind(params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+	if params.Rating == 0 {
+		return c.JSON(http.StatusBadRequest, "rating is blank")
+	}
+
+	reaction, err := usecase.RateLesson(c.Request(), lessonID, params.Rating)
+	if err != nil {
+		return lessonReactionErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, reaction)
+}
+
+func deleteLessonRating(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	reaction, err := usecase.RateLesson(c.Request(), lessonID, 0)
+	if err != nil {
+		return lessonReactionErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, reaction)
+}
+
+func aggregateLessonReactions(c echo.Context) error {
+	updated, err := usecase.AggregateLessonReactions(c.Request().Context())
+	if err != nil {
+		fatalLog(err)
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]int{"updated": updated})
+}
+
+func lessonReactionErrorResponse(c echo.Context, err error) error {
+	if reactionErr, ok := err.(domain.LessonReactionErrorCode); ok && reactionErr == domain.InvalidLessonReaction {
+		warnLog(reactionErr)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if authErr, ok := err.(domain.AuthErrorCode); ok {
+		warnLog(authErr)
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+
+	return lessonAccessErrorResponse(c, err)
+}
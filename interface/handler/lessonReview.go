@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func postLessonReviews(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	params := new(usecase.LessonReviewRequestParams)
+	if err := c.Bind(params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	links, err := usecase.RequestLessonReviews(c.Request(), lessonID, params)
+	if err != nil {
+		return lessonReviewErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, links)
+}
+
+func deleteLessonReview(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	reviewerUserID, err := strconv.ParseInt(c.Param("userID"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	if err := usecase.CancelLessonReview(c.Request(), lessonID, reviewerUserID); err != nil {
+		return lessonReviewErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, "succeeded")
+}
+
+func getLessonForReview(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	lessonForReview, err := usecase.GetLessonForReview(c.Request(), lessonID, c.QueryParam("review_key"))
+	if err != nil {
+		return lessonReviewErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, lessonForReview)
+}
+
+func postLessonReviewComment(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	params := new(usecase.LessonReviewCommentParams)
+	if err := c.Bind(params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if params.Body == "" {
+		return c.JSON(http.StatusBadRequest, "body is blank")
+	}
+
+	review, err := usecase.AddLessonReviewComment(c.Request(), lessonID, c.QueryParam("review_key"), params)
+	if err != nil {
+		return lessonReviewErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, review)
+}
+
+func patchLessonReview(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	params := new(usecase.LessonReviewSubmitParams)
+	if err := c.Bind(params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	review, err := usecase.SubmitLessonReview(c.Request(), lessonID, c.QueryParam("review_key"), params)
+	if err != nil {
+		return lessonReviewErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, review)
+}
+
+func lessonReviewErrorResponse(c echo.Context, err error) error {
+	if reviewErr, ok := err.(domain.LessonReviewErrorCode); ok {
+		warnLog(reviewErr)
+		switch reviewErr {
+		case domain.LessonReviewNotFound:
+			return c.JSON(http.StatusNotFound, err.Error())
+		case domain.InvalidLessonReviewer, domain.InvalidLessonReviewStatus:
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	if lessonErr, ok := err.(usecase.LessonErrorCode); ok {
+		switch lessonErr {
+		case usecase.LessonNotFound:
+			return c.JSON(http.StatusNotFound, err.Error())
+		case usecase.LessonNotAvailable:
+			warnLog(lessonErr)
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
+	}
+
+	if authErr, ok := err.(domain.AuthErrorCode); ok {
+		warnLog(authErr)
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+
+	fatalLog(err)
+	return c.JSON(http.StatusInternalServerError, err.Error())
+}
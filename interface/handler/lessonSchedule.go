@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func getCurrentUserLessonSchedules(c echo.Context) error {
+	schedules, err := usecase.GetCurrentUserLessonSchedules(c.Request())
+	if err != nil {
+		return lessonScheduleErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, schedules)
+}
+
+func putLessonSchedule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	params := new(usecase.LessonScheduleParams)
+	if err := c.Bind(params); err != nil {
+		warnLog(err)
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	schedule, err := usecase.SetLessonSchedule(c.Request(), id, params)
+	if err != nil {
+		return lessonScheduleErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, schedule)
+}
+
+func deleteLessonSchedule(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	if err := usecase.CancelLessonSchedule(c.Request(), id); err != nil {
+		return lessonScheduleErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, "succeeded")
+}
+
+func processLessonSchedules(c echo.Context) error {
+	processed, err := usecase.ProcessLessonSchedules(c.Request().Context())
+	if err != nil {
+		fatalLog(err)
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]int{"processed": processed})
+}
+
+func lessonScheduleErrorResponse(c echo.Context, err error) error {
+	if scheduleErr, ok := err.(domain.LessonScheduleErrorCode); ok {
+		warnLog(scheduleErr)
+		switch scheduleErr {
+		case domain.LessonScheduleNotFound:
+			return c.JSON(http.StatusNotFound, err.Error())
+		case domain.InvalidLessonSchedule:
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	if lessonErr, ok := err.(usecase.LessonErrorCode); ok {
+		warnLog(lessonErr)
+		switch lessonErr {
+		case usecase.LessonNotFound:
+			return c.JSON(http.StatusNotFound, err.Error())
+		case usecase.LessonNotAvailable:
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
+	}
+
+	if authErr, ok := err.(domain.AuthErrorCode); ok {
+		warnLog(authErr)
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+
+	fatalLog(err)
+	return c.JSON(http.StatusInternalServerError, err.Error())
+}
@@ -19,6 +19,14 @@ func postLessonThumbnail(c echo.Context) error {
 	isPublic := c.QueryParam("is_public") == "true"
 	url, err := usecase.CreateLessonThumbnailBlankFile(c.Request(), isPublic, id)
 	if err != nil {
+		lessonErr, ok := err.(usecase.LessonErrorCode)
+		if ok && lessonErr == usecase.LessonNotFound {
+			return c.JSON(http.StatusNotFound, err.Error())
+		} else if ok && lessonErr == usecase.LessonNotAvailable {
+			warnLog(lessonErr)
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
+
 		fatalLog(err)
 		return c.JSON(http.StatusInternalServerError, err.Error())
 	}
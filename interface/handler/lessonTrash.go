@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func getCurrentUserTrashedLessons(c echo.Context) error {
+	lessons, err := usecase.GetTrashedLessons(c.Request())
+	if err != nil {
+		fatalLog(err)
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	if len(lessons) == 0 {
+		return c.JSON(http.StatusNotFound, "lesson doesn't exist")
+	}
+
+	return c.JSON(http.StatusOK, lessons)
+}
+
+func postLessonRestore(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	lesson, err := usecase.RestoreLesson(c.Request(), id)
+	if err != nil {
+		if trashErr, ok := err.(domain.LessonTrashErrorCode); ok {
+			switch trashErr {
+			case domain.LessonNotInTrash:
+				warnLog(trashErr)
+				return c.JSON(http.StatusNotFound, err.Error())
+			case domain.LessonNotRestorable:
+				warnLog(trashErr)
+				return c.JSON(http.StatusGone, err.Error())
+			}
+		}
+		return lessonAccessErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, lesson)
+}
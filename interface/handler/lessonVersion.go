@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func getLessonVersions(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	versions, err := usecase.GetLessonVersions(c.Request(), lessonID)
+	if err != nil {
+		return lessonVersionErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, versions)
+}
+
+func getLessonVersion(c echo.Context) error {
+	lessonID, version, err := lessonVersionParams(c)
+	if err != nil {
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	lessonVersion, err := usecase.GetLessonVersion(c.Request(), lessonID, version)
+	if err != nil {
+		return lessonVersionErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, lessonVersion)
+}
+
+func postLessonRollback(c echo.Context) error {
+	lessonID, version, err := lessonVersionParams(c)
+	if err != nil {
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	lesson, err := usecase.RollbackLesson(c.Request(), lessonID, version)
+	if err != nil {
+		return lessonVersionErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, lesson)
+}
+
+func lessonVersionParams(c echo.Context) (int64, int32, error) {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	version, err := strconv.ParseInt(c.Param("version"), 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return lessonID, int32(version), nil
+}
+
+func lessonVersionErrorResponse(c echo.Context, err error) error {
+	if lessonErr, ok := err.(usecase.LessonErrorCode); ok {
+		switch lessonErr {
+		case usecase.LessonNotFound:
+			return c.JSON(http.StatusNotFound, err.Error())
+		case usecase.LessonNotAvailable:
+			warnLog(lessonErr)
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
+	}
+
+	if versionErr, ok := err.(domain.LessonVersionErrorCode); ok {
+		switch versionErr {
+		case domain.LessonVersionNotFound:
+			return c.JSON(http.StatusNotFound, err.Error())
+		case domain.LessonVersionIsCurrent, domain.LessonVersionNotPublished:
+			warnLog(versionErr)
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	if err == domain.LessonVersionConflicted {
+		warnLog(err)
+		return c.JSON(http.StatusConflict, err.Error())
+	}
+
+	if authErr, ok := err.(domain.AuthErrorCode); ok {
+		warnLog(authErr)
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+
+	fatalLog(err)
+	return c.JSON(http.StatusInternalServerError, err.Error())
+}
@@ -22,3 +22,13 @@ func patchLessonViewCount(c echo.Context) error {
 
 	return c.JSON(http.StatusOK, "succeeded")
 }
+
+func aggregateLessonViewCounts(c echo.Context) error {
+	updated, updatedShareLinks, err := usecase.AggregateLessonViewCounts(c.Request().Context())
+	if err != nil {
+		fatalLog(err)
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, map[string]int{"updated": updated, "updatedShareLinks": updatedShareLinks})
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// registerLocalStorageRoutesは、LocalObjectStoreが発行したURLでオブジェクトを取得、アップロードするためのルートを登録します。
+func registerLocalStorageRoutes(e *echo.Echo, store *infrastructure.LocalObjectStore) {
+	e.GET(infrastructure.LocalStoragePath+"/:bucket/*", getLocalStorageObject(store))
+	e.PUT(infrastructure.LocalStoragePath+"/:bucket/*", putLocalStorageObject(store))
+}
+
+func getLocalStorageObject(store *infrastructure.LocalObjectStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		bucket := c.Param("bucket")
+		objectPath := c.Param("*")
+
+		if err := store.VerifySignature(http.MethodGet, bucket, objectPath, "", c.QueryParam("expires"), c.QueryParam("signature")); err != nil {
+			warnLog(err)
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
+
+		contents, err := store.Get(c.Request().Context(), bucket, objectPath)
+		if err != nil {
+			if err == infrastructure.ErrObjectNotFound {
+				return c.JSON(http.StatusNotFound, err.Error())
+			}
+			fatalLog(err)
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+
+		contentType := mime.TypeByExtension(path.Ext(objectPath))
+		if contentType == "" {
+			contentType = echo.MIMEOctetStream
+		}
+
+		return c.Blob(http.StatusOK, contentType, contents)
+	}
+}
+
+func putLocalStorageObject(store *infrastructure.LocalObjectStore) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		bucket := c.Param("bucket")
+		objectPath := c.Param("*")
+		contentType := c.Request().Header.Get(echo.HeaderContentType)
+
+		if err := store.VerifySignature(http.MethodPut, bucket, objectPath, contentType, c.QueryParam("expires"), c.QueryParam("signature")); err != nil {
+			warnLog(err)
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
+
+		contents, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		if err := store.Put(c.Request().Context(), bucket, objectPath, contentType, contents); err != nil {
+			fatalLog(err)
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.NoContent(http.StatusOK)
+	}
+}
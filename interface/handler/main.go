@@ -1,20 +1,52 @@
 package handler
 
 import (
+	"context"
 	"log"
 	"net/http"
-	"os"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
+	"github.com/super-dog-human/teraconnectgo/domain"
 	"github.com/super-dog-human/teraconnectgo/infrastructure"
+	"github.com/super-dog-human/teraconnectgo/usecase"
 )
 
 // Main is handling API request.
 func Main(appEnv string) {
-	if err := infrastructure.SetAppEnv(appEnv); err != nil {
+	config, err := infrastructure.LoadConfig(appEnv)
+	if err != nil {
 		log.Fatal(err)
 	}
+	infrastructure.SetConfig(config)
+
+	store, err := infrastructure.NewDatastore(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer store.Close()
+	usecase.SetRepositories(domain.NewRepositories(store))
+
+	objectStore, err := infrastructure.NewObjectStore(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	infrastructure.SetObjectStore(objectStore)
+	infrastructure.SetSearchIndexer(infrastructure.NewSearchIndexer())
+
+	speechSynthesizer, err := infrastructure.NewSpeechSynthesizer(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer speechSynthesizer.Close()
+	infrastructure.SetSpeechSynthesizer(speechSynthesizer)
+
+	taskQueue, err := infrastructure.NewTaskQueue(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer taskQueue.Close()
+	infrastructure.SetTaskQueue(taskQueue)
 
 	e := echo.New()
 	http.Handle("/", e)
@@ -36,13 +68,39 @@ func Main(appEnv string) {
 	e.GET("/categories", getCategories)
 	e.GET("/background_images", getBackgroundImages)
 	e.GET("/lessons", getLessons)
+	e.GET("/tags", getTags)
+	e.GET("/rankings", getRankings)
 	e.GET("/lessons/:id", getLesson)
 	e.GET("/lessons/:id/graphics", getLessonGraphics)
+	e.GET("/lessons/:id/comments", getLessonComments)
+	e.GET("/lessons/:id/comments/:commentID/replies", getLessonCommentReplies)
+	e.POST("/lessons/:id/quizzes/:index/answer", postLessonQuizAnswer)
 	e.GET("/users/:id", getUser)
 	e.GET("/users/:id/lessons", getUserLessons)
+	e.GET("/users/:id/series", getUserSeries)
+	e.GET("/series/:id", getSeries)
 	e.GET("/users", getUsers)
 	e.PATCH("/lesson_view_count", patchLessonViewCount)
 
+	if localStore, ok := objectStore.(*infrastructure.LocalObjectStore); ok {
+		registerLocalStorageRoutes(e, localStore)
+	}
+
+	infrastructure.RegisterTaskHandler(domain.LessonCompressingQueue, usecase.CompressLessonMaterial)
+	infrastructure.RegisterTaskHandler(domain.DeleteOrderQueue, usecase.ProcessDeleteOrdersTask)
+	infrastructure.RegisterTaskHandler(domain.LessonScheduleQueue, usecase.ProcessLessonSchedulesTask)
+	registerTaskRoutes(e)
+	if err := taskQueue.Start(); err != nil {
+		log.Fatal(err)
+	}
+
+	internal := e.Group("/internal", InternalRequest())
+	internal.GET("/delete_orders", processDeleteOrders)
+	internal.GET("/lesson_view_counts", aggregateLessonViewCounts)
+	internal.GET("/lesson_reactions", aggregateLessonReactions)
+	internal.GET("/lesson_rankings", computeLessonRankings)
+	internal.GET("/lesson_schedules", processLessonSchedules)
+
 	e.Group("", Authentication()).POST("/users", postUser)
 
 	auth := e.Group("", Authentication(), CSRFTokenCookie(), CSRFTokenHeader())
@@ -51,6 +109,10 @@ func Main(appEnv string) {
 	auth.POST("/users/me/thumbnail", postUserThumbnail)
 	auth.DELETE("/users", deleteUser)
 	auth.GET("/users/me/lessons", getCurrentUserLessons)
+	auth.GET("/users/me/series", getCurrentUserSeries)
+	auth.GET("/users/me/lesson_schedules", getCurrentUserLessonSchedules)
+	auth.GET("/users/me/collaborations", getCurrentUserCollaborations)
+	auth.GET("/users/me/trash", getCurrentUserTrashedLessons)
 	auth.GET("/avatars", getAvatars)
 	auth.POST("/avatars", postAvatars)
 	auth.GET("/background_musics", getBackgroundMusics)
@@ -65,18 +127,54 @@ func Main(appEnv string) {
 	auth.POST("/lessons", postLesson)
 	auth.PATCH("/lessons/:id", patchLesson)
 	auth.DELETE("/lessons/:id", deleteLesson)
+	auth.POST("/lessons/:id/restore", postLessonRestore)
+	auth.POST("/lessons/:id/duplicate", postLessonDuplicate)
+	auth.GET("/lessons/:id/export", getLessonExport)
+	auth.POST("/lessons/import", postLessonImport)
+	auth.PUT("/lessons/:id/schedule", putLessonSchedule)
+	auth.DELETE("/lessons/:id/schedule", deleteLessonSchedule)
+	auth.GET("/lessons/:id/collaborators", getLessonCollaborators)
+	auth.POST("/lessons/:id/collaborators", postLessonCollaborator)
+	auth.DELETE("/lessons/:id/collaborators/:userID", deleteLessonCollaborator)
+	auth.POST("/lessons/:id/collaboration", postLessonCollaboration)
+	auth.DELETE("/lessons/:id/collaboration", deleteLessonCollaboration)
+	auth.GET("/lessons/:id/share_links", getShareLinks)
+	auth.POST("/lessons/:id/share_links", postShareLink)
+	auth.DELETE("/lessons/:id/share_links/:key", deleteShareLink)
+	auth.GET("/lessons/:id/reaction", getLessonReaction)
+	auth.PUT("/lessons/:id/like", putLessonLike)
+	auth.DELETE("/lessons/:id/like", deleteLessonLike)
+	auth.PUT("/lessons/:id/rating", putLessonRating)
+	auth.DELETE("/lessons/:id/rating", deleteLessonRating)
+	auth.POST("/lessons/:id/comments", postLessonComment)
+	auth.POST("/lessons/:id/comments/:commentID/replies", postLessonCommentReply)
+	auth.PATCH("/lessons/:id/comments/:commentID", patchLessonComment)
+	auth.DELETE("/lessons/:id/comments/:commentID", deleteLessonComment)
+	auth.PUT("/lessons/:id/comments/:commentID/hidden", putLessonCommentHidden)
+	auth.DELETE("/lessons/:id/comments/:commentID/hidden", deleteLessonCommentHidden)
+	auth.PUT("/lessons/:id/comments/:commentID/answer", putLessonCommentAnswer)
+	auth.DELETE("/lessons/:id/comments/:commentID/answer", deleteLessonCommentAnswer)
 	auth.GET("/lessons/:lessonID/materials/:id", getLessonMaterials)
 	auth.PATCH("/lessons/:lessonID/materials/:id", patchLessonMaterial)
+	auth.GET("/lessons/:id/validation", getLessonValidation)
 	auth.POST("/lessons/:id/thumbnail", postLessonThumbnail)
+	auth.GET("/lessons/:id/versions", getLessonVersions)
+	auth.GET("/lessons/:id/versions/:version", getLessonVersion)
+	auth.POST("/lessons/:id/versions/:version/rollback", postLessonRollback)
+	auth.POST("/lessons/:id/reviews", postLessonReviews)
+	auth.DELETE("/lessons/:id/reviews/:userID", deleteLessonReview)
+	auth.GET("/lessons/:id/review", getLessonForReview)
+	auth.PATCH("/lessons/:id/review", patchLessonReview)
+	auth.POST("/lessons/:id/review/comments", postLessonReviewComment)
+	auth.POST("/series", postSeries)
+	auth.PATCH("/series/:id", patchSeries)
+	auth.PUT("/series/:id/lessons", putSeriesLessons)
+	auth.DELETE("/series/:id", deleteSeries)
+	auth.POST("/series/:id/thumbnail", postSeriesThumbnail)
 
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-	}
-
-	if infrastructure.AppEnv() == "development" {
-		log.Fatal(http.ListenAndServeTLS(":443", "localhost.crt", "localhost.key", nil))
+	if config.TLSCertFile != "" {
+		log.Fatal(http.ListenAndServeTLS(":"+config.Port, config.TLSCertFile, config.TLSKeyFile, nil))
 	} else {
-		log.Fatal(e.Start(":" + port))
+		log.Fatal(e.Start(":" + config.Port))
 	}
 }
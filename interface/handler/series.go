@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func getSeries(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	series, err := usecase.GetPublicSeries(c.Request(), id)
+	if err != nil {
+		return seriesErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, series)
+}
+
+func getUserSeries(c echo.Context) error {
+	userID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	seriesList, err := usecase.GetPublicSeriesByUser(c.Request(), userID)
+	if err != nil {
+		return seriesErrorResponse(c, err)
+	}
+
+	if len(seriesList) == 0 {
+		return c.JSON(http.StatusNotFound, "series doesn't exist")
+	}
+
+	return c.JSON(http.StatusOK, seriesList)
+}
+
+func getCurrentUserSeries(c echo.Context) error {
+	seriesList, err := usecase.GetCurrentUserSeries(c.Request())
+	if err != nil {
+		return seriesErrorResponse(c, err)
+	}
+
+	if len(seriesList) == 0 {
+		return c.JSON(http.StatusNotFound, "series doesn't exist")
+	}
+
+	return c.JSON(http.StatusOK, seriesList)
+}
+
+func postSeries(c echo.Context) error {
+	params := new(usecase.NewSeriesParams)
+	if err := c.Bind(params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	if params.Title == "" {
+		return c.JSON(http.StatusBadRequest, "title is blank")
+	}
+
+	series, err := usecase.CreateSeries(c.Request(), params)
+	if err != nil {
+		return seriesErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, series)
+}
+
+func patchSeries(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	var params map[string]interface{}
+	if err := json.NewDecoder(c.Request().Body).Decode(&params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	series, err := usecase.UpdateSeries(c.Request(), id, &params)
+	if err != nil {
+		return seriesErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, series)
+}
+
+func putSeriesLessons(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	params := new(usecase.SeriesLessonsParams)
+	if err := c.Bind(params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	series, err := usecase.UpdateSeriesLessons(c.Request(), id, params.LessonIDs)
+	if err != nil {
+		return seriesErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, series)
+}
+
+func deleteSeries(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	if err := usecase.DeleteSeries(c.Request(), id); err != nil {
+		return seriesErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, "succeeded")
+}
+
+func postSeriesThumbnail(c echo.Context) error {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid ID(s) error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	url, err := usecase.CreateSeriesThumbnailBlankFile(c.Request(), id)
+	if err != nil {
+		return seriesErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, thumbnailResponse{url})
+}
+
+func seriesErrorResponse(c echo.Context, err error) error {
+	if seriesErr, ok := err.(domain.SeriesErrorCode); ok {
+		warnLog(seriesErr)
+		switch seriesErr {
+		case domain.SeriesNotFound:
+			return c.JSON(http.StatusNotFound, err.Error())
+		case domain.InvalidSeriesLessons:
+			return c.JSON(http.StatusBadRequest, err.Error())
+		case domain.LessonAlreadyInSeries:
+			return c.JSON(http.StatusConflict, err.Error())
+		}
+	}
+
+	if seriesErr, ok := err.(usecase.SeriesErrorCode); ok {
+		warnLog(seriesErr)
+		switch seriesErr {
+		case usecase.SeriesNotAvailable:
+			return c.JSON(http.StatusForbidden, err.Error())
+		case usecase.InvalidSeriesParams:
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	if authErr, ok := err.(domain.AuthErrorCode); ok {
+		warnLog(authErr)
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+
+	fatalLog(err)
+	return c.JSON(http.StatusInternalServerError, err.Error())
+}
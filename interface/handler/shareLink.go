@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func getShareLinks(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	links, err := usecase.GetShareLinks(c.Request(), lessonID)
+	if err != nil {
+		return shareLinkErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, links)
+}
+
+func postShareLink(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	params := new(usecase.ShareLinkParams)
+	if err := c.Bind(params); err != nil {
+		return c.JSON(http.StatusBadRequest, err.Error())
+	}
+
+	link, err := usecase.CreateShareLink(c.Request(), lessonID, params)
+	if err != nil {
+		return shareLinkErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusCreated, link)
+}
+
+func deleteShareLink(c echo.Context) error {
+	lessonID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errMessage := "Invalid lessonID error"
+		warnLog(errMessage)
+		return c.JSON(http.StatusBadRequest, errMessage)
+	}
+
+	link, err := usecase.RevokeShareLink(c.Request(), lessonID, c.Param("key"))
+	if err != nil {
+		return shareLinkErrorResponse(c, err)
+	}
+
+	return c.JSON(http.StatusOK, link)
+}
+
+func shareLinkErrorResponse(c echo.Context, err error) error {
+	if linkErr, ok := err.(domain.ShareLinkErrorCode); ok {
+		warnLog(linkErr)
+		switch linkErr {
+		case domain.ShareLinkNotFound:
+			return c.JSON(http.StatusNotFound, err.Error())
+		case domain.InvalidShareLink:
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	if authErr, ok := err.(domain.AuthErrorCode); ok {
+		warnLog(authErr)
+		return c.JSON(http.StatusUnauthorized, err.Error())
+	}
+
+	return lessonAccessErrorResponse(c, err)
+}
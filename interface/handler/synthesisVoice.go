@@ -22,8 +22,7 @@ func postSynthesisVoice(c echo.Context) error {
 
 	voice, err := usecase.CreateSynthesisVoice(c.Request(), param)
 	if err != nil {
-		fatalLog(err)
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return lessonAccessErrorResponse(c, err)
 	}
 
 	response := voiceResponse{ID: voice.ID, FileKey: voice.FileKey}
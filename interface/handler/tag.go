@@ -0,0 +1,18 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/usecase"
+)
+
+func getTags(c echo.Context) error {
+	tags, err := usecase.SearchTags(c.Request(), c.QueryParam("q"))
+	if err != nil {
+		fatalLog(err)
+		return c.JSON(http.StatusInternalServerError, err.Error())
+	}
+
+	return c.JSON(http.StatusOK, tags)
+}
@@ -0,0 +1,50 @@
+package handler
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// registerTaskRoutesは、handlerが登録されたキューごとにCloud Tasksから配送されるタスクを受け付けるルートを登録します。
+func registerTaskRoutes(e *echo.Echo) {
+	for _, queue := range infrastructure.RegisteredTaskQueues() {
+		e.POST(queue.RelativeURI, runTask(queue.Name))
+	}
+}
+
+func runTask(queueName string) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		// App Engineは外部からのリクエストのX-AppEngine-*ヘッダを除去するので、Cloud Tasksからのリクエストであることの確認に使える
+		if c.Request().Header.Get("X-AppEngine-QueueName") != queueName {
+			return c.NoContent(http.StatusForbidden)
+		}
+
+		queue, handler, ok := infrastructure.TaskHandlerFor(queueName)
+		if !ok {
+			return c.NoContent(http.StatusNotFound)
+		}
+
+		payload, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, err.Error())
+		}
+
+		task := infrastructure.Task{Queue: queue, Name: c.Request().Header.Get("X-AppEngine-TaskName"), Payload: payload}
+		if err := handler(c.Request().Context(), task); err != nil {
+			retryCount, _ := strconv.Atoi(c.Request().Header.Get("X-AppEngine-TaskRetryCount"))
+			if queue.Retry.Exhausted(retryCount + 1) {
+				// 再試行の上限に達したタスクは成功扱いにしてキューから取り除く
+				fatalLog(err)
+				return c.NoContent(http.StatusOK)
+			}
+			warnLog(err)
+			return c.JSON(http.StatusInternalServerError, err.Error())
+		}
+
+		return c.NoContent(http.StatusOK)
+	}
+}
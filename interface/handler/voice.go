@@ -23,6 +23,9 @@ func getVoices(c echo.Context) error {
 		if ok && voiceErr == domain.VoiceNotFound {
 			return c.JSON(http.StatusNotFound, err.Error())
 		}
+		if lessonErr, ok := err.(usecase.LessonErrorCode); ok && lessonErr == usecase.LessonNotAvailable {
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
 
@@ -37,13 +40,15 @@ func postVoice(c echo.Context) error {
 		if ok && authErr == domain.UserNotFound {
 			return c.JSON(http.StatusNotFound, err.Error())
 		}
+		if lessonErr, ok := err.(usecase.LessonErrorCode); ok && lessonErr == usecase.LessonNotAvailable {
+			return c.JSON(http.StatusForbidden, err.Error())
+		}
 		return c.JSON(http.StatusBadRequest, err.Error())
 	}
 
 	voice, signedURL, err := usecase.CreateVoiceAndBlankFile(c.Request(), param)
 	if err != nil {
-		fatalLog(err)
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return lessonAccessErrorResponse(c, err)
 	}
 
 	response := synthesisVoiceResponse{ID: voice.ID, FileKey: voice.FileKey, SignedURL: signedURL}
@@ -1,12 +1,22 @@
 package main
 
 import (
+	"log"
 	"os"
 
+	"github.com/super-dog-human/teraconnectgo/interface/command"
 	"github.com/super-dog-human/teraconnectgo/interface/handler"
 )
 
 func main() {
+	// 環境名の後にコマンド名が指定された場合は、サーバーを起動せずにバッチ処理を実行する
+	if len(os.Args) > 2 {
+		if err := command.Main(os.Args[1], os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if appEnv := os.Args[1]; appEnv != "" {
 		handler.Main(appEnv)
 	}
@@ -14,18 +14,18 @@ func GetAvailableAvatars(request *http.Request) ([]domain.Avatar, error) {
 
 	var avatars []domain.Avatar
 
-	currentUser, err := domain.GetCurrentUser(request)
+	currentUser, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return nil, err
 	}
 
-	usersAvatars, err := domain.GetCurrentUsersAvatars(ctx, currentUser.ID)
+	usersAvatars, err := repositories.Avatar.GetCurrentUsers(ctx, currentUser.ID)
 	if err != nil {
 		return nil, err
 	}
 	avatars = append(avatars, usersAvatars...)
 
-	publicAvatars, err := domain.GetPublicAvatars(ctx)
+	publicAvatars, err := repositories.Avatar.GetPublic(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -39,7 +39,7 @@ func CreateAvatarsAndBlankFile(request *http.Request, objectRequest infrastructu
 
 	var signedURLs infrastructure.SignedURLs
 
-	currentUser, err := domain.GetCurrentUser(request)
+	currentUser, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return signedURLs, err
 	}
@@ -49,12 +49,12 @@ func CreateAvatarsAndBlankFile(request *http.Request, objectRequest infrastructu
 	for i, fileRequest := range objectRequest.FileRequests {
 		avatar := new(domain.Avatar)
 
-		if err = domain.CreateAvatar(ctx, avatar, &currentUser); err != nil {
+		if err = repositories.Avatar.Create(ctx, avatar, &currentUser); err != nil {
 			return signedURLs, err
 		}
 
 		fileID := strconv.FormatInt(avatar.ID, 10)
-		url, err := infrastructure.CreateBlankFileToGCS(ctx, fileID, "avatar", fileRequest)
+		url, err := infrastructure.CreateBlankFile(ctx, fileID, "avatar", fileRequest)
 		if err != nil {
 			return signedURLs, err
 		}
@@ -9,5 +9,5 @@ import (
 // GetBackgroundImages returns image URLs in Cloud Datastore.
 func GetBackgroundImages(request *http.Request) ([]domain.BackgroundImage, error) {
 	ctx := request.Context()
-	return domain.GetAllBackgroundImages(ctx)
+	return repositories.BackgroundImage.GetAll(ctx)
 }
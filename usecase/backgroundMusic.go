@@ -16,20 +16,20 @@ type CreateBackgroundMusicParam struct {
 func GetBackgroundMusics(request *http.Request) ([]domain.BackgroundMusic, error) {
 	ctx := request.Context()
 
-	currentUser, err := domain.GetCurrentUser(request)
+	currentUser, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return nil, err
 	}
 
 	var musics []domain.BackgroundMusic
 
-	usersMusics, err := domain.GetCurrentUsersBackgroundMusics(ctx, currentUser.ID)
+	usersMusics, err := repositories.BackgroundMusic.GetCurrentUsers(ctx, currentUser.ID)
 	if err != nil {
 		return nil, err
 	}
 	musics = append(musics, usersMusics...)
 
-	publicMusics, err := domain.GetPublicBackgroundMusics(ctx)
+	publicMusics, err := repositories.BackgroundMusic.GetPublic(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +43,7 @@ func CreateBackgroundMusicAndBlankFile(request *http.Request, param *CreateBackg
 
 	var signedURL infrastructure.SignedURL
 
-	currentUser, err := domain.GetCurrentUser(request)
+	currentUser, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return signedURL, err
 	}
@@ -52,7 +52,7 @@ func CreateBackgroundMusicAndBlankFile(request *http.Request, param *CreateBackg
 	backgroundMusic.Name = param.Name
 	backgroundMusic.IsPublic = false
 
-	if err = domain.CreateBackgroundMusic(ctx, currentUser.ID, backgroundMusic); err != nil {
+	if err = repositories.BackgroundMusic.Create(ctx, currentUser.ID, backgroundMusic); err != nil {
 		return signedURL, err
 	}
 
@@ -65,7 +65,7 @@ func CreateBackgroundMusicAndBlankFile(request *http.Request, param *CreateBackg
 		ContentType: "audio/mpeg",
 	}
 
-	url, err := infrastructure.CreateBlankFileToGCS(ctx, fileID, "bgm", mp3FileRequest)
+	url, err := infrastructure.CreateBlankFile(ctx, fileID, "bgm", mp3FileRequest)
 	if err != nil {
 		return signedURL, err
 	}
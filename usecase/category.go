@@ -9,18 +9,18 @@ import (
 // GetCategory return a category by id.
 func GetCategory(request *http.Request, id int64, subjectID int64) (domain.Category, error) {
 	ctx := request.Context()
-	return domain.GetJapaneseCategory(ctx, id, subjectID)
+	return repositories.Category.GetJapaneseCategory(ctx, id, subjectID)
 }
 
 // GetCategories return categories by the subject.
 func GetCategories(request *http.Request, subjectID int64) ([]domain.ShortCategory, error) {
 	ctx := request.Context()
 	// Right now, only the Japanese category exists.
-	return domain.GetJapaneseCategories(ctx, subjectID)
+	return repositories.Category.GetJapaneseCategories(ctx, subjectID)
 }
 
 // GetCategory return all categories.
 func GetAllCategories(request *http.Request) ([]domain.ShortCategory, error) {
 	ctx := request.Context()
-	return domain.GetAllJapaneseCategories(ctx)
+	return repositories.Category.GetAllJapaneseCategories(ctx)
 }
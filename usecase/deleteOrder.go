@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+const deleteOrderPageSize = 100
+
+// ProcessDeleteOrdersは、処理日時になった未処理のDeleteOrderがなくなるまで処理し、完了した件数を返します。
+// 失敗したDeleteOrderは残して次の実行で処理するので、他のDeleteOrderの処理は続けます。
+func ProcessDeleteOrders(ctx context.Context) (int, error) {
+	processed := 0
+	skippedIDs := make(map[int64]bool)
+	var firstErr error
+
+	currentTime := time.Now()
+	for {
+		orders, err := repositories.DeleteOrder.GetPending(ctx, currentTime, deleteOrderPageSize+len(skippedIDs))
+		if err != nil {
+			return processed, err
+		}
+
+		remaining := 0
+		for i := range orders {
+			order := &orders[i]
+			if skippedIDs[order.ID] {
+				continue
+			}
+			remaining++
+
+			if err := repositories.DeleteOrder.Process(ctx, order); err != nil {
+				skippedIDs[order.ID] = true
+				// ゴミ箱のLessonのように処理日時になっていないものは、失敗ではなく次回以降に処理する
+				if err == domain.DeleteOrderNotDue {
+					continue
+				}
+				if firstErr == nil {
+					firstErr = err
+				}
+				continue
+			}
+			processed++
+		}
+
+		// Userの削除でLessonのDeleteOrderが追加されるので、未処理のものがなくなるまで繰り返す
+		if remaining == 0 {
+			break
+		}
+	}
+
+	return processed, firstErr
+}
+
+// RefreshDeleteOrdersは、全てのDeleteOrderを保存し直し、保存した件数を返します。
+func RefreshDeleteOrders(ctx context.Context) (int, error) {
+	return repositories.DeleteOrder.RefreshAll(ctx)
+}
+
+// ProcessDeleteOrdersTaskは、DeleteOrderQueueのタスクを処理します。
+func ProcessDeleteOrdersTask(ctx context.Context, task infrastructure.Task) error {
+	_, err := ProcessDeleteOrders(ctx)
+	return err
+}
+
+// enqueueDeleteOrderTaskは、DeleteOrderの処理を予約します。
+// 失敗しても定期実行でDeleteOrderは処理されるので、エラーは記録のみ行います。
+func enqueueDeleteOrderTask(ctx context.Context) {
+	task := infrastructure.Task{Queue: domain.DeleteOrderQueue}
+	if err := infrastructure.EnqueueTask(ctx, task); err != nil {
+		log.Printf("failed to enqueue delete order task. %v\n", err)
+	}
+}
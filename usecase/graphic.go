@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"context"
 	"net/http"
 	"strconv"
 
@@ -10,17 +11,11 @@ import (
 )
 
 // GetGraphicByID is fetching a graphic by id.
-func GetGraphicByID(request *http.Request, id int64) (domain.Graphic, error) {
+// lessonIDを指定した場合は、共同編集者としてLessonの作成者のGraphicを取得します。
+func GetGraphicByID(request *http.Request, id int64, lessonID int64) (domain.Graphic, error) {
 	ctx := request.Context()
 
-	var graphic domain.Graphic
-
-	currentUser, err := domain.GetCurrentUser(request)
-	if err != nil {
-		return graphic, err
-	}
-
-	graphic, err = domain.GetGraphicByID(ctx, id, currentUser.ID)
+	graphic, _, err := getGraphicWithRole(ctx, request, id, lessonID, domain.LessonRoleViewer)
 	if err != nil {
 		return graphic, err
 	}
@@ -41,17 +36,28 @@ func GetGraphicsByLessonID(request *http.Request, lessonID int64) ([]*domain.Gra
 
 	var graphics []*domain.Graphic
 
-	if _, err := currentUserAccessToLesson(ctx, request, lessonID); err != nil {
+	if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleViewer); err != nil {
 		return nil, err
 	}
 
-	if err := domain.GetGraphicsByLessonID(ctx, lessonID, &graphics); err != nil {
+	if err := repositories.Graphic.GetByLessonID(ctx, lessonID, &graphics); err != nil {
 		return nil, err
 	}
 
 	return graphics, nil
 }
 
+// GetPublicLessonGraphicsは、公開中か、viewKeyのShareLinkで参照できる限定公開のLessonのGraphicのURLを返します。
+// 教材の再生中に呼ばれるので、ShareLinkの参照回数は増分しません。
+func GetPublicLessonGraphics(request *http.Request, lessonID int64, viewKey string, ids []string) (map[int64]string, error) {
+	lesson, err := getViewableLesson(request.Context(), lessonID, viewKey, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return GetGraphicsByLessonIDAndIDs(request, lessonID, lesson.UserID, ids)
+}
+
 func GetGraphicsByLessonIDAndIDs(request *http.Request, lessonID int64, userID int64, ids []string) (map[int64]string, error) {
 	ctx := request.Context()
 
@@ -64,7 +70,7 @@ func GetGraphicsByLessonIDAndIDs(request *http.Request, lessonID int64, userID i
 		intIDs[i] = intID
 	}
 
-	graphics, err := domain.GetGraphicsByIDs(ctx, userID, intIDs)
+	graphics, err := repositories.Graphic.GetByIDs(ctx, userID, intIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -98,7 +104,7 @@ func CreateGraphicsAndBlankFiles(request *http.Request, objectRequest infrastruc
 
 	var signedURLs infrastructure.SignedURLs
 
-	userID, err := currentUserAccessToLesson(ctx, request, objectRequest.LessonID)
+	lesson, err := currentUserAccessToLesson(ctx, request, objectRequest.LessonID, domain.LessonRoleEditor)
 	if err != nil {
 		return signedURLs, err
 	}
@@ -113,13 +119,14 @@ func CreateGraphicsAndBlankFiles(request *http.Request, objectRequest infrastruc
 		graphics[i] = graphic
 	}
 
-	if err = domain.CreateGraphics(ctx, userID, graphics); err != nil {
+	// 共同編集者がアップロードしたGraphicも、Lessonの作成者のものとして保存する
+	if err = repositories.Graphic.Create(ctx, lesson.UserID, graphics); err != nil {
 		return signedURLs, err
 	}
 
 	for i, fileRequest := range objectRequest.FileRequests {
 		fileID := strconv.FormatInt(graphics[i].ID, 10)
-		url, err := infrastructure.CreateBlankFileToGCS(ctx, fileID, "graphic", fileRequest)
+		url, err := infrastructure.CreateBlankFile(ctx, fileID, "graphic", fileRequest)
 		if err != nil {
 			return signedURLs, err
 		}
@@ -129,22 +136,48 @@ func CreateGraphicsAndBlankFiles(request *http.Request, objectRequest infrastruc
 	return infrastructure.SignedURLs{SignedURLs: urls}, nil
 }
 
-func DeleteGraphic(request *http.Request, id int64) error {
+// DeleteGraphicは、Graphicを削除します。lessonIDを指定した場合は、共同編集者としてLessonの作成者のGraphicを削除します。
+func DeleteGraphic(request *http.Request, id int64, lessonID int64) error {
 	ctx := request.Context()
 
-	currentUser, err := domain.GetCurrentUser(request)
+	graphic, ownerID, err := getGraphicWithRole(ctx, request, id, lessonID, domain.LessonRoleEditor)
 	if err != nil {
 		return err
 	}
 
-	graphic, err := domain.GetGraphicByID(ctx, id, currentUser.ID)
-	if err != nil {
+	if err := repositories.Graphic.DeleteByID(ctx, graphic.ID, ownerID); err != nil {
 		return err
 	}
 
-	if err := domain.DeleteGraphicByID(ctx, graphic.ID, currentUser.ID); err != nil {
-		return err
+	return nil
+}
+
+// getGraphicWithRoleは、Graphicとその所有者のIDを返します。lessonIDが0の場合は現在のユーザーのGraphicを、
+// それ以外はLessonに対してrequiredの権限がある場合に、Lessonの作成者のGraphicを返します。
+func getGraphicWithRole(ctx context.Context, request *http.Request, id int64, lessonID int64, required domain.LessonRole) (domain.Graphic, int64, error) {
+	var ownerID int64
+	if lessonID == 0 {
+		currentUser, err := repositories.User.GetCurrent(request)
+		if err != nil {
+			return domain.Graphic{}, ownerID, err
+		}
+		ownerID = currentUser.ID
+	} else {
+		lesson, err := currentUserAccessToLesson(ctx, request, lessonID, required)
+		if err != nil {
+			return domain.Graphic{}, ownerID, err
+		}
+		ownerID = lesson.UserID
 	}
 
-	return nil
+	graphic, err := repositories.Graphic.GetByID(ctx, id, ownerID)
+	if err != nil {
+		return graphic, ownerID, err
+	}
+
+	if lessonID != 0 && graphic.LessonID != lessonID {
+		return graphic, ownerID, domain.GraphicNotFound
+	}
+
+	return graphic, ownerID, nil
 }
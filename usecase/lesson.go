@@ -3,8 +3,9 @@ package usecase
 import (
 	"context"
 	"errors"
-	"fmt"
+	"log"
 	"net/http"
+	"time"
 
 	"cloud.google.com/go/datastore"
 	"github.com/jinzhu/copier"
@@ -43,39 +44,55 @@ type NewLessonParams struct {
 	Title              string `json:"title"`
 }
 
-// GetLessonsByCategoryID for search lessons
-func GetLessonsByCategoryID(request *http.Request, categoryID int64, cursorStr string) ([]domain.ShortLesson, string, error) {
+// GetLessonsは、filterに一致する公開中のLessonを、サムネイルのURLと作者名を含めた一覧として返します。
+func GetLessons(request *http.Request, filter *domain.LessonListFilter, cursorStr string) ([]domain.LessonSummary, string, error) {
 	ctx := request.Context()
-	lessons, nextCursorStr, err := domain.GetLessonsByCategoryID(ctx, cursorStr, categoryID)
+	lessons, nextCursorStr, err := repositories.Lesson.GetPublicList(ctx, filter, cursorStr)
 	if err != nil {
 		return nil, "", err
 	}
 
-	if len(lessons) == 0 {
+	if len(lessons) == 0 && nextCursorStr == "" {
 		return nil, "", LessonNotFound
 	}
 
-	return lessons, nextCursorStr, nil
+	var userIDs []int64
+	for _, lesson := range lessons {
+		if !containsID(userIDs, lesson.UserID) {
+			userIDs = append(userIDs, lesson.UserID)
+		}
+	}
+
+	authors, err := repositories.User.GetByIDs(ctx, userIDs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	summaries := make([]domain.LessonSummary, len(lessons))
+	for i := range lessons {
+		lesson := &lessons[i]
+		if err := domain.SetLessonThumbnailURL(ctx, lesson); err != nil {
+			return nil, "", err
+		}
+
+		summaries[i] = domain.NewLessonSummary(lesson, authors[lesson.UserID].Name)
+	}
+
+	return summaries, nextCursorStr, nil
 }
 
 // GetPublicLesson for fetch the lesson by id
+// 限定公開のLessonは、viewKeyのShareLinkが有効な場合のみ返し、ShareLinkの参照回数を増分します。
 func GetPublicLesson(request *http.Request, id int64, viewKey string) (domain.Lesson, error) {
 	ctx := request.Context()
 
-	lesson, err := domain.GetLessonByID(ctx, id)
-	if err == datastore.ErrNoSuchEntity {
-		return lesson, LessonNotFound
-	} else if err != nil {
+	lesson, err := getViewableLesson(ctx, id, viewKey, true)
+	if err != nil {
 		return lesson, err
 	}
 
-	if lesson.Status != domain.LessonStatusPublic && lesson.Status != domain.LessonStatusLimited {
-		return lesson, LessonNotAvailable
-	}
-
-	if lesson.Status == domain.LessonStatusLimited && lesson.ViewKey != viewKey {
-		return lesson, LessonNotAvailable
-	}
+	// レビューは作者とレビュアーのみが参照する
+	lesson.Reviews = nil
 
 	if err = setRelationLessonTitle(ctx, &lesson); err != nil {
 		return lesson, LessonNotAvailable
@@ -89,7 +106,7 @@ func GetPublicLesson(request *http.Request, id int64, viewKey string) (domain.Le
 		return lesson, err
 	}
 
-	author, err := domain.GetUserByID(ctx, lesson.UserID)
+	author, err := repositories.User.GetByID(ctx, lesson.UserID)
 	if err != nil {
 		return lesson, nil
 	}
@@ -98,26 +115,56 @@ func GetPublicLesson(request *http.Request, id int64, viewKey string) (domain.Le
 	return lesson, nil
 }
 
-func GetPrivateLesson(request *http.Request, id int64) (domain.Lesson, error) {
-	currentUser, err := domain.GetCurrentUser(request)
-	if err != nil {
-		lesson := new(domain.Lesson)
-		return *lesson, err
-	}
-
-	ctx := request.Context()
-	lesson, err := domain.GetLessonByID(ctx, id)
+// getViewableLessonは、公開中か、viewKeyのShareLinkで参照できる限定公開のLessonを返します。
+// countsAccessがtrueの場合は、ShareLinkの参照回数を増分します。
+func getViewableLesson(ctx context.Context, id int64, viewKey string, countsAccess bool) (domain.Lesson, error) {
+	lesson, err := repositories.Lesson.GetByID(ctx, id)
 	if err == datastore.ErrNoSuchEntity {
 		return lesson, LessonNotFound
 	} else if err != nil {
 		return lesson, err
 	}
 
-	if lesson.UserID != currentUser.ID {
+	switch lesson.Status {
+	case domain.LessonStatusPublic:
+		return lesson, nil
+	case domain.LessonStatusLimited:
+		if countsAccess {
+			err = repositories.ShareLink.Use(ctx, &lesson, viewKey, infrastructure.CurrentConfig().CountsLessonViews)
+		} else {
+			err = repositories.ShareLink.Validate(ctx, &lesson, viewKey)
+		}
+		if err == domain.ShareLinkUnavailable {
+			return lesson, LessonNotAvailable
+		}
+		return lesson, err
+	default:
+		return lesson, LessonNotAvailable
+	}
+}
+
+// GetPrivateLessonは、作成者と共同編集者に編集用のLessonを返します。
+func GetPrivateLesson(request *http.Request, id int64) (domain.Lesson, error) {
+	ctx := request.Context()
+
+	currentUser, lesson, role, err := currentUserRoleForLesson(ctx, request, id)
+	if err != nil {
+		return lesson, err
+	}
+
+	if !role.Includes(domain.LessonRoleViewer) {
 		return lesson, InvalidLessonParams
 	}
 
-	lesson.Author = currentUser
+	if role == domain.LessonRoleOwner {
+		lesson.Author = currentUser
+	} else {
+		author, err := repositories.User.GetByID(ctx, lesson.UserID)
+		if err != nil {
+			return lesson, err
+		}
+		lesson.Author = author
+	}
 
 	return lesson, nil
 }
@@ -125,33 +172,42 @@ func GetPrivateLesson(request *http.Request, id int64) (domain.Lesson, error) {
 func GetPublicLessonsByUser(request *http.Request, userID int64) ([]domain.Lesson, error) {
 	ctx := request.Context()
 
-	lessons, err := domain.GetPublicLessonsByUserID(ctx, userID)
+	lessons, err := repositories.Lesson.GetPublicByUserID(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
+	for i := range lessons {
+		lessons[i].Reviews = nil
+	}
+
 	return lessons, nil
 }
 
-func GetCurrentUserLessons(request *http.Request) ([]domain.Lesson, error) {
+// GetCurrentUserLessonsは、現在のユーザーのLessonをorderの順に返します。orderが空の場合は作成日時の新しい順です。
+func GetCurrentUserLessons(request *http.Request, order domain.OwnLessonOrder) ([]domain.Lesson, error) {
 	ctx := request.Context()
 
-	currentUser, err := domain.GetCurrentUser(request)
+	currentUser, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return nil, err
 	}
 
-	lessons, err := domain.GetLessonsByUserID(ctx, currentUser.ID)
+	lessons, err := repositories.Lesson.GetByUserID(ctx, currentUser.ID)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := domain.SortOwnLessons(lessons, order); err != nil {
+		return nil, err
+	}
+
 	return lessons, nil
 }
 
 // CreateLesson is create the new lesson belongs to subject and category.
 func CreateLesson(request *http.Request, newLesson *NewLessonParams, lesson *domain.Lesson) error {
-	currentUser, err := domain.GetCurrentUser(request)
+	currentUser, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return InvalidLessonParams
 	}
@@ -162,7 +218,7 @@ func CreateLesson(request *http.Request, newLesson *NewLessonParams, lesson *dom
 
 	lesson.UserID = currentUser.ID
 
-	if err = domain.CreateLesson(ctx, lesson); err != nil {
+	if err = repositories.Lesson.Create(ctx, lesson); err != nil {
 		return err
 	}
 
@@ -173,7 +229,7 @@ func CreateLesson(request *http.Request, newLesson *NewLessonParams, lesson *dom
 
 	lesson.MaterialID = materialID
 
-	if err := domain.UpdateLesson(ctx, lesson); err != nil {
+	if err := repositories.Lesson.Update(ctx, lesson); err != nil {
 		return err
 	}
 
@@ -185,7 +241,7 @@ func CreateLesson(request *http.Request, newLesson *NewLessonParams, lesson *dom
 // Graphicはユーザーによる削除が可能なので、重複制限を行わず、Graphic作成後にエラーが発生してもロールバックは試みません。
 // LessonMaterialも、Lessonの削除後に残り続けても実害はないのでロールバックは試みません。
 func CreateIntroductionLesson(request *http.Request, needsRecording bool, lesson *domain.Lesson) error {
-	currentUser, err := domain.GetCurrentUser(request)
+	currentUser, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return InvalidLessonParams
 	}
@@ -194,35 +250,35 @@ func CreateIntroductionLesson(request *http.Request, needsRecording bool, lesson
 
 	lesson.NeedsRecording = needsRecording
 
-	if err = domain.CreateIntroductionLesson(ctx, &currentUser, lesson); err != nil {
+	if err = repositories.Lesson.CreateIntroduction(ctx, &currentUser, lesson); err != nil {
 		return err
 	}
 
-	if err = domain.CreateIntroductionGraphics(ctx, currentUser.ID, lesson.ID); err != nil {
+	if err = repositories.Graphic.CreateIntroduction(ctx, currentUser.ID, lesson.ID); err != nil {
 		// エラー時はLessonを削除する。削除時のエラーは無視する。
-		domain.DeleteLesson(ctx, lesson.ID)
+		repositories.Lesson.Delete(ctx, lesson.ID)
 		return err
 	}
 
 	materialID, err := createInitialLessonMaterial(ctx, currentUser.ID, lesson.ID)
 	if err != nil {
 		// 同上
-		domain.DeleteLesson(ctx, lesson.ID)
+		repositories.Lesson.Delete(ctx, lesson.ID)
 		return err
 	}
 
 	lesson.MaterialID = materialID
 
-	if err := domain.UpdateLesson(ctx, lesson); err != nil {
+	if err := repositories.Lesson.Update(ctx, lesson); err != nil {
 		// 同上
-		domain.DeleteLesson(ctx, lesson.ID)
+		repositories.Lesson.Delete(ctx, lesson.ID)
 		return err
 	}
 
 	currentUser.IntroductionID = lesson.ID
-	if err := domain.UpdateUser(ctx, &currentUser); err != nil {
+	if err := repositories.User.Update(ctx, &currentUser); err != nil {
 		// 同上
-		domain.DeleteLesson(ctx, lesson.ID)
+		repositories.Lesson.Delete(ctx, lesson.ID)
 		return err
 	}
 
@@ -232,41 +288,101 @@ func CreateIntroductionLesson(request *http.Request, needsRecording bool, lesson
 func UpdateLessonWithMaterial(id int64, request *http.Request, needsCopyThumbnail bool, requestID string, params *map[string]interface{}) error {
 	ctx := request.Context()
 
-	currentUser, err := domain.GetCurrentUser(request)
+	currentUser, lesson, role, err := currentUserRoleForLesson(ctx, request, id)
 	if err != nil {
 		return err
 	}
 
-	lesson, err := domain.GetLessonByID(ctx, id)
-	if err != nil {
-		if err == datastore.ErrNoSuchEntity {
-			return LessonNotFound
-		}
-		return err
+	if !role.Includes(domain.LessonRoleEditor) {
+		return InvalidLessonParams
 	}
 
-	if lesson.UserID != currentUser.ID {
-		return InvalidLessonParams
+	// 公開状態とライセンス、コメントの受け付けは作成者のみが変更できる。UpdateWithMaterialは自己紹介の公開状態をUserに記録するので、作成者を渡す
+	author := currentUser
+	if role != domain.LessonRoleOwner {
+		for _, key := range []string{"status", "license", "commentsClosed"} {
+			if _, ok := (*params)[key]; ok {
+				return LessonNotAvailable
+			}
+		}
+
+		if author, err = repositories.User.GetByID(ctx, lesson.UserID); err != nil {
+			return err
+		}
 	}
 
-	lessonFields := []string{"PrevLessonID", "NextLessonID", "SubjectID", "JapaneseCategoryID", "Status", "HasThumbnail", "Title", "Description", "References"}
+	// 前後のLessonはSeriesで管理するので、PrevLessonIDとNextLessonIDは更新しない
+	lessonFields := []string{"SubjectID", "JapaneseCategoryID", "SecondaryCategoryIDs", "Tags", "Status", "License", "HasThumbnail", "Title", "Description", "References", "CommentsClosed"}
 	lessonMaterialFields := []string{"BackgroundImageID", "AvatarID", "AvatarLightColor", "VoiceSynthesisConfig"}
-	if err := domain.UpdateLessonAndMaterial(ctx, &currentUser, &lesson, needsCopyThumbnail, requestID, params, &lessonFields, &lessonMaterialFields); err != nil {
+	// 公開中の教材を編集者の変更で置き換えないよう、教材を公開し直すのは作成者の更新のみとする
+	republishes := role == domain.LessonRoleOwner
+	if err := repositories.Lesson.UpdateWithMaterial(ctx, &author, &lesson, needsCopyThumbnail, republishes, requestID, params, &lessonFields, &lessonMaterialFields); err != nil {
+		if err == domain.LessonMaterialNotPublishable {
+			return LessonMaterialHasErrors
+		}
 		return err
 	}
 
 	return nil
 }
 
+// DuplicateLessonは、Lessonを複製した現在のユーザーの下書きのLessonを作成します。
+// 他のユーザーのLessonは、公開中で改変を許可するライセンスの場合のみ複製できます。
+func DuplicateLesson(id int64, request *http.Request) (domain.Lesson, error) {
+	ctx := request.Context()
+
+	var duplicated domain.Lesson
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return duplicated, err
+	}
+
+	source, err := repositories.Lesson.GetByID(ctx, id)
+	if err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return duplicated, LessonNotFound
+		}
+		return duplicated, err
+	}
+
+	if source.IsIntroduction {
+		return duplicated, LessonNotAvailable
+	}
+
+	if source.UserID != currentUser.ID && (source.Status != domain.LessonStatusPublic || !source.License.PermitsDerivatives()) {
+		return duplicated, LessonNotAvailable
+	}
+
+	if err := repositories.LessonDuplicate.Duplicate(ctx, currentUser.ID, &source, &duplicated); err != nil {
+		if duplicated.ID != 0 {
+			// 作成途中のLessonと複製済みのGraphicやVoiceは、DeleteOrderでまとめて削除する
+			if deleteErr := deleteLessonWithOrder(ctx, duplicated.ID); deleteErr == nil {
+				enqueueDeleteOrderTask(ctx)
+			}
+		}
+		if errors.Is(err, domain.LessonVersionNotPublished) {
+			// 公開済みの内容がないLessonは、編集中の内容を複製せずに拒否する
+			return duplicated, LessonNotAvailable
+		}
+		return duplicated, err
+	}
+
+	duplicated.Author = currentUser
+
+	return duplicated, nil
+}
+
+// DeleteLessonAndResourcesは、現在のユーザーのLessonをゴミ箱に移動します。
+// 関連するエンティティとファイルは、復元できる期間が過ぎた後にDeleteOrderで削除されます。
 func DeleteLessonAndResources(id int64, request *http.Request) error {
 	ctx := request.Context()
 
-	currentUser, err := domain.GetCurrentUser(request)
+	currentUser, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return err
 	}
 
-	lesson, err := domain.GetLessonByID(ctx, id)
+	lesson, err := repositories.Lesson.GetByID(ctx, id)
 	if err != nil {
 		if err == datastore.ErrNoSuchEntity {
 			return LessonNotFound
@@ -278,33 +394,58 @@ func DeleteLessonAndResources(id int64, request *http.Request) error {
 		return LessonNotAvailable
 	}
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
+	var trashed domain.Lesson
+	err = repositories.Transaction.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		var err error
+		trashed, err = repositories.LessonTrash.MoveToTrashInTransaction(tx, id, time.Now())
+		return err
+	})
+
 	if err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return LessonNotFound
+		}
 		return err
 	}
 
-	_, err = client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
-		if err := domain.DeleteLessonInTransaction(tx, id); err != nil {
-			return err
+	if trashed.StatusBeforeDeletion == domain.LessonStatusPublic {
+		// Lessonはゴミ箱に移動済みなので、Tagの件数と検索インデックスの更新に失敗しても削除は成功とする。
+		// どちらもrebuild-lesson-listsとreindex-lessonsで作り直せる
+		if err := repositories.Tag.Apply(ctx, trashed.Tags, nil); err != nil {
+			log.Printf("failed to update tags of deleted lesson. %v\n", err)
 		}
-
-		if err := domain.CreateDeleteLessonOrderInTransaction(tx, id); err != nil {
-			return err
+		if err := repositories.LessonSearch.Remove(ctx, id); err != nil {
+			log.Printf("failed to remove deleted lesson from search index. %v\n", err)
 		}
+	}
 
-		return nil
+	return nil
+}
+
+func deleteLessonWithOrder(ctx context.Context, id int64) error {
+	return repositories.Transaction.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		return deleteLessonWithOrderInTransaction(tx, id)
 	})
+}
 
-	if err != nil {
+// deleteLessonWithOrderInTransactionは、Lessonを削除し、関連するエンティティとファイルの削除をDeleteOrderとして予約します。
+func deleteLessonWithOrderInTransaction(tx infrastructure.Transaction, id int64) error {
+	if err := repositories.Lesson.DeleteInTransaction(tx, id); err != nil {
 		return err
 	}
 
-	return nil
+	return repositories.DeleteOrder.CreateLessonOrderInTransaction(tx, id)
 }
 
+// setRelationLessonTitleは、前後のLessonのIDとタイトルを設定します。Seriesに所属している場合は、Series内での位置も設定します。
 func setRelationLessonTitle(ctx context.Context, lesson *domain.Lesson) error {
+	if lesson.SeriesID != 0 {
+		return setSeriesPosition(ctx, lesson)
+	}
+
+	// Seriesに所属していないLessonは、以前のPrevLessonIDとNextLessonIDで前後を決める
 	if lesson.PrevLessonID != 0 {
-		prevLesson, err := domain.GetLessonByID(ctx, lesson.PrevLessonID)
+		prevLesson, err := repositories.Lesson.GetByID(ctx, lesson.PrevLessonID)
 		if err != nil {
 			if err == datastore.ErrNoSuchEntity {
 				return nil // 授業が見つからなかった場合もエラーにしない
@@ -315,7 +456,7 @@ func setRelationLessonTitle(ctx context.Context, lesson *domain.Lesson) error {
 	}
 
 	if lesson.NextLessonID != 0 {
-		nextLesson, err := domain.GetLessonByID(ctx, lesson.NextLessonID)
+		nextLesson, err := repositories.Lesson.GetByID(ctx, lesson.NextLessonID)
 		if err != nil {
 			if err == datastore.ErrNoSuchEntity {
 				return nil // 授業が見つからなかった場合もエラーにしない
@@ -328,15 +469,59 @@ func setRelationLessonTitle(ctx context.Context, lesson *domain.Lesson) error {
 	return nil
 }
 
+// setSeriesPositionは、Seriesの公開中のLessonの中での位置と前後のLessonを設定します。Seriesが削除済みの場合は何もしません。
+func setSeriesPosition(ctx context.Context, lesson *domain.Lesson) error {
+	series, err := repositories.Series.GetByID(ctx, lesson.SeriesID)
+	if err != nil {
+		if errors.Is(err, domain.SeriesNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	lessons, err := repositories.Series.GetPublicLessons(ctx, &series)
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i, seriesLesson := range lessons {
+		if seriesLesson.ID == lesson.ID {
+			index = i
+			break
+		}
+	}
+
+	// 限定公開のLessonは公開中のLessonに含まれないので、前後を設定しない
+	lesson.PrevLessonID = 0
+	lesson.NextLessonID = 0
+	lesson.Series = &domain.LessonSeriesPosition{ID: series.ID, Title: series.Title, LessonCount: len(lessons)}
+	if index == -1 {
+		return nil
+	}
+
+	lesson.Series.Position = index + 1
+	if index > 0 {
+		lesson.PrevLessonID = lessons[index-1].ID
+		lesson.PrevLessonTitle = lessons[index-1].Title
+	}
+	if index < len(lessons)-1 {
+		lesson.NextLessonID = lessons[index+1].ID
+		lesson.NextLessonTitle = lessons[index+1].Title
+	}
+
+	return nil
+}
+
 func setAvatar(ctx context.Context, lesson *domain.Lesson) error {
 	if lesson.AvatarID == 0 {
 		return nil
 	}
 
-	avatar, err := domain.GetPublicAvatarByID(ctx, lesson.AvatarID)
+	avatar, err := repositories.Avatar.GetPublicByID(ctx, lesson.AvatarID)
 	if err != nil {
 		if ok := errors.Is(err, domain.AvatarNotFound); ok {
-			avatar, err = domain.GetCurrentUsersAvatarByID(ctx, lesson.AvatarID, lesson.UserID)
+			avatar, err = repositories.Avatar.GetCurrentUsersByID(ctx, lesson.AvatarID, lesson.UserID)
 			if err != nil {
 				return err
 			}
@@ -351,38 +536,69 @@ func setAvatar(ctx context.Context, lesson *domain.Lesson) error {
 }
 
 func setResourceURLs(ctx context.Context, lesson *domain.Lesson) error {
-	speechFilePath := fmt.Sprintf("lesson/%d/speech-%d.mp3", lesson.ID, lesson.Version)
-	bodyFilePath := fmt.Sprintf("lesson/%d/body-%d.zst", lesson.ID, lesson.Version)
-
-	if lesson.Status == domain.LessonStatusPublic {
-		lesson.SpeechURL = infrastructure.CloudStorageURL + infrastructure.PublicBucketName() + "/" + speechFilePath
-		lesson.BodyURL = infrastructure.CloudStorageURL + infrastructure.PublicBucketName() + "/" + bodyFilePath
-	} else if lesson.Status == domain.LessonStatusLimited {
-		fileType := "" // this is unnecessary when GET request
-		bucketName := infrastructure.MaterialBucketName()
-
-		var speechURL string
-		var bodyURL string
-		var err error
+	if lesson.Status == domain.LessonStatusDraft {
+		return nil
+	}
 
-		g, ctx := errgroup.WithContext(ctx)
-		g.Go(func() error {
-			speechURL, err = infrastructure.GetGCSSignedURL(ctx, bucketName, speechFilePath, "GET", fileType)
-			return err
-		})
+	speechURL, bodyURL, err := lessonResourceURLs(ctx, lesson.ID, lesson.Version, lesson.Status)
+	if err != nil {
+		return err
+	}
 
-		g.Go(func() error {
-			bodyURL, err = infrastructure.GetGCSSignedURL(ctx, bucketName, bodyFilePath, "GET", fileType)
-			return err
-		})
+	lesson.SpeechURL = speechURL
+	lesson.BodyURL = bodyURL
+
+	return nil
+}
+
+// lessonResourceURLsは、statusの状態で公開されたversionの音声と教材のURLを返します。音声のないLessonもあるので、音声ファイルがなければ音声のURLは空になります。
+func lessonResourceURLs(ctx context.Context, lessonID int64, version int32, status domain.LessonStatus) (string, string, error) {
+	speechFilePath := domain.LessonSpeechFilePath(lessonID, version)
+	bodyFilePath := domain.LessonBodyFilePath(lessonID, version)
+	bucketName := domain.LessonBucketName(status)
+
+	var speechURL string
+	var bodyURL string
 
-		if err := g.Wait(); err != nil {
+	g, ctx := errgroup.WithContext(ctx)
+	g.Go(func() error {
+		exists, err := infrastructure.FileExists(ctx, bucketName, speechFilePath)
+		if err != nil || !exists {
 			return err
 		}
 
-		lesson.SpeechURL = speechURL
-		lesson.BodyURL = bodyURL
+		speechURL, err = lessonResourceURL(ctx, bucketName, speechFilePath, status)
+		return err
+	})
+
+	g.Go(func() error {
+		var err error
+		bodyURL, err = lessonResourceURL(ctx, bucketName, bodyFilePath, status)
+		return err
+	})
+
+	if err := g.Wait(); err != nil {
+		return "", "", err
 	}
 
-	return nil
+	return speechURL, bodyURL, nil
+}
+
+// lessonResourceURLは、公開中のLessonのファイルは公開URLを、限定公開のLessonのファイルは署名付きURLを返します。
+func lessonResourceURL(ctx context.Context, bucketName string, filePath string, status domain.LessonStatus) (string, error) {
+	if status == domain.LessonStatusPublic {
+		return infrastructure.PublicURL(bucketName, filePath), nil
+	}
+
+	fileType := "" // this is unnecessary when GET request
+	return infrastructure.GetSignedURL(ctx, bucketName, filePath, "GET", fileType)
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, i := range ids {
+		if i == id {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,67 @@
+package usecase
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+// ExportLessonは、現在のユーザーのLessonを教材と参照するファイルを含めたアーカイブにして返します。
+func ExportLesson(request *http.Request, id int64) ([]byte, error) {
+	ctx := request.Context()
+
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return nil, err
+	}
+
+	lesson, err := repositories.Lesson.GetByID(ctx, id)
+	if err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return nil, LessonNotFound
+		}
+		return nil, err
+	}
+
+	if lesson.UserID != currentUser.ID {
+		return nil, LessonNotAvailable
+	}
+
+	return repositories.LessonBundle.Export(ctx, &lesson)
+}
+
+// ImportLessonは、リクエストボディのアーカイブから現在のユーザーの下書きのLessonを作成します。
+func ImportLesson(request *http.Request) (domain.Lesson, error) {
+	ctx := request.Context()
+
+	var lesson domain.Lesson
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return lesson, err
+	}
+
+	data, err := ioutil.ReadAll(io.LimitReader(request.Body, domain.LessonBundleMaxSize+1))
+	if err != nil {
+		return lesson, err
+	}
+	if len(data) > domain.LessonBundleMaxSize {
+		return lesson, domain.LessonBundleTooLarge
+	}
+
+	if err := repositories.LessonBundle.Import(ctx, currentUser.ID, data, &lesson); err != nil {
+		if lesson.ID != 0 {
+			// 作成途中のLessonとインポート済みのGraphicやVoiceは、DeleteOrderでまとめて削除する
+			if deleteErr := deleteLessonWithOrder(ctx, lesson.ID); deleteErr == nil {
+				enqueueDeleteOrderTask(ctx)
+			}
+		}
+		return lesson, err
+	}
+
+	lesson.Author = currentUser
+
+	return lesson, nil
+}
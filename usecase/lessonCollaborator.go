@@ -0,0 +1,101 @@
+package usecase
+
+import (
+	"net/http"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+// LessonCollaboratorParamsは、共同編集者の招待時、リクエストボディをbindするために使用されます。
+type LessonCollaboratorParams struct {
+	UserID int64             `json:"userID"`
+	Role   domain.LessonRole `json:"role"`
+}
+
+// GetLessonCollaboratorsは、Lessonの共同編集者の一覧を返します。閲覧以上の権限が必要です。
+func GetLessonCollaborators(request *http.Request, lessonID int64) ([]domain.LessonCollaborator, error) {
+	ctx := request.Context()
+
+	if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleViewer); err != nil {
+		return nil, err
+	}
+
+	return repositories.LessonCollaborator.GetByLessonID(ctx, lessonID)
+}
+
+// InviteLessonCollaboratorは、現在のユーザーのLessonにUserを共同編集者として招待します。招待済みの場合は権限を変更します。
+func InviteLessonCollaborator(request *http.Request, lessonID int64, params *LessonCollaboratorParams) (domain.LessonCollaborator, error) {
+	ctx := request.Context()
+
+	var collaborator domain.LessonCollaborator
+	lesson, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleOwner)
+	if err != nil {
+		return collaborator, err
+	}
+
+	return repositories.LessonCollaborator.Invite(ctx, &lesson, params.UserID, params.Role)
+}
+
+// RemoveLessonCollaboratorは、現在のユーザーのLessonから共同編集者を外します。
+func RemoveLessonCollaborator(request *http.Request, lessonID int64, userID int64) error {
+	ctx := request.Context()
+
+	if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleOwner); err != nil {
+		return err
+	}
+
+	return repositories.LessonCollaborator.Remove(ctx, lessonID, userID)
+}
+
+// AcceptLessonCollaborationは、現在のユーザーへのLessonの共同編集の招待を承諾します。
+func AcceptLessonCollaboration(request *http.Request, lessonID int64) (domain.LessonCollaborator, error) {
+	var collaborator domain.LessonCollaborator
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return collaborator, err
+	}
+
+	return repositories.LessonCollaborator.Accept(request.Context(), lessonID, currentUser.ID)
+}
+
+// LeaveLessonCollaborationは、現在のユーザーがLessonの共同編集から抜けるか、招待を辞退します。
+func LeaveLessonCollaboration(request *http.Request, lessonID int64) error {
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return err
+	}
+
+	return repositories.LessonCollaborator.Remove(request.Context(), lessonID, currentUser.ID)
+}
+
+// GetCurrentUserCollaborationsは、現在のユーザーが招待されているか参加しているLessonの一覧を、Lessonのタイトルとともに返します。
+func GetCurrentUserCollaborations(request *http.Request) ([]domain.LessonCollaborator, error) {
+	ctx := request.Context()
+
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return nil, err
+	}
+
+	collaborators, err := repositories.LessonCollaborator.GetByUserID(ctx, currentUser.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]domain.LessonCollaborator, 0, len(collaborators))
+	for _, collaborator := range collaborators {
+		lesson, err := repositories.Lesson.GetByID(ctx, collaborator.LessonID)
+		if err != nil {
+			// 削除中のLessonは、DeleteOrderで共同編集者も削除されるので一覧には含めない
+			if err == datastore.ErrNoSuchEntity {
+				continue
+			}
+			return nil, err
+		}
+		collaborator.LessonTitle = lesson.Title
+		results = append(results, collaborator)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,189 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+// LessonCommentParamsは、LessonCommentの作成時と変更時、リクエストボディをbindするために使用されます。
+type LessonCommentParams struct {
+	Body        string   `json:"body"`
+	ElapsedTime *float32 `json:"elapsedTime"` // 指定した場合は教材の再生時刻に紐付ける。スレッドの先頭のみ
+}
+
+// LessonCommentAnswerParamsは、回答の選択時、リクエストボディをbindするために使用されます。
+type LessonCommentAnswerParams struct {
+	AnswerID int64 `json:"answerID"`
+}
+
+// GetLessonCommentsは、公開中のLessonのparentIDのスレッドへの返信を返します。parentIDが0の場合はスレッドの先頭を返します。
+// includesHiddenがtrueの場合は、Lessonの作者のみが非表示のものを含めて取得できます。
+func GetLessonComments(request *http.Request, lessonID int64, parentID int64, includesHidden bool, pageSize int, cursorStr string) ([]domain.LessonComment, string, error) {
+	ctx := request.Context()
+
+	if includesHidden {
+		if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleOwner); err != nil {
+			return nil, "", err
+		}
+	} else if _, err := getPublicLessonByID(ctx, lessonID); err != nil {
+		return nil, "", err
+	}
+
+	if parentID != 0 {
+		parent, err := repositories.LessonComment.GetByID(ctx, lessonID, parentID)
+		if err != nil {
+			return nil, "", err
+		}
+		if parent.ParentID != 0 || (parent.Hidden && !includesHidden) {
+			return nil, "", domain.LessonCommentNotFound
+		}
+	}
+
+	comments, nextCursorStr, err := repositories.LessonComment.GetList(ctx, lessonID, parentID, includesHidden, pageSize, cursorStr)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := setLessonCommentAuthorNames(ctx, comments); err != nil {
+		return nil, "", err
+	}
+
+	return comments, nextCursorStr, nil
+}
+
+// CreateLessonCommentは、公開中のLessonに現在のユーザーのLessonCommentを作成します。parentIDが0以外の場合は、そのスレッドへの返信です。
+func CreateLessonComment(request *http.Request, lessonID int64, parentID int64, params *LessonCommentParams) (domain.LessonComment, error) {
+	ctx := request.Context()
+
+	comment := domain.LessonComment{ParentID: parentID, Body: params.Body}
+	if params.ElapsedTime != nil {
+		comment.HasElapsedTime = true
+		comment.ElapsedTime = *params.ElapsedTime
+	}
+
+	currentUser, lesson, err := currentUserAndPublicLesson(ctx, request, lessonID)
+	if err != nil {
+		return comment, err
+	}
+	comment.UserID = currentUser.ID
+
+	if err := repositories.LessonComment.Create(ctx, &lesson, &comment); err != nil {
+		return comment, err
+	}
+	comment.AuthorName = currentUser.Name
+
+	return comment, nil
+}
+
+// UpdateLessonCommentは、現在のユーザーのLessonCommentの本文を変更します。
+func UpdateLessonComment(request *http.Request, lessonID int64, id int64, params *LessonCommentParams) (domain.LessonComment, error) {
+	ctx := request.Context()
+
+	currentUser, _, err := currentUserAndPublicLesson(ctx, request, lessonID)
+	if err != nil {
+		return domain.LessonComment{}, err
+	}
+
+	comment, err := repositories.LessonComment.GetByID(ctx, lessonID, id)
+	if err != nil {
+		return comment, err
+	}
+	if comment.UserID != currentUser.ID {
+		return comment, LessonNotAvailable
+	}
+
+	if comment, err = repositories.LessonComment.UpdateBody(ctx, lessonID, id, params.Body); err != nil {
+		return comment, err
+	}
+	comment.AuthorName = currentUser.Name
+
+	return comment, nil
+}
+
+// DeleteLessonCommentは、LessonCommentを削除します。LessonCommentの投稿者とLessonの作者が削除できます。
+func DeleteLessonComment(request *http.Request, lessonID int64, id int64) error {
+	ctx := request.Context()
+
+	currentUser, lesson, role, err := currentUserRoleForLesson(ctx, request, lessonID)
+	if err != nil {
+		return err
+	}
+
+	comment, err := repositories.LessonComment.GetByID(ctx, lessonID, id)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != currentUser.ID && !role.Includes(domain.LessonRoleOwner) {
+		return LessonNotAvailable
+	}
+	if role != domain.LessonRoleOwner && lesson.Status != domain.LessonStatusPublic {
+		return LessonNotAvailable
+	}
+
+	return repositories.LessonComment.Delete(ctx, lessonID, id)
+}
+
+// HideLessonCommentは、Lessonの作者がLessonCommentを非表示にするか、再表示します。
+func HideLessonComment(request *http.Request, lessonID int64, id int64, hidden bool) (domain.LessonComment, error) {
+	ctx := request.Context()
+
+	if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleOwner); err != nil {
+		return domain.LessonComment{}, err
+	}
+
+	comment, err := repositories.LessonComment.SetHidden(ctx, lessonID, id, hidden)
+	if err != nil {
+		return comment, err
+	}
+
+	comments := []domain.LessonComment{comment}
+	if err := setLessonCommentAuthorNames(ctx, comments); err != nil {
+		return comment, err
+	}
+
+	return comments[0], nil
+}
+
+// SetLessonCommentAnswerは、Lessonの作者がスレッドへの返信のanswerIDを回答に選びます。answerIDが0の場合は選択を解除します。
+func SetLessonCommentAnswer(request *http.Request, lessonID int64, id int64, answerID int64) (domain.LessonComment, error) {
+	ctx := request.Context()
+
+	if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleOwner); err != nil {
+		return domain.LessonComment{}, err
+	}
+
+	comment, err := repositories.LessonComment.SetAnswer(ctx, lessonID, id, answerID)
+	if err != nil {
+		return comment, err
+	}
+
+	comments := []domain.LessonComment{comment}
+	if err := setLessonCommentAuthorNames(ctx, comments); err != nil {
+		return comment, err
+	}
+
+	return comments[0], nil
+}
+
+// setLessonCommentAuthorNamesは、LessonCommentに投稿者の名前を設定します。
+func setLessonCommentAuthorNames(ctx context.Context, comments []domain.LessonComment) error {
+	var userIDs []int64
+	for _, comment := range comments {
+		if !containsID(userIDs, comment.UserID) {
+			userIDs = append(userIDs, comment.UserID)
+		}
+	}
+
+	authors, err := repositories.User.GetByIDs(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range comments {
+		comments[i].AuthorName = authors[comments[i].UserID].Name
+	}
+
+	return nil
+}
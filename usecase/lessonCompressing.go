@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"context"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// CompressLessonMaterialは、圧縮タスクを処理します。タスク名がLessonMaterialForCompressingのIDです。
+func CompressLessonMaterial(ctx context.Context, task infrastructure.Task) error {
+	var payload domain.LessonCompressingTask
+	if err := task.DecodePayload(&payload); err != nil {
+		return err
+	}
+
+	if payload.LessonID == 0 {
+		// Payloadを持たない以前の形式のタスクは、タスク名からLessonのIDを取り出す
+		lessonID, err := domain.LessonIDFromCompressingTaskName(task.Name)
+		if err != nil {
+			return err
+		}
+		payload.LessonID = lessonID
+	}
+
+	return repositories.LessonCompressing.Compress(ctx, task.Name, payload.LessonID)
+}
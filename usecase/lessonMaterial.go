@@ -22,6 +22,7 @@ type NewLessonMaterialParams struct {
 	Graphics             []domain.LessonGraphic      `json:"graphics"`
 	Musics               []domain.LessonMusic        `json:"musics"`
 	Speeches             []domain.LessonSpeech       `json:"speeches"`
+	Quizzes              []domain.LessonQuiz         `json:"quizzes"`
 }
 
 type LessonMaterialErrorCode uint
@@ -29,6 +30,7 @@ type LessonMaterialErrorCode uint
 const (
 	LessonMaterialNotAvailable LessonMaterialErrorCode = 1
 	LessonMaterialNotFound     LessonMaterialErrorCode = 2
+	LessonMaterialHasErrors    LessonMaterialErrorCode = 3
 )
 
 func (e LessonMaterialErrorCode) Error() string {
@@ -37,6 +39,8 @@ func (e LessonMaterialErrorCode) Error() string {
 		return "lesson material not available"
 	case LessonMaterialNotFound:
 		return "lesson material not found"
+	case LessonMaterialHasErrors:
+		return "lesson material has errors"
 	default:
 		return "unknown lesson error"
 	}
@@ -46,12 +50,12 @@ func GetLessonMaterial(request *http.Request, id int64, lessonID int64) (domain.
 	ctx := request.Context()
 
 	var lessonMaterial domain.LessonMaterial
-	userID, err := currentUserAccessToLesson(ctx, request, lessonID)
+	lesson, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleViewer)
 	if err != nil {
 		return lessonMaterial, LessonMaterialNotAvailable
 	}
 
-	if err := domain.GetLessonMaterial(ctx, id, lessonID, &lessonMaterial); err != nil {
+	if err := repositories.LessonMaterial.Get(ctx, id, lessonID, &lessonMaterial); err != nil {
 		if err == datastore.ErrNoSuchEntity {
 			return lessonMaterial, LessonMaterialNotFound
 		} else {
@@ -60,10 +64,10 @@ func GetLessonMaterial(request *http.Request, id int64, lessonID int64) (domain.
 	}
 
 	if lessonMaterial.AvatarID != 0 {
-		avatar, err := domain.GetPublicAvatarByID(ctx, lessonMaterial.AvatarID)
+		avatar, err := repositories.Avatar.GetPublicByID(ctx, lessonMaterial.AvatarID)
 		if err != nil {
 			if ok := errors.Is(err, domain.AvatarNotFound); ok {
-				avatar, err = domain.GetCurrentUsersAvatarByID(ctx, lessonMaterial.AvatarID, userID)
+				avatar, err = repositories.Avatar.GetCurrentUsersByID(ctx, lessonMaterial.AvatarID, lesson.UserID)
 				if err != nil {
 					return lessonMaterial, err
 				}
@@ -81,50 +85,65 @@ func GetLessonMaterial(request *http.Request, id int64, lessonID int64) (domain.
 func UpdateLessonMaterial(request *http.Request, id int64, lessonID int64, params *map[string]interface{}) error {
 	ctx := request.Context()
 
-	currentUser, err := domain.GetCurrentUser(request)
+	lesson, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleEditor)
 	if err != nil {
-		return err
-	}
-
-	lesson, err := domain.GetLessonByID(ctx, lessonID)
-	if err != nil {
-		if err == datastore.ErrNoSuchEntity {
-			return LessonNotFound
+		if err == LessonNotAvailable {
+			return LessonMaterialNotAvailable
 		}
 		return err
 	}
 
-	if lesson.UserID != currentUser.ID {
-		return LessonMaterialNotAvailable
-	}
-
 	if id != lesson.MaterialID {
 		return LessonMaterialNotAvailable
 	}
 
-	targetFields := []string{"DurationSec", "Avatars", "Drawings", "Embeddings", "Graphics", "Musics", "Speeches"}
-	if err := domain.UpdateLessonMaterial(ctx, id, lessonID, params, &targetFields); err != nil {
+	targetFields := []string{"DurationSec", "Avatars", "Drawings", "Embeddings", "Graphics", "Musics", "Speeches", "Quizzes"}
+	if err := repositories.LessonMaterial.Update(ctx, id, lessonID, params, &targetFields); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// ValidateLessonMaterialは、LessonのLessonMaterialが公開できる状態かを検証します。Lessonの編集者のみが検証できます。
+func ValidateLessonMaterial(request *http.Request, lessonID int64) (domain.LessonMaterialValidation, error) {
+	ctx := request.Context()
+
+	lesson, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleEditor)
+	if err != nil {
+		return domain.LessonMaterialValidation{}, err
+	}
+
+	return validateLessonMaterial(ctx, &lesson)
+}
+
+func validateLessonMaterial(ctx context.Context, lesson *domain.Lesson) (domain.LessonMaterialValidation, error) {
+	var lessonMaterial domain.LessonMaterial
+	if err := repositories.LessonMaterial.Get(ctx, lesson.MaterialID, lesson.ID, &lessonMaterial); err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return domain.LessonMaterialValidation{}, LessonMaterialNotFound
+		}
+		return domain.LessonMaterialValidation{}, err
+	}
+
+	return repositories.LessonMaterial.Validate(ctx, lesson.ID, &lessonMaterial)
+}
+
 func createInitialLessonMaterial(ctx context.Context, userID int64, lessonID int64) (int64, error) {
 	var materialID int64
 
-	avatars, err := domain.GetPublicAvatars(ctx) // 数が少ないので全件取得して1件使用する
+	avatars, err := repositories.Avatar.GetPublic(ctx) // 数が少ないので全件取得して1件使用する
 	if err != nil {
 		return materialID, err
 	}
 	avatarID := avatars[0].ID
 
-	backgroundImage, err := domain.GetBackgroundImage(ctx)
+	backgroundImage, err := repositories.BackgroundImage.GetOne(ctx)
 	if err != nil {
 		return materialID, err
 	}
 
-	materialID, err = domain.CreateInitialLessonMaterial(ctx, userID, avatarID, backgroundImage.ID, lessonID)
+	materialID, err = repositories.LessonMaterial.CreateInitial(ctx, userID, avatarID, backgroundImage.ID, lessonID)
 	if err != nil {
 		return materialID, err
 	}
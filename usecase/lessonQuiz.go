@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"net/http"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+// LessonQuizAnswerParamsは、LessonQuizへの回答時、リクエストボディをbindするために使用されます。
+type LessonQuizAnswerParams struct {
+	Version int32 `json:"version"` // 回答した教材のバージョン。0の場合は公開中のバージョン
+	domain.LessonQuizAnswer
+}
+
+// AnswerLessonQuizは、閲覧できるLessonの教材のindex番目のLessonQuizへの回答を採点します。
+// 採点には公開時に記録したLessonVersionの教材を使用するので、公開用の教材に正解が含まれていなくても採点できます。
+func AnswerLessonQuiz(request *http.Request, lessonID int64, index int, viewKey string, params *LessonQuizAnswerParams) (domain.LessonQuizResult, error) {
+	ctx := request.Context()
+
+	lesson, err := getViewableLesson(ctx, lessonID, viewKey, false)
+	if err != nil {
+		return domain.LessonQuizResult{}, err
+	}
+
+	version := params.Version
+	if version == 0 {
+		version = lesson.Version
+	}
+	if version < 1 || version > lesson.Version {
+		return domain.LessonQuizResult{}, domain.LessonQuizNotFound
+	}
+
+	lessonVersion, err := repositories.LessonVersion.Get(ctx, lessonID, version)
+	if err != nil {
+		if err == domain.LessonVersionNotFound {
+			return domain.LessonQuizResult{}, domain.LessonQuizNotFound
+		}
+		return domain.LessonQuizResult{}, err
+	}
+
+	quizzes := lessonVersion.Material.Quizzes
+	if index < 0 || index >= len(quizzes) {
+		return domain.LessonQuizResult{}, domain.LessonQuizNotFound
+	}
+
+	return quizzes[index].Check(&params.LessonQuizAnswer)
+}
@@ -0,0 +1,33 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+// GetLessonRankingは、定時バッチで作成された公開中のLessonのランキングを返します。
+// subjectIDとcategoryIDのどちらかを指定した場合はその範囲の、どちらも0の場合は全体のランキングです。
+func GetLessonRanking(request *http.Request, period domain.LessonRankingPeriod, subjectID int64, categoryID int64) (domain.LessonRanking, error) {
+	if period == "" {
+		period = domain.LessonRankingPeriodDaily
+	}
+
+	scope, scopeID := domain.LessonRankingScopeOverall, int64(0)
+	if subjectID != 0 && categoryID != 0 {
+		return domain.LessonRanking{}, domain.InvalidLessonRanking
+	} else if subjectID != 0 {
+		scope, scopeID = domain.LessonRankingScopeSubject, subjectID
+	} else if categoryID != 0 {
+		scope, scopeID = domain.LessonRankingScopeCategory, categoryID
+	}
+
+	return repositories.LessonRanking.Get(request.Context(), period, scope, scopeID)
+}
+
+// ComputeLessonRankingsは、全ての期間と範囲のランキングを作成し直し、作成したランキングの数を返します。
+func ComputeLessonRankings(ctx context.Context) (int, error) {
+	return repositories.LessonRanking.Compute(ctx, time.Now())
+}
@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+// LessonRatingParamsは、Lessonの評価時、リクエストボディをbindするために使用されます。
+type LessonRatingParams struct {
+	Rating int `json:"rating"`
+}
+
+// GetLessonReactionは、公開中のLessonに対する現在のユーザーのいいねと評価を返します。
+func GetLessonReaction(request *http.Request, lessonID int64) (domain.LessonReaction, error) {
+	ctx := request.Context()
+
+	currentUser, _, err := currentUserAndPublicLesson(ctx, request, lessonID)
+	if err != nil {
+		return domain.LessonReaction{}, err
+	}
+
+	return repositories.LessonReaction.Get(ctx, lessonID, currentUser.ID)
+}
+
+// LikeLessonは、公開中のLessonに現在のユーザーのいいねを設定します。likedがfalseの場合は、いいねを解除します。
+func LikeLesson(request *http.Request, lessonID int64, liked bool) (domain.LessonReaction, error) {
+	ctx := request.Context()
+
+	currentUser, lesson, err := currentUserAndPublicLesson(ctx, request, lessonID)
+	if err != nil {
+		return domain.LessonReaction{}, err
+	}
+
+	reaction, err := repositories.LessonReaction.Like(ctx, &lesson, currentUser.ID, liked)
+	if err != nil {
+		return reaction, err
+	}
+
+	// 加算に失敗してもいいねと評価は保存済みで、増分は次回の変更時か集計時に改めて加算される
+	if err := repositories.LessonReaction.ApplyPending(ctx, &reaction, infrastructure.CurrentConfig().CountsLessonReactions); err != nil {
+		log.Printf("failed to apply pending lesson reaction. %v\n", err)
+	}
+
+	return reaction, nil
+}
+
+// RateLessonは、公開中のLessonに現在のユーザーの1〜5の評価を設定します。ratingが0の場合は、評価を取り消します。
+func RateLesson(request *http.Request, lessonID int64, rating int) (domain.LessonReaction, error) {
+	ctx := request.Context()
+
+	currentUser, lesson, err := currentUserAndPublicLesson(ctx, request, lessonID)
+	if err != nil {
+		return domain.LessonReaction{}, err
+	}
+
+	reaction, err := repositories.LessonReaction.Rate(ctx, &lesson, currentUser.ID, rating)
+	if err != nil {
+		return reaction, err
+	}
+
+	// 加算に失敗してもいいねと評価は保存済みで、増分は次回の変更時か集計時に改めて加算される
+	if err := repositories.LessonReaction.ApplyPending(ctx, &reaction, infrastructure.CurrentConfig().CountsLessonReactions); err != nil {
+		log.Printf("failed to apply pending lesson reaction. %v\n", err)
+	}
+
+	return reaction, nil
+}
+
+// AggregateLessonReactionsは、加算されずに残ったいいねと評価の増分を加算し、Redisに格納された増分をLessonへ反映して、反映したLessonの数を返します。
+func AggregateLessonReactions(ctx context.Context) (int, error) {
+	buffered := infrastructure.CurrentConfig().CountsLessonReactions
+	if _, err := repositories.LessonReaction.ApplyAllPending(ctx, buffered); err != nil {
+		return 0, err
+	}
+
+	if !buffered {
+		return 0, nil
+	}
+
+	return repositories.LessonReaction.Aggregate(ctx)
+}
@@ -0,0 +1,111 @@
+package usecase
+
+import (
+	"net/http"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+// LessonReviewRequestParamsは、レビューの依頼時、リクエストボディをbindするために使用されます。
+type LessonReviewRequestParams struct {
+	ReviewerUserIDs []int64 `json:"reviewerUserIDs"`
+}
+
+// LessonReviewCommentParamsは、レビューのコメントの追加時、リクエストボディをbindするために使用されます。
+type LessonReviewCommentParams struct {
+	ElapsedTime float32 `json:"elapsedTime"`
+	Body        string  `json:"body"`
+}
+
+// LessonReviewSubmitParamsは、レビューの承認または修正依頼の際、リクエストボディをbindするために使用されます。
+type LessonReviewSubmitParams struct {
+	Status  domain.LessonReviewStatus `json:"status"`
+	Comment string                    `json:"comment"`
+}
+
+// LessonForReviewは、レビュアーが参照する下書きのLessonと教材、そのレビュアーのレビューです。
+// レビュアーは共同編集者ではないので、教材の再生に使うGraphicは署名付きURLとともに返します。
+type LessonForReview struct {
+	Lesson   domain.Lesson         `json:"lesson"`
+	Material domain.LessonMaterial `json:"material"`
+	Graphics []*domain.Graphic     `json:"graphics"`
+	Review   domain.LessonReview   `json:"review"`
+}
+
+// RequestLessonReviewsは、現在のユーザーのLessonのレビューを依頼し、レビュアーに共有するキーを返します。
+func RequestLessonReviews(request *http.Request, lessonID int64, params *LessonReviewRequestParams) ([]domain.LessonReviewLink, error) {
+	ctx := request.Context()
+
+	if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleOwner); err != nil {
+		return nil, err
+	}
+
+	return repositories.LessonReview.Request(ctx, lessonID, params.ReviewerUserIDs)
+}
+
+// CancelLessonReviewは、現在のユーザーのLessonのレビューの依頼を取り消します。
+func CancelLessonReview(request *http.Request, lessonID int64, reviewerUserID int64) error {
+	ctx := request.Context()
+
+	if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleOwner); err != nil {
+		return err
+	}
+
+	return repositories.LessonReview.Cancel(ctx, lessonID, reviewerUserID)
+}
+
+// GetLessonForReviewは、レビューを依頼された現在のユーザーに、下書きのLessonと教材を返します。
+func GetLessonForReview(request *http.Request, lessonID int64, reviewKey string) (LessonForReview, error) {
+	ctx := request.Context()
+
+	var lessonForReview LessonForReview
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return lessonForReview, err
+	}
+
+	lesson, review, err := repositories.LessonReview.GetForReviewer(ctx, lessonID, currentUser.ID, reviewKey)
+	if err != nil {
+		return lessonForReview, err
+	}
+
+	var material domain.LessonMaterial
+	if err := repositories.LessonMaterial.Get(ctx, lesson.MaterialID, lessonID, &material); err != nil {
+		return lessonForReview, err
+	}
+
+	graphics := []*domain.Graphic{}
+	if err := repositories.Graphic.GetByLessonID(ctx, lessonID, &graphics); err != nil && err != domain.GraphicNotFound {
+		return lessonForReview, err
+	}
+
+	// 他のレビュアーのレビューは含めない
+	lesson.Reviews = nil
+	lessonForReview.Lesson = lesson
+	lessonForReview.Material = material
+	lessonForReview.Graphics = graphics
+	lessonForReview.Review = review
+
+	return lessonForReview, nil
+}
+
+// AddLessonReviewCommentは、レビューを依頼された現在のユーザーのコメントを追加します。
+func AddLessonReviewComment(request *http.Request, lessonID int64, reviewKey string, params *LessonReviewCommentParams) (domain.LessonReview, error) {
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return domain.LessonReview{}, err
+	}
+
+	comment := domain.LessonReviewComment{ElapsedTime: params.ElapsedTime, Body: params.Body}
+	return repositories.LessonReview.AddComment(request.Context(), lessonID, currentUser.ID, reviewKey, comment)
+}
+
+// SubmitLessonReviewは、レビューを依頼された現在のユーザーによる承認または修正依頼を記録します。
+func SubmitLessonReview(request *http.Request, lessonID int64, reviewKey string, params *LessonReviewSubmitParams) (domain.LessonReview, error) {
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return domain.LessonReview{}, err
+	}
+
+	return repositories.LessonReview.Submit(request.Context(), lessonID, currentUser.ID, reviewKey, params.Status, params.Comment)
+}
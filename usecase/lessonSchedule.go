@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"cloud.google.com/go/datastore"
+	"github.com/super-dog-human/teraconnectgo/domain"
+	"github.com/super-dog-human/teraconnectgo/infrastructure"
+)
+
+const lessonSchedulePageSize = 100
+
+// LessonScheduleParamsは、Lessonの公開予約の設定時、リクエストボディをbindするために使用されます。
+type LessonScheduleParams struct {
+	PublishAt   *time.Time `json:"publishAt"`
+	UnpublishAt *time.Time `json:"unpublishAt"`
+}
+
+// GetCurrentUserLessonSchedulesは、現在のユーザーの未実行の予約を実行日時の早い順に返します。
+func GetCurrentUserLessonSchedules(request *http.Request) ([]domain.LessonSchedule, error) {
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return repositories.LessonSchedule.GetByUserID(request.Context(), currentUser.ID)
+}
+
+// SetLessonScheduleは、現在のユーザーのLessonの公開と公開終了の予約を置き換えます。自己紹介のLessonは予約できません。
+func SetLessonSchedule(request *http.Request, lessonID int64, params *LessonScheduleParams) (domain.LessonSchedule, error) {
+	ctx := request.Context()
+
+	var schedule domain.LessonSchedule
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return schedule, err
+	}
+
+	lesson, err := repositories.Lesson.GetByID(ctx, lessonID)
+	if err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return schedule, LessonNotFound
+		}
+		return schedule, err
+	}
+
+	if lesson.UserID != currentUser.ID {
+		return schedule, LessonNotAvailable
+	}
+	if lesson.IsIntroduction {
+		return schedule, domain.InvalidLessonSchedule
+	}
+
+	schedule.LessonID = lessonID
+	schedule.UserID = currentUser.ID
+	if params.PublishAt != nil {
+		schedule.PublishAt = *params.PublishAt
+	}
+	if params.UnpublishAt != nil {
+		schedule.UnpublishAt = *params.UnpublishAt
+	}
+
+	if err := repositories.LessonSchedule.Set(ctx, &schedule); err != nil {
+		return schedule, err
+	}
+
+	return schedule, nil
+}
+
+// CancelLessonScheduleは、現在のユーザーのLessonの予約を取り消します。
+func CancelLessonSchedule(request *http.Request, lessonID int64) error {
+	ctx := request.Context()
+
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return err
+	}
+
+	schedule, err := repositories.LessonSchedule.GetByLessonID(ctx, lessonID)
+	if err != nil {
+		return err
+	}
+
+	if schedule.UserID != currentUser.ID {
+		return LessonNotAvailable
+	}
+
+	return repositories.LessonSchedule.Delete(ctx, lessonID)
+}
+
+// ProcessLessonSchedulesは、実行日時を過ぎた予約がなくなるまで処理し、実行した件数を返します。
+// 失敗した予約は残して次の実行で再試行するので、他の予約の処理は続けます。
+func ProcessLessonSchedules(ctx context.Context) (int, error) {
+	processed := 0
+	failedIDs := make(map[int64]bool)
+	var firstErr error
+
+	for {
+		schedules, err := repositories.LessonSchedule.GetDue(ctx, time.Now(), lessonSchedulePageSize+len(failedIDs))
+		if err != nil {
+			return processed, err
+		}
+
+		remaining := 0
+		for i := range schedules {
+			schedule := &schedules[i]
+			if failedIDs[schedule.LessonID] {
+				continue
+			}
+			remaining++
+
+			if err := runLessonSchedule(ctx, schedule); err != nil {
+				failedIDs[schedule.LessonID] = true
+				if firstErr == nil {
+					firstErr = err
+				}
+				if recordErr := repositories.LessonSchedule.RecordError(ctx, schedule.LessonID, err); recordErr != nil {
+					return processed, recordErr
+				}
+				continue
+			}
+			processed++
+		}
+
+		// 公開と公開終了の両方が過ぎていた予約は、公開の実行後に再度取得されるので、なくなるまで繰り返す
+		if remaining == 0 {
+			break
+		}
+	}
+
+	return processed, firstErr
+}
+
+// ProcessLessonSchedulesTaskは、LessonScheduleQueueのタスクを処理します。
+func ProcessLessonSchedulesTask(ctx context.Context, task infrastructure.Task) error {
+	_, err := ProcessLessonSchedules(ctx)
+	return err
+}
+
+// runLessonScheduleは、予約されたLessonの状態の変更を、作者が編集画面から変更した場合と同じ手順で実行します。
+// 既に予約どおりの状態であるか、Lessonが削除されていた場合は何もせずに予約を完了します。
+func runLessonSchedule(ctx context.Context, schedule *domain.LessonSchedule) error {
+	status, runAt, ok := schedule.Due(time.Now())
+	if !ok {
+		return nil
+	}
+
+	lesson, err := repositories.Lesson.GetByID(ctx, schedule.LessonID)
+	if err != nil && err != datastore.ErrNoSuchEntity {
+		return err
+	}
+
+	if err == nil && lesson.Status != status {
+		user, err := repositories.User.GetByID(ctx, lesson.UserID)
+		if err != nil {
+			return err
+		}
+
+		requestID, err := domain.UUIDWithoutHypen()
+		if err != nil {
+			return err
+		}
+
+		params := map[string]interface{}{"status": status.String()}
+		lessonFields := []string{"Status"}
+		lessonMaterialFields := []string{}
+		// 予約後にLessonMaterialが変更されている場合があるので、編集画面からの公開と同じく検証される
+		if err := repositories.Lesson.UpdateWithMaterial(ctx, &user, &lesson, lesson.HasThumbnail, true, requestID, &params, &lessonFields, &lessonMaterialFields); err != nil {
+			if err == domain.LessonMaterialNotPublishable {
+				return LessonMaterialHasErrors
+			}
+			return err
+		}
+	}
+
+	return repositories.LessonSchedule.Complete(ctx, schedule.LessonID, runAt)
+}
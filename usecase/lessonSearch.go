@@ -0,0 +1,10 @@
+package usecase
+
+import (
+	"context"
+)
+
+// ReindexLessonsは、公開中の全てのLessonで検索インデックスを作り直し、登録した件数を返します。
+func ReindexLessons(ctx context.Context) (int, error) {
+	return repositories.LessonSearch.Reindex(ctx)
+}
@@ -9,6 +9,11 @@ import (
 // CreateLessonThumbnailBlankFile is create blank image file to public or private bucket.
 func CreateLessonThumbnailBlankFile(request *http.Request, isPublic bool, id int64) (string, error) {
 	ctx := request.Context()
+
+	if _, err := currentUserAccessToLesson(ctx, request, id, domain.LessonRoleEditor); err != nil {
+		return "", err
+	}
+
 	url, err := domain.CreateLessonThumbnailBlankFile(ctx, id, isPublic)
 	if err != nil {
 		return "", err
@@ -0,0 +1,55 @@
+package usecase
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+// GetTrashedLessonsは、現在のユーザーのゴミ箱にあるLessonを、ゴミ箱に移動した日時の新しい順に返します。
+func GetTrashedLessons(request *http.Request) ([]domain.Lesson, error) {
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return repositories.LessonTrash.GetByUserID(request.Context(), currentUser.ID)
+}
+
+// RestoreLessonは、現在のユーザーのゴミ箱にあるLessonを、ゴミ箱に移動する前の状態に戻します。
+// Seriesからは外れたままなので、必要であれば改めて追加します。
+func RestoreLesson(request *http.Request, id int64) (domain.Lesson, error) {
+	ctx := request.Context()
+
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return domain.Lesson{}, err
+	}
+
+	lesson, err := repositories.LessonTrash.GetByID(ctx, id)
+	if err != nil {
+		return domain.Lesson{}, err
+	}
+
+	if currentUser.ID != lesson.UserID {
+		return domain.Lesson{}, LessonNotAvailable
+	}
+
+	if lesson, err = repositories.LessonTrash.Restore(ctx, id, time.Now()); err != nil {
+		return lesson, err
+	}
+
+	if lesson.Status == domain.LessonStatusPublic {
+		// Lessonは復元済みなので、Tagの件数と検索インデックスの更新に失敗しても復元は成功とする
+		if err := repositories.Tag.Apply(ctx, nil, lesson.Tags); err != nil {
+			log.Printf("failed to update tags of restored lesson. %v\n", err)
+		}
+		if err := repositories.LessonSearch.Upsert(ctx, &lesson); err != nil {
+			log.Printf("failed to index restored lesson. %v\n", err)
+		}
+	}
+
+	return lesson, nil
+}
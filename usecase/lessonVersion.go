@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"net/http"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+// GetLessonVersionsは、現在のユーザーのLessonの公開履歴を新しい順に返します。
+func GetLessonVersions(request *http.Request, lessonID int64) ([]domain.ShortLessonVersion, error) {
+	ctx := request.Context()
+
+	if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleViewer); err != nil {
+		return nil, err
+	}
+
+	return repositories.LessonVersion.GetByLessonID(ctx, lessonID)
+}
+
+// GetLessonVersionは、現在のユーザーのLessonの指定したバージョンを、再生用のURLと教材のスナップショットを含めて返します。
+func GetLessonVersion(request *http.Request, lessonID int64, version int32) (domain.LessonVersion, error) {
+	ctx := request.Context()
+
+	var lessonVersion domain.LessonVersion
+	if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleViewer); err != nil {
+		return lessonVersion, err
+	}
+
+	lessonVersion, err := repositories.LessonVersion.Get(ctx, lessonID, version)
+	if err != nil {
+		return lessonVersion, err
+	}
+
+	speechURL, bodyURL, err := lessonResourceURLs(ctx, lessonID, version, lessonVersion.Status)
+	if err != nil {
+		return lessonVersion, err
+	}
+	lessonVersion.SpeechURL = speechURL
+	lessonVersion.BodyURL = bodyURL
+
+	return lessonVersion, nil
+}
+
+// RollbackLessonは、現在のユーザーのLessonを指定したバージョンの内容で公開し直し、更新後のLessonを返します。
+func RollbackLesson(request *http.Request, lessonID int64, version int32) (domain.Lesson, error) {
+	ctx := request.Context()
+
+	lesson, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleOwner)
+	if err != nil {
+		return lesson, err
+	}
+
+	if err := repositories.LessonVersion.Rollback(ctx, &lesson, version); err != nil {
+		return lesson, err
+	}
+
+	return lesson, nil
+}
@@ -1,6 +1,7 @@
 package usecase
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/super-dog-human/teraconnectgo/domain"
@@ -11,7 +12,7 @@ import (
 func UpdateLessonViewCount(request *http.Request, lessonID int64) error {
 	ctx := request.Context()
 
-	if infrastructure.AppEnv() == "production" {
+	if infrastructure.CurrentConfig().CountsLessonViews {
 		if err := domain.IncrementLessonViewCount(ctx, lessonID); err != nil {
 			return err
 		}
@@ -19,3 +20,18 @@ func UpdateLessonViewCount(request *http.Request, lessonID int64) error {
 
 	return nil
 }
+
+// AggregateLessonViewCountsは、Redisに格納された参照回数をLessonとUser、ShareLinkへ反映し、反映したLessonとShareLinkの数を返します。
+func AggregateLessonViewCounts(ctx context.Context) (int, int, error) {
+	lessonCount, err := repositories.LessonViewCount.Aggregate(ctx)
+	if err != nil {
+		return lessonCount, 0, err
+	}
+
+	shareLinkCount, err := repositories.ShareLink.AggregateAccess(ctx)
+	if err != nil {
+		return lessonCount, shareLinkCount, err
+	}
+
+	return lessonCount, shareLinkCount, nil
+}
@@ -0,0 +1,12 @@
+package usecase
+
+import (
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+var repositories domain.Repositories
+
+// SetRepositoriesは、usecaseが使用するリポジトリを設定します。起動時に一度だけ呼び出されることを想定しています。
+func SetRepositories(r domain.Repositories) {
+	repositories = r
+}
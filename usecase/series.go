@@ -0,0 +1,201 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+type SeriesErrorCode uint
+
+const (
+	SeriesNotAvailable  SeriesErrorCode = 1
+	InvalidSeriesParams SeriesErrorCode = 2
+)
+
+func (e SeriesErrorCode) Error() string {
+	switch e {
+	case SeriesNotAvailable:
+		return "series not available"
+	case InvalidSeriesParams:
+		return "invalid series params"
+	default:
+		return "unknown series error"
+	}
+}
+
+// NewSeriesParamsは、Seriesの新規作成時、リクエストボディをbindするために使用されます。
+type NewSeriesParams struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+}
+
+// SeriesLessonsParamsは、SeriesのLessonの並びを置き換える際、リクエストボディをbindするために使用されます。
+type SeriesLessonsParams struct {
+	LessonIDs []int64 `json:"lessonIDs"`
+}
+
+// GetPublicSeriesは、Seriesを公開中のLessonのみを含めて返します。
+func GetPublicSeries(request *http.Request, id int64) (domain.Series, error) {
+	ctx := request.Context()
+
+	series, err := repositories.Series.GetByID(ctx, id)
+	if err != nil {
+		return series, err
+	}
+
+	if err := setPublicSeriesLessons(ctx, &series); err != nil {
+		return series, err
+	}
+
+	return series, nil
+}
+
+// GetPublicSeriesByUserは、Userの公開中のLessonを含むSeriesを返します。
+func GetPublicSeriesByUser(request *http.Request, userID int64) ([]domain.Series, error) {
+	ctx := request.Context()
+
+	seriesList, err := repositories.Series.GetByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var publicSeriesList []domain.Series
+	for _, series := range seriesList {
+		if err := setPublicSeriesLessons(ctx, &series); err != nil {
+			return nil, err
+		}
+		if len(series.LessonIDs) > 0 {
+			publicSeriesList = append(publicSeriesList, series)
+		}
+	}
+
+	return publicSeriesList, nil
+}
+
+// GetCurrentUserSeriesは、現在のユーザーの全てのSeriesを返します。
+func GetCurrentUserSeries(request *http.Request) ([]domain.Series, error) {
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return repositories.Series.GetByUserID(request.Context(), currentUser.ID)
+}
+
+// CreateSeriesは、現在のユーザーのSeriesを作成します。Lessonは空の状態で作成されます。
+func CreateSeries(request *http.Request, params *NewSeriesParams) (domain.Series, error) {
+	var series domain.Series
+
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return series, err
+	}
+
+	series.UserID = currentUser.ID
+	series.Title = params.Title
+	series.Description = params.Description
+
+	if err := repositories.Series.Create(request.Context(), &series); err != nil {
+		return series, err
+	}
+
+	return series, nil
+}
+
+// UpdateSeriesは、現在のユーザーのSeriesのタイトル、説明、サムネイルの有無を更新します。
+func UpdateSeries(request *http.Request, id int64, params *map[string]interface{}) (domain.Series, error) {
+	ctx := request.Context()
+
+	series, err := getCurrentUsersSeries(ctx, request, id)
+	if err != nil {
+		return series, err
+	}
+
+	seriesFields := []string{"Title", "Description", "HasThumbnail"}
+	domain.MergeJsonToStruct(params, &series, &seriesFields)
+	if series.Title == "" {
+		return series, InvalidSeriesParams
+	}
+
+	if err := repositories.Series.Update(ctx, &series); err != nil {
+		return series, err
+	}
+
+	return series, nil
+}
+
+// UpdateSeriesLessonsは、現在のユーザーのSeriesのLessonをlessonIDsの順序で置き換えます。
+func UpdateSeriesLessons(request *http.Request, id int64, lessonIDs []int64) (domain.Series, error) {
+	ctx := request.Context()
+
+	series, err := getCurrentUsersSeries(ctx, request, id)
+	if err != nil {
+		return series, err
+	}
+
+	if err := repositories.Series.SetLessons(ctx, &series, lessonIDs); err != nil {
+		return series, err
+	}
+
+	return series, nil
+}
+
+// DeleteSeriesは、現在のユーザーのSeriesを削除します。所属していたLessonは削除されません。
+func DeleteSeries(request *http.Request, id int64) error {
+	ctx := request.Context()
+
+	series, err := getCurrentUsersSeries(ctx, request, id)
+	if err != nil {
+		return err
+	}
+
+	return repositories.Series.Delete(ctx, &series)
+}
+
+// CreateSeriesThumbnailBlankFileは、現在のユーザーのSeriesのサムネイルのアップロード先を作成します。
+func CreateSeriesThumbnailBlankFile(request *http.Request, id int64) (string, error) {
+	ctx := request.Context()
+
+	if _, err := getCurrentUsersSeries(ctx, request, id); err != nil {
+		return "", err
+	}
+
+	return domain.CreateSeriesThumbnailBlankFile(ctx, id)
+}
+
+func getCurrentUsersSeries(ctx context.Context, request *http.Request, id int64) (domain.Series, error) {
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return domain.Series{}, err
+	}
+
+	series, err := repositories.Series.GetByID(ctx, id)
+	if err != nil {
+		return series, err
+	}
+
+	if series.UserID != currentUser.ID {
+		return series, SeriesNotAvailable
+	}
+
+	return series, nil
+}
+
+// setPublicSeriesLessonsは、Seriesの公開中のLessonのみをLessonsとLessonIDsに設定します。非公開のLessonのIDは公開しません。
+func setPublicSeriesLessons(ctx context.Context, series *domain.Series) error {
+	lessons, err := repositories.Series.GetPublicLessons(ctx, series)
+	if err != nil {
+		return err
+	}
+
+	series.LessonIDs = make([]int64, len(lessons))
+	series.Lessons = make([]domain.ShortLesson, len(lessons))
+	for i, lesson := range lessons {
+		series.LessonIDs[i] = lesson.ID
+		series.Lessons[i] = domain.ShortLesson{ID: lesson.ID, UserID: lesson.UserID, Title: lesson.Title, Description: lesson.Description}
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+// ShareLinkParamsは、ShareLinkの作成時、リクエストボディをbindするために使用されます。
+type ShareLinkParams struct {
+	Label     string     `json:"label"`
+	ExpiresAt *time.Time `json:"expiresAt"`
+	MaxViews  int64      `json:"maxViews"`
+}
+
+// GetShareLinksは、現在のユーザーのLessonのShareLinkを、取り消し済みのものと参照回数を含めて返します。
+func GetShareLinks(request *http.Request, lessonID int64) ([]domain.ShareLink, error) {
+	ctx := request.Context()
+
+	lesson, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleOwner)
+	if err != nil {
+		return nil, err
+	}
+
+	return repositories.ShareLink.GetByLesson(ctx, &lesson)
+}
+
+// CreateShareLinkは、現在のユーザーのLessonにShareLinkを作成します。リンクはLessonが限定公開の間のみ使用できます。
+func CreateShareLink(request *http.Request, lessonID int64, params *ShareLinkParams) (domain.ShareLink, error) {
+	ctx := request.Context()
+
+	link := domain.ShareLink{Label: params.Label, MaxViews: params.MaxViews}
+	if params.ExpiresAt != nil {
+		link.ExpiresAt = *params.ExpiresAt
+	}
+
+	lesson, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleOwner)
+	if err != nil {
+		return link, err
+	}
+	if lesson.IsIntroduction {
+		return link, domain.InvalidShareLink
+	}
+
+	if err := repositories.ShareLink.Create(ctx, lessonID, &link); err != nil {
+		return link, err
+	}
+
+	return link, nil
+}
+
+// RevokeShareLinkは、現在のユーザーのLessonのShareLinkを取り消します。
+func RevokeShareLink(request *http.Request, lessonID int64, key string) (domain.ShareLink, error) {
+	ctx := request.Context()
+
+	if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleOwner); err != nil {
+		return domain.ShareLink{}, err
+	}
+
+	return repositories.ShareLink.Revoke(ctx, lessonID, key)
+}
@@ -9,5 +9,5 @@ import (
 // GetSubjects for fetch avatar object from Cloud Datastore
 func GetSubjects(request *http.Request) ([]domain.Subject, error) {
 	ctx := request.Context()
-	return domain.GetAllSubjects(ctx)
+	return repositories.Subject.GetAll(ctx)
 }
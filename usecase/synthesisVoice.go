@@ -14,16 +14,16 @@ func CreateSynthesisVoice(request *http.Request, params *domain.CreateSynthesisV
 		IsSynthesis: true,
 	}
 
-	userID, err := currentUserAccessToLesson(ctx, request, params.LessonID)
+	lesson, err := currentUserAccessToLesson(ctx, request, params.LessonID, domain.LessonRoleEditor)
 	if err != nil {
 		return voice, err
 	}
 
-	voice.UserID = userID
+	voice.UserID = lesson.UserID
 	voice.LessonID = params.LessonID
 
 	// ID採番のためだけにVoiceを作成する
-	if err = domain.CreateVoice(ctx, &voice); err != nil {
+	if err = repositories.Voice.Create(ctx, &voice); err != nil {
 		return voice, err
 	}
 
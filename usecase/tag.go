@@ -0,0 +1,31 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/super-dog-human/teraconnectgo/domain"
+)
+
+const tagSuggestionCount = 10
+
+// SearchTagsは、prefixで始まるTagを、入力補完の候補として公開中のLessonの多い順に返します。
+func SearchTags(request *http.Request, prefix string) ([]domain.Tag, error) {
+	return repositories.Tag.Search(request.Context(), prefix, tagSuggestionCount)
+}
+
+// RebuildLessonListsは、公開中の全てのLessonを一覧の絞り込みと並べ替えに使用できるよう保存し直し、Tagを作り直します。
+// 保存したLessonの件数とTagの件数を返します。
+func RebuildLessonLists(ctx context.Context) (int, int, error) {
+	lessonCount, err := repositories.Lesson.RefreshPublicList(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	tagCount, err := repositories.Tag.Rebuild(ctx)
+	if err != nil {
+		return lessonCount, 0, err
+	}
+
+	return lessonCount, tagCount, nil
+}
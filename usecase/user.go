@@ -4,7 +4,6 @@ import (
 	"net/http"
 	"time"
 
-	"cloud.google.com/go/datastore"
 	"github.com/jinzhu/copier"
 	"github.com/super-dog-human/teraconnectgo/domain"
 	"github.com/super-dog-human/teraconnectgo/infrastructure"
@@ -41,7 +40,7 @@ type NewUserParams struct {
 // GetCurrentUser for fetch current user account
 func GetCurrentUser(request *http.Request) (domain.User, error) {
 	var currentUser domain.User
-	currentUser, err := domain.GetCurrentUser(request)
+	currentUser, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return currentUser, err
 	}
@@ -54,7 +53,7 @@ func GetUser(request *http.Request, id int64) (domain.User, error) {
 	ctx := request.Context()
 	var user domain.User
 
-	user, err := domain.GetUserByID(ctx, id)
+	user, err := repositories.User.GetByID(ctx, id)
 	if err != nil {
 		return user, err
 	}
@@ -66,7 +65,7 @@ func GetUser(request *http.Request, id int64) (domain.User, error) {
 func GetUsers(request *http.Request, cursorStr string) ([]domain.User, string, error) {
 	ctx := request.Context()
 
-	users, nextCursorStr, err := domain.GetUsers(ctx, cursorStr)
+	users, nextCursorStr, err := repositories.User.GetList(ctx, cursorStr)
 	if err != nil {
 		return nil, "", err
 	}
@@ -85,18 +84,13 @@ func CreateUser(request *http.Request, newUser *NewUserParams) error {
 	var user domain.User
 	copier.Copy(&user, &newUser)
 
-	client, err := datastore.NewClient(ctx, infrastructure.ProjectID())
-	if err != nil {
-		return err
-	}
-
 	providerID, err := domain.ProviderID(request)
 	if err != nil {
 		return err
 	}
 	user.ProviderID = providerID
 
-	backgroundImages, err := domain.GetAllBackgroundImages(ctx)
+	backgroundImages, err := repositories.BackgroundImage.GetAll(ctx)
 	if err != nil {
 		return err
 	}
@@ -113,8 +107,8 @@ func CreateUser(request *http.Request, newUser *NewUserParams) error {
 	}
 	user.BackgroundImageID = backgroundImage.ID
 
-	_, err = client.RunInTransaction(ctx, func(tx *datastore.Transaction) error {
-		err = domain.ReserveUserProviderIDInTransaction(tx, providerID)
+	err = repositories.Transaction.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		err = repositories.User.ReserveProviderIDInTransaction(tx, providerID)
 		if err == domain.AlreadyProviderIDExists {
 			return AlreadyUserExists
 		}
@@ -122,7 +116,7 @@ func CreateUser(request *http.Request, newUser *NewUserParams) error {
 			return err
 		}
 
-		if _, err = domain.CreateUserInTransaction(tx, &user); err != nil {
+		if err = repositories.User.CreateInTransaction(tx, &user); err != nil {
 			return err
 		}
 
@@ -139,13 +133,13 @@ func CreateUser(request *http.Request, newUser *NewUserParams) error {
 func UpdateUser(request *http.Request, params *map[string]interface{}) (domain.User, error) {
 	ctx := request.Context()
 
-	user, err := domain.GetCurrentUser(request)
+	user, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return user, UserNotAvailable
 	}
 
 	targetFields := []string{"Name", "Profile", "Email"}
-	if err = domain.UpdateUserByJson(ctx, &user, params, &targetFields); err != nil {
+	if err = repositories.User.UpdateByJson(ctx, &user, params, &targetFields); err != nil {
 		return user, err
 	}
 
@@ -155,14 +149,28 @@ func UpdateUser(request *http.Request, params *map[string]interface{}) (domain.U
 func UnsubscribeCurrentUser(request *http.Request) error {
 	ctx := request.Context()
 
-	currentUser, err := domain.GetCurrentUser(request)
+	currentUser, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return err
 	}
 
-	if err = domain.DeleteUser(ctx, currentUser.ID); err != nil {
+	err = repositories.Transaction.RunInTransaction(ctx, func(tx infrastructure.Transaction) error {
+		if err := repositories.User.DeleteInTransaction(tx, currentUser.ID); err != nil {
+			return err
+		}
+
+		if err := repositories.DeleteOrder.CreateUserOrderInTransaction(tx, currentUser.ID); err != nil {
+			return err
+		}
+
+		return nil
+	})
+
+	if err != nil {
 		return err
 	}
 
+	enqueueDeleteOrderTask(ctx)
+
 	return nil
 }
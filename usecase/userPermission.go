@@ -4,23 +4,77 @@ import (
 	"context"
 	"net/http"
 
+	"cloud.google.com/go/datastore"
 	"github.com/super-dog-human/teraconnectgo/domain"
 )
 
-func currentUserAccessToLesson(ctx context.Context, request *http.Request, lessonID int64) (int64, error) {
-	currentUser, err := domain.GetCurrentUser(request)
+// currentUserAccessToLessonは、現在のユーザーがLessonに対してrequiredの権限を持つ場合にLessonを返します。
+// 共同編集者が作成するGraphicやVoiceも作成者のものとして保存するので、所有者にはLesson.UserIDを使用します。
+func currentUserAccessToLesson(ctx context.Context, request *http.Request, lessonID int64, required domain.LessonRole) (domain.Lesson, error) {
+	_, lesson, role, err := currentUserRoleForLesson(ctx, request, lessonID)
 	if err != nil {
-		return currentUser.ID, err
+		return lesson, err
 	}
 
-	lesson, err := domain.GetLessonByID(ctx, lessonID)
+	if !role.Includes(required) {
+		return lesson, LessonNotAvailable
+	}
+
+	return lesson, nil
+}
+
+// currentUserRoleForLessonは、現在のユーザーとLesson、Lessonに対する現在のユーザーの権限を返します。
+func currentUserRoleForLesson(ctx context.Context, request *http.Request, lessonID int64) (domain.User, domain.Lesson, domain.LessonRole, error) {
+	var lesson domain.Lesson
+
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return currentUser, lesson, domain.LessonRoleNone, err
+	}
+
+	lesson, err = repositories.Lesson.GetByID(ctx, lessonID)
+	if err != nil {
+		if err == datastore.ErrNoSuchEntity {
+			return currentUser, lesson, domain.LessonRoleNone, LessonNotFound
+		}
+		return currentUser, lesson, domain.LessonRoleNone, err
+	}
+
+	role, err := repositories.LessonCollaborator.GetRole(ctx, &lesson, currentUser.ID)
 	if err != nil {
-		return currentUser.ID, err
+		return currentUser, lesson, domain.LessonRoleNone, err
+	}
+
+	return currentUser, lesson, role, nil
+}
+
+// currentUserAndPublicLessonは、現在のユーザーと公開中のLessonを返します。
+func currentUserAndPublicLesson(ctx context.Context, request *http.Request, lessonID int64) (domain.User, domain.Lesson, error) {
+	currentUser, err := repositories.User.GetCurrent(request)
+	if err != nil {
+		return currentUser, domain.Lesson{}, err
+	}
+
+	lesson, err := getPublicLessonByID(ctx, lessonID)
+	if err != nil {
+		return currentUser, lesson, err
+	}
+
+	return currentUser, lesson, nil
+}
+
+// getPublicLessonByIDは、公開中のLessonを返します。限定公開や下書きの場合はLessonNotAvailableを返します。
+func getPublicLessonByID(ctx context.Context, lessonID int64) (domain.Lesson, error) {
+	lesson, err := repositories.Lesson.GetByID(ctx, lessonID)
+	if err == datastore.ErrNoSuchEntity {
+		return lesson, LessonNotFound
+	} else if err != nil {
+		return lesson, err
 	}
 
-	if lesson.UserID != currentUser.ID {
-		return currentUser.ID, LessonNotAvailable
+	if lesson.Status != domain.LessonStatusPublic {
+		return lesson, LessonNotAvailable
 	}
 
-	return currentUser.ID, nil
+	return lesson, nil
 }
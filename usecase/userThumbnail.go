@@ -8,7 +8,7 @@ import (
 
 // CreateLessonThumbnailBlankFile is create blank image file.
 func CreateUserThumbnailBlankFile(request *http.Request) (string, error) {
-	user, err := domain.GetCurrentUser(request)
+	user, err := repositories.User.GetCurrent(request)
 	if err != nil {
 		return "", UserNotAvailable
 	}
@@ -19,11 +19,11 @@ func GetVoices(request *http.Request, lessonID int64) ([]domain.Voice, error) {
 
 	var voices []domain.Voice
 
-	if _, err := currentUserAccessToLesson(ctx, request, lessonID); err != nil {
+	if _, err := currentUserAccessToLesson(ctx, request, lessonID, domain.LessonRoleViewer); err != nil {
 		return nil, err
 	}
 
-	if err := domain.GetVoices(ctx, lessonID, &voices); err != nil {
+	if err := repositories.Voice.GetByLessonID(ctx, lessonID, &voices); err != nil {
 		return nil, err
 	}
 
@@ -36,17 +36,18 @@ func CreateVoiceAndBlankFile(request *http.Request, params *CreateVoiceParam) (d
 
 	var voice domain.Voice
 
-	userID, err := currentUserAccessToLesson(ctx, request, params.LessonID)
+	lesson, err := currentUserAccessToLesson(ctx, request, params.LessonID, domain.LessonRoleEditor)
 	if err != nil {
 		return voice, "", err
 	}
 
-	voice.UserID = userID
+	// 共同編集者が録音したVoiceも、Lessonの作成者のものとして保存する
+	voice.UserID = lesson.UserID
 	voice.LessonID = params.LessonID
 	voice.ElapsedTime = params.ElapsedTime
 	voice.DurationSec = params.DurationSec
 
-	if err = domain.CreateVoice(ctx, &voice); err != nil {
+	if err = repositories.Voice.Create(ctx, &voice); err != nil {
 		return voice, "", err
 	}
 
@@ -61,7 +62,7 @@ func CreateVoiceAndBlankFile(request *http.Request, params *CreateVoiceParam) (d
 	}
 
 	filePath := lessonID + "/" + fileName
-	mp3URL, err := infrastructure.CreateBlankFileToPublicGCS(ctx, filePath, "voice", mp3FileRequest)
+	mp3URL, err := infrastructure.CreateBlankFileToPublic(ctx, filePath, "voice", mp3FileRequest)
 	if err != nil {
 		return voice, mp3URL, err
 	}